@@ -5,7 +5,9 @@ import (
 	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"eth2-exporter/db"
+	"eth2-exporter/ens"
 	"eth2-exporter/erc20"
 	"eth2-exporter/rpc"
 	"eth2-exporter/services"
@@ -17,9 +19,12 @@ import (
 	"io/ioutil"
 	"math/big"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
 	"strings"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/coocood/freecache"
@@ -68,6 +73,7 @@ func main() {
 	configPath := flag.String("config", "", "Path to the config file, if empty string defaults will be used")
 
 	enableEnsUpdater := flag.Bool("ens.enabled", false, "Enable ens update process")
+	ensQueueSize := flag.Int("ens.queue.size", 0, "Size of the queue to validate freshly indexed ens keys right away, 0 disables the queue and keys are only validated by the ens update process")
 
 	flag.Parse()
 
@@ -189,8 +195,16 @@ func main() {
 		// }
 	}
 
-	transforms := make([]func(blk *types.Eth1Block, cache *freecache.Cache) (*types.BulkMutations, *types.BulkMutations, error), 0)
-	transforms = append(transforms,
+	err = ens.SetEventTopics(utils.Config.Indexer.EnsTransformer.EventTopics)
+	if err != nil {
+		utils.LogFatal(err, "error setting ens event topics", 0)
+	}
+
+	// with a confirmation depth the ens transformer does not run on the chain head but is indexed separately once blocks are deep enough,
+	// ranges given on the command line are indexed with all transformers
+	ensConfirmationDepth := utils.Config.Indexer.EnsTransformer.ConfirmationDepth
+	headTransforms := make([]func(blk *types.Eth1Block, cache *freecache.Cache) (*types.BulkMutations, *types.BulkMutations, error), 0)
+	headTransforms = append(headTransforms,
 		bt.TransformBlock,
 		bt.TransformTx,
 		bt.TransformItx,
@@ -198,8 +212,39 @@ func main() {
 		bt.TransformERC721,
 		bt.TransformERC1155,
 		bt.TransformUncle,
-		bt.TransformWithdrawals,
-		bt.TransformEnsNameRegistered)
+		bt.TransformWithdrawals)
+	transforms := make([]func(blk *types.Eth1Block, cache *freecache.Cache) (*types.BulkMutations, *types.BulkMutations, error), 0, len(headTransforms)+1)
+	transforms = append(transforms, headTransforms...)
+	transforms = append(transforms, bt.TransformEnsNameRegistered)
+	if ensConfirmationDepth == 0 {
+		headTransforms = append(headTransforms, bt.TransformEnsNameRegistered)
+	}
+
+	if ensConfirmationDepth > 0 && utils.Config.Indexer.EnsTransformer.ConfirmationStartBlock == 0 {
+		// the first run needs a block to start at, later runs continue after the stored block
+		lastIndexed, err := bt.GetEnsConfirmedBlock()
+		if err != nil {
+			utils.LogFatal(err, "error getting last confirmed ens block", 0)
+		}
+		if lastIndexed == 0 {
+			utils.LogFatal(fmt.Errorf("no confirmed ens block is stored"), "ens confirmation start block must be configured with a confirmation depth", 0)
+		}
+	}
+
+	if *enableEnsUpdater {
+		// ens events are written to postgres once their block is beyond the reach of the reorg handling
+		err = db.SetEnsReorgDepth(*reorgDepth)
+		if err != nil {
+			utils.LogFatal(err, "error setting ens reorg depth", 0)
+		}
+		// registrations stored before their chain was recorded belong to the chain of this deployment
+		err = db.BackfillEnsChainId(utils.Config.Chain.Config.DepositChainID)
+		if err != nil {
+			utils.LogFatal(err, "error backfilling ens chain id", 0)
+		}
+		// the indexing cache is cleared after every run, resolved ens names are cached separately so they span runs
+		db.SetEnsResolveCache(freecache.NewCache(10 * 1024 * 1024)) // 10 MB limit
+	}
 
 	cache := freecache.NewCache(100 * 1024 * 1024) // 100 MB limit
 
@@ -245,8 +290,50 @@ func main() {
 		return
 	}
 
+	// a SIGTERM stops the ens background loops, cancels the ens update run between two batches and stops the indexer once the current run completed
+	shutdownCtx, stopShutdown := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopShutdown()
+
+	if *enableEnsUpdater && *ensQueueSize > 0 {
+		ensQueue := db.NewEnsValidationQueue(*ensQueueSize)
+		bt.SetEnsValidationQueue(ensQueue)
+		go ensQueue.Run(shutdownCtx, 100, func(keys []string) error {
+			return bt.ValidateEnsKeys(client.GetNativeClient(), keys)
+		})
+	}
+
+	if *enableEnsUpdater && utils.Config.Indexer.EnsTransformer.RevalidationPeriod > 0 {
+		interval := utils.Config.Indexer.EnsTransformer.RevalidationInterval
+		if interval <= 0 {
+			interval = time.Hour
+		}
+		go bt.RequeueStaleEnsNames(shutdownCtx, utils.Config.Indexer.EnsTransformer.RevalidationPeriod, interval)
+	}
+
+	if *enableEnsUpdater && utils.Config.Indexer.EnsTransformer.ExpiryWindowDays > 0 {
+		interval := utils.Config.Indexer.EnsTransformer.RevalidationInterval
+		if interval <= 0 {
+			interval = time.Hour
+		}
+		go bt.MonitorExpiringEnsNames(shutdownCtx, utils.Config.Indexer.EnsTransformer.ExpiryWindowDays, interval)
+	}
+
+	if *enableEnsUpdater {
+		retentionDays := utils.Config.Indexer.EnsTransformer.AuditRetentionDays
+		if retentionDays <= 0 {
+			retentionDays = 30
+		}
+		go db.MonitorEnsValidationAudit(shutdownCtx, time.Hour*24*time.Duration(retentionDays), time.Hour)
+		// counting the backlog reads all dirty keys, so it is sampled far less often than the update runs
+		go bt.MonitorEnsDirtyKeys(shutdownCtx, time.Minute*10)
+	}
+
 	lastSuccessulBlockIndexingTs := time.Now()
 	for ; ; time.Sleep(time.Second * 14) {
+		if shutdownCtx.Err() != nil {
+			logrus.Infof("shutting down eth1indexer")
+			return
+		}
 		err := HandleChainReorgs(bt, client, *reorgDepth)
 		if err != nil {
 			logrus.Errorf("error handling chain reorgs: %v", err)
@@ -304,7 +391,7 @@ func main() {
 			// transforms = append(transforms, bt.TransformTx)
 
 			logrus.Infof("missing blocks %v to %v in data table, indexing ...", lastBlockFromDataTable, lastBlockFromNode)
-			err = bt.IndexEventsWithTransformers(int64(lastBlockFromDataTable)-*offsetData, int64(lastBlockFromNode), transforms, *concurrencyData, cache)
+			err = bt.IndexEventsWithTransformers(int64(lastBlockFromDataTable)-*offsetData, int64(lastBlockFromNode), headTransforms, *concurrencyData, cache)
 			if err != nil {
 				logrus.WithError(err).Errorf("error indexing from bigtable")
 				cache.Clear()
@@ -313,12 +400,26 @@ func main() {
 			cache.Clear()
 		}
 
+		if ensConfirmationDepth > 0 {
+			err = bt.IndexEnsConfirmedBlocks(lastBlockFromNode, *concurrencyData, cache)
+			if err != nil {
+				logrus.WithError(err).Errorf("error indexing confirmed ens blocks")
+				cache.Clear()
+				continue
+			}
+			cache.Clear()
+		}
+
 		if *enableBalanceUpdater {
 			ProcessMetadataUpdates(bt, client, balanceUpdaterPrefix, *balanceUpdaterBatchSize, 10)
 		}
 
 		if *enableEnsUpdater {
-			err := bt.ImportEnsUpdates(client.GetNativeClient())
+			err := bt.ImportEnsUpdates(shutdownCtx, client.GetNativeClient())
+			if errors.Is(err, context.Canceled) {
+				logrus.Infof("ens update run cancelled, shutting down eth1indexer")
+				return
+			}
 			if err != nil {
 				logrus.WithError(err).Errorf("error updating ens")
 				continue
@@ -359,6 +359,9 @@ func main() {
 		apiV1Router.HandleFunc("/validator/{indexOrPubkey}/widget", handlers.GetMobileWidgetStatsGet).Methods("GET")
 		apiV1Router.HandleFunc("/dashboard/widget", handlers.GetMobileWidgetStatsPost).Methods("POST")
 		apiV1Router.HandleFunc("/ens/lookup/{domain}", handlers.ResolveEnsDomain).Methods("GET", "OPTIONS")
+		apiV1Router.HandleFunc("/ens/lookup", handlers.ApiEnsLookupAddresses).Methods("POST", "OPTIONS")
+		apiV1Router.HandleFunc("/ens/reverse/{address}", handlers.ApiEnsReverseLookup).Methods("GET", "OPTIONS")
+		apiV1Router.HandleFunc("/ens/resolve/{name}", handlers.ApiEnsResolve).Methods("GET", "OPTIONS")
 		apiV1Router.Use(utils.CORSMiddleware)
 
 		apiV1AuthRouter := apiV1Router.PathPrefix("/user").Subrouter()
@@ -34,6 +34,7 @@ func main() {
 	bigtableInstance := flag.String("bigtable.instance", "", "Bigtable instance")
 
 	transformerFlag := flag.String("transformers", "", "Comma separated list of transformer functions")
+	ensBackfill := flag.Bool("ens.backfill", false, "Backfill the ens events of the block range, an interrupted backfill of the same range resumes where it stopped")
 
 	versionFlag := flag.Bool("version", false, "Print version and exit")
 
@@ -112,6 +113,19 @@ func main() {
 	}
 	defer bt.Close()
 
+	if *ensBackfill {
+		if *startBlock < 0 || *endBlock < *startBlock {
+			utils.LogFatal(nil, "invalid block range for the ens backfill", 0)
+		}
+		logrus.Infof("Starting to backfill ens blocks %d to %d", *startBlock, *endBlock)
+		err = bt.BackfillEns(context.Background(), client.GetNativeClient(), uint64(*startBlock), uint64(*endBlock))
+		if err != nil {
+			utils.LogFatal(err, "error backfilling ens", 0)
+		}
+		logrus.Infof("ens backfill completed")
+		return
+	}
+
 	transforms := make([]func(blk *types.Eth1Block, cache *freecache.Cache) (*types.BulkMutations, *types.BulkMutations, error), 0)
 
 	logrus.Infof("transformers: %v", transformerList)
@@ -156,7 +170,7 @@ func main() {
 		}
 		cache.Clear()
 		if importENSChanges {
-			if err = bt.ImportEnsUpdates(client.GetNativeClient()); err != nil {
+			if err = bt.ImportEnsUpdates(context.Background(), client.GetNativeClient()); err != nil {
 				utils.LogError(err, "error importing ens from events", 0)
 				return
 			}
@@ -197,7 +211,7 @@ func main() {
 	}
 
 	if importENSChanges {
-		if err = bt.ImportEnsUpdates(client.GetNativeClient()); err != nil {
+		if err = bt.ImportEnsUpdates(context.Background(), client.GetNativeClient()); err != nil {
 			utils.LogError(err, "error importing ens from events", 0)
 			return
 		}
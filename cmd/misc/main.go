@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"eth2-exporter/db"
 	"eth2-exporter/exporter"
 	"eth2-exporter/rpc"
@@ -10,8 +11,11 @@ import (
 	"eth2-exporter/version"
 	"fmt"
 	"math/big"
+	"os"
 	"strconv"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	_ "github.com/jackc/pgx/v4/stdlib"
 
 	"flag"
@@ -31,11 +35,21 @@ var opts = struct {
 	Family        string
 	Key           string
 	DryRun        bool
+	File          string
+	Name          string
+	Address       string
+	SampleSize    int
+	MarkDirty     bool
+	Format        string
+	Owner         string
+	ValidToFrom   string
+	ValidToUntil  string
+	Output        string
 }{}
 
 func main() {
 	configPath := flag.String("config", "config/default.config.yml", "Path to the config file")
-	flag.StringVar(&opts.Command, "command", "", "command to run, available: updateAPIKey, applyDbSchema, epoch-export, debug-rewards, clear-bigtable")
+	flag.StringVar(&opts.Command, "command", "", "command to run, available: updateAPIKey, applyDbSchema, epoch-export, debug-rewards, clear-bigtable, ens-clean-orphans, ens-seed, ens-revalidate-primary, ens-resync, ens-verify, ens-export")
 	flag.Uint64Var(&opts.StartEpoch, "start-epoch", 0, "start epoch")
 	flag.Uint64Var(&opts.EndEpoch, "end-epoch", 0, "end epoch")
 	flag.Uint64Var(&opts.User, "user", 0, "user id")
@@ -45,6 +59,16 @@ func main() {
 	flag.Int64Var(&opts.TargetVersion, "target-version", -2, "Db migration target version, use -2 to apply up to the latest version, -1 to apply only the next version or the specific versions")
 	flag.StringVar(&opts.Family, "family", "", "big table family")
 	flag.StringVar(&opts.Key, "key", "", "big table key")
+	flag.StringVar(&opts.File, "file", "", "input file, e.g. the newline delimited name file for ens-seed")
+	flag.StringVar(&opts.Name, "name", "", "ens name to resync with ens-resync")
+	flag.StringVar(&opts.Address, "address", "", "address to resync with ens-resync")
+	flag.IntVar(&opts.SampleSize, "sample-size", 1000, "number of ens names compared against the chain by ens-verify")
+	flag.BoolVar(&opts.MarkDirty, "mark-dirty", false, "queue the mismatched names found by ens-verify for revalidation")
+	flag.StringVar(&opts.Format, "format", "csv", "format of the ens-export output, csv or ndjson")
+	flag.StringVar(&opts.Owner, "owner", "", "only export the ens names owned by this address")
+	flag.StringVar(&opts.ValidToFrom, "valid-to-from", "", "only export the ens names expiring at or after this date (YYYY-MM-DD)")
+	flag.StringVar(&opts.ValidToUntil, "valid-to-until", "", "only export the ens names expiring before this date (YYYY-MM-DD)")
+	flag.StringVar(&opts.Output, "output", "", "output file of ens-export, defaults to stdout")
 	dryRun := flag.String("dry-run", "true", "if 'false' it deletes all rows starting with the key, per default it only logs the rows that would be deleted, but does not really delete them")
 	flag.Parse()
 
@@ -135,6 +159,109 @@ func main() {
 		CompareRewards(opts.StartDay, opts.EndDay, opts.Validator, bt)
 	case "clear-bigtable":
 		ClearBigtable(opts.Family, opts.Key, opts.DryRun, bt)
+	case "ens-seed":
+		client, err := rpc.NewErigonClient(utils.Config.Eth1ErigonEndpoint)
+		if err != nil {
+			utils.LogFatal(err, "erigon client creation error", 0)
+		}
+		result, err := db.SeedEnsFromNameFile(context.Background(), client.GetNativeClient(), opts.File)
+		if err != nil {
+			logrus.WithError(err).Fatal("error seeding ens names")
+		}
+		for _, skipped := range result.Skipped {
+			logrus.Warnf("skipped line %v (%v): %v", skipped.Line, skipped.Text, skipped.Reason)
+		}
+		logrus.Infof("seeded ens names: %v total, %v validated, %v failed, %v skipped", result.Total, result.Validated, result.Failed, len(result.Skipped))
+	case "ens-clean-orphans":
+		deleted, err := db.CleanOrphanedEnsRecords()
+		if err != nil {
+			logrus.WithError(err).Fatal("error cleaning orphaned ens records")
+		}
+		logrus.Infof("removed %v orphaned ens records", deleted)
+	case "ens-revalidate-primary":
+		client, err := rpc.NewErigonClient(utils.Config.Eth1ErigonEndpoint)
+		if err != nil {
+			utils.LogFatal(err, "erigon client creation error", 0)
+		}
+		result, err := db.RevalidateAllPrimaryNames(context.Background(), client.GetNativeClient())
+		if err != nil {
+			logrus.WithError(err).Fatal("error revalidating ens primary names")
+		}
+		logrus.Infof("revalidated ens primary names: %v total, %v validated, %v failed", result.Total, result.Validated, result.Failed)
+	case "ens-resync":
+		if opts.Name == "" && opts.Address == "" {
+			logrus.Fatal("ens-resync requires a name or an address")
+		}
+		if opts.Address != "" && !common.IsHexAddress(opts.Address) {
+			logrus.Fatalf("invalid address %v", opts.Address)
+		}
+		client, err := rpc.NewErigonClient(utils.Config.Eth1ErigonEndpoint)
+		if err != nil {
+			utils.LogFatal(err, "erigon client creation error", 0)
+		}
+		if opts.Name != "" {
+			err = db.ResyncEnsName(context.Background(), client.GetNativeClient(), opts.Name)
+			if err != nil {
+				logrus.WithError(err).Fatalf("error resyncing ens name %v", opts.Name)
+			}
+			logrus.Infof("resynced ens name %v", opts.Name)
+		}
+		if opts.Address != "" {
+			err = db.ResyncEnsAddress(context.Background(), client.GetNativeClient(), common.HexToAddress(opts.Address))
+			if err != nil {
+				logrus.WithError(err).Fatalf("error resyncing ens address %v", opts.Address)
+			}
+			logrus.Infof("resynced ens address %v", opts.Address)
+		}
+	case "ens-verify":
+		client, err := rpc.NewErigonClient(utils.Config.Eth1ErigonEndpoint)
+		if err != nil {
+			utils.LogFatal(err, "erigon client creation error", 0)
+		}
+		report, err := bt.VerifyEnsConsistency(context.Background(), client.GetNativeClient(), opts.SampleSize, opts.MarkDirty)
+		if err != nil {
+			logrus.WithError(err).Fatal("error verifying ens consistency")
+		}
+		for _, mismatch := range report.Mismatches {
+			logrus.Warnf("ens name %v: stored %v %v, chain %v", mismatch.Name, mismatch.Kind, mismatch.Stored, mismatch.Actual)
+		}
+		logrus.Infof("verified ens consistency: %v checked, %v mismatches, %v failed", report.Checked, len(report.Mismatches), report.Failed)
+	case "ens-export":
+		filter := types.EnsExportFilter{}
+		if opts.Owner != "" {
+			if !common.IsHexAddress(opts.Owner) {
+				logrus.Fatalf("invalid owner address %v", opts.Owner)
+			}
+			owner := common.HexToAddress(opts.Owner)
+			filter.Owner = &owner
+		}
+		if opts.ValidToFrom != "" {
+			from, err := time.Parse("2006-01-02", opts.ValidToFrom)
+			if err != nil {
+				logrus.WithError(err).Fatalf("invalid date %v", opts.ValidToFrom)
+			}
+			filter.ValidToFrom = &from
+		}
+		if opts.ValidToUntil != "" {
+			until, err := time.Parse("2006-01-02", opts.ValidToUntil)
+			if err != nil {
+				logrus.WithError(err).Fatalf("invalid date %v", opts.ValidToUntil)
+			}
+			filter.ValidToUntil = &until
+		}
+		output := os.Stdout
+		if opts.Output != "" {
+			output, err = os.Create(opts.Output)
+			if err != nil {
+				logrus.WithError(err).Fatalf("error creating output file %v", opts.Output)
+			}
+			defer output.Close()
+		}
+		exported, err := db.ExportEnsRecords(output, opts.Format, filter)
+		if err != nil {
+			logrus.WithError(err).Fatal("error exporting ens records")
+		}
+		logrus.Infof("exported %v ens records", exported)
 
 	default:
 		utils.LogFatal(nil, "unknown command", 0)
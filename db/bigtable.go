@@ -50,6 +50,11 @@ type Bigtable struct {
 
 	redisCache *redis.Client
 
+	// ensTable replaces the data table for the ens indexing if set, see getEnsTable
+	ensTable ensBigtable
+	// ensValidationQueue is signaled with the freshly written ENS:V keys, see SetEnsValidationQueue
+	ensValidationQueue *EnsValidationQueue
+
 	chainId string
 }
 
@@ -741,6 +741,7 @@ func (bigtable *Bigtable) IndexEventsWithTransformers(start, end int64, transfor
 						if err != nil {
 							return fmt.Errorf("error writing to bigtable data table: %w", err)
 						}
+						bigtable.signalEnsValidationQueue(bulkMutsData.Keys)
 					}
 
 					if len(bulkMutsMetadataUpdate.Keys) > 0 {
@@ -1,26 +1,47 @@
 package db
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"database/sql"
+	"encoding/binary"
+	"encoding/csv"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"eth2-exporter/ens"
+	"eth2-exporter/metrics"
 	"eth2-exporter/types"
 	"eth2-exporter/utils"
 	"fmt"
+	"io"
 	"log"
+	"math"
+	"math/big"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	gcp_bigtable "cloud.google.com/go/bigtable"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
 
 	"github.com/coocood/freecache"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	eth_types "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
 
 	go_ens "github.com/wealdtech/go-ens/v3"
 )
@@ -46,6 +67,13 @@ import (
 // Cell:   nil
 // Example scan: "5:ENS:I:A:05579fadcf7cc6544f7aa018a2726c85251600c5:e627ae94bd16eb1ed8774cd4003fc25625159f13f8a2612cc1c7f8d2ab11b1d7"
 //
+// - by registration time, newest first
+// Row:    <chainID>:ENS:T:<reversedTimestamp>:<nameHash>
+// Family: f
+// Column: nil
+// Cell:   nil
+// Example scan: "5:ENS:T:9223372035167371807:4ae569dd0aa2f6e9207e41423c956d0d27cbc376a499ee8d90fe1d84489ae9d1"
+//
 // ==================================================
 //
 // Track for later verification via the node ("set dirty")
@@ -71,18 +99,347 @@ import (
 // Cell:   nil
 // Example scan: "5:ENS:V:A:27234cb8734d5b1fac0521c6f5dc5aebc6e839b6"
 //
+// - by text record
+// Row:    <chainID>:ENS:V:T:<nameHash>:<key>
+// Family: f
+// Column: nil
+// Cell:   nil
+// Example scan: "5:ENS:V:T:6f5d9cc23e60abe836401b4fd386ec9280a1f671d47d9bf3ec75dab76380d845:com.twitter"
+//
+// ==================================================
+//
+// Track the owners of names
+//
+// - by owner address
+// Row:    <chainID>:ENS:O:A:<address>:<nameHash>
+// Family: f
+// Column: owned
+// Cell:   0x01 if the address gained the name, 0x00 if it lost it
+// Example scan: "5:ENS:O:A:27234cb8734d5b1fac0521c6f5dc5aebc6e839b6:6f5d9cc23e60abe836401b4fd386ec9280a1f671d47d9bf3ec75dab76380d845"
+//
+// ==================================================
+//
+// Track the registrar controllers of the base registrar
+//
+// - by controller address
+// Row:    <chainID>:ENS:C:<address>
+// Family: f
+// Column: active
+// Cell:   0x01 if the controller was added, 0x00 if it was removed
+// Example scan: "5:ENS:C:283af0b28c62c092c9727f1ee09c02ca627eb7f5"
+//
+// ==================================================
+//
+// Keep the registrations, renewals, transfers, multicoin addresses and address changes of a block until it is confirmed,
+// they are written to postgres by the ens update run
+//
+// - by block
+// Row:    <chainID>:ENS:E:<paddedBlockNumber>
+// Family: f
+// Column: events
+// Cell:   the json encoded events of the block
+// Example scan: "5:ENS:E:000017000000"
+//
 // ==================================================
 
+var sharedEnsFilterer struct {
+	once     sync.Once
+	filterer *ens.EnsRegistrarFilterer
+	err      error
+}
+
+// getEnsFilterer returns the filterer that is shared by all block transforms, so the contract abis are only parsed once.
+// The filterer is only used to unpack logs which reads the parsed abis but never mutates the bound contracts,
+// so it is safe to use the same instance while blocks are transformed concurrently.
+func getEnsFilterer() (*ens.EnsRegistrarFilterer, error) {
+	sharedEnsFilterer.once.Do(func() {
+		sharedEnsFilterer.filterer, sharedEnsFilterer.err = ens.NewEnsRegistrarFilterer(common.Address{}, nil)
+	})
+	return sharedEnsFilterer.filterer, sharedEnsFilterer.err
+}
+
+// ensBigtable is the subset of the bigtable operations used by the ens code, it allows to run the ens code against an in-memory table in tests
+type ensBigtable interface {
+	ReadRows(ctx context.Context, arg gcp_bigtable.RowSet, f func(gcp_bigtable.Row) bool, opts ...gcp_bigtable.ReadOption) error
+	WriteBulk(mutations *types.BulkMutations) error
+}
+
+// ensDataTable implements ensBigtable on top of the data table
+type ensDataTable struct {
+	bigtable *Bigtable
+}
+
+func (t *ensDataTable) ReadRows(ctx context.Context, arg gcp_bigtable.RowSet, f func(gcp_bigtable.Row) bool, opts ...gcp_bigtable.ReadOption) error {
+	return t.bigtable.tableData.ReadRows(ctx, arg, f, opts...)
+}
+
+func (t *ensDataTable) WriteBulk(mutations *types.BulkMutations) error {
+	return t.bigtable.WriteBulk(mutations, t.bigtable.tableData)
+}
+
+func (bigtable *Bigtable) getEnsTable() ensBigtable {
+	if bigtable.ensTable != nil {
+		return bigtable.ensTable
+	}
+	return &ensDataTable{bigtable: bigtable}
+}
+
+// ensNameHash computes the node of a name with the namehash scheme of the naming service the name belongs to
+func ensNameHash(name string) ([32]byte, error) {
+	hasher, err := ens.NewNameHasher(utils.Config.Indexer.EnsTransformer.NameHashRoots)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return hasher.NameHash(name)
+}
+
+// qualifyEnsName returns the normalized full name of a name emitted by a registrar controller.
+// Some controllers emit only the label ("vitalik") while others emit the full name ("vitalik.eth"), a name without a dot is a label of the .eth registrar.
+func qualifyEnsName(name string) string {
+	if !strings.Contains(name, ".") {
+		name = fmt.Sprintf("%s.eth", name)
+	}
+	normalized, err := go_ens.Normalize(name)
+	if err != nil {
+		// the name is kept as is and will be rejected by the validation
+		return name
+	}
+	return normalized
+}
+
+// ensNameHashCache memoizes name hashes within a single block transform or validation run where the same names are hashed repeatedly.
+// The zero value is ready to use.
+type ensNameHashCache struct {
+	mux    sync.Mutex
+	hashes map[string][32]byte
+}
+
+func (c *ensNameHashCache) nameHash(name string) ([32]byte, error) {
+	c.mux.Lock()
+	hash, ok := c.hashes[name]
+	c.mux.Unlock()
+	if ok {
+		return hash, nil
+	}
+
+	hash, err := ensNameHash(name)
+	if err != nil {
+		return hash, err
+	}
+
+	c.mux.Lock()
+	if c.hashes == nil {
+		c.hashes = make(map[string][32]byte)
+	}
+	c.hashes[name] = hash
+	c.mux.Unlock()
+	return hash, nil
+}
+
+// ENS_EVENTS_COLUMN is the column of the ENS:E row of a block that holds its json encoded events
+const ENS_EVENTS_COLUMN = "events"
+
+// ensEventConfirmations is the depth a block needs before its events are written to postgres. It exceeds the reorg lookback of the indexer
+// so the ENS:E row of an orphaned block is deleted by the reorg handling before its events are written. The default matches the default
+// lookback of the eth1indexer, SetEnsReorgDepth keeps it in line with the configured one.
+var ensEventConfirmations uint64 = 21
+
+// ensBlockEvents are the events of a block that are stored in postgres. The transformer only writes them to the ENS:E row of the block,
+// the ens update run writes them to postgres once the block is confirmed.
+type ensBlockEvents struct {
+	BlockNumber        uint64
+	Registrations      []*ensRegistration
+	Renewals           []*ensRenewal
+	Transfers          []*types.EnsTransfer
+	MulticoinAddresses []*ensMulticoinAddress
+	AddressChanges     []*ensAddressChange
+	UndecodableNames   []*ensUndecodableName
+}
+
+func (e *ensBlockEvents) empty() bool {
+	return len(e.Registrations) == 0 && len(e.Renewals) == 0 && len(e.Transfers) == 0 && len(e.MulticoinAddresses) == 0 && len(e.AddressChanges) == 0 && len(e.UndecodableNames) == 0
+}
+
+// ensEventsKey returns the ENS:E row of a block, the block number is padded so the rows are read in the order of the blocks
+func ensEventsKey(chainId string, blockNumber uint64) string {
+	return fmt.Sprintf("%s:ENS:E:%012d", chainId, blockNumber)
+}
+
+// SetEnsReorgDepth sets the lookback of the reorg handling, the events of a block are written to postgres once the block is beyond it
+func SetEnsReorgDepth(depth int) error {
+	if depth < 0 {
+		return fmt.Errorf("invalid reorg depth %v", depth)
+	}
+	ensEventConfirmations = uint64(depth) + 1
+	return nil
+}
+
+// ensEventsConfirmed reports whether the events of a block are deep enough to be written to postgres
+func ensEventsConfirmed(blockNumber, head uint64) bool {
+	return head >= ensEventConfirmations && blockNumber <= head-ensEventConfirmations
+}
+
+// ensLogFields returns the fields attached to the errors of an ens event, so the offending transaction can be found in the logs
+func ensLogFields(event string, log eth_types.Log) map[string]interface{} {
+	return map[string]interface{}{
+		"event":     event,
+		"block":     log.BlockNumber,
+		"tx":        log.TxHash.Hex(),
+		"log_index": log.Index,
+	}
+}
+
+// ensTxLogs holds the indices of the ENS logs of a transaction
+type ensTxLogs struct {
+	nameRegistered []int
+	// nameRegisteredWithReferrer is set for every registration that was emitted by a controller that supports referrers
+	nameRegisteredWithReferrer []bool
+	newResolver                []int
+	nameRenewed                []int
+	nameChanged                int
+	newOwner                   int
+	addressChanged             []int
+	controllerChanged          []int
+	transfers                  []int
+	textChanged                []int
+	wrapperChanged             []int
+	reverseClaimed             []int
+	// legacyRegistered holds the registrations of the legacy auction and permanent registrars, which emit a different registration event
+	legacyRegistered []int
+}
+
+func newEnsTxLogs() *ensTxLogs {
+	return &ensTxLogs{
+		nameChanged: -1,
+		newOwner:    -1,
+	}
+}
+
+// add records the index of a log of the given event type and reports whether the event type is handled
+func (l *ensTxLogs) add(eventType ens.EventType, index int) bool {
+	switch eventType {
+	case ens.NameRegisteredEvent, ens.NameRegisteredWithReferrerEvent:
+		l.nameRegistered = append(l.nameRegistered, index)
+		l.nameRegisteredWithReferrer = append(l.nameRegisteredWithReferrer, eventType == ens.NameRegisteredWithReferrerEvent)
+	case ens.NewResolverEvent:
+		l.newResolver = append(l.newResolver, index)
+	case ens.NameRenewedEvent:
+		l.nameRenewed = append(l.nameRenewed, index)
+	case ens.NameChangedEvent:
+		l.nameChanged = index
+	case ens.NewOwnerEvent:
+		l.newOwner = index
+	case ens.AddressChangedEvent:
+		l.addressChanged = append(l.addressChanged, index)
+	case ens.ControllerAddedEvent, ens.ControllerRemovedEvent:
+		l.controllerChanged = append(l.controllerChanged, index)
+	case ens.RegistrarTransferEvent:
+		l.transfers = append(l.transfers, index)
+	case ens.TextChangedEvent:
+		l.textChanged = append(l.textChanged, index)
+	case ens.NameWrappedEvent, ens.NameUnwrappedEvent:
+		l.wrapperChanged = append(l.wrapperChanged, index)
+	case ens.ReverseClaimedEvent:
+		l.reverseClaimed = append(l.reverseClaimed, index)
+	case ens.LegacyHashRegisteredEvent, ens.LegacyNameRegisteredEvent:
+		l.legacyRegistered = append(l.legacyRegistered, index)
+	default:
+		return false
+	}
+	return true
+}
+
+// registrationResolvers pairs every registration with the log index of the nearest NewResolver event, a preceding event is preferred
+// as controllers set the resolver before emitting the registration. Every NewResolver event is paired once, registrations left without
+// one are paired with -1.
+func (l *ensTxLogs) registrationResolvers() []int {
+	distance := func(a, b int) int {
+		if a > b {
+			return a - b
+		}
+		return b - a
+	}
+	paired := make([]int, len(l.nameRegistered))
+	used := make([]bool, len(l.newResolver))
+	for k, registered := range l.nameRegistered {
+		nearest := -1
+		for r, resolver := range l.newResolver {
+			if used[r] {
+				continue
+			}
+			if nearest == -1 || distance(registered, resolver) < distance(registered, l.newResolver[nearest]) ||
+				(distance(registered, resolver) == distance(registered, l.newResolver[nearest]) && resolver < registered) {
+				nearest = r
+			}
+		}
+		paired[k] = -1
+		if nearest > -1 {
+			used[nearest] = true
+			paired[k] = l.newResolver[nearest]
+		}
+	}
+	return paired
+}
+
+// isEnsEventInScope reports whether an event is relevant for the contract that emitted it and the receiver of the tx
+func isEnsEventInScope(eventType ens.EventType, log *types.Eth1Log, isRegistrarTx bool, isLegacyRegistrarTx bool) bool {
+	switch eventType {
+	case ens.LegacyHashRegisteredEvent, ens.LegacyNameRegisteredEvent:
+		// the legacy registration events share their topics with unrelated contracts (e.g. the current base registrar), so they are only trusted in txs to a legacy registrar
+		return isLegacyRegistrarTx
+	case ens.ControllerAddedEvent, ens.ControllerRemovedEvent:
+		// controller changes are emitted by the base registrar and usually triggered by the ens dao, so we match the emitting contract instead of the tx receiver
+		return isEnsBaseRegistrarContract(common.BytesToAddress(log.GetAddress()))
+	case ens.RegistrarTransferEvent:
+		// name tokens are transferred by the base registrar, the erc721 transfer has the token id as third indexed topic
+		return isEnsBaseRegistrarContract(common.BytesToAddress(log.GetAddress())) && len(log.GetTopics()) == 4
+	case ens.NameRegisteredEvent, ens.NameRegisteredWithReferrerEvent, ens.NewResolverEvent, ens.NameRenewedEvent:
+		return isRegistrarTx
+	case ens.TextChangedEvent:
+		// text records are often set by the registrar tx right away
+		return true
+	case ens.NameWrappedEvent, ens.NameUnwrappedEvent:
+		// names are wrapped by the registrar tx or later on, both are only trusted if emitted by the name wrapper
+		return isEnsNameWrapperContract(common.BytesToAddress(log.GetAddress()))
+	default:
+		// events of resolvers and the registry are validated when loading the related events
+		return !isRegistrarTx
+	}
+}
+
 func (bigtable *Bigtable) TransformEnsNameRegistered(blk *types.Eth1Block, cache *freecache.Cache) (bulkData *types.BulkMutations, bulkMetadataUpdates *types.BulkMutations, err error) {
+	bulkData, events, err := bigtable.transformEnsBlock(blk)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !events.empty() {
+		// the events are kept with the other rows of the block, so a reorg deletes them before they are written to postgres
+		value, err := json.Marshal(events)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error encoding ens events of block %v: %w", blk.GetNumber(), err)
+		}
+		mut := gcp_bigtable.NewMutation()
+		mut.Set(DEFAULT_FAMILY, ENS_EVENTS_COLUMN, gcp_bigtable.Timestamp(0), value)
+
+		bulkData.Keys = append(bulkData.Keys, ensEventsKey(bigtable.chainId, blk.GetNumber()))
+		bulkData.Muts = append(bulkData.Muts, mut)
+	}
+	return bulkData, &types.BulkMutations{}, nil
+}
+
+// transformEnsBlock creates the index and dirty key mutations of the ens events of a block and collects the events that are stored in postgres
+func (bigtable *Bigtable) transformEnsBlock(blk *types.Eth1Block) (bulkData *types.BulkMutations, events *ensBlockEvents, err error) {
 	bulkData = &types.BulkMutations{}
-	bulkMetadataUpdates = &types.BulkMutations{}
 
-	filterer, err := ens.NewEnsRegistrarFilterer(common.Address{}, nil)
+	filterer, err := getEnsFilterer()
 	if err != nil {
 		log.Printf("error creating filterer: %v", err)
 		return nil, nil, err
 	}
 	keys := make(map[string]bool)
+	events = &ensBlockEvents{BlockNumber: blk.GetNumber()}
+	nameHashes := ensNameHashCache{}
+	owners := ensOwnerChanges{}
 
 	for i, tx := range blk.GetTransactions() {
 		if i > 9999 {
@@ -92,40 +449,27 @@ func (bigtable *Bigtable) TransformEnsNameRegistered(blk *types.Eth1Block, cache
 		// We look for the different ENS events,
 		// 	most will be triggered by a main registrar contract,
 		//  but some are triggered on a different contracts (like a resolver contract), these will be validated when loading the related events
-		var isRegistarContract = len(utils.Config.Indexer.EnsTransformer.ValidRegistrarContracts) > 0 && utils.SliceContains(utils.Config.Indexer.EnsTransformer.ValidRegistrarContracts, common.BytesToAddress(tx.To).String())
-		foundNameIndex := -1
-		foundResolverIndex := -1
-		foundNameRenewedIndex := -1
-		foundAddressChangedIndices := []int{}
-		foundNameChangedIndex := -1
-		foundNewOwnerIndex := -1
+		var isRegistarContract = isEnsRegistrarTx(tx)
+		var isLegacyRegistrarContract = isEnsLegacyRegistrarContract(common.BytesToAddress(tx.GetTo()))
+		found := newEnsTxLogs()
 		logs := tx.GetLogs()
 		for j, log := range logs {
 			if j > 99999 {
 				return nil, nil, fmt.Errorf("unexpected number of logs in block expected at most 99999 but got: %v tx: %x", j, tx.GetHash())
 			}
 			for _, lTopic := range log.GetTopics() {
-				if isRegistarContract {
-					if bytes.Equal(lTopic, ens.NameRegisteredTopic) {
-						foundNameIndex = j
-					} else if bytes.Equal(lTopic, ens.NewResolverTopic) {
-						foundResolverIndex = j
-					} else if bytes.Equal(lTopic, ens.NameRenewedTopic) {
-						foundNameRenewedIndex = j
-					}
-				} else if bytes.Equal(lTopic, ens.AddressChangedTopic) {
-					foundAddressChangedIndices = append(foundAddressChangedIndices, j)
-				} else if bytes.Equal(lTopic, ens.NameChangedTopic) {
-					foundNameChangedIndex = j
-				} else if bytes.Equal(lTopic, ens.NewOwnerTopic) {
-					foundNewOwnerIndex = j
+				eventType := ens.GetEventType(lTopic)
+				if eventType == ens.UnknownEvent || !isEnsEventInScope(eventType, log, isRegistarContract, isLegacyRegistrarContract) {
+					continue
 				}
+				found.add(eventType, j)
 			}
 		}
-		// We found a register name event
-		if foundNameIndex > -1 && foundResolverIndex > -1 {
-
-			log := logs[foundNameIndex]
+		// We found register name events, bulk registrations register many names in one transaction
+		resolverIndices := found.registrationResolvers()
+		registeredNodes := map[common.Hash]bool{}
+		for k, nameRegisteredIndex := range found.nameRegistered {
+			log := logs[nameRegisteredIndex]
 			topics := make([]common.Hash, 0, len(log.GetTopics()))
 
 			for _, lTopic := range log.GetTopics() {
@@ -140,18 +484,90 @@ func (bigtable *Bigtable) TransformEnsNameRegistered(blk *types.Eth1Block, cache
 				TxHash:      common.BytesToHash(tx.GetHash()),
 				TxIndex:     uint(i),
 				BlockHash:   common.BytesToHash(blk.GetHash()),
-				Index:       uint(foundNameIndex),
+				Index:       uint(nameRegisteredIndex),
 				Removed:     log.GetRemoved(),
 			}
 
-			log = logs[foundResolverIndex]
-			topics = make([]common.Hash, 0, len(log.GetTopics()))
+			nameRegistered, premium, referrer, err := parseEnsNameRegistered(filterer, nameLog, found.nameRegisteredWithReferrer[k])
+			if err != nil {
+				utils.LogError(err, "indexing of register event failed parse register event", 0, ensLogFields("NameRegistered", nameLog))
+				continue
+			}
+
+			var node common.Hash
+			if resolverIndices[k] > -1 {
+				log = logs[resolverIndices[k]]
+				topics = make([]common.Hash, 0, len(log.GetTopics()))
+
+				for _, lTopic := range log.GetTopics() {
+					topics = append(topics, common.BytesToHash(lTopic))
+				}
+
+				resolverLog := eth_types.Log{
+					Address:     common.BytesToAddress(log.GetAddress()),
+					Data:        log.Data,
+					Topics:      topics,
+					BlockNumber: blk.GetNumber(),
+					TxHash:      common.BytesToHash(tx.GetHash()),
+					TxIndex:     uint(i),
+					BlockHash:   common.BytesToHash(blk.GetHash()),
+					Index:       uint(resolverIndices[k]),
+					Removed:     log.GetRemoved(),
+				}
+
+				resolver, err := filterer.ParseNewResolver(resolverLog)
+				if err != nil {
+					utils.LogError(err, "indexing of register event failed parse resolver event", 0, ensLogFields("NewResolver", resolverLog))
+					continue
+				}
+				node = resolver.Node
+			} else {
+				// without a NewResolver event (the resolver is set in a later tx) the node is derived from the label,
+				// registrars only register .eth second level names
+				node = crypto.Keccak256Hash(ens.EthNode[:], nameRegistered.Label[:])
+			}
+
+			registeredNodes[node] = true
+			keys[fmt.Sprintf("%s:ENS:I:H:%x:%x", bigtable.chainId, node, tx.GetHash())] = true
+			keys[fmt.Sprintf("%s:ENS:I:A:%x:%x", bigtable.chainId, nameRegistered.Owner, tx.GetHash())] = true
+			keys[fmt.Sprintf("%s:ENS:T:%s:%x", bigtable.chainId, reversedEnsTimestamp(blk.GetTime().AsTime()), node)] = true
+			keys[fmt.Sprintf("%s:ENS:V:A:%x", bigtable.chainId, nameRegistered.Owner)] = true
+			owners.set(bigtable.chainId, nameRegistered.Owner, node, true, blk.GetNumber(), i)
+			if utf8.ValidString(nameRegistered.Name) {
+				keys[fmt.Sprintf("%s:ENS:V:N:%s", bigtable.chainId, qualifyEnsName(nameRegistered.Name))] = true
+			} else {
+				// the name can neither be hashed nor stored as text, so we record the registration by its hashes only
+				logger.WithFields(ensLogFields("NameRegistered", nameLog)).Warnf("ens name registered in tx %x can not be decoded, storing it by label hash %x", tx.GetHash(), nameRegistered.Label)
+				events.UndecodableNames = append(events.UndecodableNames, &ensUndecodableName{
+					NameHash: node,
+					Label:    nameRegistered.Label,
+					Expires:  nameRegistered.Expires,
+				})
+			}
+
+			events.Registrations = append(events.Registrations, &ensRegistration{
+				NameHash:    node.Bytes(),
+				TxHash:      tx.GetHash(),
+				BlockNumber: blk.GetNumber(),
+				Ts:          blk.GetTime().AsTime(),
+				Controller:  tx.GetTo(),
+				Owner:       nameRegistered.Owner.Bytes(),
+				ChainId:     utils.Config.Chain.Config.DepositChainID,
+				Referrer:    referrer,
+				CostWei:     ensCostWei(nameRegistered.Cost),
+				PremiumWei:  premium,
+			})
+		}
+		// We found registrations of the legacy registrars, they only report the label hash so the names are validated by their name hash
+		for _, legacyRegisteredIndex := range found.legacyRegistered {
+			log := logs[legacyRegisteredIndex]
+			topics := make([]common.Hash, 0, len(log.GetTopics()))
 
 			for _, lTopic := range log.GetTopics() {
 				topics = append(topics, common.BytesToHash(lTopic))
 			}
 
-			resolverLog := eth_types.Log{
+			legacyLog := eth_types.Log{
 				Address:     common.BytesToAddress(log.GetAddress()),
 				Data:        log.Data,
 				Topics:      topics,
@@ -159,28 +575,57 @@ func (bigtable *Bigtable) TransformEnsNameRegistered(blk *types.Eth1Block, cache
 				TxHash:      common.BytesToHash(tx.GetHash()),
 				TxIndex:     uint(i),
 				BlockHash:   common.BytesToHash(blk.GetHash()),
-				Index:       uint(foundResolverIndex),
+				Index:       uint(legacyRegisteredIndex),
 				Removed:     log.GetRemoved(),
 			}
 
-			nameRegistered, err := filterer.ParseNameRegistered(nameLog)
-			if err != nil {
-				utils.LogError(err, "indexing of register event failed parse register event", 0)
-				continue
+			var label common.Hash
+			var owner common.Address
+			var cost *big.Int
+			if ens.GetEventType(log.GetTopics()[0]) == ens.LegacyHashRegisteredEvent {
+				hashRegistered, err := filterer.ParseHashRegistered(legacyLog)
+				if err != nil {
+					utils.LogError(err, "indexing of register event failed parse legacy hash registered event", 0, ensLogFields("HashRegistered", legacyLog))
+					continue
+				}
+				// the value is the winning bid of the auction that was locked in the deed of the name
+				label, owner, cost = hashRegistered.Hash, hashRegistered.Owner, hashRegistered.Value
+			} else {
+				nameRegistered, err := filterer.ParseLegacyNameRegistered(legacyLog)
+				if err != nil {
+					utils.LogError(err, "indexing of register event failed parse legacy name registered event", 0, ensLogFields("LegacyNameRegistered", legacyLog))
+					continue
+				}
+				label, owner = common.BigToHash(nameRegistered.Id), nameRegistered.Owner
 			}
-			resolver, err := filterer.ParseNewResolver(resolverLog)
-			if err != nil {
-				utils.LogError(err, "indexing of register event failed parse resolver event", 0)
+
+			node := crypto.Keccak256Hash(ens.EthNode[:], label[:])
+			if registeredNodes[node] {
+				// controllers emit the current registration event next to the one of the registrar, the registration is already indexed
 				continue
 			}
+			registeredNodes[node] = true
+			keys[fmt.Sprintf("%s:ENS:I:H:%x:%x", bigtable.chainId, node, tx.GetHash())] = true
+			keys[fmt.Sprintf("%s:ENS:I:A:%x:%x", bigtable.chainId, owner, tx.GetHash())] = true
+			keys[fmt.Sprintf("%s:ENS:T:%s:%x", bigtable.chainId, reversedEnsTimestamp(blk.GetTime().AsTime()), node)] = true
+			keys[fmt.Sprintf("%s:ENS:V:H:%x", bigtable.chainId, node)] = true
+			keys[fmt.Sprintf("%s:ENS:V:A:%x", bigtable.chainId, owner)] = true
+			owners.set(bigtable.chainId, owner, node, true, blk.GetNumber(), i)
 
-			keys[fmt.Sprintf("%s:ENS:I:H:%x:%x", bigtable.chainId, resolver.Node, tx.GetHash())] = true
-			keys[fmt.Sprintf("%s:ENS:I:A:%x:%x", bigtable.chainId, nameRegistered.Owner, tx.GetHash())] = true
-			keys[fmt.Sprintf("%s:ENS:V:A:%x", bigtable.chainId, nameRegistered.Owner)] = true
-			keys[fmt.Sprintf("%s:ENS:V:N:%s", bigtable.chainId, nameRegistered.Name)] = true
-
-		} else if foundNameRenewedIndex > -1 { // We found a renew name event
-			log := logs[foundNameRenewedIndex]
+			events.Registrations = append(events.Registrations, &ensRegistration{
+				NameHash:    node.Bytes(),
+				TxHash:      tx.GetHash(),
+				BlockNumber: blk.GetNumber(),
+				Ts:          blk.GetTime().AsTime(),
+				Controller:  tx.GetTo(),
+				Owner:       owner.Bytes(),
+				ChainId:     utils.Config.Chain.Config.DepositChainID,
+				CostWei:     ensCostWei(cost),
+			})
+		}
+		// We found renew name events, bulk renewals renew many names in one transaction, also next to a registration
+		for _, nameRenewedIndex := range found.nameRenewed {
+			log := logs[nameRenewedIndex]
 			topics := make([]common.Hash, 0, len(log.GetTopics()))
 
 			for _, lTopic := range log.GetTopics() {
@@ -195,27 +640,37 @@ func (bigtable *Bigtable) TransformEnsNameRegistered(blk *types.Eth1Block, cache
 				TxHash:      common.BytesToHash(tx.GetHash()),
 				TxIndex:     uint(i),
 				BlockHash:   common.BytesToHash(blk.GetHash()),
-				Index:       uint(foundNameRenewedIndex),
+				Index:       uint(nameRenewedIndex),
 				Removed:     log.GetRemoved(),
 			}
 
 			nameRenewed, err := filterer.ParseNameRenewed(nameRenewedLog)
 			if err != nil {
-				utils.LogError(err, "indexing of renew event failed parse event", 0)
+				utils.LogError(err, "indexing of renew event failed parse event", 0, ensLogFields("NameRenewed", nameRenewedLog))
 				continue
 			}
 
-			nameHash, err := go_ens.NameHash(nameRenewed.Name)
+			name := qualifyEnsName(nameRenewed.Name)
+			nameHash, err := nameHashes.nameHash(name)
 			if err != nil {
-				utils.LogError(err, "error hashing ens name", 0)
+				utils.LogError(err, fmt.Errorf("error hashing ens name %v", name), 0, ensLogFields("NameRenewed", nameRenewedLog))
 				continue
 			}
 			keys[fmt.Sprintf("%s:ENS:I:H:%x:%x", bigtable.chainId, nameHash, tx.GetHash())] = true
-			keys[fmt.Sprintf("%s:ENS:V:N:%s", bigtable.chainId, nameRenewed.Name)] = true
-
-		} else if foundNameChangedIndex > -1 && foundNewOwnerIndex > -1 { // we found a name change event
+			keys[fmt.Sprintf("%s:ENS:V:N:%s", bigtable.chainId, name)] = true
+			events.Renewals = append(events.Renewals, &ensRenewal{
+				NameHash:    nameHash[:],
+				TxHash:      tx.GetHash(),
+				LogIndex:    uint64(nameRenewedIndex),
+				BlockNumber: blk.GetNumber(),
+				Ts:          blk.GetTime().AsTime(),
+				CostWei:     ensCostWei(nameRenewed.Cost),
+				ChainId:     utils.Config.Chain.Config.DepositChainID,
+			})
+		}
+		if found.nameChanged > -1 && found.newOwner > -1 { // we found a name change event
 
-			log := logs[foundNewOwnerIndex]
+			log := logs[found.newOwner]
 			topics := make([]common.Hash, 0, len(log.GetTopics()))
 
 			for _, lTopic := range log.GetTopics() {
@@ -229,21 +684,75 @@ func (bigtable *Bigtable) TransformEnsNameRegistered(blk *types.Eth1Block, cache
 				TxHash:      common.BytesToHash(tx.GetHash()),
 				TxIndex:     uint(i),
 				BlockHash:   common.BytesToHash(blk.GetHash()),
-				Index:       uint(foundNewOwnerIndex),
+				Index:       uint(found.newOwner),
 				Removed:     log.GetRemoved(),
 			}
 
 			newOwner, err := filterer.ParseNewOwner(newOwnerLog)
 			if err != nil {
-				utils.LogError(err, fmt.Errorf("indexing of new owner event failed parse event at index %v", foundNewOwnerIndex), 0)
+				utils.LogError(err, "indexing of new owner event failed parse event", 0, ensLogFields("NewOwner", newOwnerLog))
 				continue
 			}
 
-			keys[fmt.Sprintf("%s:ENS:I:A:%x:%x", bigtable.chainId, newOwner.Owner, tx.GetHash())] = true
-			keys[fmt.Sprintf("%s:ENS:V:A:%x", bigtable.chainId, newOwner.Owner)] = true
+			log = logs[found.nameChanged]
+			topics = make([]common.Hash, 0, len(log.GetTopics()))
+
+			for _, lTopic := range log.GetTopics() {
+				topics = append(topics, common.BytesToHash(lTopic))
+			}
+			nameChangedLog := eth_types.Log{
+				Address:     common.BytesToAddress(log.GetAddress()),
+				Data:        log.Data,
+				Topics:      topics,
+				BlockNumber: blk.GetNumber(),
+				TxHash:      common.BytesToHash(tx.GetHash()),
+				TxIndex:     uint(i),
+				BlockHash:   common.BytesToHash(blk.GetHash()),
+				Index:       uint(found.nameChanged),
+				Removed:     log.GetRemoved(),
+			}
+
+			nameChanged, err := filterer.ParseNameChanged(nameChangedLog)
+			if err != nil {
+				utils.LogError(err, "indexing of name changed event failed parse event", 0, ensLogFields("NameChanged", nameChangedLog))
+				continue
+			}
+			if nameChanged.Name == "" {
+				// an empty name clears the reverse record, the validation of the address drops its primary name
+				logger.Infof("reverse record of address %x cleared in tx %x", newOwner.Owner, tx.GetHash())
+			}
+			// the name wrapper only holds wrapped names, their real owners are taken from the NameWrapped events
+			if !isEnsNameWrapperContract(newOwner.Owner) {
+				keys[fmt.Sprintf("%s:ENS:I:A:%x:%x", bigtable.chainId, newOwner.Owner, tx.GetHash())] = true
+				keys[fmt.Sprintf("%s:ENS:V:A:%x", bigtable.chainId, newOwner.Owner)] = true
+			}
+			owners.set(bigtable.chainId, newOwner.Owner, crypto.Keccak256Hash(newOwner.Node[:], newOwner.Label[:]), true, blk.GetNumber(), i)
+		}
+		// We found reverse claims, the claimed address can differ from the owner of the reverse node (e.g. a contract claiming through setNameForAddr)
+		for _, reverseClaimedIndex := range found.reverseClaimed {
+			topics := logs[reverseClaimedIndex].GetTopics()
+			if len(topics) != 3 {
+				continue
+			}
+			address := common.BytesToAddress(topics[1])
+			if ens.ReverseNode(address) != common.BytesToHash(topics[2]) {
+				// the claim does not belong to the reverse node of the address
+				continue
+			}
+			keys[fmt.Sprintf("%s:ENS:I:A:%x:%x", bigtable.chainId, address, tx.GetHash())] = true
+			keys[fmt.Sprintf("%s:ENS:V:A:%x", bigtable.chainId, address)] = true
+		}
+		// We found a name change of a reverse node without a claim, contracts like multisigs usually set their name on the resolver of their existing reverse node
+		if found.nameChanged > -1 && len(found.reverseClaimed) == 0 {
+			if topics := logs[found.nameChanged].GetTopics(); len(topics) == 2 {
+				if address, ok := ensReverseNodeAddress(common.BytesToHash(topics[1]), common.BytesToAddress(tx.GetFrom()), common.BytesToAddress(tx.GetTo())); ok {
+					keys[fmt.Sprintf("%s:ENS:I:A:%x:%x", bigtable.chainId, address, tx.GetHash())] = true
+					keys[fmt.Sprintf("%s:ENS:V:A:%x", bigtable.chainId, address)] = true
+				}
+			}
 		}
 		// We found a change address event, there can be multiple within one transaction
-		for _, addressChangeIndex := range foundAddressChangedIndices {
+		for _, addressChangeIndex := range found.addressChanged {
 
 			log := logs[addressChangeIndex]
 			topics := make([]common.Hash, 0, len(log.GetTopics()))
@@ -266,292 +775,5309 @@ func (bigtable *Bigtable) TransformEnsNameRegistered(blk *types.Eth1Block, cache
 
 			addressChanged, err := filterer.ParseAddressChanged(addressChangedLog)
 			if err != nil {
-				utils.LogError(err, "indexing of address change event failed parse event at index ", 0)
+				utils.LogError(err, "indexing of address change event failed parse event", 0, ensLogFields("AddressChanged", addressChangedLog))
 				continue
 			}
 
 			keys[fmt.Sprintf("%s:ENS:I:H:%x:%x", bigtable.chainId, addressChanged.Node, tx.GetHash())] = true
 			keys[fmt.Sprintf("%s:ENS:V:H:%x", bigtable.chainId, addressChanged.Node)] = true
 
-		}
-	}
-	for key := range keys {
-		mut := gcp_bigtable.NewMutation()
-		mut.Set(DEFAULT_FAMILY, key, gcp_bigtable.Timestamp(0), nil)
-
-		bulkData.Keys = append(bulkData.Keys, key)
-		bulkData.Muts = append(bulkData.Muts, mut)
-	}
+			// the eth address is stored with the name, the addresses of other coins are taken from the event until the validation
+			// of the name queries the resolver for every coin type seen
+			if addressChanged.CoinType != nil && addressChanged.CoinType.IsUint64() && addressChanged.CoinType.Uint64() != ENS_ETH_COIN_TYPE {
+				events.MulticoinAddresses = append(events.MulticoinAddresses, &ensMulticoinAddress{
+					NameHash:     addressChanged.Node[:],
+					CoinType:     addressChanged.CoinType.Uint64(),
+					AddressBytes: addressChanged.NewAddress,
+					BlockNumber:  blk.GetNumber(),
+				})
+			}
+			// the eth address changes are kept as validity windows, so the name of an address can be looked up at a past block
+			if addressChanged.CoinType != nil && addressChanged.CoinType.IsUint64() && addressChanged.CoinType.Uint64() == ENS_ETH_COIN_TYPE {
+				events.AddressChanges = append(events.AddressChanges, &ensAddressChange{
+					NameHash:    addressChanged.Node[:],
+					Address:     addressChanged.NewAddress,
+					BlockNumber: blk.GetNumber(),
+				})
+			}
 
-	return bulkData, bulkMetadataUpdates, nil
-}
+		}
+		// We found text record changes, there can be multiple within one transaction
+		for _, textChangedIndex := range found.textChanged {
+			log := logs[textChangedIndex]
+			topics := make([]common.Hash, 0, len(log.GetTopics()))
 
-type EnsCheckedDictionary struct {
-	mux     sync.Mutex
-	address map[common.Address]bool
-	name    map[string]bool
-}
+			for _, lTopic := range log.GetTopics() {
+				topics = append(topics, common.BytesToHash(lTopic))
+			}
 
-func (bigtable *Bigtable) ImportEnsUpdates(client *ethclient.Client) error {
-	key := fmt.Sprintf("%s:ENS:V", bigtable.chainId)
+			textChangedLog := eth_types.Log{
+				Address:     common.BytesToAddress(log.GetAddress()),
+				Data:        log.Data,
+				Topics:      topics,
+				BlockNumber: blk.GetNumber(),
+				TxHash:      common.BytesToHash(tx.GetHash()),
+				TxIndex:     uint(i),
+				BlockHash:   common.BytesToHash(blk.GetHash()),
+				Index:       uint(textChangedIndex),
+				Removed:     log.GetRemoved(),
+			}
 
-	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
-	defer done()
+			textChanged, err := filterer.ParseTextChanged(textChangedLog)
+			if err != nil {
+				utils.LogError(err, "indexing of text changed event failed parse event", 0, ensLogFields("TextChanged", textChangedLog))
+				continue
+			}
+			if len(textChanged.Key) == 0 || len(textChanged.Key) > ENS_MAX_TEXT_KEY_LENGTH {
+				logger.WithFields(ensLogFields("TextChanged", textChangedLog)).Warnf("skipping text record of node %x with a key of length %v in tx %x", textChanged.Node, len(textChanged.Key), tx.GetHash())
+				continue
+			}
 
-	rowRange := gcp_bigtable.PrefixRange(key)
-	keys := []string{}
+			keys[fmt.Sprintf("%s:ENS:I:H:%x:%x", bigtable.chainId, textChanged.Node, tx.GetHash())] = true
+			keys[fmt.Sprintf("%s:ENS:V:T:%x:%s", bigtable.chainId, textChanged.Node, textChanged.Key)] = true
+		}
+		// We found names being wrapped or unwrapped by the name wrapper, the registry then reports the wrapper as owner
+		for _, wrapperChangedIndex := range found.wrapperChanged {
+			log := logs[wrapperChangedIndex]
+			topics := make([]common.Hash, 0, len(log.GetTopics()))
 
-	err := bigtable.tableData.ReadRows(ctx, rowRange, func(row gcp_bigtable.Row) bool {
-		row_ := row[DEFAULT_FAMILY][0]
-		keys = append(keys, row_.Row)
-		return true
-	})
-	if err != nil {
-		return err
-	}
+			for _, lTopic := range log.GetTopics() {
+				topics = append(topics, common.BytesToHash(lTopic))
+			}
 
-	if len(keys) == 0 {
-		logger.Info("No ENS entries to validate")
-		return nil
-	}
+			wrapperChangedLog := eth_types.Log{
+				Address:     common.BytesToAddress(log.GetAddress()),
+				Data:        log.Data,
+				Topics:      topics,
+				BlockNumber: blk.GetNumber(),
+				TxHash:      common.BytesToHash(tx.GetHash()),
+				TxIndex:     uint(i),
+				BlockHash:   common.BytesToHash(blk.GetHash()),
+				Index:       uint(wrapperChangedIndex),
+				Removed:     log.GetRemoved(),
+			}
 
-	logger.Infof("Validating %v ENS entries", len(keys))
-	alreadyChecked := EnsCheckedDictionary{
-		address: make(map[common.Address]bool),
-		name:    make(map[string]bool),
-	}
-	mutsDelete := &types.BulkMutations{
-		Keys: make([]string, 0, 1),
-		Muts: make([]*gcp_bigtable.Mutation, 0, 1),
-	}
-
-	batchSize := 100
-	total := len(keys)
-	for i := 0; i < total; i += batchSize {
-		to := i + batchSize
-		if to > total {
-			to = total
-		}
-		batch := keys[i:to]
-		logger.Infof("Batching ENS entries %v:%v of %v", i, to, total)
-		g := new(errgroup.Group)
-		mutDelete := gcp_bigtable.NewMutation()
-		mutDelete.DeleteRow()
-		for _, k := range batch {
-			key := k
-			var name string
-			var address *common.Address
-			split := strings.Split(key, ":")
-			value := split[4]
-			switch split[3] {
-			case "H":
-				// if we have a hash we look if we find a name in the db. If not we can ignore it.
-				nameHash, err := hex.DecodeString(value)
+			var node [32]byte
+			var owner common.Address
+			if ens.GetEventType(log.GetTopics()[0]) == ens.NameWrappedEvent {
+				nameWrapped, err := filterer.ParseNameWrapped(wrapperChangedLog)
 				if err != nil {
-					utils.LogError(err, fmt.Errorf("name hash could not be decoded: %v", value), 0)
+					utils.LogError(err, "indexing of name wrapped event failed parse event", 0, ensLogFields("NameWrapped", wrapperChangedLog))
+					continue
+				}
+				name, err := ens.DecodeDnsName(nameWrapped.Name)
+				if err != nil || !utf8.ValidString(name) {
+					utils.LogError(err, fmt.Errorf("indexing of name wrapped event failed decode name of node %x", nameWrapped.Node), 0, ensLogFields("NameWrapped", wrapperChangedLog))
 				} else {
-					err := ReaderDb.Get(&name, `
-					SELECT
-						ens_name
-					FROM ens
-					WHERE name_hash = $1
-					`, nameHash[:])
-					if err != nil && err != sql.ErrNoRows {
-						return err
-					}
+					keys[fmt.Sprintf("%s:ENS:V:N:%s", bigtable.chainId, name)] = true
 				}
-			case "A":
-				addressHash, err := hex.DecodeString(value)
+				node = nameWrapped.Node
+				owner = nameWrapped.Owner
+			} else {
+				nameUnwrapped, err := filterer.ParseNameUnwrapped(wrapperChangedLog)
 				if err != nil {
-					utils.LogError(err, fmt.Errorf("address hash could not be decoded: %v", value), 0)
-				} else {
-					add := common.BytesToAddress(addressHash)
-					address = &add
+					utils.LogError(err, "indexing of name unwrapped event failed parse event", 0, ensLogFields("NameUnwrapped", wrapperChangedLog))
+					continue
 				}
-			case "N":
-				name = value
+				node = nameUnwrapped.Node
+				owner = nameUnwrapped.Owner
 			}
 
-			mutsDelete.Keys = append(mutsDelete.Keys, key)
-			mutsDelete.Muts = append(mutsDelete.Muts, mutDelete)
-
-			g.Go(func() error {
-				if name != "" {
-					err := validateEnsName(client, name, &alreadyChecked, nil)
-					if err != nil {
-						return err
-					}
-				} else if address != nil {
-					err := validateEnsAddress(client, *address, &alreadyChecked)
-					if err != nil {
-						return err
-					}
-				}
-				return nil
-			})
-		}
-		if err := g.Wait(); err != nil {
-			return err
+			keys[fmt.Sprintf("%s:ENS:I:H:%x:%x", bigtable.chainId, node, tx.GetHash())] = true
+			keys[fmt.Sprintf("%s:ENS:V:H:%x", bigtable.chainId, node)] = true
+			if owner != (common.Address{}) {
+				keys[fmt.Sprintf("%s:ENS:I:A:%x:%x", bigtable.chainId, owner, tx.GetHash())] = true
+				keys[fmt.Sprintf("%s:ENS:V:A:%x", bigtable.chainId, owner)] = true
+			}
+			// the owner of a wrapped name keeps it, the wrapper only holds the token on its behalf
+			owners.set(bigtable.chainId, owner, node, true, blk.GetNumber(), i)
 		}
-	}
-	logger.Info("ens key indexing completed")
-	// After processing the keys we remove them from bigtable
-	return bigtable.WriteBulk(mutsDelete, bigtable.tableData)
-}
+		// We found a controller being added to or removed from the base registrar
+		for _, controllerChangedIndex := range found.controllerChanged {
 
-func validateEnsAddress(client *ethclient.Client, address common.Address, alreadyChecked *EnsCheckedDictionary) error {
+			log := logs[controllerChangedIndex]
+			topics := make([]common.Hash, 0, len(log.GetTopics()))
+
+			for _, lTopic := range log.GetTopics() {
+				topics = append(topics, common.BytesToHash(lTopic))
+			}
+
+			controllerChangedLog := eth_types.Log{
+				Address:     common.BytesToAddress(log.GetAddress()),
+				Data:        log.Data,
+				Topics:      topics,
+				BlockNumber: blk.GetNumber(),
+				TxHash:      common.BytesToHash(tx.GetHash()),
+				TxIndex:     uint(i),
+				BlockHash:   common.BytesToHash(blk.GetHash()),
+				Index:       uint(controllerChangedIndex),
+				Removed:     log.GetRemoved(),
+			}
+
+			var controller common.Address
+			active := bytes.Equal(log.GetTopics()[0], ens.ControllerAddedTopic)
+			if active {
+				controllerAdded, err := filterer.ParseControllerAdded(controllerChangedLog)
+				if err != nil {
+					utils.LogError(err, "indexing of controller added event failed parse event", 0, ensLogFields("ControllerAdded", controllerChangedLog))
+					continue
+				}
+				controller = controllerAdded.Controller
+			} else {
+				controllerRemoved, err := filterer.ParseControllerRemoved(controllerChangedLog)
+				if err != nil {
+					utils.LogError(err, "indexing of controller removed event failed parse event", 0, ensLogFields("ControllerRemoved", controllerChangedLog))
+					continue
+				}
+				controller = controllerRemoved.Controller
+			}
+
+			// the cell timestamp is derived from the block number and tx index so the latest version always reflects the latest change
+			value := []byte{0}
+			if active {
+				value = []byte{1}
+			}
+			mut := gcp_bigtable.NewMutation()
+			mut.Set(DEFAULT_FAMILY, ENS_CONTROLLER_ACTIVE_COLUMN, gcp_bigtable.Timestamp((blk.GetNumber()*10000+uint64(i))*1000), value)
+
+			bulkData.Keys = append(bulkData.Keys, fmt.Sprintf("%s:ENS:C:%x", bigtable.chainId, controller))
+			bulkData.Muts = append(bulkData.Muts, mut)
+		}
+		// We found name tokens being minted, transferred or burned
+		for _, transferIndex := range found.transfers {
+
+			log := logs[transferIndex]
+			topics := make([]common.Hash, 0, len(log.GetTopics()))
+
+			for _, lTopic := range log.GetTopics() {
+				topics = append(topics, common.BytesToHash(lTopic))
+			}
+
+			transferLog := eth_types.Log{
+				Address:     common.BytesToAddress(log.GetAddress()),
+				Data:        log.Data,
+				Topics:      topics,
+				BlockNumber: blk.GetNumber(),
+				TxHash:      common.BytesToHash(tx.GetHash()),
+				TxIndex:     uint(i),
+				BlockHash:   common.BytesToHash(blk.GetHash()),
+				Index:       uint(transferIndex),
+				Removed:     log.GetRemoved(),
+			}
+
+			transfer, err := filterer.ParseRegistrarTransfer(transferLog)
+			if err != nil {
+				utils.LogError(err, "indexing of registrar transfer event failed parse event", 0, ensLogFields("RegistrarTransfer", transferLog))
+				continue
+			}
+
+			// both sides of a transfer are validated again, the previous owner may lose and the new owner may gain a primary name
+			for _, owner := range []common.Address{transfer.From, transfer.To} {
+				if owner == (common.Address{}) {
+					continue
+				}
+				keys[fmt.Sprintf("%s:ENS:I:A:%x:%x", bigtable.chainId, owner, tx.GetHash())] = true
+				keys[fmt.Sprintf("%s:ENS:V:A:%x", bigtable.chainId, owner)] = true
+			}
+
+			// the token id is the label hash, so the node of the name is derived from the eth node
+			node := crypto.Keccak256Hash(ens.EthNode[:], common.BigToHash(transfer.TokenId).Bytes())
+			if !isEnsNameWrapperContract(transfer.To) {
+				// wrapping a name moves the token to the wrapper, but the name stays with its owner
+				owners.set(bigtable.chainId, transfer.From, node, false, blk.GetNumber(), i)
+			}
+			owners.set(bigtable.chainId, transfer.To, node, true, blk.GetNumber(), i)
+			events.Transfers = append(events.Transfers, &types.EnsTransfer{
+				NameHash:    node.Bytes(),
+				TxHash:      tx.GetHash(),
+				LogIndex:    uint64(transferIndex),
+				BlockNumber: blk.GetNumber(),
+				Ts:          blk.GetTime().AsTime(),
+				From:        transfer.From.Bytes(),
+				To:          transfer.To.Bytes(),
+			})
+		}
+	}
+	for key := range keys {
+		sharedEnsResolveCache.invalidate(key)
+		mut := gcp_bigtable.NewMutation()
+		mut.Set(DEFAULT_FAMILY, key, gcp_bigtable.Timestamp(0), nil)
+
+		bulkData.Keys = append(bulkData.Keys, key)
+		bulkData.Muts = append(bulkData.Muts, mut)
+	}
+	for key, change := range owners {
+		// like the controller index, the cell timestamp is derived from the block number and tx index so the latest version reflects the latest change
+		value := []byte{0}
+		if change.owned {
+			value = []byte{1}
+		}
+		mut := gcp_bigtable.NewMutation()
+		mut.Set(DEFAULT_FAMILY, ENS_OWNER_OWNED_COLUMN, gcp_bigtable.Timestamp((change.blockNumber*10000+uint64(change.txIndex))*1000), value)
+
+		bulkData.Keys = append(bulkData.Keys, key)
+		bulkData.Muts = append(bulkData.Muts, mut)
+	}
+
+	return bulkData, events, nil
+}
+
+const ENS_CONTROLLER_ACTIVE_COLUMN = "active"
+
+const ENS_OWNER_OWNED_COLUMN = "owned"
+
+// ensReverseNodeAddress returns the candidate whose reverse node is the given node. The primary name is only marked after the validation of
+// the address resolved the name forward, so a name change on a spoofed reverse node does not affect any address.
+func ensReverseNodeAddress(node common.Hash, candidates ...common.Address) (common.Address, bool) {
+	for _, candidate := range candidates {
+		if candidate != (common.Address{}) && ens.ReverseNode(candidate) == node {
+			return candidate, true
+		}
+	}
+	return common.Address{}, false
+}
+
+type ensOwnerChange struct {
+	owned       bool
+	blockNumber uint64
+	txIndex     int
+}
+
+// ensOwnerChanges collects the changes of the ENS:O index of a block by row, a row is written once with its latest change
+type ensOwnerChanges map[string]ensOwnerChange
+
+// set records that the owner gained or lost a name, the zero address and the name wrapper are never recorded as owners
+func (c ensOwnerChanges) set(chainId string, owner common.Address, nameHash [32]byte, owned bool, blockNumber uint64, txIndex int) {
+	if owner == (common.Address{}) || isEnsNameWrapperContract(owner) {
+		return
+	}
+	c[fmt.Sprintf("%s:ENS:O:A:%x:%x", chainId, owner, nameHash)] = ensOwnerChange{owned: owned, blockNumber: blockNumber, txIndex: txIndex}
+}
+
+type ensRegistration struct {
+	NameHash    []byte    `db:"name_hash"`
+	TxHash      []byte    `db:"tx_hash"`
+	BlockNumber uint64    `db:"block_number"`
+	Ts          time.Time `db:"ts"`
+	Controller  []byte    `db:"controller"`
+	Owner       []byte    `db:"owner"`
+	ChainId     uint64    `db:"chain_id"`
+	Referrer    []byte    `db:"referrer"`
+	// CostWei is the price paid for the registration including the premium, PremiumWei is only known for controllers that report it separately
+	CostWei    decimal.Decimal     `db:"cost_wei"`
+	PremiumWei decimal.NullDecimal `db:"premium_wei"`
+}
+
+// ensRenewal is a renewal of a name, a bulk renewal emits one event per name
+type ensRenewal struct {
+	NameHash    []byte          `db:"name_hash"`
+	TxHash      []byte          `db:"tx_hash"`
+	LogIndex    uint64          `db:"log_index"`
+	BlockNumber uint64          `db:"block_number"`
+	Ts          time.Time       `db:"ts"`
+	CostWei     decimal.Decimal `db:"cost_wei"`
+	ChainId     uint64          `db:"chain_id"`
+}
+
+// ensCostWei converts the cost of a registration or renewal event, a missing cost is stored as zero
+func ensCostWei(cost *big.Int) decimal.Decimal {
+	if cost == nil {
+		return decimal.Zero
+	}
+	return decimal.NewFromBigInt(cost, 0)
+}
+
+// parseEnsNameRegistered parses the registration events of the different registrar controllers. The older controllers report a single cost,
+// the controllers that support referrers report the base cost and the premium separately, the cost of the returned event is their sum.
+// The premium and the referrer are only set for controllers that report them.
+func parseEnsNameRegistered(filterer *ens.EnsRegistrarFilterer, log eth_types.Log, withReferrer bool) (*ens.NameRegistered, decimal.NullDecimal, []byte, error) {
+	if !withReferrer {
+		nameRegistered, err := filterer.ParseNameRegistered(log)
+		return nameRegistered, decimal.NullDecimal{}, nil, err
+	}
+	event, err := filterer.ParseNameRegisteredWithReferrer(log)
+	if err != nil {
+		return nil, decimal.NullDecimal{}, nil, err
+	}
+	var referrer []byte
+	if event.Referrer != [32]byte{} {
+		// the referrer is a left padded address
+		referrer = common.BytesToAddress(event.Referrer[:]).Bytes()
+	}
+	return &ens.NameRegistered{
+		Name:    event.Label,
+		Label:   event.Labelhash,
+		Owner:   event.Owner,
+		Cost:    new(big.Int).Add(event.BaseCost, event.Premium),
+		Expires: event.Expires,
+		Raw:     event.Raw,
+	}, decimal.NewNullDecimal(ensCostWei(event.Premium)), referrer, nil
+}
+
+// saveEnsRegistrations stores the registrations found in a block, reindexing a block will not create duplicates
+func saveEnsRegistrations(tx *sqlx.Tx, registrations []*ensRegistration) error {
+	for _, registration := range registrations {
+		_, err := tx.NamedExec(`
+		INSERT INTO ens_registrations (
+			name_hash,
+			tx_hash,
+			block_number,
+			ts,
+			controller,
+			owner,
+			chain_id,
+			referrer,
+			cost_wei,
+			premium_wei)
+		VALUES (:name_hash, :tx_hash, :block_number, :ts, :controller, :owner, :chain_id, :referrer, :cost_wei, :premium_wei)
+		ON CONFLICT
+			(name_hash, tx_hash)
+		DO UPDATE SET
+			block_number = excluded.block_number,
+			ts = excluded.ts,
+			controller = excluded.controller,
+			owner = excluded.owner,
+			chain_id = excluded.chain_id,
+			referrer = excluded.referrer,
+			cost_wei = excluded.cost_wei,
+			premium_wei = excluded.premium_wei
+		`, registration)
+		if err != nil {
+			return fmt.Errorf("error saving ens registration for name hash %x in tx %x: %w", registration.NameHash, registration.TxHash, err)
+		}
+		// names that were validated before link to their latest registration and registrant right away, new names get them on validation
+		_, err = tx.Exec(`
+		UPDATE ens
+		SET 
+			registration_tx = $2,
+			owner_address = $3
+		WHERE name_hash = $1
+		`, registration.NameHash, registration.TxHash, registration.Owner)
+		if err != nil {
+			return fmt.Errorf("error saving ens registration tx for name hash %x in tx %x: %w", registration.NameHash, registration.TxHash, err)
+		}
+	}
+	return nil
+}
+
+// saveEnsRenewals stores the renewals found in a block, reindexing a block will not create duplicates
+func saveEnsRenewals(tx *sqlx.Tx, renewals []*ensRenewal) error {
+	for _, renewal := range renewals {
+		_, err := tx.NamedExec(`
+		INSERT INTO ens_renewals (
+			name_hash,
+			tx_hash,
+			log_index,
+			block_number,
+			ts,
+			cost_wei,
+			chain_id)
+		VALUES (:name_hash, :tx_hash, :log_index, :block_number, :ts, :cost_wei, :chain_id)
+		ON CONFLICT
+			(name_hash, tx_hash, log_index)
+		DO UPDATE SET
+			block_number = excluded.block_number,
+			ts = excluded.ts,
+			cost_wei = excluded.cost_wei,
+			chain_id = excluded.chain_id
+		`, renewal)
+		if err != nil {
+			return fmt.Errorf("error saving ens renewal for name hash %x in tx %x: %w", renewal.NameHash, renewal.TxHash, err)
+		}
+	}
+	return nil
+}
+
+// saveEnsTransfers stores the name token transfers found in a block, reindexing a block will not create duplicates
+func saveEnsTransfers(tx *sqlx.Tx, transfers []*types.EnsTransfer) error {
+	for _, transfer := range transfers {
+		_, err := tx.NamedExec(`
+		INSERT INTO ens_transfers (
+			name_hash,
+			tx_hash,
+			log_index,
+			block_number,
+			ts,
+			from_address,
+			to_address)
+		VALUES (:name_hash, :tx_hash, :log_index, :block_number, :ts, :from_address, :to_address)
+		ON CONFLICT
+			(name_hash, tx_hash, log_index)
+		DO NOTHING
+		`, transfer)
+		if err != nil {
+			return fmt.Errorf("error saving ens transfer for name hash %x in tx %x: %w", transfer.NameHash, transfer.TxHash, err)
+		}
+	}
+	return nil
+}
+
+// ENS_ETH_COIN_TYPE is the SLIP-44 coin type of eth addresses, they are stored in the address column of the ens table
+const ENS_ETH_COIN_TYPE = 60
+
+type ensMulticoinAddress struct {
+	NameHash     []byte `db:"name_hash"`
+	CoinType     uint64 `db:"coin_type"`
+	AddressBytes []byte `db:"address_bytes"`
+	BlockNumber  uint64 `db:"block_number"`
+}
+
+// saveEnsMulticoinAddresses stores the non eth addresses of names found in events, an address is only replaced by addresses set in the same or a later block.
+// An empty address clears the record of the coin type, so its row is deleted instead. Addresses of names that are not indexed are skipped as the rows reference
+// the name, the events are only imported after their confirmations so the name of a registration has been validated by then.
+func saveEnsMulticoinAddresses(tx *sqlx.Tx, addresses []*ensMulticoinAddress) error {
+	for _, address := range addresses {
+		if len(address.AddressBytes) == 0 {
+			_, err := tx.NamedExec(`
+			DELETE FROM ens_coin_addresses
+			WHERE
+				name_hash = :name_hash AND
+				coin_type = :coin_type AND
+				block_number <= :block_number
+			`, address)
+			if err != nil {
+				return fmt.Errorf("error deleting ens multicoin address for name hash %x and coin type %v: %w", address.NameHash, address.CoinType, err)
+			}
+			continue
+		}
+		_, err := tx.NamedExec(`
+		INSERT INTO ens_coin_addresses (
+			name_hash,
+			coin_type,
+			address_bytes,
+			block_number)
+		SELECT :name_hash, :coin_type, :address_bytes, :block_number
+		WHERE EXISTS (SELECT 1 FROM ens WHERE name_hash = :name_hash)
+		ON CONFLICT
+			(name_hash, coin_type)
+		DO UPDATE SET
+			address_bytes = excluded.address_bytes,
+			block_number = excluded.block_number
+		WHERE ens_coin_addresses.block_number <= excluded.block_number
+		`, address)
+		if err != nil {
+			return fmt.Errorf("error saving ens multicoin address for name hash %x and coin type %v: %w", address.NameHash, address.CoinType, err)
+		}
+	}
+	return nil
+}
+
+type ensAddressChange struct {
+	NameHash    []byte `db:"name_hash"`
+	Address     []byte `db:"address"`
+	BlockNumber uint64 `db:"block_number"`
+}
+
+// saveEnsHistory appends the eth address changes of names to the ens history. A change starts a window that lasts until the next change of
+// the name, so the window of the preceding change is closed and a change that is indexed late (e.g. by a backfill) ends at the following one.
+// An empty address clears the record, it closes the preceding window without opening one. Changes of the same name and block replace each other.
+// Like the multicoin addresses, windows are only opened for indexed names.
+func saveEnsHistory(tx *sqlx.Tx, changes []*ensAddressChange) error {
+	for _, change := range changes {
+		_, err := tx.NamedExec(`
+		UPDATE ens_history
+		SET valid_to_block = :block_number
+		WHERE
+			name_hash = :name_hash AND
+			valid_from_block < :block_number AND
+			(valid_to_block IS NULL OR valid_to_block > :block_number)
+		`, change)
+		if err != nil {
+			return fmt.Errorf("error closing ens history of name hash %x at block %v: %w", change.NameHash, change.BlockNumber, err)
+		}
+		if len(change.Address) == 0 || common.BytesToAddress(change.Address) == (common.Address{}) {
+			_, err = tx.NamedExec(`
+			DELETE FROM ens_history
+			WHERE
+				name_hash = :name_hash AND
+				valid_from_block = :block_number
+			`, change)
+			if err != nil {
+				return fmt.Errorf("error clearing ens history of name hash %x at block %v: %w", change.NameHash, change.BlockNumber, err)
+			}
+			continue
+		}
+		_, err = tx.NamedExec(`
+		INSERT INTO ens_history (
+			name_hash,
+			address,
+			valid_from_block,
+			valid_to_block)
+		SELECT :name_hash, :address, :block_number, (
+			SELECT min(valid_from_block)
+			FROM ens_history
+			WHERE
+				name_hash = :name_hash AND
+				valid_from_block > :block_number)
+		WHERE EXISTS (SELECT 1 FROM ens WHERE name_hash = :name_hash)
+		ON CONFLICT
+			(name_hash, valid_from_block)
+		DO UPDATE SET
+			address = excluded.address
+		`, change)
+		if err != nil {
+			return fmt.Errorf("error saving ens history of name hash %x at block %v: %w", change.NameHash, change.BlockNumber, err)
+		}
+	}
+	return nil
+}
+
+// validateEnsCoinAddresses queries the resolver of a name for the current address of every coin type seen in its events and stores them
+func validateEnsCoinAddresses(ctx context.Context, client *ethclient.Client, name string, nameHash [32]byte) error {
+	coinTypes := []uint64{}
+	err := ReaderDb.Select(&coinTypes, `
+	SELECT coin_type
+	FROM ens_coin_addresses
+	WHERE name_hash = $1
+	`, nameHash[:])
+	if err != nil || len(coinTypes) == 0 {
+		return err
+	}
+	if err := waitForEnsRpc(ctx); err != nil {
+		return err
+	}
+	resolver, err := go_ens.NewResolver(client, name)
+	if err != nil {
+		utils.LogError(err, fmt.Errorf("error getting resolver of name %v", name), 0)
+		return nil
+	}
+	addresses := resolveEnsCoinAddresses(coinTypes, func(coinType uint64) ([]byte, error) {
+		if err := waitForEnsRpc(ctx); err != nil {
+			return nil, err
+		}
+		return resolver.MultiAddress(coinType)
+	})
+	updated, cleared := splitClearedEnsCoinAddresses(addresses)
+	for coinType, address := range updated {
+		_, err := WriterDb.Exec(`
+		UPDATE ens_coin_addresses
+		SET address_bytes = $3
+		WHERE
+			name_hash = $1 AND
+			coin_type = $2
+		`, nameHash[:], coinType, address)
+		if err != nil {
+			return fmt.Errorf("error saving ens coin address of name %v and coin type %v: %w", name, coinType, err)
+		}
+	}
+	for _, coinType := range cleared {
+		_, err := WriterDb.Exec(`
+		DELETE FROM ens_coin_addresses
+		WHERE
+			name_hash = $1 AND
+			coin_type = $2
+		`, nameHash[:], coinType)
+		if err != nil {
+			return fmt.Errorf("error deleting cleared ens coin address of name %v and coin type %v: %w", name, coinType, err)
+		}
+	}
+	return nil
+}
+
+// splitClearedEnsCoinAddresses separates the resolved addresses from the coin types whose record was cleared, a resolver returns an
+// empty address for those and their stale rows have to be deleted
+func splitClearedEnsCoinAddresses(addresses map[uint64][]byte) (updated map[uint64][]byte, cleared []uint64) {
+	updated = make(map[uint64][]byte, len(addresses))
+	for coinType, address := range addresses {
+		if len(address) == 0 {
+			cleared = append(cleared, coinType)
+			continue
+		}
+		updated[coinType] = address
+	}
+	sort.Slice(cleared, func(i, j int) bool { return cleared[i] < cleared[j] })
+	return updated, cleared
+}
+
+// resolveEnsCoinAddresses resolves the address of every coin type except eth, coin types that can not be resolved are left out
+func resolveEnsCoinAddresses(coinTypes []uint64, resolve func(coinType uint64) ([]byte, error)) map[uint64][]byte {
+	addresses := make(map[uint64][]byte, len(coinTypes))
+	for _, coinType := range coinTypes {
+		if coinType == ENS_ETH_COIN_TYPE {
+			continue
+		}
+		if _, ok := addresses[coinType]; ok {
+			continue
+		}
+		address, err := resolve(coinType)
+		if err != nil {
+			logger.Warnf("error resolving address of coin type %v: %v", coinType, err)
+			continue
+		}
+		addresses[coinType] = address
+	}
+	return addresses
+}
+
+// GetEnsNameForMulticoinAddress returns the name whose address record of the given coin type points at the address.
+// The address is expected in the binary format of the coin (e.g. the script of a BTC address) as defined by ENSIP-9.
+func GetEnsNameForMulticoinAddress(coinType uint64, addressBytes []byte) (*string, error) {
+	if coinType == ENS_ETH_COIN_TYPE {
+		return GetEnsNameForAddress(common.BytesToAddress(addressBytes))
+	}
+	var name *string
+	err := ReaderDb.Get(&name, `
+	SELECT ens.ens_name
+	FROM ens_coin_addresses
+	INNER JOIN ens ON ens.name_hash = ens_coin_addresses.name_hash
+	WHERE
+		ens_coin_addresses.coin_type = $1 AND
+		ens_coin_addresses.address_bytes = $2 AND
+		ens.valid_to >= now()
+	ORDER BY ens.last_validated_at DESC NULLS LAST
+	LIMIT 1
+	`, coinType, addressBytes)
+	return name, err
+}
+
+const (
+	ENS_TRANSFER_MINT     = "mint"
+	ENS_TRANSFER_BURN     = "burn"
+	ENS_TRANSFER_TRANSFER = "transfer"
+)
+
+// ensTransferKind distinguishes mints (from the zero address) and burns (to the zero address) from regular transfers
+func ensTransferKind(from, to []byte) string {
+	if common.BytesToAddress(from) == (common.Address{}) {
+		return ENS_TRANSFER_MINT
+	}
+	if common.BytesToAddress(to) == (common.Address{}) {
+		return ENS_TRANSFER_BURN
+	}
+	return ENS_TRANSFER_TRANSFER
+}
+
+// GetEnsTransferHistory returns all transfers of the name token of a .eth name in chronological order
+func GetEnsTransferHistory(name string) ([]types.EnsTransfer, error) {
+	name = utils.TrimEnsName(name)
+	nameHash, err := ensNameHash(name)
+	if err != nil {
+		return nil, err
+	}
+	transfers := []types.EnsTransfer{}
+	err = ReaderDb.Select(&transfers, `
+	SELECT
+		name_hash,
+		tx_hash,
+		log_index,
+		block_number,
+		ts,
+		from_address,
+		to_address
+	FROM ens_transfers
+	WHERE name_hash = $1
+	ORDER BY block_number ASC, log_index ASC
+	`, nameHash[:])
+	if err != nil {
+		return nil, err
+	}
+	for i := range transfers {
+		transfers[i].Kind = ensTransferKind(transfers[i].From, transfers[i].To)
+	}
+	return transfers, nil
+}
+
+// undecodableEnsName returns the placeholder name for a label that can not be decoded, following the ens convention of "[<labelHash>].eth"
+func undecodableEnsName(label [32]byte) string {
+	return fmt.Sprintf("[%x].eth", label)
+}
+
+// ensUndecodableName is a registration of a name that contains invalid utf-8, only its label hash is known
+type ensUndecodableName struct {
+	NameHash [32]byte
+	Label    [32]byte
+	Expires  *big.Int
+}
+
+// saveUndecodableEnsName records a registration whose name contains invalid utf-8 by its name hash and label hash.
+// The row is flagged so it is never treated as a resolvable name.
+func saveUndecodableEnsName(tx *sqlx.Tx, name *ensUndecodableName) error {
+	_, err := tx.Exec(`
+	INSERT INTO ens (
+		name_hash, 
+		ens_name, 
+		is_primary_name, 
+		valid_to,
+		name_undecodable,
+		tld)
+	VALUES ($1, $2, false, $3, true, $4) 
+	ON CONFLICT 
+		(name_hash) 
+	DO UPDATE SET 
+		valid_to = excluded.valid_to,
+		name_undecodable = true
+	`, name.NameHash[:], undecodableEnsName(name.Label), time.Unix(name.Expires.Int64(), 0), ensTld(undecodableEnsName(name.Label)))
+	if err != nil {
+		return fmt.Errorf("error saving undecodable ens name with label hash %x: %w", name.Label, err)
+	}
+	return nil
+}
+
+// GetEnsRegistrationsByController returns the number of registrations per registrar controller within the given time range.
+// Registrations without a controller (e.g. of a contract creation) are not attributed to any controller.
+func GetEnsRegistrationsByController(from, to time.Time) (map[common.Address]int, error) {
+	rows := []struct {
+		Controller []byte `db:"controller"`
+		Count      int    `db:"count"`
+	}{}
+	err := ReaderDb.Select(&rows, `
+	SELECT controller, COUNT(*) AS count
+	FROM ens_registrations
+	WHERE
+		ts >= $1 AND
+		ts < $2 AND
+		octet_length(controller) = 20
+	GROUP BY controller
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[common.Address]int, len(rows))
+	for _, row := range rows {
+		result[common.BytesToAddress(row.Controller)] = row.Count
+	}
+	return result, nil
+}
+
+// GetEnsRegistrationsByReferrer returns the number of registrations referred by the given address within the given time range
+func GetEnsRegistrationsByReferrer(referrer common.Address, from, to time.Time) (int, error) {
+	var count int
+	err := ReaderDb.Get(&count, `
+	SELECT COUNT(*)
+	FROM ens_registrations
+	WHERE
+		referrer = $1 AND
+		ts >= $2 AND
+		ts < $3
+	`, referrer.Bytes(), from, to)
+	return count, err
+}
+
+// GetEnsTopReferrers returns the referrers with the most registrations within the given time range, ordered by their number of registrations
+func GetEnsTopReferrers(from, to time.Time, limit int) ([]types.EnsReferrerCount, error) {
+	rows := []struct {
+		Referrer []byte `db:"referrer"`
+		Count    int    `db:"count"`
+	}{}
+	err := ReaderDb.Select(&rows, `
+	SELECT referrer, COUNT(*) AS count
+	FROM ens_registrations
+	WHERE
+		referrer IS NOT NULL AND
+		ts >= $1 AND
+		ts < $2
+	GROUP BY referrer
+	ORDER BY count DESC, referrer
+	LIMIT $3
+	`, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]types.EnsReferrerCount, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, types.EnsReferrerCount{Referrer: common.BytesToAddress(row.Referrer), Count: row.Count})
+	}
+	return result, nil
+}
+
+// ensRegistrarContracts holds the registrar controllers loaded from the base registrar events
+var ensRegistrarContracts = struct {
+	sync.RWMutex
+	controllers map[common.Address]bool
+}{controllers: make(map[common.Address]bool)}
+
+// isEnsRegistrarContract returns true if the address is a configured registrar contract
+// or, if auto update is enabled, a controller currently added to the base registrar
+func isEnsRegistrarContract(address common.Address) bool {
+	if utils.SliceContains(utils.Config.Indexer.EnsTransformer.ValidRegistrarContracts, address.String()) {
+		return true
+	}
+	if !utils.Config.Indexer.EnsTransformer.AutoUpdateRegistrarContracts {
+		return false
+	}
+	ensRegistrarContracts.RLock()
+	defer ensRegistrarContracts.RUnlock()
+	return ensRegistrarContracts.controllers[address]
+}
+
+// isEnsRegistrarTx returns true if a tx was sent to a registrar contract. Registrars deployed behind a proxy or called via an aggregator
+// are not the receiver of the tx, so if MatchEmittingContract is enabled the contracts that emitted the logs are checked as well.
+func isEnsRegistrarTx(tx *types.Eth1Transaction) bool {
+	if isEnsRegistrarContract(common.BytesToAddress(tx.GetTo())) {
+		return true
+	}
+	if !utils.Config.Indexer.EnsTransformer.MatchEmittingContract {
+		return false
+	}
+	for _, log := range tx.GetLogs() {
+		if isEnsRegistrarContract(common.BytesToAddress(log.GetAddress())) {
+			return true
+		}
+	}
+	return false
+}
+
+// isEnsLegacyRegistrarContract returns true if the address is a configured legacy registrar, the auction registrar or an early controller
+func isEnsLegacyRegistrarContract(address common.Address) bool {
+	for _, legacyRegistrar := range utils.Config.Indexer.EnsTransformer.LegacyRegistrarContracts {
+		if common.HexToAddress(legacyRegistrar) == address {
+			return true
+		}
+	}
+	return false
+}
+
+func isEnsBaseRegistrarContract(address common.Address) bool {
+	baseRegistrar := utils.Config.Indexer.EnsTransformer.BaseRegistrarContract
+	return baseRegistrar != "" && common.HexToAddress(baseRegistrar) == address
+}
+
+// GetEnsRegistrarControllers returns all controllers that are currently added to the base registrar
+func (bigtable *Bigtable) GetEnsRegistrarControllers() ([]common.Address, error) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	controllers := []common.Address{}
+	prefix := fmt.Sprintf("%s:ENS:C:", bigtable.chainId)
+	err := bigtable.getEnsTable().ReadRows(ctx, gcp_bigtable.PrefixRange(prefix), func(row gcp_bigtable.Row) bool {
+		for _, item := range row[DEFAULT_FAMILY] {
+			if strings.HasSuffix(item.Column, ENS_CONTROLLER_ACTIVE_COLUMN) && bytes.Equal(item.Value, []byte{1}) {
+				controllers = append(controllers, common.HexToAddress(strings.TrimPrefix(row.Key(), prefix)))
+			}
+		}
+		return true
+	}, gcp_bigtable.RowFilter(gcp_bigtable.LatestNFilter(1)))
+	if err != nil {
+		return nil, err
+	}
+	return controllers, nil
+}
+
+// GetEnsNamesOwnedBy returns the hashes of the names an address currently owns from the ENS:O index
+func (bigtable *Bigtable) GetEnsNamesOwnedBy(address common.Address) ([]common.Hash, error) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	nameHashes := []common.Hash{}
+	prefix := fmt.Sprintf("%s:ENS:O:A:%x:", bigtable.chainId, address)
+	err := bigtable.getEnsTable().ReadRows(ctx, gcp_bigtable.PrefixRange(prefix), func(row gcp_bigtable.Row) bool {
+		for _, item := range row[DEFAULT_FAMILY] {
+			if strings.HasSuffix(item.Column, ENS_OWNER_OWNED_COLUMN) && bytes.Equal(item.Value, []byte{1}) {
+				nameHash, err := decodeEnsNameHash(strings.TrimPrefix(row.Key(), prefix))
+				if err == nil {
+					nameHashes = append(nameHashes, common.BytesToHash(nameHash))
+				}
+			}
+		}
+		return true
+	}, gcp_bigtable.RowFilter(gcp_bigtable.LatestNFilter(1)))
+	if err != nil {
+		return nil, err
+	}
+	return nameHashes, nil
+}
+
+// UpdateEnsRegistrarContracts reloads the set of registrar controllers that is used in addition to the configured ValidRegistrarContracts
+func (bigtable *Bigtable) UpdateEnsRegistrarContracts() error {
+	controllers, err := bigtable.GetEnsRegistrarControllers()
+	if err != nil {
+		return err
+	}
+	controllerMap := make(map[common.Address]bool, len(controllers))
+	for _, controller := range controllers {
+		controllerMap[controller] = true
+	}
+
+	ensRegistrarContracts.Lock()
+	ensRegistrarContracts.controllers = controllerMap
+	ensRegistrarContracts.Unlock()
+
+	logger.Infof("loaded %v ens registrar controllers", len(controllers))
+	return nil
+}
+
+// IndexEnsConfirmedBlocks runs the ens transformer on the blocks that are at least ConfirmationDepth blocks deep and were not indexed yet.
+// The last indexed block is persisted so that the indexer catches up on blocks it missed, e.g. during downtime. The first run starts at
+// ConfirmationStartBlock, a catch-up is split into batches of BackfillBatchSize blocks over consecutive runs.
+func (bigtable *Bigtable) IndexEnsConfirmedBlocks(head uint64, concurrency int64, cache *freecache.Cache) error {
+	lastIndexed, err := bigtable.GetEnsConfirmedBlock()
+	if err != nil {
+		return err
+	}
+	startBlock := utils.Config.Indexer.EnsTransformer.ConfirmationStartBlock
+	if lastIndexed == 0 && startBlock == 0 {
+		return fmt.Errorf("no confirmed ens block is stored and no confirmation start block is configured")
+	}
+	from, to, ok := ensConfirmedRange(lastIndexed, startBlock, head, utils.Config.Indexer.EnsTransformer.ConfirmationDepth, ensBackfillBatchSize())
+	if !ok {
+		return nil
+	}
+	logger.Infof("indexing confirmed ens blocks %v to %v", from, to)
+
+	transforms := []func(blk *types.Eth1Block, cache *freecache.Cache) (*types.BulkMutations, *types.BulkMutations, error){bigtable.TransformEnsNameRegistered}
+	err = bigtable.IndexEventsWithTransformers(int64(from), int64(to), transforms, concurrency, cache)
+	if err != nil {
+		return err
+	}
+	return bigtable.SaveEnsConfirmedBlock(to)
+}
+
+// ensConfirmedRange returns the next batch of blocks that are deep enough to be indexed, if there is nothing to index ok is false.
+// Without a stored cursor the range starts at the start block.
+func ensConfirmedRange(lastIndexed, startBlock, head, depth, batchSize uint64) (from, to uint64, ok bool) {
+	if head < depth {
+		return 0, 0, false
+	}
+	to = head - depth
+	from = lastIndexed + 1
+	if lastIndexed == 0 {
+		from = startBlock
+	}
+	if from > to {
+		return 0, 0, false
+	}
+	if batchSize > 0 && to-from >= batchSize {
+		to = from + batchSize - 1
+	}
+	return from, to, true
+}
+
+// GetEnsConfirmedBlock returns the last block that was indexed by IndexEnsConfirmedBlocks
+func (bigtable *Bigtable) GetEnsConfirmedBlock() (uint64, error) {
+	return bigtable.getEnsBlockCursor(fmt.Sprintf("%s:ENS_CONFIRMED_BLOCK", bigtable.chainId))
+}
+
+// SaveEnsConfirmedBlock stores the last block that was indexed by IndexEnsConfirmedBlocks
+func (bigtable *Bigtable) SaveEnsConfirmedBlock(block uint64) error {
+	return bigtable.saveEnsBlockCursor(fmt.Sprintf("%s:ENS_CONFIRMED_BLOCK", bigtable.chainId), block)
+}
+
+// getEnsBlockCursor reads the block number stored in the given row, 0 if the row does not exist
+func (bigtable *Bigtable) getEnsBlockCursor(key string) (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	var value []byte
+	err := bigtable.getEnsTable().ReadRows(ctx, gcp_bigtable.RowList{key}, func(row gcp_bigtable.Row) bool {
+		value = row[DEFAULT_FAMILY][0].Value
+		return false
+	})
+	if err != nil {
+		return 0, err
+	}
+	if value == nil {
+		return 0, nil
+	}
+	return strconv.ParseUint(string(value), 10, 64)
+}
+
+// saveEnsBlockCursor stores a block number in the given row
+func (bigtable *Bigtable) saveEnsBlockCursor(key string, block uint64) error {
+	mut := gcp_bigtable.NewMutation()
+	mut.Set(DEFAULT_FAMILY, DATA_COLUMN, gcp_bigtable.Timestamp(0), []byte(strconv.FormatUint(block, 10)))
+
+	mutsWrite := &types.BulkMutations{
+		Keys: []string{key},
+		Muts: []*gcp_bigtable.Mutation{mut},
+	}
+	return bigtable.getEnsTable().WriteBulk(mutsWrite)
+}
+
+// BackfillEns replays the ens events of a block range after a fix of the transformer, without reindexing the other transformers.
+// The blocks are transformed in batches of BackfillBatchSize blocks and the next block of the range is persisted after every batch,
+// so an interrupted backfill of the same range resumes where it stopped. The names and addresses found are validated at the end.
+func (bigtable *Bigtable) BackfillEns(ctx context.Context, client *ethclient.Client, startBlock, endBlock uint64) error {
+	if startBlock > endBlock {
+		return fmt.Errorf("invalid ens backfill range %v to %v", startBlock, endBlock)
+	}
+	cursorKey := fmt.Sprintf("%s:ENS_BACKFILL_BLOCK:%d:%d", bigtable.chainId, startBlock, endBlock)
+	next, err := bigtable.getEnsBlockCursor(cursorKey)
+	if err != nil {
+		return err
+	}
+	from, ok := ensBackfillStart(next, startBlock, endBlock)
+	if !ok {
+		logger.Infof("ens backfill of blocks %v to %v already completed", startBlock, endBlock)
+	} else {
+		if from > startBlock {
+			logger.Infof("resuming ens backfill of blocks %v to %v at block %v", startBlock, endBlock, from)
+		}
+		batchSize := ensBackfillBatchSize()
+		cache := freecache.NewCache(100 * 1024 * 1024) // 100 MB limit
+		transforms := []func(blk *types.Eth1Block, cache *freecache.Cache) (*types.BulkMutations, *types.BulkMutations, error){bigtable.TransformEnsNameRegistered}
+		for batchStart := from; batchStart <= endBlock; batchStart += batchSize {
+			// a cancelled backfill stops after the last completed batch and resumes there when it is started again
+			if err := ctx.Err(); err != nil {
+				logger.Infof("ens backfill of blocks %v to %v stopped at block %v: %v", startBlock, endBlock, batchStart, err)
+				return err
+			}
+			batchEnd := batchStart + batchSize - 1
+			if batchEnd > endBlock {
+				batchEnd = endBlock
+			}
+			logger.Infof("backfilling ens blocks %v to %v", batchStart, batchEnd)
+			err = bigtable.IndexEventsWithTransformers(int64(batchStart), int64(batchEnd), transforms, 1, cache)
+			if err != nil {
+				return err
+			}
+			cache.Clear()
+			err = bigtable.saveEnsBlockCursor(cursorKey, batchEnd+1)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return bigtable.ImportEnsUpdates(ctx, client)
+}
+
+// ensBackfillStart returns the first block of a backfill range that was not backfilled yet from the persisted next block,
+// ok is false if the whole range was backfilled
+func ensBackfillStart(next, startBlock, endBlock uint64) (from uint64, ok bool) {
+	from = startBlock
+	if next > startBlock {
+		from = next
+	}
+	if from > endBlock {
+		return 0, false
+	}
+	return from, true
+}
+
+// ensBackfillBatchSize returns the configured number of blocks of a backfill batch, defaults to 1000
+func ensBackfillBatchSize() uint64 {
+	if batchSize := utils.Config.Indexer.EnsTransformer.BackfillBatchSize; batchSize > 0 {
+		return uint64(batchSize)
+	}
+	return 1000
+}
+
+type EnsCheckedDictionary struct {
+	mux        sync.Mutex
+	address    map[common.Address]bool
+	name       map[string]bool
+	nameHashes ensNameHashCache
+	// removedNames are the unresolvable names found by the workers, they are deleted at once by flushRemovedNames
+	removedNames []string
+	// resolutions are the names of the current batch read by batchResolveEns, names without resolution are resolved one by one
+	resolutions map[string]*ensResolution
+	// skippedNames counts the validations of names that were skipped as the names were already validated in this run
+	skippedNames int
+	// audits are the outcomes of the name validations, they are written at once by flushValidationAudits
+	audits []ensValidationAudit
+}
+
+func (alreadyChecked *EnsCheckedDictionary) setResolutions(resolutions map[string]*ensResolution) {
+	alreadyChecked.mux.Lock()
+	defer alreadyChecked.mux.Unlock()
+	alreadyChecked.resolutions = resolutions
+}
+
+func (alreadyChecked *EnsCheckedDictionary) resolution(name string) *ensResolution {
+	alreadyChecked.mux.Lock()
+	defer alreadyChecked.mux.Unlock()
+	return alreadyChecked.resolutions[name]
+}
+
+// ensNameRemover deletes the given names from the ens table, it is replaced in tests that run without a database
+var ensNameRemover = removeEnsNames
+
+func (alreadyChecked *EnsCheckedDictionary) removeName(name string) {
+	alreadyChecked.mux.Lock()
+	defer alreadyChecked.mux.Unlock()
+	alreadyChecked.removedNames = append(alreadyChecked.removedNames, name)
+}
+
+// flushRemovedNames deletes the names collected since the last flush with a single statement
+func (alreadyChecked *EnsCheckedDictionary) flushRemovedNames() error {
+	alreadyChecked.mux.Lock()
+	names := alreadyChecked.removedNames
+	alreadyChecked.removedNames = nil
+	alreadyChecked.mux.Unlock()
+	if len(names) == 0 {
+		return nil
+	}
+	return ensNameRemover(names)
+}
+
+// ensResolveCache caches the resolved addresses of names and the primary names of addresses across ens update runs.
+// Every entry carries the expiry of its name, so a name is never served from the cache after it expired.
+type ensResolveCache struct {
+	cache *freecache.Cache
+}
+
+var sharedEnsResolveCache ensResolveCache
+
+// SetEnsResolveCache sets the cache that is consulted before names and addresses are resolved via the node.
+// It should not be cleared between indexing runs, as its purpose is to span them.
+func SetEnsResolveCache(cache *freecache.Cache) {
+	sharedEnsResolveCache.cache = cache
+}
+
+func ensResolveCacheNameKey(nameHash [32]byte) []byte {
+	return []byte(fmt.Sprintf("ENS:R:H:%x", nameHash))
+}
+
+func ensResolveCacheAddressKey(address common.Address) []byte {
+	return []byte(fmt.Sprintf("ENS:R:A:%x", address))
+}
+
+func (c *ensResolveCache) set(key []byte, value []byte, validTo time.Time) {
+	if c.cache == nil {
+		return
+	}
+	ttl := utils.Config.Indexer.EnsTransformer.ResolveCacheTTL
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	if untilExpiry := time.Until(validTo); untilExpiry < ttl {
+		ttl = untilExpiry
+	}
+	if ttl < time.Second {
+		return
+	}
+	entry := make([]byte, 8, 8+len(value))
+	binary.BigEndian.PutUint64(entry, uint64(validTo.Unix()))
+	entry = append(entry, value...)
+	err := c.cache.Set(key, entry, int(ttl.Seconds()))
+	if err != nil {
+		logger.Warnf("error caching ens resolution %s: %v", key, err)
+	}
+}
+
+func (c *ensResolveCache) get(key []byte) ([]byte, bool) {
+	if c.cache == nil {
+		return nil, false
+	}
+	entry, err := c.cache.Get(key)
+	if err != nil || len(entry) < 8 {
+		return nil, false
+	}
+	if time.Unix(int64(binary.BigEndian.Uint64(entry)), 0).Before(time.Now()) {
+		c.cache.Del(key)
+		return nil, false
+	}
+	return entry[8:], true
+}
+
+func (c *ensResolveCache) setAddress(nameHash [32]byte, address common.Address, validTo time.Time) {
+	c.set(ensResolveCacheNameKey(nameHash), address.Bytes(), validTo)
+}
+
+func (c *ensResolveCache) getAddress(nameHash [32]byte) (common.Address, bool) {
+	value, ok := c.get(ensResolveCacheNameKey(nameHash))
+	if !ok || len(value) != common.AddressLength {
+		return common.Address{}, false
+	}
+	return common.BytesToAddress(value), true
+}
+
+func (c *ensResolveCache) setPrimaryName(address common.Address, name string, validTo time.Time) {
+	c.set(ensResolveCacheAddressKey(address), []byte(name), validTo)
+}
+
+func (c *ensResolveCache) getPrimaryName(address common.Address) (string, bool) {
+	value, ok := c.get(ensResolveCacheAddressKey(address))
+	return string(value), ok
+}
+
+// invalidate drops the cached resolution of the name or address of a dirty ENS:V key, as its records just changed
+func (c *ensResolveCache) invalidate(key string) {
+	if c.cache == nil {
+		return
+	}
+	split := strings.Split(key, ":")
+	if len(split) < 5 || split[1] != "ENS" || split[2] != "V" {
+		return
+	}
+	switch split[3] {
+	case "N":
+		nameHash, err := ensNameHash(split[4])
+		if err == nil {
+			c.cache.Del(ensResolveCacheNameKey(nameHash))
+		}
+	case "H":
+		nameHash, err := hex.DecodeString(split[4])
+		if err == nil && len(nameHash) == 32 {
+			c.cache.Del(ensResolveCacheNameKey(common.BytesToHash(nameHash)))
+		}
+	case "A":
+		address, err := hex.DecodeString(split[4])
+		if err == nil {
+			c.cache.Del(ensResolveCacheAddressKey(common.BytesToAddress(address)))
+		}
+	}
+}
+
+// forgetName drops the cached resolution of a name
+func (c *ensResolveCache) forgetName(nameHash [32]byte) {
+	if c.cache != nil {
+		c.cache.Del(ensResolveCacheNameKey(nameHash))
+	}
+}
+
+// forgetAddress drops the cached primary name of an address
+func (c *ensResolveCache) forgetAddress(address common.Address) {
+	if c.cache != nil {
+		c.cache.Del(ensResolveCacheAddressKey(address))
+	}
+}
+
+// ImportEnsUpdates validates the dirty ENS:V keys batch by batch. A cancelled ctx stops the run between two batches, the validations of a
+// batch that are still running are cancelled as well and its keys are kept for the next run.
+func (bigtable *Bigtable) ImportEnsUpdates(ctx context.Context, client *ethclient.Client) error {
+	if utils.Config.Indexer.EnsTransformer.AutoUpdateRegistrarContracts {
+		err := bigtable.UpdateEnsRegistrarContracts()
+		if err != nil {
+			return err
+		}
+	}
+
+	// the events of the confirmed blocks are written first, so the validations below see the registrations of their names
+	err := bigtable.importEnsEvents(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	prefix := fmt.Sprintf("%s:ENS:V", bigtable.chainId)
+	batchSize, readTimeout := ensImportSettings()
+	alreadyChecked := EnsCheckedDictionary{
+		address: make(map[common.Address]bool),
+		name:    make(map[string]bool),
+	}
+
+	// the keys are read and validated batch by batch, so a large backlog is never held in memory at once and
+	// the batches that were validated before a failure or a cancellation stay deleted
+	start := time.Now()
+	rpcCallsBefore := atomic.LoadUint64(&ensRpcCalls)
+	total := 0
+	after := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			logger.Infof("ens update run stopped after %v keys: %v", total, err)
+			return err
+		}
+		keys, err := bigtable.readEnsKeyBatch(ctx, prefix, after, batchSize, readTimeout)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			break
+		}
+		metrics.EnsKeysRead.Add(float64(len(keys)))
+		logger.Infof("Batching ENS entries %v:%v", total, total+len(keys))
+		total += len(keys)
+		err = bigtable.validateEnsKeys(ctx, client, keys, &alreadyChecked)
+		if err != nil {
+			return err
+		}
+		if len(keys) < batchSize {
+			break
+		}
+		// keys with a transient error are kept, so the next batch starts after the last key instead of at the prefix
+		after = keys[len(keys)-1]
+	}
+	// every batch deleted its validated keys before the next one was read, so only a run that got here is reported as healthy
+	metrics.EnsLastImportSuccess.Set(float64(time.Now().Unix()))
+	metrics.EnsLastImportDuration.Set(time.Since(start).Seconds())
+	if total == 0 {
+		logger.Info("No ENS entries to validate")
+		return nil
+	}
+	alreadyChecked.mux.Lock()
+	skippedNames := alreadyChecked.skippedNames
+	alreadyChecked.mux.Unlock()
+	logger.Infof("ens key indexing completed, validated %v keys with %v node lookups, skipped %v validations of names already validated in this run", total, atomic.LoadUint64(&ensRpcCalls)-rpcCallsBefore, skippedNames)
+	return nil
+}
+
+// MonitorEnsDirtyKeys periodically counts the dirty ens keys for the backlog metric. Counting reads every key, so it runs on its own slow
+// interval instead of after every ens update run, a count has to complete within the interval.
+func (bigtable *Bigtable) MonitorEnsDirtyKeys(ctx context.Context, interval time.Duration) {
+	prefix := fmt.Sprintf("%s:ENS:V", bigtable.chainId)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		count, err := bigtable.countEnsKeys(ctx, prefix, interval)
+		if err != nil && ctx.Err() == nil {
+			utils.LogError(err, "error counting dirty ens keys", 0)
+		}
+		if err == nil {
+			metrics.EnsDirtyKeys.Set(float64(count))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// countEnsKeys counts the keys with the given prefix, only the keys are read
+func (bigtable *Bigtable) countEnsKeys(ctx context.Context, prefix string, readTimeout time.Duration) (int, error) {
+	readCtx, done := context.WithTimeout(ctx, readTimeout)
+	defer done()
+
+	count := 0
+	err := bigtable.getEnsTable().ReadRows(readCtx, gcp_bigtable.PrefixRange(prefix), func(row gcp_bigtable.Row) bool {
+		count++
+		return true
+	}, gcp_bigtable.RowFilter(gcp_bigtable.ChainFilters(gcp_bigtable.CellsPerRowLimitFilter(1), gcp_bigtable.StripValueFilter())))
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// readEnsKeyBatch reads up to batchSize dirty keys with the given prefix that come after the given key, an empty after
+// starts at the first key. Every batch is a read of its own, so the read timeout applies per batch.
+func (bigtable *Bigtable) readEnsKeyBatch(ctx context.Context, prefix, after string, batchSize int, readTimeout time.Duration) ([]string, error) {
+	readCtx, done := context.WithTimeout(ctx, readTimeout)
+	defer done()
+
+	var rowSet gcp_bigtable.RowSet = gcp_bigtable.PrefixRange(prefix)
+	if after != "" {
+		rowSet = gcp_bigtable.NewRange(after+"\x00", ensPrefixSuccessor(prefix))
+	}
+	keys := make([]string, 0, batchSize)
+	err := bigtable.getEnsTable().ReadRows(readCtx, rowSet, func(row gcp_bigtable.Row) bool {
+		keys = append(keys, row[DEFAULT_FAMILY][0].Row)
+		return len(keys) < batchSize
+	}, gcp_bigtable.LimitRows(int64(batchSize)))
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// importEnsEvents writes the events of the ENS:E rows of confirmed blocks to postgres and deletes the rows afterwards. The rows of unconfirmed
+// blocks are kept for a later run, the rows of orphaned blocks are deleted by the reorg handling before they are confirmed.
+// The events of a batch are written in one transaction, so the rows of a failed batch are imported again by the next run.
+func (bigtable *Bigtable) importEnsEvents(ctx context.Context, client *ethclient.Client) error {
+	prefix := fmt.Sprintf("%s:ENS:E:", bigtable.chainId)
+	batchSize, readTimeout := ensImportSettings()
+
+	mutDelete := gcp_bigtable.NewMutation()
+	mutDelete.DeleteRow()
+	head := uint64(0)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rows, err := bigtable.readEnsEventRows(ctx, prefix, batchSize, readTimeout)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		if head == 0 {
+			head, err = client.BlockNumber(ctx)
+			if err != nil {
+				return fmt.Errorf("error getting head block for ens events: %w", err)
+			}
+		}
+		events, imported := decodeConfirmedEnsEvents(rows, head)
+		if len(imported) == 0 {
+			return nil
+		}
+		err = saveEnsEvents(events)
+		if err != nil {
+			return err
+		}
+		mutsDelete := &types.BulkMutations{
+			Keys: imported,
+			Muts: make([]*gcp_bigtable.Mutation, len(imported)),
+		}
+		for i := range imported {
+			mutsDelete.Muts[i] = mutDelete
+		}
+		err = bigtable.getEnsTable().WriteBulk(mutsDelete)
+		if err != nil {
+			return fmt.Errorf("error deleting %v imported ens event rows: %w", len(imported), err)
+		}
+		logger.Infof("imported ens events of %v blocks", len(events))
+		if len(imported) < len(rows) || len(rows) < batchSize {
+			return nil
+		}
+	}
+}
+
+// readEnsEventRows reads up to batchSize ENS:E rows starting at the lowest block
+func (bigtable *Bigtable) readEnsEventRows(ctx context.Context, prefix string, batchSize int, readTimeout time.Duration) ([]gcp_bigtable.Row, error) {
+	readCtx, done := context.WithTimeout(ctx, readTimeout)
+	defer done()
+
+	rows := make([]gcp_bigtable.Row, 0, batchSize)
+	err := bigtable.getEnsTable().ReadRows(readCtx, gcp_bigtable.PrefixRange(prefix), func(row gcp_bigtable.Row) bool {
+		rows = append(rows, row)
+		return len(rows) < batchSize
+	}, gcp_bigtable.LimitRows(int64(batchSize)))
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// decodeConfirmedEnsEvents decodes the ENS:E rows of the blocks that are confirmed at the given head and returns the keys of the rows that
+// can be deleted once the events are written. The rows are sorted by block, so the first unconfirmed block ends the import.
+// A row that can not be decoded is deleted without importing it, it would block the import of the following blocks otherwise.
+func decodeConfirmedEnsEvents(rows []gcp_bigtable.Row, head uint64) (events []*ensBlockEvents, imported []string) {
+	for _, row := range rows {
+		var value []byte
+		for _, item := range row[DEFAULT_FAMILY] {
+			if item.Column == DEFAULT_FAMILY+":"+ENS_EVENTS_COLUMN {
+				value = item.Value
+			}
+		}
+		blockEvents := &ensBlockEvents{}
+		err := json.Unmarshal(value, blockEvents)
+		if err != nil {
+			logger.Warnf("dropping ens event row %v: %v", row.Key(), err)
+			imported = append(imported, row.Key())
+			continue
+		}
+		if !ensEventsConfirmed(blockEvents.BlockNumber, head) {
+			break
+		}
+		events = append(events, blockEvents)
+		imported = append(imported, row.Key())
+	}
+	return events, imported
+}
+
+// saveEnsEvents writes the events of confirmed blocks to postgres in one transaction, reimporting a block will not create duplicates
+func saveEnsEvents(events []*ensBlockEvents) error {
+	tx, err := WriterDb.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, blockEvents := range events {
+		for _, name := range blockEvents.UndecodableNames {
+			err = saveUndecodableEnsName(tx, name)
+			if err != nil {
+				return fmt.Errorf("error saving undecodable ens name of block %v: %w", blockEvents.BlockNumber, err)
+			}
+		}
+		err = saveEnsRegistrations(tx, blockEvents.Registrations)
+		if err != nil {
+			return fmt.Errorf("error saving ens registrations of block %v: %w", blockEvents.BlockNumber, err)
+		}
+		err = saveEnsRenewals(tx, blockEvents.Renewals)
+		if err != nil {
+			return fmt.Errorf("error saving ens renewals of block %v: %w", blockEvents.BlockNumber, err)
+		}
+		err = saveEnsTransfers(tx, blockEvents.Transfers)
+		if err != nil {
+			return fmt.Errorf("error saving ens transfers of block %v: %w", blockEvents.BlockNumber, err)
+		}
+		err = saveEnsMulticoinAddresses(tx, blockEvents.MulticoinAddresses)
+		if err != nil {
+			return fmt.Errorf("error saving ens multicoin addresses of block %v: %w", blockEvents.BlockNumber, err)
+		}
+		err = saveEnsHistory(tx, blockEvents.AddressChanges)
+		if err != nil {
+			return fmt.Errorf("error saving ens address history of block %v: %w", blockEvents.BlockNumber, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ensPrefixSuccessor returns the smallest key that is greater than every key with the given prefix
+func ensPrefixSuccessor(prefix string) string {
+	successor := []byte(prefix)
+	for len(successor) > 0 {
+		if successor[len(successor)-1] < 0xff {
+			successor[len(successor)-1]++
+			return string(successor)
+		}
+		successor = successor[:len(successor)-1]
+	}
+	return ""
+}
+
+// ensImportSettings returns the configured batch size and read timeout of an ens update run with their defaults applied
+func ensImportSettings() (batchSize int, readTimeout time.Duration) {
+	batchSize = utils.Config.Indexer.EnsTransformer.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	readTimeout = time.Second * 30
+	if seconds := utils.Config.Indexer.EnsTransformer.ReadTimeoutSeconds; seconds > 0 {
+		readTimeout = time.Second * time.Duration(seconds)
+	}
+	return batchSize, readTimeout
+}
+
+// ensValidationConcurrency returns the configured number of keys of a batch that are validated at once, defaults to 10
+func ensValidationConcurrency() int {
+	concurrency := utils.Config.Indexer.EnsTransformer.Concurrency
+	if concurrency <= 0 {
+		return 10
+	}
+	return concurrency
+}
+
+// runWithEnsTimeout runs the validation of a single key and gives up waiting for it once the timeout or the context expires.
+// The node calls of the ens library can not be cancelled, so an abandoned validation finishes its current call in the background
+// and stops at its next wait for the rate limit, which gets the context of the validation.
+func runWithEnsTimeout(ctx context.Context, timeout time.Duration, validate func(ctx context.Context) error) error {
+	if timeout <= 0 {
+		return validate(ctx)
+	}
+	ctx, done := context.WithTimeout(ctx, timeout)
+	defer done()
+	result := make(chan error, 1)
+	go func() {
+		result <- validate(ctx)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ValidateEnsKeys validates the names and addresses of the given ENS:V keys and removes the keys from bigtable afterwards
+func (bigtable *Bigtable) ValidateEnsKeys(client *ethclient.Client, keys []string) error {
+	alreadyChecked := EnsCheckedDictionary{
+		address: make(map[common.Address]bool),
+		name:    make(map[string]bool),
+	}
+	return bigtable.validateEnsKeys(context.Background(), client, keys, &alreadyChecked)
+}
+
+func (bigtable *Bigtable) validateEnsKeys(ctx context.Context, client *ethclient.Client, keys []string, alreadyChecked *EnsCheckedDictionary) error {
+	// the validation outcomes of the batch are written at once, also when the batch fails
+	defer alreadyChecked.flushValidationAudits()
+	mutsDelete := &types.BulkMutations{
+		Keys: make([]string, 0, len(keys)),
+		Muts: make([]*gcp_bigtable.Mutation, 0, len(keys)),
+	}
+
+	deletedMux := sync.Mutex{}
+	resolveTimeout := time.Second * time.Duration(utils.Config.Indexer.EnsTransformer.ResolveTimeoutSeconds)
+	concurrency := ensValidationConcurrency()
+	mutDelete := gcp_bigtable.NewMutation()
+	mutDelete.DeleteRow()
+	textRecords := []ensTextRecordKey{}
+	dirtyKeys := make([]ensDirtyKey, 0, len(keys))
+	batchNames := []string{}
+	// changedNodes are the nodes whose records changed, they may be wildcard parents whose subnames resolve differently now
+	changedNodes := [][]byte{}
+	for _, key := range keys {
+		var name string
+		var address *common.Address
+		split := strings.Split(key, ":")
+		value := split[4]
+		switch split[3] {
+		case "T":
+			// the text key may contain colons, it is everything after the name hash
+			parts := strings.SplitN(key, ":", 6)
+			nameHash, err := decodeEnsNameHash(value)
+			if err == nil && len(parts) != 6 {
+				err = fmt.Errorf("missing text record key")
+			}
+			if err != nil {
+				logger.Warnf("dropping ens text record key %v: %v", key, err)
+			} else {
+				textRecords = append(textRecords, ensTextRecordKey{dirtyKey: key, nameHash: nameHash, key: parts[5]})
+				changedNodes = append(changedNodes, nameHash)
+				// the key is deleted once its record was validated
+				continue
+			}
+		case "H":
+			// if we have a hash we look if we find a name in the db. If not we can ignore it.
+			nameHash, err := decodeEnsNameHash(value)
+			if err != nil {
+				// a malformed hash would not match any name, so the key is dropped instead of querying for it
+				logger.Warnf("dropping ens key %v: %v", key, err)
+			} else {
+				err := ReaderDb.Get(&name, `
+				SELECT
+					ens_name
+				FROM ens
+				WHERE 
+					name_hash = $1 AND
+					NOT name_undecodable
+				`, nameHash[:])
+				if err != nil && err != sql.ErrNoRows {
+					return err
+				}
+				if name != "" {
+					changedNodes = append(changedNodes, nameHash)
+				}
+			}
+		case "A":
+			addressHash, err := hex.DecodeString(value)
+			if err != nil {
+				utils.LogError(err, fmt.Errorf("address hash could not be decoded: %v", value), 0)
+			} else {
+				add := common.BytesToAddress(addressHash)
+				address = &add
+			}
+		case "N":
+			name = value
+		}
+		dirtyKeys = append(dirtyKeys, ensDirtyKey{key: key, name: name, address: address})
+		// names that are too long are dropped by their validation, they must not fail the batch resolution of the others
+		if name != "" && checkEnsNameLength(utils.NormalizeEnsName(name)) == nil {
+			batchNames = append(batchNames, utils.NormalizeEnsName(name))
+		}
+	}
+
+	if len(batchNames) > 0 {
+		resolutions, err := batchResolveEns(ctx, client, batchNames)
+		if err != nil {
+			logger.Warnf("error batch resolving %v ens names, resolving them one by one: %v", len(batchNames), err)
+		}
+		alreadyChecked.setResolutions(resolutions)
+	}
+
+	validateKey := func(gCtx context.Context, key, name string, address *common.Address) error {
+		if gCtx.Err() != nil {
+			return gCtx.Err()
+		}
+		var err error
+		if name != "" {
+			start := time.Now()
+			err = runWithEnsTimeout(gCtx, resolveTimeout, func(ctx context.Context) error {
+				return validateEnsName(ctx, client, name, alreadyChecked, nil, nil)
+			})
+			if err == nil {
+				metrics.TaskDuration.WithLabelValues("ens_validate_name").Observe(time.Since(start).Seconds())
+			}
+		} else if address != nil {
+			err = runWithEnsTimeout(gCtx, resolveTimeout, func(ctx context.Context) error {
+				return validateEnsAddress(ctx, client, *address, alreadyChecked)
+			})
+		}
+		var transientErr *ensTransientError
+		if errors.As(err, &transientErr) {
+			logger.Warnf("keeping ens key %v for the next run: %v", key, err)
+			return nil
+		}
+		if err != nil {
+			if name != "" {
+				metrics.EnsNamesValidated.WithLabelValues(ENS_VALIDATION_FAILED).Inc()
+			}
+			return err
+		}
+		deletedMux.Lock()
+		mutsDelete.Keys = append(mutsDelete.Keys, key)
+		mutsDelete.Muts = append(mutsDelete.Muts, mutDelete)
+		deletedMux.Unlock()
+		return nil
+	}
+	// the addresses are validated before the names, as the validation of an address validates the names it claims and resolves to
+	// on the way and the dictionary skips them for the name keys
+	for _, phase := range orderEnsDirtyKeys(dirtyKeys) {
+		g, gCtx := errgroup.WithContext(ctx)
+		g.SetLimit(concurrency)
+		for _, d := range phase {
+			key, name, address := d.key, d.name, d.address
+			g.Go(func() error {
+				return validateKey(gCtx, key, name, address)
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return err
+		}
+	}
+	if err := alreadyChecked.flushRemovedNames(); err != nil {
+		return err
+	}
+	if utils.Config.Indexer.EnsTransformer.EnableWildcardSubnames && len(changedNodes) > 0 {
+		if err := bigtable.queueEnsWildcardSubnames(ctx, client, changedNodes); err != nil {
+			return err
+		}
+	}
+	// text records are validated after the names, so the records of freshly registered names can be stored
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for _, r := range textRecords {
+		record := r
+		g.Go(func() error {
+			err := runWithEnsTimeout(gCtx, resolveTimeout, func(ctx context.Context) error {
+				return validateEnsTextRecord(ctx, client, record.nameHash, record.key)
+			})
+			var transientErr *ensTransientError
+			if errors.As(err, &transientErr) {
+				logger.Warnf("keeping ens key %v for the next run: %v", record.dirtyKey, err)
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			deletedMux.Lock()
+			mutsDelete.Keys = append(mutsDelete.Keys, record.dirtyKey)
+			mutsDelete.Muts = append(mutsDelete.Muts, mutDelete)
+			deletedMux.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	// After processing the keys we remove them from bigtable
+	return bigtable.getEnsTable().WriteBulk(mutsDelete)
+}
+
+// decodeEnsNameHash decodes the hex encoded name hash of a key, name hashes are stored as exactly 32 bytes
+func decodeEnsNameHash(value string) ([]byte, error) {
+	nameHash, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name hash %q: %w", value, err)
+	}
+	if len(nameHash) != 32 {
+		return nil, fmt.Errorf("invalid name hash %q: expected 32 bytes, got %v", value, len(nameHash))
+	}
+	return nameHash, nil
+}
+
+// ensDirtyKey is an ENS:V key with the name or address it refers to
+type ensDirtyKey struct {
+	key     string
+	name    string
+	address *common.Address
+}
+
+// orderEnsDirtyKeys splits the dirty keys into the phases they are validated in, the address keys come first and all other keys second.
+// An error of a phase cancels its remaining validations, so an error of the address phase leaves the name keys for the next run.
+func orderEnsDirtyKeys(dirtyKeys []ensDirtyKey) [][]ensDirtyKey {
+	addresses := make([]ensDirtyKey, 0, len(dirtyKeys))
+	others := make([]ensDirtyKey, 0, len(dirtyKeys))
+	for _, d := range dirtyKeys {
+		if d.name == "" && d.address != nil {
+			addresses = append(addresses, d)
+		} else {
+			others = append(others, d)
+		}
+	}
+	return [][]ensDirtyKey{addresses, others}
+}
+
+// ensResolution is the state of a name read by batchResolveEns. Err is set if the name has no resolver and
+// reverseErr if the reverse record of the address could not be read, analogous to the errors of the per name node calls.
+type ensResolution struct {
+	address     common.Address
+	resolver    common.Address
+	err         error
+	owner       common.Address
+	registrant  common.Address
+	expires     time.Time
+	reverseName string
+	reverseErr  error
+}
+
+// batchResolveEns resolves the addresses, expiries and primary names of a batch of names with a few multicall rounds instead of
+// several node calls per name. Only .eth second level names are part of the result, names that are left out take the per name path.
+func batchResolveEns(ctx context.Context, client *ethclient.Client, names []string) (map[string]*ensResolution, error) {
+	baseRegistrar := utils.Config.Indexer.EnsTransformer.BaseRegistrarContract
+	if baseRegistrar == "" {
+		return nil, fmt.Errorf("no base registrar contract configured")
+	}
+	multicall := utils.Config.Indexer.EnsTransformer.MulticallContract
+	if multicall == "" {
+		multicall = ens.Multicall3Address
+	}
+	if err := waitForEnsRpc(ctx); err != nil {
+		return nil, err
+	}
+	registry, err := go_ens.NewRegistry(client)
+	if err != nil {
+		return nil, err
+	}
+	return batchResolveEnsWith(ctx, client, common.HexToAddress(multicall), registry.ContractAddr, common.HexToAddress(baseRegistrar), names)
+}
+
+// ensBatchCall is a call of the registry, a resolver or the base registrar that is run via multicall
+type ensBatchCall struct {
+	target common.Address
+	method string
+	args   []interface{}
+}
+
+// aggregateEnsCalls runs the calls with a single multicall, the result of a failed call is nil
+func aggregateEnsCalls(ctx context.Context, multicall *ens.Multicall3Caller, calls []ensBatchCall) ([][]interface{}, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+	multicallCalls := make([]ens.Multicall3Call, 0, len(calls))
+	for _, call := range calls {
+		data, err := ens.EnsCallsABI.Pack(call.method, call.args...)
+		if err != nil {
+			return nil, err
+		}
+		multicallCalls = append(multicallCalls, ens.Multicall3Call{Target: call.target, AllowFailure: true, CallData: data})
+	}
+	if err := waitForEnsRpc(ctx); err != nil {
+		return nil, err
+	}
+	results, err := multicall.Aggregate3(nil, multicallCalls)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) != len(calls) {
+		return nil, fmt.Errorf("multicall returned %v results for %v calls", len(results), len(calls))
+	}
+	values := make([][]interface{}, len(calls))
+	for i, result := range results {
+		if !result.Success {
+			continue
+		}
+		unpacked, err := ens.EnsCallsABI.Unpack(calls[i].method, result.ReturnData)
+		if err != nil || len(unpacked) == 0 {
+			continue
+		}
+		values[i] = unpacked
+	}
+	return values, nil
+}
+
+func batchResolveEnsWith(ctx context.Context, caller bind.ContractCaller, multicallAddress, registry, baseRegistrar common.Address, names []string) (map[string]*ensResolution, error) {
+	multicall, err := ens.NewMulticall3Caller(multicallAddress, caller)
+	if err != nil {
+		return nil, err
+	}
+	type pendingName struct {
+		name       string
+		node       [32]byte
+		resolver   common.Address
+		resolution *ensResolution
+	}
+
+	// the resolver, owner, expiry and registrant of every name
+	pending := []*pendingName{}
+	calls := []ensBatchCall{}
+	for _, name := range names {
+		labels := strings.Split(name, ".")
+		if len(labels) != 2 || labels[1] != "eth" {
+			continue
+		}
+		node, err := ensNameHash(name)
+		if err != nil {
+			continue
+		}
+		label := crypto.Keccak256Hash([]byte(labels[0]))
+		pending = append(pending, &pendingName{name: name, node: node})
+		calls = append(calls,
+			ensBatchCall{target: registry, method: "resolver", args: []interface{}{node}},
+			ensBatchCall{target: registry, method: "owner", args: []interface{}{node}},
+			ensBatchCall{target: baseRegistrar, method: "nameExpires", args: []interface{}{label.Big()}},
+			ensBatchCall{target: baseRegistrar, method: "ownerOf", args: []interface{}{label.Big()}})
+	}
+	values, err := aggregateEnsCalls(ctx, multicall, calls)
+	if err != nil {
+		return nil, err
+	}
+	resolutions := make(map[string]*ensResolution, len(pending))
+	withResolver := []*pendingName{}
+	calls = []ensBatchCall{}
+	for i, p := range pending {
+		resolver, owner, expires, registrant := values[i*4], values[i*4+1], values[i*4+2], values[i*4+3]
+		if resolver == nil || owner == nil || expires == nil || expires[0].(*big.Int).Sign() == 0 {
+			continue
+		}
+		p.resolver = resolver[0].(common.Address)
+		p.resolution = &ensResolution{resolver: p.resolver, owner: owner[0].(common.Address), expires: time.Unix(expires[0].(*big.Int).Int64(), 0)}
+		if registrant != nil {
+			// ownerOf reverts for expired names, their owner is left to the per name lookup
+			p.resolution.registrant = registrant[0].(common.Address)
+		}
+		if p.resolver == (common.Address{}) {
+			p.resolution.err = fmt.Errorf("no resolver")
+			resolutions[p.name] = p.resolution
+			continue
+		}
+		withResolver = append(withResolver, p)
+		calls = append(calls, ensBatchCall{target: p.resolver, method: "addr", args: []interface{}{p.node}})
+	}
+
+	// the address of every name with a resolver
+	values, err = aggregateEnsCalls(ctx, multicall, calls)
+	if err != nil {
+		return nil, err
+	}
+	resolved := []*pendingName{}
+	reverseNodes := map[common.Address][32]byte{}
+	reverseAddresses := []common.Address{}
+	calls = []ensBatchCall{}
+	for i, p := range withResolver {
+		if values[i] == nil {
+			continue
+		}
+		p.resolution.address = values[i][0].(common.Address)
+		resolved = append(resolved, p)
+		if _, ok := reverseNodes[p.resolution.address]; ok {
+			continue
+		}
+		reverseNode, err := ensNameHash(fmt.Sprintf("%x.addr.reverse", p.resolution.address.Bytes()))
+		if err != nil {
+			return nil, err
+		}
+		reverseNodes[p.resolution.address] = reverseNode
+		reverseAddresses = append(reverseAddresses, p.resolution.address)
+		calls = append(calls, ensBatchCall{target: registry, method: "resolver", args: []interface{}{reverseNode}})
+	}
+
+	// the reverse resolver of every resolved address
+	values, err = aggregateEnsCalls(ctx, multicall, calls)
+	if err != nil {
+		return nil, err
+	}
+	reverseErrs := map[common.Address]error{}
+	withReverseResolver := []common.Address{}
+	calls = []ensBatchCall{}
+	for i, address := range reverseAddresses {
+		if values[i] == nil {
+			reverseErrs[address] = fmt.Errorf("error reading reverse resolver of %v", address.Hex())
+			continue
+		}
+		reverseResolver := values[i][0].(common.Address)
+		if reverseResolver == (common.Address{}) {
+			reverseErrs[address] = fmt.Errorf("no resolution")
+			continue
+		}
+		withReverseResolver = append(withReverseResolver, address)
+		calls = append(calls, ensBatchCall{target: reverseResolver, method: "name", args: []interface{}{reverseNodes[address]}})
+	}
+
+	// the primary name of every address with a reverse resolver
+	values, err = aggregateEnsCalls(ctx, multicall, calls)
+	if err != nil {
+		return nil, err
+	}
+	reverseNames := map[common.Address]string{}
+	for i, address := range withReverseResolver {
+		if values[i] == nil {
+			reverseErrs[address] = fmt.Errorf("error reading reverse name of %v", address.Hex())
+			continue
+		}
+		reverseName := values[i][0].(string)
+		if reverseName == "" {
+			reverseErrs[address] = fmt.Errorf("no resolution")
+			continue
+		}
+		reverseNames[address] = reverseName
+	}
+
+	for _, p := range resolved {
+		p.resolution.reverseName = reverseNames[p.resolution.address]
+		p.resolution.reverseErr = reverseErrs[p.resolution.address]
+		resolutions[p.name] = p.resolution
+	}
+	return resolutions, nil
+}
+
+// RequeueStaleEnsNames periodically marks the least recently validated names as dirty, so every name is revalidated within the given period
+// even if it never sees a new event again. Each run requeues the share of all names that is due within the interval.
+func (bigtable *Bigtable) RequeueStaleEnsNames(ctx context.Context, period, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		err := bigtable.requeueStaleEnsNames(period, interval)
+		if err != nil {
+			utils.LogError(err, "error requeueing stale ens names", 0)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (bigtable *Bigtable) requeueStaleEnsNames(period, interval time.Duration) error {
+	var total int
+	err := ReaderDb.Get(&total, `
+	SELECT COUNT(*)
+	FROM ens
+	WHERE NOT name_undecodable
+	`)
+	if err != nil {
+		return err
+	}
+	limit := ensRequeueBatchSize(total, period, interval)
+	if limit == 0 {
+		return nil
+	}
+	nameHashes := [][]byte{}
+	err = ReaderDb.Select(&nameHashes, `
+	SELECT name_hash
+	FROM ens
+	WHERE NOT name_undecodable
+	ORDER BY last_validated_at ASC NULLS FIRST
+	LIMIT $1
+	`, limit)
+	if err != nil {
+		return err
+	}
+	logger.Infof("requeueing %v of %v ens names for revalidation", len(nameHashes), total)
+	return bigtable.queueEnsNameHashes(nameHashes)
+}
+
+// ensRequeueBatchSize returns the number of names that have to be requeued per interval to revalidate all names within the period
+func ensRequeueBatchSize(total int, period, interval time.Duration) int {
+	if total <= 0 || period <= 0 || interval <= 0 {
+		return 0
+	}
+	if interval >= period {
+		return total
+	}
+	return int(math.Ceil(float64(total) * float64(interval) / float64(period)))
+}
+
+// queueEnsNameHashes writes the ENS:V:H keys of the given names, so they are validated by the next ImportEnsUpdates run
+func (bigtable *Bigtable) queueEnsNameHashes(nameHashes [][]byte) error {
+	mutations := &types.BulkMutations{
+		Keys: make([]string, 0, len(nameHashes)),
+		Muts: make([]*gcp_bigtable.Mutation, 0, len(nameHashes)),
+	}
+	for _, nameHash := range nameHashes {
+		mut := gcp_bigtable.NewMutation()
+		mut.Set(DEFAULT_FAMILY, fmt.Sprintf("%s:ENS:V:H:%x", bigtable.chainId, nameHash), gcp_bigtable.Timestamp(0), nil)
+
+		mutations.Keys = append(mutations.Keys, fmt.Sprintf("%s:ENS:V:H:%x", bigtable.chainId, nameHash))
+		mutations.Muts = append(mutations.Muts, mut)
+	}
+	return bigtable.getEnsTable().WriteBulk(mutations)
+}
+
+// reversedEnsTimestamp pads the inverted unix time of a registration, so a scan of the ENS:T index returns the newest registrations first
+func reversedEnsTimestamp(ts time.Time) string {
+	return fmt.Sprintf("%019d", MAX_INT-ts.Unix())
+}
+
+// GetRecentEnsRegistrations returns the most recently registered names from the ENS:T index, newest first.
+// Registrations of names that are not (or no longer) stored in the ens table are left out.
+func (bigtable *Bigtable) GetRecentEnsRegistrations(limit int) ([]types.EnsRecentRegistration, error) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	registrations := make([]types.EnsRecentRegistration, 0, limit)
+	nameHashes := make(pq.ByteaArray, 0, limit)
+	prefix := fmt.Sprintf("%s:ENS:T:", bigtable.chainId)
+	err := bigtable.getEnsTable().ReadRows(ctx, gcp_bigtable.PrefixRange(prefix), func(row gcp_bigtable.Row) bool {
+		split := strings.Split(strings.TrimPrefix(row.Key(), prefix), ":")
+		if len(split) != 2 {
+			return true
+		}
+		reversed, err := strconv.ParseInt(split[0], 10, 64)
+		if err != nil {
+			return true
+		}
+		nameHash, err := decodeEnsNameHash(split[1])
+		if err != nil {
+			return true
+		}
+		registrations = append(registrations, types.EnsRecentRegistration{NameHash: nameHash, Ts: time.Unix(MAX_INT-reversed, 0)})
+		nameHashes = append(nameHashes, nameHash)
+		return len(registrations) < limit
+	}, gcp_bigtable.LimitRows(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	if len(registrations) == 0 {
+		return registrations, nil
+	}
+
+	names := []struct {
+		NameHash []byte `db:"name_hash"`
+		Name     string `db:"ens_name"`
+	}{}
+	err = ReaderDb.Select(&names, `
+	SELECT name_hash, ens_name
+	FROM ens
+	WHERE name_hash = ANY($1) AND NOT name_undecodable
+	`, nameHashes)
+	if err != nil {
+		return nil, err
+	}
+	namesByHash := make(map[string]string, len(names))
+	for _, name := range names {
+		namesByHash[string(name.NameHash)] = name.Name
+	}
+	known := registrations[:0]
+	for _, registration := range registrations {
+		name, ok := namesByHash[string(registration.NameHash)]
+		if !ok {
+			continue
+		}
+		registration.Name = name
+		known = append(known, registration)
+	}
+	return known, nil
+}
+
+// GetEnsTransactionsForAddress returns a page of the hashes of the ens transactions of an address from the ENS:I:A index.
+// The returned page token continues after the last returned transaction, it is empty if there are no more transactions.
+func (bigtable *Bigtable) GetEnsTransactionsForAddress(address common.Address, limit int, pageToken string) ([]common.Hash, string, error) {
+	return bigtable.getEnsTransactions(fmt.Sprintf("%s:ENS:I:A:%x:", bigtable.chainId, address), limit, pageToken)
+}
+
+// GetEnsTransactionsForNameHash returns a page of the hashes of the ens transactions of a name from the ENS:I:H index, see GetEnsTransactionsForAddress
+func (bigtable *Bigtable) GetEnsTransactionsForNameHash(nameHash common.Hash, limit int, pageToken string) ([]common.Hash, string, error) {
+	return bigtable.getEnsTransactions(fmt.Sprintf("%s:ENS:I:H:%x:", bigtable.chainId, nameHash), limit, pageToken)
+}
+
+func (bigtable *Bigtable) getEnsTransactions(prefix string, limit int, pageToken string) ([]common.Hash, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("invalid limit %v", limit)
+	}
+	var rowSet gcp_bigtable.RowSet = gcp_bigtable.PrefixRange(prefix)
+	if pageToken != "" {
+		// the page token is the last row key of the previous page
+		if !strings.HasPrefix(pageToken, prefix) {
+			return nil, "", fmt.Errorf("invalid page token %v", pageToken)
+		}
+		rowSet = gcp_bigtable.NewRange(pageToken+"\x00", ensPrefixSuccessor(prefix))
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	txHashes := make([]common.Hash, 0, limit)
+	lastKey := ""
+	err := bigtable.getEnsTable().ReadRows(ctx, rowSet, func(row gcp_bigtable.Row) bool {
+		txHash, err := hex.DecodeString(strings.TrimPrefix(row.Key(), prefix))
+		if err == nil && len(txHash) == 32 {
+			txHashes = append(txHashes, common.BytesToHash(txHash))
+		}
+		lastKey = row.Key()
+		return len(txHashes) < limit
+	}, gcp_bigtable.LimitRows(int64(limit)))
+	if err != nil {
+		return nil, "", err
+	}
+	nextPageToken := ""
+	if len(txHashes) == limit {
+		nextPageToken = lastKey
+	}
+	return txHashes, nextPageToken, nil
+}
+
+// ENS_NAME_HISTORY_TX_LIMIT limits the transactions of an address that are read from the ENS:I:A index for its name history
+const ENS_NAME_HISTORY_TX_LIMIT = 1000
+
+// GetEnsNameHistory returns the names an address is or was associated with. The names are collected from the registrations and
+// transfers of the ens transactions of the address, its past primary names and the names it currently resolves from, owns or claims.
+// A name is current if it still resolves to the address.
+func (bigtable *Bigtable) GetEnsNameHistory(address common.Address) ([]types.EnsNameRecord, error) {
+	txHashes, _, err := bigtable.GetEnsTransactionsForAddress(address, ENS_NAME_HISTORY_TX_LIMIT, "")
+	if err != nil {
+		return nil, fmt.Errorf("error getting ens transactions of address %v: %w", address, err)
+	}
+	txs := make(pq.ByteaArray, 0, len(txHashes))
+	for _, txHash := range txHashes {
+		txs = append(txs, txHash.Bytes())
+	}
+	records := []types.EnsNameRecord{}
+	err = ReaderDb.Select(&records, `
+	WITH associated AS (
+		SELECT name_hash FROM ens_registrations WHERE tx_hash = ANY($2) OR owner = $1
+		UNION
+		SELECT name_hash FROM ens_transfers WHERE tx_hash = ANY($2) OR from_address = $1 OR to_address = $1
+		UNION
+		SELECT ens.name_hash FROM ens_primary_name_changes changes INNER JOIN ens ON md5(ens.ens_name) = md5(changes.old_name) AND ens.ens_name = changes.old_name WHERE changes.address = $1
+		UNION
+		SELECT name_hash FROM ens WHERE address = $1 OR primary_claimed_by = $1 OR owner_address = $1
+	)
+	SELECT 
+		ens.name_hash, 
+		ens.ens_name, 
+		ens.valid_to,
+		COALESCE(ens.address = $1 AND ens.valid_to >= now(), false) AS is_current,
+		COALESCE(ens.address = $1 AND ens.is_primary_name, false) AS is_primary_name
+	FROM associated
+	INNER JOIN ens ON ens.name_hash = associated.name_hash
+	ORDER BY is_current DESC, ens.valid_to DESC
+	`, address.Bytes(), txs)
+	return records, err
+}
+
+// queueEnsAddresses writes the ENS:V:A keys of the given addresses, so their primary names are validated by the next ImportEnsUpdates run
+func (bigtable *Bigtable) queueEnsAddresses(addresses [][]byte) error {
+	mutations := &types.BulkMutations{
+		Keys: make([]string, 0, len(addresses)),
+		Muts: make([]*gcp_bigtable.Mutation, 0, len(addresses)),
+	}
+	for _, address := range addresses {
+		mut := gcp_bigtable.NewMutation()
+		mut.Set(DEFAULT_FAMILY, fmt.Sprintf("%s:ENS:V:A:%x", bigtable.chainId, address), gcp_bigtable.Timestamp(0), nil)
+
+		mutations.Keys = append(mutations.Keys, fmt.Sprintf("%s:ENS:V:A:%x", bigtable.chainId, address))
+		mutations.Muts = append(mutations.Muts, mut)
+	}
+	return bigtable.getEnsTable().WriteBulk(mutations)
+}
+
+// MonitorExpiringEnsNames periodically runs CheckExpiringEnsNames, see there
+func (bigtable *Bigtable) MonitorExpiringEnsNames(ctx context.Context, withinDays int, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		err := bigtable.CheckExpiringEnsNames(withinDays)
+		if err != nil {
+			utils.LogError(err, "error checking expiring ens names", 0)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// CheckExpiringEnsNames marks the addresses of the names that expire within the given number of days or expired within as many days as dirty.
+// Expiry does not emit an event, so without this an expired name would stay the primary name of its address until the next event of the address.
+func (bigtable *Bigtable) CheckExpiringEnsNames(withinDays int) error {
+	if withinDays <= 0 {
+		return nil
+	}
+	window := time.Hour * 24 * time.Duration(withinDays)
+	now := time.Now()
+	names, err := GetExpiringEnsNames(now.Add(-window), now.Add(window))
+	if err != nil {
+		return err
+	}
+	addresses := make([][]byte, 0, len(names))
+	seen := make(map[common.Address]bool, len(names))
+	for _, name := range names {
+		address := common.BytesToAddress(name.Address)
+		if len(name.Address) == 0 || seen[address] {
+			continue
+		}
+		seen[address] = true
+		addresses = append(addresses, name.Address)
+	}
+	if len(addresses) == 0 {
+		return nil
+	}
+	logger.Infof("queueing %v addresses of %v expiring ens names for revalidation", len(addresses), len(names))
+	return bigtable.queueEnsAddresses(addresses)
+}
+
+// GetExpiringEnsNames returns the names with an expiry within the given time range, ordered by expiry
+func GetExpiringEnsNames(from, to time.Time) ([]types.EnsName, error) {
+	names := []types.EnsName{}
+	err := ReaderDb.Select(&names, `
+	SELECT
+		name_hash,
+		ens_name,
+		address,
+		is_primary_name,
+		valid_to
+	FROM ens
+	WHERE
+		valid_to >= $1 AND
+		valid_to < $2
+	ORDER BY valid_to ASC
+	`, from, to)
+	return names, err
+}
+
+// ENS_MAX_TEXT_KEY_LENGTH limits the length of indexed text record keys, as they are part of the bigtable row key
+const ENS_MAX_TEXT_KEY_LENGTH = 256
+
+type ensTextRecordKey struct {
+	// dirtyKey is the ENS:V:T key of the record, it is kept if the record could not be validated due to a transient error
+	dirtyKey string
+	nameHash []byte
+	key      string
+}
+
+// validateEnsTextRecord queries the resolver of a name for the current value of a text record and stores it, an empty value removes the record.
+// Records of unknown names are dropped, they are stored once the name has been validated.
+func validateEnsTextRecord(ctx context.Context, client *ethclient.Client, nameHash []byte, key string) error {
+	var name string
+	err := ReaderDb.Get(&name, `
+	SELECT
+		ens_name
+	FROM ens
+	WHERE 
+		name_hash = $1 AND
+		NOT name_undecodable
+	`, nameHash)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var resolver *go_ens.Resolver
+	err = retryEnsCall(ctx, func() (err error) {
+		resolver, err = go_ens.NewResolver(client, name)
+		return err
+	})
+	if err != nil && !isEnsNotFoundError(err) {
+		return &ensTransientError{err: fmt.Errorf("error getting resolver of name %v: %w", name, err)}
+	}
+	if err != nil {
+		utils.LogError(err, fmt.Errorf("error getting resolver of name %v", name), 0)
+		return nil
+	}
+	var value string
+	err = retryEnsCall(ctx, func() (err error) {
+		value, err = resolver.Text(key)
+		return err
+	})
+	if err != nil && !isEnsNotFoundError(err) {
+		return &ensTransientError{err: fmt.Errorf("error getting text record %v of name %v: %w", key, name, err)}
+	}
+	if err != nil {
+		utils.LogError(err, fmt.Errorf("error getting text record %v of name %v", key, name), 0)
+		return nil
+	}
+	return saveEnsTextRecord(nameHash, key, value)
+}
+
+func saveEnsTextRecord(nameHash []byte, key, value string) error {
+	if value == "" {
+		_, err := WriterDb.Exec(`
+		DELETE FROM ens_text_records
+		WHERE
+			name_hash = $1 AND
+			key = $2
+		`, nameHash, key)
+		return err
+	}
+	_, err := WriterDb.Exec(`
+	INSERT INTO ens_text_records (
+		name_hash,
+		key,
+		value)
+	SELECT $1, $2, $3
+	WHERE EXISTS (SELECT 1 FROM ens WHERE name_hash = $1)
+	ON CONFLICT
+		(name_hash, key)
+	DO UPDATE SET
+		value = excluded.value
+	`, nameHash, key, value)
+	if err != nil {
+		return fmt.Errorf("error saving ens text record %v for name hash %x: %w", key, nameHash, err)
+	}
+	return nil
+}
+
+// EnsValidationQueue is a bounded queue of ENS:V keys that were just written by the indexer.
+// It allows to validate fresh keys right away instead of waiting for the next ImportEnsUpdates run,
+// which remains as a backstop for keys that did not fit into the queue.
+type EnsValidationQueue struct {
+	keys chan string
+}
+
+func NewEnsValidationQueue(size int) *EnsValidationQueue {
+	return &EnsValidationQueue{keys: make(chan string, size)}
+}
+
+// Push adds the ENS:V keys to the queue without blocking and returns the number of keys that were dropped because the queue is full
+func (q *EnsValidationQueue) Push(keys []string) (dropped int) {
+	for _, key := range keys {
+		if !strings.Contains(key, ":ENS:V:") {
+			continue
+		}
+		select {
+		case q.keys <- key:
+		default:
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// Run passes the queued keys to validate in batches of at most batchSize keys until the context is cancelled
+func (q *EnsValidationQueue) Run(ctx context.Context, batchSize int, validate func(keys []string) error) {
+	for {
+		var batch []string
+		select {
+		case <-ctx.Done():
+			return
+		case key := <-q.keys:
+			batch = append(batch, key)
+		}
+		// take whatever else is already queued so a block with many keys is validated in one go
+	drain:
+		for len(batch) < batchSize {
+			select {
+			case key := <-q.keys:
+				batch = append(batch, key)
+			default:
+				break drain
+			}
+		}
+		err := validate(batch)
+		if err != nil {
+			// the keys are still stored in bigtable and will be picked up by ImportEnsUpdates
+			utils.LogError(err, fmt.Errorf("error validating %v queued ens keys", len(batch)), 0)
+		}
+	}
+}
+
+// SetEnsValidationQueue registers a queue that is signaled with the ENS:V keys of every block written by IndexEventsWithTransformers
+func (bigtable *Bigtable) SetEnsValidationQueue(q *EnsValidationQueue) {
+	bigtable.ensValidationQueue = q
+}
+
+func (bigtable *Bigtable) signalEnsValidationQueue(keys []string) {
+	if bigtable.ensValidationQueue == nil {
+		return
+	}
+	dropped := bigtable.ensValidationQueue.Push(keys)
+	if dropped > 0 {
+		logger.Warnf("ens validation queue is full, %v keys are left for the next ens update run", dropped)
+	}
+}
+
+func validateEnsAddress(ctx context.Context, client *ethclient.Client, address common.Address, alreadyChecked *EnsCheckedDictionary) error {
+	if isEnsNameWrapperContract(address) {
+		// the name wrapper holds the wrapped names on behalf of their owners and never claims a primary name itself
+		return nil
+	}
+
+	alreadyChecked.mux.Lock()
+	if alreadyChecked.address[address] {
+		alreadyChecked.mux.Unlock()
+		return nil
+	}
+	alreadyChecked.address[address] = true
+	alreadyChecked.mux.Unlock()
+
+	if name, ok := sharedEnsResolveCache.getPrimaryName(address); ok {
+		currentName, err := GetEnsNameForAddress(address)
+		if err == nil && currentName != nil && *currentName == name {
+			return nil
+		}
+	}
+
+	name, err := ensReverseResolveWithFallback(func() (name string, err error) {
+		err = retryEnsCall(ctx, func() (err error) {
+			name, err = go_ens.ReverseResolve(client, address)
+			return err
+		})
+		return name, err
+	}, func() (string, error) {
+		if !utils.Config.Indexer.EnsTransformer.EnableEnsip19Reverse {
+			return "", nil
+		}
+		// the primary name might only be set via a chain specific reverse resolver
+		return ensip19ReverseResolve(ctx, client, address)
+	})
+	if err != nil && isEnsNoReverseRecordError(err) {
+		// the reverse record was cleared (e.g. by setting an empty name), so the address has no primary name anymore
+		return clearEnsPrimaryName(address)
+	}
+	if err != nil && !isEnsNotFoundError(err) {
+		// a failing node does not tell anything about the address, so it is kept as is and validated again later
+		return &ensTransientError{err: fmt.Errorf("error reverse resolving address %v: %w", address, err)}
+	}
+	if err != nil {
+		utils.LogError(err, fmt.Errorf("address could not be reverse resolved: %v", address), 0)
+		return removeEnsAddress(ctx, client, address, alreadyChecked)
+	}
+
+	currentName, err := GetEnsNameForAddress(address)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	isPrimary := false
+	if currentName != nil {
+		if *currentName == name {
+			return nil
+		}
+		logger.Infof("Address [%x] has a new main name from %x to: %v", address, *currentName, name)
+		err := validateEnsName(ctx, client, *currentName, alreadyChecked, &isPrimary, &address)
+		if err != nil {
+			return err
+		}
+		err = saveEnsPrimaryNameChange(address, *currentName, name, utils.Config.Indexer.EnsTransformer.NotifyPrimaryNameChanges)
+		if err != nil {
+			return err
+		}
+	}
+	isPrimary = true
+	logger.Infof("Address [%x] has a primary name: %v", address, name)
+	err = validateEnsName(ctx, client, name, alreadyChecked, &isPrimary, &address)
+	if err != nil || currentName != nil {
+		return err
+	}
+	// the first primary name of an address is recorded once it resolves back to the address, it is part of the history but not notified
+	newName, err := GetEnsNameForAddress(address)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if newName != nil && *newName == name {
+		return saveEnsPrimaryNameChange(address, "", name, false)
+	}
+	return nil
+}
+
+// saveEnsPrimaryNameChange records the change of the primary name of an address, an empty name is no primary name. The changes are the history
+// of the primary names (see GetEnsNameForAddressAsOf) and notify the users watching the address, a change that is not notified is stored as notified.
+// The validation runs detached from the transaction that changed the reverse record, so a change is recorded by the time it was detected.
+func saveEnsPrimaryNameChange(address common.Address, oldName, newName string, notify bool) error {
+	_, err := WriterDb.Exec(`
+	INSERT INTO ens_primary_name_changes (address, old_name, new_name, notified_at)
+	VALUES ($1, $2, $3, CASE WHEN $4 THEN NULL ELSE now() END)
+	`, address.Bytes(), oldName, newName, notify)
+	if err != nil {
+		utils.LogError(err, fmt.Errorf("error saving primary name change of address [%x]", address), 0)
+	}
+	return err
+}
+
+// GetUnnotifiedEnsPrimaryNameChanges returns the primary name changes that have not been notified yet, oldest first
+func GetUnnotifiedEnsPrimaryNameChanges() ([]types.EnsPrimaryNameChange, error) {
+	changes := []types.EnsPrimaryNameChange{}
+	err := ReaderDb.Select(&changes, `
+	SELECT id, address, old_name, new_name, ts
+	FROM ens_primary_name_changes
+	WHERE notified_at IS NULL
+	ORDER BY id ASC
+	`)
+	return changes, err
+}
+
+// MarkEnsPrimaryNameChangesNotified marks the given primary name changes as notified
+func MarkEnsPrimaryNameChangesNotified(ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := WriterDb.Exec(`
+	UPDATE ens_primary_name_changes
+	SET notified_at = now()
+	WHERE id = ANY($1)
+	`, pq.Array(ids))
+	return err
+}
+
+// ensPrimaryStatus determines whether a name is the primary name of the address it resolves to from the outcome of its reverse resolution.
+// An address without reverse record is a confirmed non-primary, any other error leaves the status unknown (known is false).
+func ensPrimaryStatus(name, reverseName string, reverseErr error) (isPrimary bool, known bool) {
+	if reverseErr != nil {
+		return false, isEnsNoReverseRecordError(reverseErr)
+	}
+	return reverseName == name, true
+}
+
+// ensPrimaryFlag determines whether a name is the primary name of the address it resolves to and which address claimed it.
+// Several reverse records can point to the same name, but a name is only the primary name of the address it resolves to. A claim or
+// release by any other address is therefore decided by the reverse record of the resolved address, so the stored flag does not depend
+// on the order the addresses are validated in. known is false if the reverse record could not be read.
+func ensPrimaryFlag(name string, addr common.Address, isPrimaryName *bool, primaryClaimedBy *common.Address, reverseResolve func() (string, error)) (isPrimary bool, known bool, claimedBy []byte, err error) {
+	if isPrimaryName != nil && primaryClaimedBy != nil && *primaryClaimedBy != addr {
+		isPrimaryName = nil
+	}
+	if isPrimaryName == nil {
+		reverseName, err := reverseResolve()
+		isPrimary, known = ensPrimaryStatus(name, reverseName, err)
+		if isPrimary {
+			claimedBy = addr.Bytes()
+		}
+		return isPrimary, known, claimedBy, err
+	}
+	if *isPrimaryName {
+		isPrimary = true
+		if primaryClaimedBy != nil {
+			claimedBy = primaryClaimedBy.Bytes()
+		}
+	}
+	return isPrimary, true, claimedBy, nil
+}
+
+// isEnsNotFoundError returns true if a node call failed definitively because the name or record does not exist,
+// any other error (timeouts, rate limits, unavailable nodes) is transient and must not lead to the removal of a name
+func isEnsNotFoundError(err error) bool {
+	if errors.Is(err, bind.ErrNoCode) {
+		return true
+	}
+	msg := err.Error()
+	for _, notFound := range []string{"unregistered name", "no resolver", "no resolution", "no address", "not a resolver", "invalid name", "execution reverted"} {
+		if strings.Contains(msg, notFound) {
+			return true
+		}
+	}
+	return false
+}
+
+// ensTransientError is returned by the validation if a node call kept failing with a transient error,
+// the dirty key of the name or address is kept so it is validated again by the next run
+type ensTransientError struct {
+	err error
+}
+
+func (e *ensTransientError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ensTransientError) Unwrap() error {
+	return e.err
+}
+
+// isEnsPrimaryNameConflict returns true if a write failed because the address already has another primary name
+func isEnsPrimaryNameConflict(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_ens_unique_primary_address"
+}
+
+// ensRetryBackoff is the wait before the second attempt of a failed node call, it doubles with every further attempt
+var ensRetryBackoff = time.Millisecond * 500
+
+// retryEnsCall runs a node call until it succeeds, fails definitively or the configured number of attempts is exhausted.
+// A done context stops the retries with the error of the context.
+func retryEnsCall(ctx context.Context, call func() error) error {
+	attempts := utils.Config.Indexer.EnsTransformer.RetryAttempts
+	if attempts <= 0 {
+		attempts = 3
+	}
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(ensRetryBackoff << (attempt - 1)):
+			}
+		}
+		if waitErr := waitForEnsRpc(ctx); waitErr != nil {
+			return waitErr
+		}
+		err = call()
+		if err == nil || isEnsNotFoundError(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// errEnsNoResolution is returned if none of the reverse namespaces of an address has a name set
+var errEnsNoResolution = errors.New("no resolution")
+
+// isEnsNoReverseRecordError returns true if the reverse resolution failed because the address has no reverse record or resolver
+func isEnsNoReverseRecordError(err error) bool {
+	if errors.Is(err, errEnsNoResolution) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "no resolution") || strings.Contains(msg, "no resolver") || strings.Contains(msg, "unregistered name")
+}
+
+var ensRpcLimiter struct {
+	once    sync.Once
+	limiter *rate.Limiter
+}
+
+// newEnsRpcLimiter returns a limiter that allows the given number of rpc calls per second, a rate of 0 does not limit the calls
+func newEnsRpcLimiter(ratePerSecond float64) *rate.Limiter {
+	if ratePerSecond <= 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+	return rate.NewLimiter(rate.Limit(ratePerSecond), 1)
+}
+
+// ensRpcCalls counts the lookups of the ens validation, it is used to log the node calls of an ens update run
+var ensRpcCalls uint64
+
+// waitForEnsRpc waits for the limiter configured by RpcRateLimit, it has to be called before every lookup of the ens validation.
+// Some lookups (e.g. resolving a name) consist of more than one rpc call, so the limit should leave some headroom to the limit of the provider.
+// It returns the error of the context if the context is done before the call is allowed.
+func waitForEnsRpc(ctx context.Context) error {
+	atomic.AddUint64(&ensRpcCalls, 1)
+	ensRpcLimiter.once.Do(func() {
+		ensRpcLimiter.limiter = newEnsRpcLimiter(utils.Config.Indexer.EnsTransformer.RpcRateLimit)
+	})
+	return ensRpcLimiter.limiter.Wait(ctx)
+}
+
+// ENSIP19_DEFAULT_COIN_TYPE is the coin type of the default evm reverse namespace "default.reverse"
+const ENSIP19_DEFAULT_COIN_TYPE = 0x80000000
+
+// ensip19ReverseName returns the reverse name of an address in the namespace of a coin type as defined by ENSIP-19,
+// e.g. "<address>.8000000a.reverse" for optimism. Unlike the classic "<address>.addr.reverse" the namespace depends on the chain.
+func ensip19ReverseName(address common.Address, coinType uint64) string {
+	namespace := "default"
+	if coinType != ENSIP19_DEFAULT_COIN_TYPE {
+		namespace = strconv.FormatUint(coinType, 16)
+	}
+	return fmt.Sprintf("%x.%s.reverse", address, namespace)
+}
+
+// ensReverseResolveWithFallback reverse resolves an address and only asks the fallback if the address has no reverse record,
+// so a transient error of the reverse resolution is kept. A fallback that returns an empty name without error is disabled.
+func ensReverseResolveWithFallback(resolve, fallback func() (string, error)) (string, error) {
+	name, err := resolve()
+	if err == nil || !isEnsNoReverseRecordError(err) {
+		return name, err
+	}
+	fallbackName, fallbackErr := fallback()
+	if fallbackErr == nil && fallbackName == "" {
+		return name, err
+	}
+	return fallbackName, fallbackErr
+}
+
+// ensip19ReverseResolve returns the primary name of an address from the first configured ENSIP-19 reverse namespace that has a name set,
+// errEnsNoResolution is returned if no namespace has a name set. Node errors are returned, so the address is validated again later.
+func ensip19ReverseResolve(ctx context.Context, client *ethclient.Client, address common.Address) (string, error) {
+	coinTypes := utils.Config.Indexer.EnsTransformer.Ensip19CoinTypes
+	if len(coinTypes) == 0 {
+		coinTypes = []uint64{ENSIP19_DEFAULT_COIN_TYPE}
+	}
+	if err := waitForEnsRpc(ctx); err != nil {
+		return "", err
+	}
+	registry, err := go_ens.NewRegistry(client)
+	if err != nil {
+		return "", err
+	}
+	for _, coinType := range coinTypes {
+		reverseName := ensip19ReverseName(address, coinType)
+		var resolverAddress common.Address
+		err := retryEnsCall(ctx, func() (err error) {
+			resolverAddress, err = registry.ResolverAddress(reverseName)
+			return err
+		})
+		if err != nil && !isEnsNotFoundError(err) {
+			return "", fmt.Errorf("error getting resolver of reverse node %v: %w", reverseName, err)
+		}
+		if err != nil || resolverAddress == (common.Address{}) {
+			continue
+		}
+		node, err := ensNameHash(reverseName)
+		if err != nil {
+			return "", err
+		}
+		resolver, err := ens.NewNameResolverCaller(resolverAddress, client)
+		if err != nil {
+			return "", err
+		}
+		var name string
+		err = retryEnsCall(ctx, func() (err error) {
+			name, err = resolver.Name(nil, node)
+			return err
+		})
+		if err != nil && !isEnsNotFoundError(err) {
+			return "", fmt.Errorf("error getting name of reverse node %v: %w", reverseName, err)
+		}
+		if err == nil && name != "" {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no ensip-19 reverse record found for address %v", errEnsNoResolution, address)
+}
+
+// validateEnsName resolves a name and stores the result. If isPrimaryName is nil the primary flag is determined via the reverse record,
+// primaryClaimedBy is the address whose reverse record points to the name if the caller already knows it. A claim by an address the
+// name does not resolve to is checked against the reverse record of the resolved address instead.
+func validateEnsName(ctx context.Context, client *ethclient.Client, name string, alreadyChecked *EnsCheckedDictionary, isPrimaryName *bool, primaryClaimedBy *common.Address) (err error) {
+	// names without a top level domain are .eth names, any other top level domain is a dns imported name
+	name = utils.NormalizeEnsName(name)
+	alreadyChecked.mux.Lock()
+	if alreadyChecked.name[name] {
+		alreadyChecked.skippedNames++
+		alreadyChecked.mux.Unlock()
+		return nil
+	}
+	alreadyChecked.name[name] = true
+	alreadyChecked.mux.Unlock()
+	defer func() {
+		// a name that failed transiently was not validated, so its own key must not be skipped later in the run
+		var transientErr *ensTransientError
+		if errors.As(err, &transientErr) {
+			alreadyChecked.mux.Lock()
+			delete(alreadyChecked.name, name)
+			alreadyChecked.mux.Unlock()
+		}
+	}()
+
+	if err := checkEnsNameLength(name); err != nil {
+		// the name can never be stored, so its key is dropped instead of failing the batch with every run
+		logger.Warnf("skipping ens name %q…: %v", truncateEnsName(name, 64), err)
+		metrics.EnsNamesValidated.WithLabelValues(ENS_VALIDATION_FAILED).Inc()
+		return nil
+	}
+
+	start := time.Now()
+	nameHash, err := alreadyChecked.nameHashes.nameHash(name)
+	if err != nil {
+		// the dirty key is kept, dropping it would lose the name for good as nothing was stored for it
+		logger.Warnf("could not hash ens name %q, keeping it for the next run: %v", name, err)
+		return &ensTransientError{err: fmt.Errorf("error hashing name %v: %w", name, err)}
+	}
+
+	// a name read by batchResolveEns skips the per name node calls
+	resolution := alreadyChecked.resolution(name)
+	addr, cached := sharedEnsResolveCache.getAddress(nameHash)
+	if !cached && resolution != nil {
+		addr, err = resolution.address, resolution.err
+	} else if !cached {
+		err = retryEnsCall(ctx, func() (err error) {
+			addr, err = go_ens.Resolve(client, name)
+			return err
+		})
+	}
+	if err != nil && !isEnsNotFoundError(err) {
+		alreadyChecked.recordValidation(name, ENS_VALIDATION_RETRIED, time.Since(start))
+		return &ensTransientError{err: fmt.Errorf("error resolving name %v: %w", name, err)}
+	}
+	// subnames of wildcard resolvers have no node of their own, the universal resolver finds the resolver of their closest ancestor
+	var wildcardResolver common.Address
+	if err != nil && utils.Config.Indexer.EnsTransformer.EnableWildcardSubnames {
+		var wildcardAddr common.Address
+		wildcardAddr, wildcardResolver, err = resolveEnsNameUniversal(ctx, client, name, nameHash)
+		if errors.Is(err, ens.ErrCcipGatewayUnavailable) {
+			alreadyChecked.recordValidation(name, ENS_VALIDATION_RETRIED, time.Since(start))
+			return &ensTransientError{err: fmt.Errorf("error resolving name %v via the universal resolver: %w", name, err)}
+		}
+		if err != nil && !isEnsNotFoundError(err) {
+			alreadyChecked.recordValidation(name, ENS_VALIDATION_RETRIED, time.Since(start))
+			return &ensTransientError{err: fmt.Errorf("error resolving name %v via the universal resolver: %w", name, err)}
+		}
+		if err == nil {
+			addr = wildcardAddr
+		}
+	}
+	// names of offchain and L2 resolvers revert with an OffchainLookup, their address is fetched from the gateway of the resolver
+	var offchainResolver common.Address
+	if err != nil && utils.Config.Indexer.EnsTransformer.EnableCcipRead {
+		var offchainAddr common.Address
+		offchainAddr, offchainResolver, err = resolveEnsNameOffchain(ctx, client, name, nameHash)
+		if errors.Is(err, ens.ErrCcipGatewayUnavailable) {
+			alreadyChecked.recordValidation(name, ENS_VALIDATION_RETRIED, time.Since(start))
+			return &ensTransientError{err: fmt.Errorf("error resolving name %v offchain: %w", name, err)}
+		}
+		if err == nil {
+			addr = offchainAddr
+		}
+	}
+	if err != nil {
+		utils.LogError(err, fmt.Errorf("error resolving name: %v", name), 0)
+		// a name whose resolver lost its code is kept and flagged, as the owner has to take action to make it resolvable again
+		resolver, hasCode, codeErr := getEnsResolverCode(ctx, client, name)
+		if codeErr == nil && resolver != (common.Address{}) && !hasCode {
+			logger.Warnf("resolver %v of name %v has no code", resolver, name)
+			alreadyChecked.recordValidation(name, ENS_VALIDATION_RESOLVED, time.Since(start))
+			return flagEnsCodelessResolver(nameHash)
+		}
+		alreadyChecked.recordValidation(name, ENS_VALIDATION_REMOVED, time.Since(start))
+		alreadyChecked.removeName(name)
+		return nil
+	}
+	var resolver common.Address
+	if offchainResolver != (common.Address{}) {
+		resolver = offchainResolver
+	} else if wildcardResolver != (common.Address{}) {
+		resolver = wildcardResolver
+	} else if resolution != nil {
+		resolver = resolution.resolver
+	} else {
+		err = retryEnsCall(ctx, func() (err error) {
+			resolver, err = getEnsResolverAddress(ctx, client, name)
+			return err
+		})
+		if err != nil {
+			alreadyChecked.recordValidation(name, ENS_VALIDATION_RETRIED, time.Since(start))
+			return &ensTransientError{err: fmt.Errorf("error getting resolver of ens name %v: %w", name, err)}
+		}
+	}
+	if resolver == (common.Address{}) {
+		// a name without resolver can not be resolved, whatever was resolved before is outdated
+		logger.Warnf("name %v has no resolver", name)
+		alreadyChecked.recordValidation(name, ENS_VALIDATION_REMOVED, time.Since(start))
+		alreadyChecked.removeName(name)
+		return nil
+	}
+	if cached {
+		// a cached address skips the resolver, which might have lost its code (e.g. self destructed) since it was cached
+		hasCode := false
+		err = retryEnsCall(ctx, func() (err error) {
+			hasCode, err = ensContractHasCode(client, resolver)
+			return err
+		})
+		if err != nil {
+			alreadyChecked.recordValidation(name, ENS_VALIDATION_RETRIED, time.Since(start))
+			return &ensTransientError{err: fmt.Errorf("error getting code of resolver %v of ens name %v: %w", resolver, name, err)}
+		}
+		if !hasCode {
+			logger.Warnf("resolver %v of name %v has no code", resolver, name)
+			sharedEnsResolveCache.forgetName(nameHash)
+			alreadyChecked.recordValidation(name, ENS_VALIDATION_RESOLVED, time.Since(start))
+			return flagEnsCodelessResolver(nameHash)
+		}
+	}
+	var expires time.Time
+	var wrapperExpiry uint64
+	wrapped := false
+	if resolution != nil {
+		expires = resolution.expires
+		if isEnsNameWrapperContract(resolution.owner) {
+			wrapperExpiry, wrapped, err = getEnsNameWrapperExpiry(ctx, client, name, nameHash)
+			if err != nil {
+				utils.LogError(err, fmt.Errorf("error getting name wrapper expiry for name: %v", name), 0)
+			}
+		}
+		expires = ensExpiry(expires, wrapperExpiry, wrapped)
+	} else {
+		expires, wrapped, err = GetEnsNameExpiry(ctx, client, name, nameHash)
+		if err != nil && !isEnsNotFoundError(err) {
+			alreadyChecked.recordValidation(name, ENS_VALIDATION_RETRIED, time.Since(start))
+			return &ensTransientError{err: fmt.Errorf("error getting expiry of ens name %v: %w", name, err)}
+		}
+		if err != nil {
+			utils.LogError(err, fmt.Errorf("error get ens expire date: %v", name), 0)
+			alreadyChecked.recordValidation(name, ENS_VALIDATION_REMOVED, time.Since(start))
+			alreadyChecked.removeName(name)
+			return nil
+		}
+	}
+	// the owner is only informational, a failed lookup keeps the stored owner instead of failing the validation
+	var ownerAddress []byte
+	owner, err := getEnsNameOwner(ctx, client, name, nameHash, resolution)
+	if err != nil {
+		utils.LogError(err, fmt.Errorf("error getting owner of ens name %v", name), 0)
+	} else if owner != (common.Address{}) {
+		ownerAddress = owner.Bytes()
+	}
+	isPrimary, primaryKnown, claimedBy, err := ensPrimaryFlag(name, addr, isPrimaryName, primaryClaimedBy, func() (reverseName string, err error) {
+		if resolution != nil && resolution.address == addr {
+			return resolution.reverseName, resolution.reverseErr
+		}
+		err = retryEnsCall(ctx, func() (err error) {
+			reverseName, err = go_ens.ReverseResolve(client, addr)
+			return err
+		})
+		return reverseName, err
+	})
+	if !primaryKnown {
+		// a failing node does not tell anything about the primary name, so the stored primary flag is kept until the next validation
+		utils.LogError(err, fmt.Errorf("error reverse resolving address %v of name %v, keeping the stored primary flag", addr, name), 0)
+	}
+	if wrapped && claimedBy != nil {
+		// a claim made through the name wrapper belongs to the owner of the wrapped name
+		owner, err := ensNameWrapperOwner(ctx, client, common.BytesToAddress(claimedBy), nameHash)
+		if err != nil {
+			// the claim can not be attributed without the owner, so the name is validated again by the next run
+			alreadyChecked.recordValidation(name, ENS_VALIDATION_RETRIED, time.Since(start))
+			return &ensTransientError{err: fmt.Errorf("error getting the owner of wrapped name %v: %w", name, err)}
+		}
+		claimedBy = owner.Bytes()
+	}
+	avatarUrl := getEnsAvatarUrl(ctx, client, name, addr)
+	// the checksummed address is only a companion for external tools, the bytea address stays the source of truth
+	var addressHex *string
+	if utils.Config.Indexer.EnsTransformer.StoreAddressHex {
+		checksummed := addr.Hex()
+		addressHex = &checksummed
+	}
+	tx, err := WriterDb.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if isPrimary && primaryKnown {
+		// an address has at most one primary name, the name it was flagged on before loses the flag when its reverse record changes
+		_, err = tx.Exec(`
+		UPDATE ens
+		SET
+			is_primary_name = false,
+			primary_claimed_by = NULL
+		WHERE
+			address = $1 AND
+			is_primary_name AND
+			name_hash <> $2
+		`, addr.Bytes(), nameHash[:])
+		if err != nil {
+			utils.LogError(err, fmt.Errorf("error clearing the previous primary name of address [%x]", addr), 0)
+			return err
+		}
+	}
+	// a stored primary flag that is kept as the reverse record could not be checked only stays valid if the name still resolves to the same address
+	_, err = tx.Exec(`
+	INSERT INTO ens (
+		name_hash, 
+		ens_name, 
+		address,
+		is_primary_name, 
+		valid_to,
+		address_hex,
+		primary_claimed_by,
+		tld,
+		last_validated_at,
+		resolver_has_code,
+		avatar_url,
+		resolver_address,
+		owner_address,
+		registration_tx)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, now(), true, $10, $11, $12, (
+		SELECT tx_hash
+		FROM ens_registrations
+		WHERE name_hash = $1
+		ORDER BY block_number DESC
+		LIMIT 1)) 
+	ON CONFLICT 
+		(name_hash) 
+	DO UPDATE SET 
+		ens_name = excluded.ens_name,
+		address = excluded.address,
+		is_primary_name = CASE WHEN $9 THEN excluded.is_primary_name ELSE ens.is_primary_name AND ens.address = excluded.address END,
+		valid_to = excluded.valid_to,
+		address_hex = excluded.address_hex,
+		primary_claimed_by = CASE WHEN $9 THEN excluded.primary_claimed_by WHEN ens.address = excluded.address THEN ens.primary_claimed_by END,
+		tld = excluded.tld,
+		last_validated_at = excluded.last_validated_at,
+		resolver_has_code = excluded.resolver_has_code,
+		avatar_url = excluded.avatar_url,
+		resolver_address = excluded.resolver_address,
+		owner_address = COALESCE(excluded.owner_address, ens.owner_address),
+		registration_tx = COALESCE(excluded.registration_tx, ens.registration_tx)
+	`, nameHash[:], name, addr.Bytes(), isPrimary, expires, addressHex, claimedBy, ensTld(name), primaryKnown, avatarUrl, resolver.Bytes(), ownerAddress)
+	if isEnsPrimaryNameConflict(err) {
+		// another name of the address was flagged as primary by a concurrent validation, the next run sees the committed flag and clears it
+		alreadyChecked.recordValidation(name, ENS_VALIDATION_RETRIED, time.Since(start))
+		return &ensTransientError{err: fmt.Errorf("error flagging name %v as primary name of address %x: %w", name, addr, err)}
+	}
+	if err != nil {
+		utils.LogError(err, fmt.Errorf("error writing ens data for name [%v]", name), 0)
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	err = validateEnsCoinAddresses(ctx, client, name, nameHash)
+	if err != nil {
+		utils.LogError(err, fmt.Errorf("error validating coin addresses of name [%v]", name), 0)
+		return err
+	}
+	sharedEnsResolveCache.setAddress(nameHash, addr, expires)
+	if isPrimary && claimedBy != nil {
+		sharedEnsResolveCache.setPrimaryName(common.BytesToAddress(claimedBy), name, expires)
+	}
+	alreadyChecked.recordValidation(name, ENS_VALIDATION_RESOLVED, time.Since(start))
+	logger.Infof("Name [%v] resolved -> %x, expires: %v, is primary: %v", name, addr, expires, isPrimary)
+	return nil
+}
+
+// ENS_CCIP_READ_TIMEOUT limits the resolution of a name through offchain lookups, including all gateway requests
+const ENS_CCIP_READ_TIMEOUT = time.Second * 30
+
+var ensCcipHttpClient = &http.Client{Timeout: time.Second * 10}
+
+// resolveEnsNameOffchain resolves a name through the resolver of the name or, for wildcard names, of its closest ancestor (ENSIP-10)
+// and follows the ccip-read lookups of the resolver. The resolver the name was resolved with is returned along with the address.
+func resolveEnsNameOffchain(ctx context.Context, client *ethclient.Client, name string, nameHash [32]byte) (address common.Address, resolver common.Address, err error) {
+	if err := waitForEnsRpc(ctx); err != nil {
+		return address, resolver, err
+	}
+	registry, err := go_ens.NewRegistry(client)
+	if err != nil {
+		return address, resolver, err
+	}
+	for parent := name; parent != "" && resolver == (common.Address{}); {
+		if err := waitForEnsRpc(ctx); err != nil {
+			return address, resolver, err
+		}
+		resolver, err = registry.ResolverAddress(parent)
+		if err != nil {
+			return address, resolver, err
+		}
+		_, parent, _ = strings.Cut(parent, ".")
+	}
+	if resolver == (common.Address{}) {
+		return address, resolver, fmt.Errorf("no resolver found for name %v or its parents", name)
+	}
+	caller, err := ens.NewCcipReadCaller(client, ensCcipHttpClient)
+	if err != nil {
+		return address, resolver, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, ENS_CCIP_READ_TIMEOUT)
+	defer cancel()
+	if err := waitForEnsRpc(ctx); err != nil {
+		return address, resolver, err
+	}
+	address, err = caller.ResolveAddress(ctx, resolver, name, nameHash)
+	if err != nil {
+		return address, resolver, err
+	}
+	if address == (common.Address{}) {
+		return address, resolver, fmt.Errorf("no address for name %v", name)
+	}
+	return address, resolver, nil
+}
+
+// resolveEnsNameUniversal resolves a name through the universal resolver, the offchain lookups of the resolver are only followed if ccip-read is enabled.
+// The resolver the name was resolved with is returned along with the address.
+func resolveEnsNameUniversal(ctx context.Context, client *ethclient.Client, name string, nameHash [32]byte) (address common.Address, resolver common.Address, err error) {
+	universalResolverAddress := utils.Config.Indexer.EnsTransformer.UniversalResolverContract
+	if universalResolverAddress == "" {
+		universalResolverAddress = ens.UniversalResolverAddress
+	}
+	var universalResolver *ens.UniversalResolver
+	if utils.Config.Indexer.EnsTransformer.EnableCcipRead {
+		caller, err := ens.NewCcipReadCaller(client, ensCcipHttpClient)
+		if err != nil {
+			return address, resolver, err
+		}
+		universalResolver, err = ens.NewUniversalResolverWithCcipRead(common.HexToAddress(universalResolverAddress), caller)
+		if err != nil {
+			return address, resolver, err
+		}
+	} else {
+		universalResolver, err = ens.NewUniversalResolver(common.HexToAddress(universalResolverAddress), client)
+		if err != nil {
+			return address, resolver, err
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, ENS_CCIP_READ_TIMEOUT)
+	defer cancel()
+	if err := waitForEnsRpc(ctx); err != nil {
+		return address, resolver, err
+	}
+	address, resolver, err = universalResolver.ResolveAddress(ctx, name, nameHash)
+	if err != nil {
+		return address, resolver, err
+	}
+	if address == (common.Address{}) {
+		return address, resolver, fmt.Errorf("no address for name %v", name)
+	}
+	return address, resolver, nil
+}
+
+// isEnsWildcardParent reports whether the resolver of a name implements ENSIP-10, so it may answer for subnames without a node of their own
+func isEnsWildcardParent(ctx context.Context, client *ethclient.Client, name string) (bool, error) {
+	resolver, err := getEnsResolverAddress(ctx, client, name)
+	if err != nil || resolver == (common.Address{}) {
+		return false, err
+	}
+	caller, err := ens.NewCcipReadCaller(client, ensCcipHttpClient)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, ENS_CCIP_READ_TIMEOUT)
+	defer cancel()
+	if err := waitForEnsRpc(ctx); err != nil {
+		return false, err
+	}
+	return caller.SupportsWildcard(ctx, resolver)
+}
+
+// queueEnsWildcardSubnames queues the known subnames of the changed nodes that are wildcard parents, as a wildcard resolver may answer
+// for the subnames with the records of the parent. Subnames are only known once they were seen, e.g. as the primary name of an address.
+func (bigtable *Bigtable) queueEnsWildcardSubnames(ctx context.Context, client *ethclient.Client, changedNodes [][]byte) error {
+	checked := make(map[string]bool, len(changedNodes))
+	subnameHashes := [][]byte{}
+	for _, node := range changedNodes {
+		var parent string
+		err := ReaderDb.Get(&parent, `
+		SELECT ens_name
+		FROM ens
+		WHERE
+			name_hash = $1 AND
+			NOT name_undecodable
+		`, node)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if checked[parent] {
+			continue
+		}
+		checked[parent] = true
+		// the subnames are looked up first, so the resolver is only checked for parents with known subnames
+		hashes := [][]byte{}
+		err = ReaderDb.Select(&hashes, `
+		SELECT name_hash
+		FROM ens
+		WHERE ens_name LIKE '%.' || $1 ESCAPE '\'
+		`, escapeLikePattern(parent))
+		if err != nil {
+			return err
+		}
+		if len(hashes) == 0 {
+			continue
+		}
+		wildcard, err := isEnsWildcardParent(ctx, client, parent)
+		if err != nil {
+			logger.Warnf("error checking whether ens name %v is a wildcard parent: %v", parent, err)
+			continue
+		}
+		if !wildcard {
+			continue
+		}
+		logger.Infof("queueing %v known subnames of wildcard parent %v", len(hashes), parent)
+		subnameHashes = append(subnameHashes, hashes...)
+	}
+	if len(subnameHashes) == 0 {
+		return nil
+	}
+	return bigtable.queueEnsNameHashes(subnameHashes)
+}
+
+// ENS_IPFS_GATEWAY is the gateway ipfs avatars are served from
+const ENS_IPFS_GATEWAY = "https://ipfs.io/ipfs/"
+
+// getEnsAvatarUrl returns the url of the avatar record of a name, a missing, invalid or unverifiable avatar returns nil without failing the validation
+func getEnsAvatarUrl(ctx context.Context, client *ethclient.Client, name string, address common.Address) *string {
+	if err := waitForEnsRpc(ctx); err != nil {
+		return nil
+	}
+	resolver, err := go_ens.NewResolver(client, name)
+	if err != nil {
+		return nil
+	}
+	if err := waitForEnsRpc(ctx); err != nil {
+		return nil
+	}
+	record, err := resolver.Text("avatar")
+	if err != nil || record == "" {
+		return nil
+	}
+	url, err := resolveEnsAvatar(ctx, client, record, address)
+	if err != nil {
+		logger.Warnf("avatar record %v of name %v could not be resolved: %v", record, name, err)
+		return nil
+	}
+	return &url
+}
+
+// ensAvatar is a parsed avatar record, either a url or a reference to an NFT
+type ensAvatar struct {
+	url      string
+	chainId  uint64
+	standard string
+	contract common.Address
+	tokenId  *big.Int
+}
+
+// parseEnsAvatar parses an avatar record as defined by ENSIP-12, https and ipfs urls and eip155 NFT references are supported
+func parseEnsAvatar(record string) (*ensAvatar, error) {
+	record = strings.TrimSpace(record)
+	lower := strings.ToLower(record)
+	switch {
+	case strings.HasPrefix(lower, "https://"):
+		return &ensAvatar{url: record}, nil
+	case strings.HasPrefix(lower, "ipfs://"):
+		return &ensAvatar{url: ensIpfsUrl(record)}, nil
+	case strings.HasPrefix(lower, "eip155:"):
+		// eip155:<chainId>/<erc721|erc1155>:<contract>/<tokenId>
+		parts := strings.Split(record[len("eip155:"):], "/")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("malformed nft avatar record: %v", record)
+		}
+		chainId, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed chain id of nft avatar record %v: %w", record, err)
+		}
+		asset := strings.SplitN(parts[1], ":", 2)
+		if len(asset) != 2 || (strings.ToLower(asset[0]) != "erc721" && strings.ToLower(asset[0]) != "erc1155") {
+			return nil, fmt.Errorf("unsupported asset of nft avatar record: %v", record)
+		}
+		if !common.IsHexAddress(asset[1]) {
+			return nil, fmt.Errorf("malformed contract of nft avatar record: %v", record)
+		}
+		tokenId, ok := new(big.Int).SetString(parts[2], 10)
+		if !ok || tokenId.Sign() < 0 {
+			return nil, fmt.Errorf("malformed token id of nft avatar record: %v", record)
+		}
+		return &ensAvatar{
+			chainId:  chainId,
+			standard: strings.ToLower(asset[0]),
+			contract: common.HexToAddress(asset[1]),
+			tokenId:  tokenId,
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported avatar record: %v", record)
+}
+
+func ensIpfsUrl(uri string) string {
+	return ENS_IPFS_GATEWAY + strings.TrimPrefix(uri[len("ipfs://"):], "ipfs/")
+}
+
+// ensNFTUrl returns the url of the metadata uri of an NFT, ERC1155 uris contain the hex token id as placeholder
+func ensNFTUrl(uri string, tokenId *big.Int) (string, error) {
+	uri = strings.ReplaceAll(strings.TrimSpace(uri), "{id}", fmt.Sprintf("%064x", tokenId))
+	lower := strings.ToLower(uri)
+	switch {
+	case strings.HasPrefix(lower, "https://"):
+		return uri, nil
+	case strings.HasPrefix(lower, "ipfs://"):
+		return ensIpfsUrl(uri), nil
+	}
+	return "", fmt.Errorf("unsupported nft metadata uri: %v", uri)
+}
+
+// resolveEnsAvatar returns the url of an avatar record of a name that resolves to the given address.
+// NFT avatars are only accepted if the NFT is owned by that address.
+func resolveEnsAvatar(ctx context.Context, caller bind.ContractCaller, record string, address common.Address) (string, error) {
+	avatar, err := parseEnsAvatar(record)
+	if err != nil {
+		return "", err
+	}
+	if avatar.url != "" {
+		return avatar.url, nil
+	}
+	if avatar.chainId != utils.Config.Chain.Config.DepositChainID {
+		return "", fmt.Errorf("nft avatar on chain %v can not be verified", avatar.chainId)
+	}
+	nft, err := ens.NewAvatarNFTCaller(avatar.contract, caller)
+	if err != nil {
+		return "", err
+	}
+	var uri string
+	switch avatar.standard {
+	case "erc721":
+		if err := waitForEnsRpc(ctx); err != nil {
+			return "", err
+		}
+		owner, err := nft.OwnerOf(nil, avatar.tokenId)
+		if err != nil {
+			return "", err
+		}
+		if owner != address {
+			return "", fmt.Errorf("nft avatar is owned by %v instead of %v", owner, address)
+		}
+		if err := waitForEnsRpc(ctx); err != nil {
+			return "", err
+		}
+		uri, err = nft.TokenURI(nil, avatar.tokenId)
+		if err != nil {
+			return "", err
+		}
+	case "erc1155":
+		if err := waitForEnsRpc(ctx); err != nil {
+			return "", err
+		}
+		balance, err := nft.BalanceOf(nil, address, avatar.tokenId)
+		if err != nil {
+			return "", err
+		}
+		if balance.Sign() <= 0 {
+			return "", fmt.Errorf("nft avatar is not owned by %v", address)
+		}
+		if err := waitForEnsRpc(ctx); err != nil {
+			return "", err
+		}
+		uri, err = nft.Uri(nil, avatar.tokenId)
+		if err != nil {
+			return "", err
+		}
+	}
+	return ensNFTUrl(uri, avatar.tokenId)
+}
+
+// getEnsResolverCode returns the resolver of a name and whether it has code, a codeless (e.g. self destructed) resolver can not resolve any name
+func getEnsResolverCode(ctx context.Context, client *ethclient.Client, name string) (resolver common.Address, hasCode bool, err error) {
+	resolver, err = getEnsResolverAddress(ctx, client, name)
+	if err != nil || resolver == (common.Address{}) {
+		return resolver, false, err
+	}
+	if err := waitForEnsRpc(ctx); err != nil {
+		return common.Address{}, false, err
+	}
+	hasCode, err = ensContractHasCode(client, resolver)
+	return resolver, hasCode, err
+}
+
+// getEnsResolverAddress returns the resolver a name points to in the registry, the zero address if it has none
+func getEnsResolverAddress(ctx context.Context, client *ethclient.Client, name string) (common.Address, error) {
+	if err := waitForEnsRpc(ctx); err != nil {
+		return common.Address{}, err
+	}
+	registry, err := go_ens.NewRegistry(client)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if err := waitForEnsRpc(ctx); err != nil {
+		return common.Address{}, err
+	}
+	return registry.ResolverAddress(name)
+}
+
+func ensContractHasCode(caller bind.ContractCaller, address common.Address) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	code, err := caller.CodeAt(ctx, address, nil)
+	if err != nil {
+		return false, err
+	}
+	return len(code) > 0, nil
+}
+
+// flagEnsCodelessResolver marks a stored name as unresolvable because its resolver has no code
+func flagEnsCodelessResolver(nameHash [32]byte) error {
+	_, err := WriterDb.Exec(`
+	UPDATE ens
+	SET
+		address = NULL,
+		resolver_has_code = false,
+		last_validated_at = now()
+	WHERE name_hash = $1
+	`, nameHash[:])
+	return err
+}
+
+// GetEnsNamesWithCodelessResolver returns active names whose resolver has no code, their owners have to set a new resolver
+func GetEnsNamesWithCodelessResolver(limit int) ([]types.EnsName, error) {
+	names := []types.EnsName{}
+	err := ReaderDb.Select(&names, `
+	SELECT
+		name_hash,
+		ens_name,
+		address,
+		is_primary_name,
+		valid_to
+	FROM ens
+	WHERE
+		resolver_has_code = false AND
+		valid_to >= now()
+	ORDER BY valid_to ASC
+	LIMIT $1
+	`, limit)
+	return names, err
+}
+
+// getEnsNameWrapperExpiry returns the expiry that the NameWrapper tracks for a name, wrapped is false if the name is not owned by the NameWrapper
+func getEnsNameWrapperExpiry(ctx context.Context, client *ethclient.Client, name string, nameHash [32]byte) (expiry uint64, wrapped bool, err error) {
+	nameWrapperContract := utils.Config.Indexer.EnsTransformer.NameWrapperContract
+	if nameWrapperContract == "" {
+		return 0, false, nil
+	}
+	nameWrapper := common.HexToAddress(nameWrapperContract)
+	if err := waitForEnsRpc(ctx); err != nil {
+		return 0, false, err
+	}
+	registry, err := go_ens.NewRegistry(client)
+	if err != nil {
+		return 0, false, err
+	}
+	if err := waitForEnsRpc(ctx); err != nil {
+		return 0, false, err
+	}
+	owner, err := registry.Owner(name)
+	if err != nil {
+		return 0, false, err
+	}
+	if owner != nameWrapper {
+		return 0, false, nil
+	}
+	caller, err := ens.NewNameWrapperCaller(nameWrapper, client)
+	if err != nil {
+		return 0, true, err
+	}
+	if err := waitForEnsRpc(ctx); err != nil {
+		return 0, false, err
+	}
+	_, _, expiry, err = caller.GetData(nil, nameHash)
+	if err != nil {
+		return 0, true, err
+	}
+	return expiry, true, nil
+}
+
+// isEnsNameWrapperContract returns true if the address is the configured name wrapper
+func isEnsNameWrapperContract(address common.Address) bool {
+	nameWrapperContract := utils.Config.Indexer.EnsTransformer.NameWrapperContract
+	return nameWrapperContract != "" && common.HexToAddress(nameWrapperContract) == address
+}
+
+// getEnsNameOwner returns the owner of a name, which is the registrant holding the token of a .eth second level name and the registry
+// owner of any other name. The owner of a wrapped name is the holder of the wrapped token. The owner may differ from the resolved address.
+func getEnsNameOwner(ctx context.Context, client *ethclient.Client, name string, nameHash [32]byte, resolution *ensResolution) (common.Address, error) {
+	var owner common.Address
+	labels := strings.Split(name, ".")
+	baseRegistrar := utils.Config.Indexer.EnsTransformer.BaseRegistrarContract
+	if resolution != nil && resolution.registrant != (common.Address{}) {
+		owner = resolution.registrant
+	} else if len(labels) == 2 && labels[1] == "eth" && baseRegistrar != "" {
+		label := crypto.Keccak256Hash([]byte(labels[0]))
+		contract := bind.NewBoundContract(common.HexToAddress(baseRegistrar), ens.EnsCallsABI, client, nil, nil)
+		out := []interface{}{}
+		if err := waitForEnsRpc(ctx); err != nil {
+			return common.Address{}, err
+		}
+		err := contract.Call(nil, &out, "ownerOf", label.Big())
+		if err != nil {
+			return common.Address{}, err
+		}
+		owner = out[0].(common.Address)
+	} else {
+		if err := waitForEnsRpc(ctx); err != nil {
+			return common.Address{}, err
+		}
+		registry, err := go_ens.NewRegistry(client)
+		if err != nil {
+			return common.Address{}, err
+		}
+		if err := waitForEnsRpc(ctx); err != nil {
+			return common.Address{}, err
+		}
+		owner, err = registry.Owner(name)
+		if err != nil {
+			return common.Address{}, err
+		}
+	}
+	return ensNameWrapperOwner(ctx, client, owner, nameHash)
+}
+
+// ensNameWrapperOwner returns the real owner of a wrapped name if the given owner is the name wrapper, any other owner is returned as is
+func ensNameWrapperOwner(ctx context.Context, caller bind.ContractCaller, owner common.Address, nameHash [32]byte) (common.Address, error) {
+	if !isEnsNameWrapperContract(owner) {
+		return owner, nil
+	}
+	nameWrapper, err := ens.NewNameWrapperCaller(owner, caller)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var wrappedOwner common.Address
+	err = retryEnsCall(ctx, func() (err error) {
+		wrappedOwner, err = nameWrapper.OwnerOf(nil, nameHash)
+		return err
+	})
+	return wrappedOwner, err
+}
+
+// GetEnsNameExpiry returns the expiry of a name from the node. Dns imported names do not expire on chain, subnames of .eth names expire
+// with the second level name they belong to and wrapped names use the expiry of the NameWrapper as long as it is set.
+// Wrapped is true if the name is owned by the NameWrapper, isEnsNotFoundError tells whether an error means that the name is not registered.
+func GetEnsNameExpiry(ctx context.Context, client *ethclient.Client, name string, nameHash [32]byte) (expires time.Time, wrapped bool, err error) {
+	wrapperExpiry, wrapped, err := getEnsNameWrapperExpiry(ctx, client, name, nameHash)
+	if err != nil {
+		utils.LogError(err, fmt.Errorf("error getting name wrapper expiry for name: %v", name), 0)
+	}
+	if ensTld(name) != "eth" {
+		// dns imported names are not registered with the base registrar, asking it for an expiry would revert
+		return ensExpiry(ENS_DNS_NAME_VALID_TO, wrapperExpiry, wrapped), wrapped, nil
+	}
+	registered, err := ensRegisteredName(name)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	var ensName *go_ens.Name
+	err = retryEnsCall(ctx, func() (err error) {
+		ensName, err = go_ens.NewName(client, registered)
+		return err
+	})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	err = retryEnsCall(ctx, func() (err error) {
+		expires, err = ensName.Expires()
+		return err
+	})
+	if err != nil && !wrapped {
+		return time.Time{}, false, err
+	}
+	return ensExpiry(expires, wrapperExpiry, wrapped), wrapped, nil
+}
+
+// ensRegisteredName returns the second level name a .eth name belongs to, which is the name that is registered with the base registrar
+func ensRegisteredName(name string) (string, error) {
+	labels := strings.Split(name, ".")
+	if len(labels) < 2 || labels[len(labels)-2] == "" {
+		return "", fmt.Errorf("invalid name %v", name)
+	}
+	return strings.Join(labels[len(labels)-2:], "."), nil
+}
+
+// ResolveEnsNameFromNode resolves a name that has not been validated yet via the node, with the rate limit, the retries and the expiry
+// of the validation. It returns sql.ErrNoRows if the name is not registered, expired or does not resolve. The lookup is abandoned
+// once the timeout or the context expires.
+func ResolveEnsNameFromNode(ctx context.Context, client *ethclient.Client, name string, timeout time.Duration) (*types.EnsResolveResponse, error) {
+	var data *types.EnsResolveResponse
+	err := runWithEnsTimeout(ctx, timeout, func(ctx context.Context) error {
+		nameHash, err := ensNameHash(name)
+		if err != nil {
+			return sql.ErrNoRows
+		}
+		var resolved common.Address
+		err = retryEnsCall(ctx, func() (err error) {
+			resolved, err = go_ens.Resolve(client, name)
+			return err
+		})
+		if err != nil && isEnsNotFoundError(err) {
+			return sql.ErrNoRows
+		}
+		if err != nil {
+			return fmt.Errorf("error resolving ens name %v: %w", name, err)
+		}
+		validTo, _, err := GetEnsNameExpiry(ctx, client, name, nameHash)
+		if err != nil && isEnsNotFoundError(err) {
+			return sql.ErrNoRows
+		}
+		if err != nil {
+			return fmt.Errorf("error getting expiry of ens name %v: %w", name, err)
+		}
+		if validTo.Before(time.Now()) {
+			return sql.ErrNoRows
+		}
+		var reverseName string
+		err = retryEnsCall(ctx, func() (err error) {
+			reverseName, err = go_ens.ReverseResolve(client, resolved)
+			return err
+		})
+		// the primary name status is informational, a failed reverse resolution reports the name as not primary
+		data = &types.EnsResolveResponse{Name: name, Address: resolved.Hex(), ValidTo: validTo, IsPrimaryName: err == nil && reverseName == name}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ENS_DNS_NAME_VALID_TO is stored as the expiry of dns imported names. They do not expire on chain and stay valid as long as they
+// resolve, which is checked whenever they are validated.
+var ENS_DNS_NAME_VALID_TO = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// ensExpiry returns the authoritative expiry of a name. The base registrar expiry can be stale or missing for wrapped names,
+// so the expiry of the NameWrapper is used for them as long as it is set.
+func ensExpiry(registrarExpiry time.Time, wrapperExpiry uint64, wrapped bool) time.Time {
+	if wrapped && wrapperExpiry > 0 {
+		return time.Unix(int64(wrapperExpiry), 0)
+	}
+	return registrarExpiry
+}
+
+const (
+	ENS_VALIDATION_RESOLVED = "resolved"
+	ENS_VALIDATION_REMOVED  = "removed"
+	ENS_VALIDATION_RETRIED  = "retried"
+	ENS_VALIDATION_FAILED   = "failed"
+)
+
+// ENS_MAX_NAME_LENGTH limits the length of the stored names in bytes, the btree indexes on ens_name only cover its hash or prefix so they fit any stored name
+const ENS_MAX_NAME_LENGTH = 1024
+
+// ENS_MAX_LABEL_LENGTH is the maximum length of a label in bytes, longer labels can not be dns encoded, so no ENSIP-10 resolver can be asked for them
+const ENS_MAX_LABEL_LENGTH = 255
+
+// checkEnsNameLength returns an error if a normalized name or one of its labels is too long to be stored and resolved
+func checkEnsNameLength(name string) error {
+	if len(name) > ENS_MAX_NAME_LENGTH {
+		return fmt.Errorf("name of length %v exceeds the maximum length of %v", len(name), ENS_MAX_NAME_LENGTH)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if len(label) > ENS_MAX_LABEL_LENGTH {
+			return fmt.Errorf("label of length %v exceeds the maximum length of %v", len(label), ENS_MAX_LABEL_LENGTH)
+		}
+	}
+	return nil
+}
+
+// truncateEnsName returns at most the first maxLength bytes of a name without splitting a character, it is used to log names that are too long
+func truncateEnsName(name string, maxLength int) string {
+	if len(name) <= maxLength {
+		return name
+	}
+	for maxLength > 0 && !utf8.RuneStart(name[maxLength]) {
+		maxLength--
+	}
+	return name[:maxLength]
+}
+
+// ensValidationAudit is the outcome of a name validation, it is stored for provider quality monitoring
+type ensValidationAudit struct {
+	name     string
+	outcome  string
+	duration time.Duration
+}
+
+// ENS_VALIDATION_AUDIT_BATCH_SIZE is the number of collected validation outcomes that are written before the end of a batch,
+// it bounds the outcomes held in memory by long runs like a seed
+const ENS_VALIDATION_AUDIT_BATCH_SIZE = 1000
+
+// ensValidationAuditWriter stores the given validation outcomes, it is replaced in tests that run without a database
+var ensValidationAuditWriter = saveEnsValidationAudits
+
+// recordValidation updates the validation metrics and collects the outcome of a name validation for the validation audit
+func (alreadyChecked *EnsCheckedDictionary) recordValidation(name string, outcome string, duration time.Duration) {
+	metrics.EnsNamesValidated.WithLabelValues(outcome).Inc()
+	metrics.EnsResolveDuration.Observe(duration.Seconds())
+	alreadyChecked.mux.Lock()
+	alreadyChecked.audits = append(alreadyChecked.audits, ensValidationAudit{name: name, outcome: outcome, duration: duration})
+	full := len(alreadyChecked.audits) >= ENS_VALIDATION_AUDIT_BATCH_SIZE
+	alreadyChecked.mux.Unlock()
+	if full {
+		alreadyChecked.flushValidationAudits()
+	}
+}
+
+// flushValidationAudits writes the validation outcomes collected since the last flush with a single statement, failures are only logged
+func (alreadyChecked *EnsCheckedDictionary) flushValidationAudits() {
+	alreadyChecked.mux.Lock()
+	audits := alreadyChecked.audits
+	alreadyChecked.audits = nil
+	alreadyChecked.mux.Unlock()
+	if len(audits) == 0 {
+		return
+	}
+	err := ensValidationAuditWriter(audits)
+	if err != nil {
+		utils.LogError(err, fmt.Errorf("error writing ens validation audit of %v validations", len(audits)), 0)
+	}
+}
+
+func saveEnsValidationAudits(audits []ensValidationAudit) error {
+	names := make(pq.StringArray, 0, len(audits))
+	outcomes := make(pq.StringArray, 0, len(audits))
+	durations := make(pq.Int64Array, 0, len(audits))
+	for _, audit := range audits {
+		names = append(names, audit.name)
+		outcomes = append(outcomes, audit.outcome)
+		durations = append(durations, audit.duration.Milliseconds())
+	}
+	_, err := WriterDb.Exec(`
+	INSERT INTO ens_validation_audit (ens_name, outcome, duration_ms)
+	SELECT * FROM unnest($1::text[], $2::text[], $3::int[])
+	`, names, outcomes, durations)
+	return err
+}
+
+// PruneEnsValidationAudit deletes the validation outcomes older than the given retention and returns the number of deleted rows
+func PruneEnsValidationAudit(retention time.Duration) (int64, error) {
+	res, err := WriterDb.Exec(`
+	DELETE FROM ens_validation_audit
+	WHERE ts < $1
+	`, time.Now().Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("error pruning ens validation audit: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// MonitorEnsValidationAudit periodically runs PruneEnsValidationAudit, see there
+func MonitorEnsValidationAudit(ctx context.Context, retention time.Duration, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		deleted, err := PruneEnsValidationAudit(retention)
+		if err != nil {
+			utils.LogError(err, "error pruning ens validation audit", 0)
+		} else if deleted > 0 {
+			logger.Infof("pruned %v ens validation audit rows older than %v", deleted, retention)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// ensValidationLatency are the latency percentiles of the validations within a time window in milliseconds, they are null without validations
+type ensValidationLatency struct {
+	P50 sql.NullFloat64 `db:"p50"`
+	P95 sql.NullFloat64 `db:"p95"`
+	P99 sql.NullFloat64 `db:"p99"`
+}
+
+func (latency ensValidationLatency) durations() (p50, p95, p99 time.Duration, err error) {
+	if !latency.P50.Valid || !latency.P95.Valid || !latency.P99.Valid {
+		return 0, 0, 0, fmt.Errorf("no ens name validations recorded")
+	}
+	ms := func(f sql.NullFloat64) time.Duration {
+		return time.Duration(f.Float64 * float64(time.Millisecond))
+	}
+	return ms(latency.P50), ms(latency.P95), ms(latency.P99), nil
+}
+
+// GetEnsValidationLatencyPercentiles returns the p50, p95 and p99 end-to-end name validation latency of the validations since the given time.
+// The percentiles are interpolated from the durations in the validation audit, so they cover the validations of all processes.
+// Retried validations did not complete and are not included.
+func GetEnsValidationLatencyPercentiles(since time.Time) (p50, p95, p99 time.Duration, err error) {
+	latency := ensValidationLatency{}
+	err = ReaderDb.Get(&latency, `
+	SELECT
+		percentile_cont(0.5) WITHIN GROUP (ORDER BY duration_ms) AS p50,
+		percentile_cont(0.95) WITHIN GROUP (ORDER BY duration_ms) AS p95,
+		percentile_cont(0.99) WITHIN GROUP (ORDER BY duration_ms) AS p99
+	FROM ens_validation_audit
+	WHERE
+		ts >= $1 AND
+		outcome <> $2
+	`, since, ENS_VALIDATION_RETRIED)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return latency.durations()
+}
+
+// ensValidationOutcomeStats are the validations of one outcome within a time window
+type ensValidationOutcomeStats struct {
+	Outcome         string `db:"outcome"`
+	Count           uint64 `db:"count"`
+	TotalDurationMs uint64 `db:"total_duration_ms"`
+}
+
+// GetEnsValidationStats returns aggregated validation outcomes since the given time
+func GetEnsValidationStats(since time.Time) (*types.EnsValidationStats, error) {
+	outcomes := []ensValidationOutcomeStats{}
+	err := ReaderDb.Select(&outcomes, `
+	SELECT
+		outcome,
+		COUNT(*) AS count,
+		COALESCE(SUM(duration_ms), 0) AS total_duration_ms
+	FROM ens_validation_audit
+	WHERE ts >= $1
+	GROUP BY outcome
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	return aggregateEnsValidationStats(outcomes), nil
+}
+
+// aggregateEnsValidationStats sums the validations per outcome up, retried validations did not complete and are not counted as validated.
+// The average latency is taken over all validations including the retried ones.
+func aggregateEnsValidationStats(outcomes []ensValidationOutcomeStats) *types.EnsValidationStats {
+	stats := &types.EnsValidationStats{}
+	var count, totalDurationMs uint64
+	for _, outcome := range outcomes {
+		count += outcome.Count
+		totalDurationMs += outcome.TotalDurationMs
+		switch outcome.Outcome {
+		case ENS_VALIDATION_RETRIED:
+			stats.Retried += outcome.Count
+			continue
+		case ENS_VALIDATION_RESOLVED:
+			stats.Resolved += outcome.Count
+		case ENS_VALIDATION_REMOVED:
+			stats.Removed += outcome.Count
+		}
+		stats.Validated += outcome.Count
+	}
+	if count > 0 {
+		stats.AvgLatencyMs = float64(totalDurationMs) / float64(count)
+	}
+	return stats
+}
+
+// ensStatsCache holds the result of the last GetEnsStats query, the counts scan the whole ens table
+var ensStatsCache = struct {
+	sync.Mutex
+	stats     types.EnsStats
+	fetchedAt time.Time
+}{}
+
+// ENS_STATS_CACHE_DURATION is the time a result of GetEnsStats is reused
+const ENS_STATS_CACHE_DURATION = time.Minute
+
+// GetEnsStats returns the counters of the indexed names, the result is cached for a minute
+func GetEnsStats() (types.EnsStats, error) {
+	ensStatsCache.Lock()
+	defer ensStatsCache.Unlock()
+	if !ensStatsCache.fetchedAt.IsZero() && time.Since(ensStatsCache.fetchedAt) < ENS_STATS_CACHE_DURATION {
+		return ensStatsCache.stats, nil
+	}
+
+	stats := types.EnsStats{}
+	err := ReaderDb.Get(&stats, `
+	SELECT
+		COUNT(*) AS total,
+		COUNT(*) FILTER (WHERE is_primary_name AND valid_to >= now()) AS primary_names,
+		COUNT(*) FILTER (WHERE valid_to >= now()) AS active,
+		COUNT(*) FILTER (WHERE valid_to >= now() AND valid_to < now() + interval '30 days') AS expiring,
+		COUNT(DISTINCT address) AS addresses
+	FROM ens
+	`)
+	if err != nil {
+		return stats, err
+	}
+	ensStatsCache.stats = stats
+	ensStatsCache.fetchedAt = time.Now()
+	return stats, nil
+}
+
+// ResolveEnsNameWithResolver resolves a name against the given resolver contract instead of the resolver set in the registry.
+// This allows operators to verify new resolver deployments before names are migrated to them.
+func ResolveEnsNameWithResolver(client *ethclient.Client, name string, resolver common.Address) (*common.Address, error) {
+	name = utils.TrimEnsName(name)
+	if resolver == (common.Address{}) {
+		return nil, fmt.Errorf("no resolver address provided for name: %v", name)
+	}
+	ensResolver, err := go_ens.NewResolverAt(client, name, resolver)
+	if err != nil {
+		return nil, err
+	}
+	address, err := ensResolver.Address()
+	if err != nil {
+		return nil, err
+	}
+	if address == (common.Address{}) {
+		return nil, fmt.Errorf("no address for name %v at resolver %v", name, resolver)
+	}
+	return &address, nil
+}
+
+func removeEnsAddress(ctx context.Context, client *ethclient.Client, address common.Address, alreadyChecked *EnsCheckedDictionary) error {
+	name, err := GetEnsNameForAddress(address)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if name == nil {
+		return nil
+	}
+	isPrimary := false
+	return validateEnsName(ctx, client, *name, alreadyChecked, &isPrimary, nil)
+}
+
+// clearEnsPrimaryName removes the primary flag of the names an address claimed or resolved to as primary
+func clearEnsPrimaryName(address common.Address) error {
+	cleared := []string{}
+	err := WriterDb.Select(&cleared, `
+	UPDATE ens
+	SET
+		is_primary_name = false,
+		primary_claimed_by = NULL
+	WHERE
+		is_primary_name AND
+		(address = $1 OR primary_claimed_by = $1)
+	RETURNING ens_name
+	`, address.Bytes())
+	if err != nil {
+		utils.LogError(err, fmt.Errorf("error clearing primary ens name of address [%x]", address), 0)
+		return err
+	}
+	if len(cleared) == 0 {
+		return nil
+	}
+	logger.Infof("Address [%x] has no primary name anymore", address)
+	// the cleared reverse record is part of the history of the primary names but not notified
+	return saveEnsPrimaryNameChange(address, cleared[0], "", false)
+}
+
+// ensRemovedName is a row of the ens table deleted by removeEnsNames
+type ensRemovedName struct {
+	NameHash      []byte `db:"name_hash"`
+	Name          string `db:"ens_name"`
+	Address       []byte `db:"address"`
+	IsPrimaryName bool   `db:"is_primary_name"`
+}
+
+// ensNameHashTables are the tables with rows of a name that only describe its current resolution, they are cleaned up with the name
+// and reference it with a cascading foreign key. The registrations, renewals and transfers are events of the chain and are kept.
+var ensNameHashTables = []string{"ens_text_records", "ens_coin_addresses", "ens_history"}
+
+// removeEnsNames deletes the given names and the rows of the related tables in one transaction. The addresses that lose their
+// primary name have no primary name until their reverse record points to a name resolving to them again.
+func removeEnsNames(names []string) error {
+	tx, err := WriterDb.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	removed := []ensRemovedName{}
+	err = tx.Select(&removed, `
+	DELETE FROM ens 
+	WHERE 
+		md5(ens_name) = ANY(SELECT md5(name) FROM unnest($1::text[]) name) AND
+		ens_name = ANY($1)
+	RETURNING name_hash, ens_name, address, is_primary_name
+	;`, pq.StringArray(names))
+	if err != nil {
+		utils.LogError(err, fmt.Errorf("error deleting %v ens names", len(names)), 0)
+		return err
+	}
+	nameHashes := make(pq.ByteaArray, 0, len(removed))
+	for _, name := range removed {
+		nameHashes = append(nameHashes, name.NameHash)
+	}
+	for _, table := range ensNameHashTables {
+		_, err = tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE name_hash = ANY($1)`, table), nameHashes)
+		if err != nil {
+			utils.LogError(err, fmt.Errorf("error deleting the %v rows of %v ens names", table, len(removed)), 0)
+			return err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range removed {
+		sharedEnsResolveCache.forgetName(common.BytesToHash(name.NameHash))
+	}
+	for _, name := range ensLostPrimaryNames(removed) {
+		address := common.BytesToAddress(name.Address)
+		sharedEnsResolveCache.forgetAddress(address)
+		logger.Infof("Address [%x] has no primary name anymore", address)
+		err = saveEnsPrimaryNameChange(address, name.Name, "", utils.Config.Indexer.EnsTransformer.NotifyPrimaryNameChanges)
+		if err != nil {
+			return err
+		}
+	}
+	logger.Infof("Ens names removed from db: %v", names)
+	return nil
+}
+
+// ensLostPrimaryNames returns the removed names that were the primary name of the address they resolved to
+func ensLostPrimaryNames(removed []ensRemovedName) []ensRemovedName {
+	lost := []ensRemovedName{}
+	for _, name := range removed {
+		if name.IsPrimaryName && len(name.Address) > 0 && common.BytesToAddress(name.Address) != (common.Address{}) {
+			lost = append(lost, name)
+		}
+	}
+	return lost
+}
+
+func GetAddressForEnsName(name string) (address *common.Address, err error) {
+	record, err := GetEnsRecordForName(name)
+	if err == nil && record.Address != nil {
+		add := common.BytesToAddress(record.Address)
+		address = &add
+	}
+	return address, err
+}
+
+// GetEnsRecordForName returns the address, expiry and primary flag of a name that has not expired yet with a single query
+func GetEnsRecordForName(name string) (*types.EnsRecord, error) {
+	record := &types.EnsRecord{}
+	err := ReaderDb.Get(record, `
+	SELECT name_hash, address, valid_to, is_primary_name
+	FROM ens
+	WHERE
+		md5(ens_name) = md5($1) AND
+		ens_name = $1 AND
+		valid_to >= now()
+	`, utils.TrimEnsName(name))
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// GetEnsNameValidity returns the expiry and primary flag of a name that has not expired yet
+func GetEnsNameValidity(name string) (validTo time.Time, isPrimaryName bool, err error) {
+	row := struct {
+		ValidTo       time.Time `db:"valid_to"`
+		IsPrimaryName bool      `db:"is_primary_name"`
+	}{}
+	err = ReaderDb.Get(&row, `
+	SELECT valid_to, is_primary_name
+	FROM ens
+	WHERE
+		md5(ens_name) = md5($1) AND
+		ens_name = $1 AND
+		valid_to >= now()
+	`, utils.TrimEnsName(name))
+	return row.ValidTo, row.IsPrimaryName, err
+}
+
+// GetEnsNameForAddress returns the primary name of an address. If more than one name is flagged as primary the most recently validated one is returned.
+func GetEnsNameForAddress(address common.Address) (name *string, err error) {
+	err = ReaderDb.Get(&name, `
+	SELECT ens_name 
+	FROM ens
+	WHERE
+		address = $1 AND
+		is_primary_name AND
+		valid_to >= now()
+	ORDER BY last_validated_at DESC NULLS LAST
+	LIMIT 1
+	;`, address.Bytes())
+	return name, err
+}
+
+// GetEnsNameForAddressOrOwner returns the primary name of an address like GetEnsNameForAddress. If matchOwner is set and the address has
+// no primary name, a valid name owned by the address is returned instead, the owner of a name may differ from the address it resolves to.
+func GetEnsNameForAddressOrOwner(address common.Address, matchOwner bool) (name *string, err error) {
+	if !matchOwner {
+		return GetEnsNameForAddress(address)
+	}
+	err = ReaderDb.Get(&name, `
+	SELECT ens_name 
+	FROM ens
+	WHERE
+		((address = $1 AND is_primary_name) OR owner_address = $1) AND
+		valid_to >= now()
+	ORDER BY (address = $1 AND is_primary_name) DESC, last_validated_at DESC NULLS LAST
+	LIMIT 1
+	;`, address.Bytes())
+	return name, err
+}
+
+// ensPrimaryNameCandidate is a name with the time of its first indexed registration
+type ensPrimaryNameCandidate struct {
+	Name         string       `db:"ens_name"`
+	ValidTo      time.Time    `db:"valid_to"`
+	RegisteredAt sql.NullTime `db:"registered_at"`
+}
+
+// ensPrimaryNameChange is a change of the primary name of an address, an empty name is no primary name
+type ensPrimaryNameChange struct {
+	OldName string    `db:"old_name"`
+	NewName string    `db:"new_name"`
+	Ts      time.Time `db:"ts"`
+}
+
+// GetEnsNameForAddressAsOf returns the primary name of an address that was valid at the given time, this is used to render historical pages.
+// The primary name at that time is taken from the recorded primary name changes of the address, without any the current primary name is used.
+func GetEnsNameForAddressAsOf(address common.Address, at time.Time) (name *string, err error) {
+	changes := []ensPrimaryNameChange{}
+	err = ReaderDb.Select(&changes, `
+	SELECT old_name, new_name, ts
+	FROM ens_primary_name_changes
+	WHERE address = $1
+	ORDER BY ts ASC, id ASC
+	;`, address.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	current := ""
+	if len(changes) == 0 {
+		err = ReaderDb.Get(&current, `
+		SELECT ens_name
+		FROM ens
+		WHERE
+			address = $1 AND
+			is_primary_name
+		ORDER BY last_validated_at DESC NULLS LAST
+		LIMIT 1
+		;`, address.Bytes())
+		if err != nil {
+			return nil, err
+		}
+	}
+	primaryName := ensPrimaryNameAsOf(changes, current, at)
+	if primaryName == "" {
+		return nil, sql.ErrNoRows
+	}
+
+	candidate := ensPrimaryNameCandidate{}
+	err = ReaderDb.Get(&candidate, `
+	SELECT 
+		ens_name, 
+		valid_to,
+		(SELECT min(ts) FROM ens_registrations WHERE ens_registrations.name_hash = ens.name_hash) AS registered_at
+	FROM ens
+	WHERE
+		md5(ens_name) = md5($1) AND
+		ens_name = $1
+	;`, primaryName)
+	if err != nil {
+		return nil, err
+	}
+	if !ensNameValidAsOf(candidate, at) {
+		return nil, sql.ErrNoRows
+	}
+	return &candidate.Name, nil
+}
+
+// ensPrimaryNameAsOf returns the primary name an address had at the given time from its changes ordered by time, an empty name is no primary name.
+// Before its first change the address had the old name of that change, without changes it had its current primary name all along.
+func ensPrimaryNameAsOf(changes []ensPrimaryNameChange, current string, at time.Time) string {
+	if len(changes) == 0 {
+		return current
+	}
+	name := changes[0].OldName
+	for _, change := range changes {
+		if change.Ts.After(at) {
+			break
+		}
+		name = change.NewName
+	}
+	return name
+}
+
+// ensNameValidAsOf reports whether a name was registered and not expired at the given time. Names without an indexed registration (e.g.
+// names registered before the indexed registrar contracts) are only checked against their expiry.
+func ensNameValidAsOf(candidate ensPrimaryNameCandidate, at time.Time) bool {
+	if candidate.ValidTo.Before(at) {
+		return false
+	}
+	return !candidate.RegisteredAt.Valid || !candidate.RegisteredAt.Time.After(at)
+}
+
+// GetEnsNameForAddressAtBlock returns the name that resolved to an address when the given block was mined. Of several names resolving to the
+// address the current primary name is preferred, then the name whose address was set most recently.
+func GetEnsNameForAddressAtBlock(address common.Address, block uint64) (name *string, err error) {
+	err = ReaderDb.Get(&name, `
+	SELECT ens.ens_name
+	FROM ens_history
+	INNER JOIN ens ON ens.name_hash = ens_history.name_hash
+	WHERE
+		ens_history.address = $1 AND
+		ens_history.valid_from_block <= $2 AND
+		(ens_history.valid_to_block IS NULL OR ens_history.valid_to_block > $2)
+	ORDER BY ens.is_primary_name DESC, ens_history.valid_from_block DESC
+	LIMIT 1
+	;`, address.Bytes(), block)
+	return name, err
+}
+
+// GetEnsNamesForTx returns the primary ens names of the sender and receiver of a transaction using a single query
+func GetEnsNamesForTx(from, to common.Address) (fromName, toName *string, err error) {
+	rows := []ensAddressName{}
+	err = ReaderDb.Select(&rows, `
+	SELECT address, ens_name 
+	FROM ens
+	WHERE
+		address = ANY($1) AND
+		is_primary_name AND
+		valid_to >= now()
+	;`, pq.ByteaArray{from.Bytes(), to.Bytes()})
+	if err != nil {
+		return nil, nil, err
+	}
+	fromName, toName = ensNamesForTx(from, to, rows)
+	return fromName, toName, nil
+}
+
+// ensAddressName is the primary name of an address
+type ensAddressName struct {
+	Address []byte `db:"address"`
+	Name    string `db:"ens_name"`
+}
+
+// ensNamesForTx assigns the primary names to the sender and receiver of a transaction, both get the same name for a self transfer
+func ensNamesForTx(from, to common.Address, rows []ensAddressName) (fromName, toName *string) {
+	for _, row := range rows {
+		name := row.Name
+		address := common.BytesToAddress(row.Address)
+		if address == from {
+			fromName = &name
+		}
+		if address == to {
+			toName = &name
+		}
+	}
+	return fromName, toName
+}
+
+// GetEnsNamesForAddresses returns the primary ens names of the given addresses using a single query, addresses without a name are not part of the map
+func GetEnsNamesForAddresses(addresses []common.Address) (map[common.Address]string, error) {
+	names := make(map[common.Address]string, len(addresses))
+	if len(addresses) == 0 {
+		return names, nil
+	}
+	addressBytes := make(pq.ByteaArray, 0, len(addresses))
+	for _, address := range addresses {
+		addressBytes = append(addressBytes, address.Bytes())
+	}
+	rows := []struct {
+		Address []byte `db:"address"`
+		Name    string `db:"ens_name"`
+	}{}
+	err := ReaderDb.Select(&rows, `
+	SELECT DISTINCT ON (address) address, ens_name
+	FROM ens
+	WHERE
+		address = ANY($1) AND
+		is_primary_name AND
+		valid_to >= now()
+	ORDER BY address, last_validated_at DESC NULLS LAST
+	;`, addressBytes)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		names[common.BytesToAddress(row.Address)] = row.Name
+	}
+	return names, nil
+}
+
+// GetEnsNamesForValidatorWithdrawalAddresses returns the primary ens names of the withdrawal addresses of the given validators, the names are
+// looked up with GetEnsNamesForAddresses. Validators with BLS (0x00) credentials have no withdrawal address and are not part of the map.
+func GetEnsNamesForValidatorWithdrawalAddresses(validators []uint64) (map[uint64]string, error) {
+	names := make(map[uint64]string, len(validators))
+	if len(validators) == 0 {
+		return names, nil
+	}
+	rows := []struct {
+		ValidatorIndex        uint64 `db:"validatorindex"`
+		WithdrawalCredentials []byte `db:"withdrawalcredentials"`
+	}{}
+	err := ReaderDb.Select(&rows, `
+	SELECT validatorindex, withdrawalcredentials
+	FROM validators
+	WHERE validatorindex = ANY($1)
+	;`, pq.Array(validators))
+	if err != nil {
+		return nil, err
+	}
+	withdrawalAddresses := make(map[uint64]common.Address, len(rows))
+	addresses := make([]common.Address, 0, len(rows))
+	for _, row := range rows {
+		address, ok := ensWithdrawalAddress(row.WithdrawalCredentials)
+		if !ok {
+			continue
+		}
+		withdrawalAddresses[row.ValidatorIndex] = address
+		addresses = append(addresses, address)
+	}
+	addressNames, err := GetEnsNamesForAddresses(addresses)
+	if err != nil {
+		return nil, err
+	}
+	for validator, address := range withdrawalAddresses {
+		if name, ok := addressNames[address]; ok {
+			names[validator] = name
+		}
+	}
+	return names, nil
+}
+
+// ensWithdrawalAddress returns the execution address of execution (0x01) and compounding (0x02) withdrawal credentials,
+// BLS (0x00) credentials have no address
+func ensWithdrawalAddress(credentials []byte) (common.Address, bool) {
+	if len(credentials) != 32 || (credentials[0] != 0x01 && credentials[0] != 0x02) {
+		return common.Address{}, false
+	}
+	return common.BytesToAddress(credentials[12:]), true
+}
+
+// GetOldestEnsNames returns the active names with the oldest registration.
+// A re-registered name counts from its latest registration and names without an indexed registration are excluded.
+func GetOldestEnsNames(limit int) ([]types.EnsName, error) {
+	names := []types.EnsName{}
+	err := ReaderDb.Select(&names, `
+	SELECT
+		ens.name_hash,
+		ens.ens_name,
+		ens.address,
+		ens.is_primary_name,
+		ens.valid_to,
+		registrations.registered_at
+	FROM ens
+	INNER JOIN (
+		SELECT name_hash, MAX(ts) AS registered_at
+		FROM ens_registrations
+		GROUP BY name_hash
+	) registrations ON registrations.name_hash = ens.name_hash
+	WHERE ens.valid_to >= now()
+	ORDER BY registrations.registered_at ASC
+	LIMIT $1
+	`, limit)
+	return names, err
+}
+
+// GetMostExpensiveEnsRegistrations returns the registrations with the highest cost since a point in time, the cost includes the premium
+// of names registered shortly after they expired
+func GetMostExpensiveEnsRegistrations(since time.Time, limit int) ([]types.EnsRegistrationCost, error) {
+	registrations := []types.EnsRegistrationCost{}
+	err := ReaderDb.Select(&registrations, `
+	SELECT
+		ens.ens_name,
+		r.tx_hash,
+		r.ts,
+		r.cost_wei
+	FROM ens_registrations r
+	INNER JOIN ens ON ens.name_hash = r.name_hash
+	WHERE
+		r.cost_wei IS NOT NULL AND
+		r.ts >= $1
+	ORDER BY r.cost_wei DESC
+	LIMIT $2
+	`, since, limit)
+	return registrations, err
+}
+
+// GetEnsNameAge returns the time since the latest registration of a name
+func GetEnsNameAge(name string) (time.Duration, error) {
+	name = utils.TrimEnsName(name)
+	var registeredAt sql.NullTime
+	err := ReaderDb.Get(&registeredAt, `
+	SELECT MAX(ens_registrations.ts)
+	FROM ens_registrations
+	INNER JOIN ens ON ens.name_hash = ens_registrations.name_hash
+	WHERE
+		md5(ens.ens_name) = md5($1) AND
+		ens.ens_name = $1
+	`, name)
+	if err != nil {
+		return 0, err
+	}
+	if !registeredAt.Valid {
+		return 0, sql.ErrNoRows
+	}
+	return time.Since(registeredAt.Time), nil
+}
+
+// GetEnsRegistrationTx returns the hash of the tx that registered the name, for names registered multiple times the latest registration is returned
+func GetEnsRegistrationTx(name string) (*common.Hash, error) {
+	name = utils.TrimEnsName(name)
+	var registrationTx []byte
+	err := ReaderDb.Get(&registrationTx, `
+	SELECT registration_tx
+	FROM ens
+	WHERE
+		md5(ens_name) = md5($1) AND
+		ens_name = $1
+	`, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(registrationTx) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	txHash := common.BytesToHash(registrationTx)
+	return &txHash, nil
+}
+
+// GetEnsOneWayPrimaryNames returns primary names whose resolved address differs from the address whose reverse record claims them.
+// Rows that were stored before the claiming address was tracked are included as well, so they get re-validated.
+func GetEnsOneWayPrimaryNames(limit int) ([]types.EnsName, error) {
+	names := []types.EnsName{}
+	err := ReaderDb.Select(&names, `
+	SELECT
+		name_hash,
+		ens_name,
+		address,
+		is_primary_name,
+		valid_to
+	FROM ens
+	WHERE
+		is_primary_name AND
+		(address IS NULL OR primary_claimed_by IS NULL OR primary_claimed_by <> address)
+	ORDER BY valid_to DESC
+	LIMIT $1
+	`, limit)
+	return names, err
+}
+
+// GetEnsIdentity returns the primary name of an address with its avatar text record and whether the name is verified in both directions
+func GetEnsIdentity(address common.Address) (*types.EnsIdentity, error) {
+	identity := &types.EnsIdentity{}
+	err := ReaderDb.Get(identity, `
+	SELECT
+		ens.ens_name,
+		ens.avatar_url,
+		COALESCE(ens.primary_claimed_by = ens.address, false) AS verified
+	FROM ens
+	WHERE
+		ens.address = $1 AND
+		ens.is_primary_name AND
+		ens.valid_to >= now()
+	ORDER BY ens.last_validated_at DESC NULLS LAST
+	LIMIT 1
+	`, address.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+// GetEnsNamesWithNoAddress returns active names that do not resolve to an address, either because no address is stored or it is the zero address
+func GetEnsNamesWithNoAddress(limit int) ([]types.EnsName, error) {
+	names := []types.EnsName{}
+	err := ReaderDb.Select(&names, `
+	SELECT
+		name_hash,
+		ens_name,
+		address,
+		is_primary_name,
+		valid_to
+	FROM ens
+	WHERE
+		(address IS NULL OR address = $1) AND
+		NOT name_undecodable AND
+		valid_to >= now()
+	ORDER BY valid_to ASC
+	LIMIT $2
+	`, common.Address{}.Bytes(), limit)
+	return names, err
+}
+
+// GetEnsExpirationBuckets returns the number of active names expiring within the given time range, grouped by "week" or "month".
+// The buckets are keyed by their start in UTC.
+func GetEnsExpirationBuckets(from, to time.Time, bucket string) (map[time.Time]int, error) {
+	if bucket != "week" && bucket != "month" {
+		return nil, fmt.Errorf("unsupported bucket %v, expected week or month", bucket)
+	}
+	rows := []struct {
+		Bucket time.Time `db:"bucket"`
+		Count  int       `db:"count"`
+	}{}
+	err := ReaderDb.Select(&rows, `
+	SELECT
+		date_trunc($1, valid_to) AS bucket,
+		COUNT(*) AS count
+	FROM ens
+	WHERE
+		valid_to >= GREATEST($2, now()) AND
+		valid_to < $3
+	GROUP BY bucket
+	`, bucket, from, to)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[time.Time]int, len(rows))
+	for _, row := range rows {
+		// the driver returns the timestamps in an unnamed zone, the keys would not match the times of the caller otherwise
+		result[row.Bucket.UTC()] = row.Count
+	}
+	return result, nil
+}
+
+// SearchEns matches a query against names, resolved addresses and owners. An address is matched exactly against the resolved address
+// and the owner of the latest registration, anything else is treated as a name prefix. Exact and primary names are ranked first.
+func SearchEns(query string, limit int) ([]types.EnsSearchResult, error) {
+	query = utils.TrimEnsName(query)
+	results := []types.EnsSearchResult{}
+	if query == "" {
+		return results, nil
+	}
+
+	if utils.IsValidEth1Address(query) {
+		address := common.HexToAddress(query)
+		err := ReaderDb.Select(&results, `
+		SELECT
+			ens_name,
+			address,
+			is_primary_name,
+			valid_to,
+			CASE WHEN address = $1 THEN 'address' ELSE 'owner' END AS matched_on
+		FROM ens
+		WHERE
+			(
+				address = $1 OR
+				name_hash IN (
+					SELECT r.name_hash
+					FROM ens_registrations r
+					WHERE
+						r.owner = $1 AND
+						NOT EXISTS (SELECT 1 FROM ens_registrations newer WHERE newer.name_hash = r.name_hash AND newer.ts > r.ts)
+				)
+			) AND
+			NOT name_undecodable AND
+			valid_to >= now()
+		ORDER BY COALESCE(address = $1, false) DESC, is_primary_name DESC, ens_name ASC
+		LIMIT $2
+		`, address.Bytes(), limit)
+		return results, err
+	}
+
+	err := ReaderDb.Select(&results, `
+	SELECT
+		ens_name,
+		address,
+		is_primary_name,
+		valid_to,
+		'name' AS matched_on
+	FROM ens
+	WHERE
+		ens_name LIKE $2 ESCAPE '\' AND
+		NOT name_undecodable AND
+		valid_to >= now()
+	ORDER BY ens_name = $1 DESC, is_primary_name DESC, LENGTH(ens_name) ASC, ens_name ASC
+	LIMIT $3
+	`, strings.ToLower(query), escapeLikePattern(strings.ToLower(query))+"%", limit)
+	return results, err
+}
+
+// SearchEnsNames returns the valid primary names starting with a prefix for the autocompletion of the search bar, e.g. "vita" suggests
+// "vitalik.eth". The prefix is lowercased like the names are when they are validated, long lived names are ranked first.
+func SearchEnsNames(prefix string, limit int) ([]string, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	names := []string{}
+	if prefix == "" {
+		return names, nil
+	}
+	// the prefix index covers the first characters of the names, longer prefixes are matched against the full name
+	err := ReaderDb.Select(&names, `
+	SELECT ens_name
+	FROM ens
+	WHERE
+		left(ens_name, 256) LIKE $1 || '%' ESCAPE '\' AND
+		ens_name LIKE $2 || '%' ESCAPE '\' AND
+		is_primary_name AND
+		NOT name_undecodable AND
+		valid_to >= now()
+	ORDER BY valid_to DESC, ens_name ASC
+	LIMIT $3
+	`, escapeLikePattern(ensNameIndexPrefix(prefix)), escapeLikePattern(prefix), limit)
+	return names, err
+}
+
+// ENS_NAME_INDEX_PREFIX_LENGTH is the number of characters of a name covered by the prefix index, it keeps the index entries of long names
+// below the index row size limit and has to match the left(ens_name, 256) expression of the index
+const ENS_NAME_INDEX_PREFIX_LENGTH = 256
+
+// ensNameIndexPrefix returns the part of a prefix that is covered by the prefix index, i.e. its first ENS_NAME_INDEX_PREFIX_LENGTH characters
+func ensNameIndexPrefix(prefix string) string {
+	runes := 0
+	for i := range prefix {
+		if runes == ENS_NAME_INDEX_PREFIX_LENGTH {
+			return prefix[:i]
+		}
+		runes++
+	}
+	return prefix
+}
 
-	alreadyChecked.mux.Lock()
-	if alreadyChecked.address[address] {
-		alreadyChecked.mux.Unlock()
-		return nil
+// escapeLikePattern escapes the wildcards of a LIKE pattern so user input is matched literally
+func escapeLikePattern(pattern string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(pattern)
+}
+
+// CleanOrphanedEnsRecords removes the rows of the name hash tables whose name no longer exists and validates their foreign keys afterwards,
+// new orphans are prevented by the cascading deletes. The registrations, renewals and transfers are not cleaned and have no foreign key,
+// they are the on chain history of a name hash and are recorded before the name itself is validated or even when it never is (e.g. an undecodable name).
+func CleanOrphanedEnsRecords() (int, error) {
+	tx, err := WriterDb.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	total := 0
+	for _, table := range ensNameHashTables {
+		res, err := tx.Exec(fmt.Sprintf(`
+		DELETE FROM %[1]s
+		WHERE NOT EXISTS (SELECT 1 FROM ens WHERE ens.name_hash = %[1]s.name_hash)
+		`, table))
+		if err != nil {
+			return 0, fmt.Errorf("error deleting orphaned rows of %v: %w", table, err)
+		}
+		deleted, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.Exec(fmt.Sprintf(`ALTER TABLE %s VALIDATE CONSTRAINT %s`, table, ensNameHashForeignKey(table)))
+		if err != nil {
+			return 0, fmt.Errorf("error validating foreign key of %v: %w", table, err)
+		}
+		logger.Infof("removed %v orphaned rows of %v", deleted, table)
+		total += int(deleted)
 	}
-	alreadyChecked.address[address] = true
-	alreadyChecked.mux.Unlock()
 
-	name, err := go_ens.ReverseResolve(client, address)
+	err = tx.Commit()
 	if err != nil {
-		utils.LogError(err, fmt.Errorf("address could not be reverse resolved: %v", address), 0)
-		return removeEnsAddress(client, address, alreadyChecked)
+		return 0, err
 	}
+	return total, nil
+}
 
-	currentName, err := GetEnsNameForAddress(address)
-	if err != nil && err != sql.ErrNoRows {
+// ensNameHashForeignKey returns the name of the cascading foreign key of a name hash table
+func ensNameHashForeignKey(table string) string {
+	return fmt.Sprintf("fk_%s_name_hash", table)
+}
+
+// ensTld returns the top level domain of a name, e.g. "eth" for "vitalik.eth"
+func ensTld(name string) string {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// GetEnsNameCountsByTld returns the number of active names per top level domain
+func GetEnsNameCountsByTld() (map[string]int, error) {
+	rows := []struct {
+		Tld   string `db:"tld"`
+		Count int    `db:"count"`
+	}{}
+	err := ReaderDb.Select(&rows, `
+	SELECT tld, COUNT(*) AS count
+	FROM ens
+	WHERE
+		tld IS NOT NULL AND
+		valid_to >= now()
+	GROUP BY tld
+	`)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]int, len(rows))
+	for _, row := range rows {
+		result[row.Tld] = row.Count
+	}
+	return result, nil
+}
+
+// ensBigtableKeyExport is a single row of an ens key export, one row is written per line
+type ensBigtableKeyExport struct {
+	Key   string                  `json:"key"`
+	Cells []ensBigtableCellExport `json:"cells"`
+}
+
+type ensBigtableCellExport struct {
+	Family    string `json:"family"`
+	Column    string `json:"column"`
+	Timestamp int64  `json:"timestamp"`
+	Value     []byte `json:"value"`
+}
+
+// ExportEnsBigtableKeys writes all rows below the given ens prefix (e.g. "ENS:I" or "ENS:V") of the current chain as newline delimited json.
+// Rows are written while they are read so the export does not need to fit into memory.
+func (bigtable *Bigtable) ExportEnsBigtableKeys(w io.Writer, prefix string) error {
+	if !strings.HasPrefix(prefix, "ENS") {
+		return fmt.Errorf("invalid ens prefix %v", prefix)
+	}
+	ctx, done := context.WithTimeout(context.Background(), time.Hour)
+	defer done()
+
+	encoder := json.NewEncoder(w)
+	var encodeErr error
+	exported := 0
+	err := bigtable.getEnsTable().ReadRows(ctx, gcp_bigtable.PrefixRange(fmt.Sprintf("%s:%s", bigtable.chainId, prefix)), func(row gcp_bigtable.Row) bool {
+		export := ensBigtableKeyExport{Key: row.Key()}
+		for family, items := range row {
+			for _, item := range items {
+				export.Cells = append(export.Cells, ensBigtableCellExport{
+					Family:    family,
+					Column:    strings.TrimPrefix(item.Column, family+":"),
+					Timestamp: int64(item.Timestamp),
+					Value:     item.Value,
+				})
+			}
+		}
+		encodeErr = encoder.Encode(export)
+		exported++
+		return encodeErr == nil
+	})
+	if err != nil {
 		return err
 	}
-	isPrimary := false
-	if currentName != nil {
-		if *currentName == name {
-			return nil
+	if encodeErr != nil {
+		return encodeErr
+	}
+	logger.Infof("exported %v ens rows with prefix %v", exported, prefix)
+	return nil
+}
+
+// ImportEnsBigtableKeys restores rows that were written by ExportEnsBigtableKeys
+func (bigtable *Bigtable) ImportEnsBigtableKeys(r io.Reader) error {
+	batchSize := 1000
+	muts := &types.BulkMutations{}
+	imported := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
 		}
-		logger.Infof("Address [%x] has a new main name from %x to: %v", address, *currentName, name)
-		err := validateEnsName(client, *currentName, alreadyChecked, &isPrimary)
+		export := ensBigtableKeyExport{}
+		err := json.Unmarshal(scanner.Bytes(), &export)
+		if err != nil {
+			return fmt.Errorf("error decoding ens row %v: %w", imported+1, err)
+		}
+		if !strings.Contains(export.Key, ":ENS") {
+			return fmt.Errorf("row %v is not an ens row", export.Key)
+		}
+
+		mut := gcp_bigtable.NewMutation()
+		for _, cell := range export.Cells {
+			mut.Set(cell.Family, cell.Column, gcp_bigtable.Timestamp(cell.Timestamp), cell.Value)
+		}
+		muts.Keys = append(muts.Keys, export.Key)
+		muts.Muts = append(muts.Muts, mut)
+		imported++
+
+		if len(muts.Keys) >= batchSize {
+			err := bigtable.getEnsTable().WriteBulk(muts)
+			if err != nil {
+				return err
+			}
+			muts = &types.BulkMutations{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(muts.Keys) > 0 {
+		err := bigtable.getEnsTable().WriteBulk(muts)
 		if err != nil {
 			return err
 		}
 	}
-	isPrimary = true
-	logger.Infof("Address [%x] has a primary name: %v", address, name)
-	return validateEnsName(client, name, alreadyChecked, &isPrimary)
+	logger.Infof("imported %v ens rows", imported)
+	return nil
 }
 
-func validateEnsName(client *ethclient.Client, name string, alreadyChecked *EnsCheckedDictionary, isPrimaryName *bool) error {
-	// For now only .eth is supported other ens domains use different techniques and require and individual implementation
-	if !strings.HasSuffix(name, ".eth") {
-		name = fmt.Sprintf("%s.eth", name)
+// SeedEnsFromNameFile validates all names of a newline delimited file and stores them, it is used to bootstrap the ens table with known names.
+// Empty lines and lines starting with # are ignored, malformed names are skipped and reported in the result.
+func SeedEnsFromNameFile(ctx context.Context, client *ethclient.Client, path string) (types.EnsImportResult, error) {
+	result := types.EnsImportResult{}
+	file, err := os.Open(path)
+	if err != nil {
+		return result, err
 	}
-	alreadyChecked.mux.Lock()
-	if alreadyChecked.name[name] {
-		alreadyChecked.mux.Unlock()
-		return nil
+	defer file.Close()
+
+	names, skipped, err := readEnsNameFile(file)
+	if err != nil {
+		return result, err
 	}
-	alreadyChecked.name[name] = true
-	alreadyChecked.mux.Unlock()
+	result.Total = len(names) + len(skipped)
+	result.Skipped = skipped
+	logger.Infof("seeding %v ens names from %v, skipped %v malformed lines", len(names), path, len(skipped))
 
-	nameHash, err := go_ens.NameHash(name)
+	alreadyChecked := EnsCheckedDictionary{
+		address: make(map[common.Address]bool),
+		name:    make(map[string]bool),
+	}
+	defer alreadyChecked.flushValidationAudits()
+	var mux sync.Mutex
+	// a cancelled seed stops the pending validations, the running ones stop at their next wait for the rate limit
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(ensValidationConcurrency())
+	for _, n := range names {
+		name := n
+		g.Go(func() error {
+			if err := gCtx.Err(); err != nil {
+				return err
+			}
+			err := validateEnsName(gCtx, client, name, &alreadyChecked, nil, nil)
+			mux.Lock()
+			defer mux.Unlock()
+			if err != nil {
+				utils.LogError(err, fmt.Errorf("error seeding ens name %v", name), 0)
+				result.Failed++
+				return nil
+			}
+			result.Validated++
+			return nil
+		})
+	}
+	err = g.Wait()
 	if err != nil {
-		utils.LogError(err, fmt.Errorf("could not hash name: %v", name), 0)
-		return nil
+		return result, err
+	}
+	return result, alreadyChecked.flushRemovedNames()
+}
+
+// RevalidateAllPrimaryNames re-checks the primary name claim of every address in the ens table.
+// This allows to re-derive all primary names after a migration of the reverse registrar without replaying blocks.
+func RevalidateAllPrimaryNames(ctx context.Context, client *ethclient.Client) (types.EnsImportResult, error) {
+	addresses := [][]byte{}
+	err := ReaderDb.Select(&addresses, `
+	SELECT DISTINCT address
+	FROM ens
+	WHERE address IS NOT NULL
+	`)
+	if err != nil {
+		return types.EnsImportResult{}, err
 	}
+	logger.Infof("revalidating the primary names of %v addresses", len(addresses))
 
-	addr, err := go_ens.Resolve(client, name)
+	alreadyChecked := EnsCheckedDictionary{
+		address: make(map[common.Address]bool),
+		name:    make(map[string]bool),
+	}
+	defer alreadyChecked.flushValidationAudits()
+	result, err := revalidateEnsAddresses(ctx, addresses, func(ctx context.Context, address common.Address) error {
+		return validateEnsAddress(ctx, client, address, &alreadyChecked)
+	})
 	if err != nil {
-		utils.LogError(err, fmt.Errorf("error resolving name: %v", name), 0)
-		return removeEnsName(client, name)
+		return result, err
 	}
-	ensName, err := go_ens.NewName(client, name)
+	return result, alreadyChecked.flushRemovedNames()
+}
+
+const (
+	ENS_MISMATCH_ADDRESS = "address"
+	ENS_MISMATCH_EXPIRY  = "expiry"
+	ENS_MISMATCH_PRIMARY = "primary"
+)
+
+// ensStoredRecord is a row of the ens table sampled by the consistency checker
+type ensStoredRecord struct {
+	NameHash      []byte    `db:"name_hash"`
+	Name          string    `db:"ens_name"`
+	Address       []byte    `db:"address"`
+	IsPrimaryName bool      `db:"is_primary_name"`
+	ValidTo       time.Time `db:"valid_to"`
+}
+
+// VerifyEnsConsistency compares a random sample of stored names against the chain and reports the names whose address, expiry or primary flag
+// differ, the mismatches are counted by the ens_consistency_mismatches metric. Nothing is corrected, if markDirty is set the mismatched names
+// are queued for the next ImportEnsUpdates run instead.
+func (bigtable *Bigtable) VerifyEnsConsistency(ctx context.Context, client *ethclient.Client, sampleSize int, markDirty bool) (*types.EnsConsistencyReport, error) {
+	records := []ensStoredRecord{}
+	err := ReaderDb.Select(&records, `
+	SELECT name_hash, ens_name, address, is_primary_name, valid_to
+	FROM ens
+	WHERE
+		NOT name_undecodable AND
+		valid_to >= now()
+	ORDER BY random()
+	LIMIT $1
+	`, sampleSize)
 	if err != nil {
-		utils.LogError(err, fmt.Errorf("error getting create ens name: %v", name), 0)
-		return removeEnsName(client, name)
+		return nil, err
+	}
+	report := &types.EnsConsistencyReport{Mismatches: []types.EnsConsistencyMismatch{}}
+	if len(records) == 0 {
+		return report, nil
 	}
-	expires, err := ensName.Expires()
+
+	names := make([]string, 0, len(records))
+	for _, record := range records {
+		names = append(names, record.Name)
+	}
+	// the .eth second level names are resolved in one batch, all other names one by one
+	resolutions, err := batchResolveEns(ctx, client, names)
 	if err != nil {
-		utils.LogError(err, fmt.Errorf("error get ens expire date: %v", name), 0)
-		return removeEnsName(client, name)
+		logger.Warnf("error batch resolving %v ens names, resolving them one by one: %v", len(names), err)
 	}
-	isPrimary := false
-	if isPrimaryName == nil {
-		reverseName, err := go_ens.ReverseResolve(client, addr)
-		if err == nil && reverseName == name {
-			isPrimary = true
+
+	var mux sync.Mutex
+	dirty := [][]byte{}
+	g := new(errgroup.Group)
+	g.SetLimit(ensValidationConcurrency())
+	for _, r := range records {
+		record := r
+		g.Go(func() error {
+			var err error
+			resolution := resolutions[record.Name]
+			if resolution == nil {
+				resolution, err = resolveEnsRecord(ctx, client, record.Name)
+			}
+			mux.Lock()
+			defer mux.Unlock()
+			if err != nil {
+				utils.LogError(err, fmt.Errorf("error resolving ens name %v for the consistency check", record.Name), 0)
+				report.Failed++
+				return nil
+			}
+			report.Checked++
+			metrics.EnsConsistencyChecked.Inc()
+			mismatches := compareEnsRecord(record, resolution)
+			for _, mismatch := range mismatches {
+				logger.Warnf("ens name %v is inconsistent, stored %v %v but the chain has %v", mismatch.Name, mismatch.Kind, mismatch.Stored, mismatch.Actual)
+				metrics.EnsConsistencyMismatches.WithLabelValues(mismatch.Kind).Inc()
+			}
+			if len(mismatches) > 0 {
+				report.Mismatches = append(report.Mismatches, mismatches...)
+				dirty = append(dirty, record.NameHash)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return report, err
+	}
+	logger.Infof("checked the consistency of %v ens names, %v mismatches, %v names could not be checked", report.Checked, len(report.Mismatches), report.Failed)
+	if markDirty && len(dirty) > 0 {
+		return report, bigtable.queueEnsNameHashes(dirty)
+	}
+	return report, nil
+}
+
+// resolveEnsRecord reads the address and primary name of a name that is not part of a batch resolution, its expiry is left unset
+func resolveEnsRecord(ctx context.Context, client *ethclient.Client, name string) (*ensResolution, error) {
+	resolution := &ensResolution{}
+	err := retryEnsCall(ctx, func() (err error) {
+		resolution.address, err = go_ens.Resolve(client, name)
+		return err
+	})
+	if err != nil && !isEnsNotFoundError(err) {
+		return nil, err
+	}
+	resolution.err = err
+	if resolution.err == nil {
+		resolution.reverseErr = retryEnsCall(ctx, func() (err error) {
+			resolution.reverseName, err = go_ens.ReverseResolve(client, resolution.address)
+			return err
+		})
+	}
+	return resolution, nil
+}
+
+// compareEnsRecord returns the differences between a stored name and its resolution. The expiry is only compared if it is known and the name
+// is not wrapped, as the name wrapper may shorten it, the primary flag is only compared if the reverse record could be read.
+func compareEnsRecord(record ensStoredRecord, resolution *ensResolution) []types.EnsConsistencyMismatch {
+	mismatches := []types.EnsConsistencyMismatch{}
+	var resolved common.Address
+	if resolution.err == nil {
+		resolved = resolution.address
+	}
+	if stored := common.BytesToAddress(record.Address); stored != resolved {
+		mismatches = append(mismatches, types.EnsConsistencyMismatch{Name: record.Name, Kind: ENS_MISMATCH_ADDRESS, Stored: stored.Hex(), Actual: resolved.Hex()})
+	}
+	if !resolution.expires.IsZero() && !isEnsNameWrapperContract(resolution.owner) && !resolution.expires.Equal(record.ValidTo) {
+		mismatches = append(mismatches, types.EnsConsistencyMismatch{Name: record.Name, Kind: ENS_MISMATCH_EXPIRY, Stored: record.ValidTo.UTC().String(), Actual: resolution.expires.UTC().String()})
+	}
+	if resolution.err == nil && (resolution.reverseErr == nil || isEnsNotFoundError(resolution.reverseErr)) {
+		isPrimary := resolution.reverseErr == nil && utils.NormalizeEnsName(resolution.reverseName) == record.Name
+		if isPrimary != record.IsPrimaryName {
+			mismatches = append(mismatches, types.EnsConsistencyMismatch{Name: record.Name, Kind: ENS_MISMATCH_PRIMARY, Stored: strconv.FormatBool(record.IsPrimaryName), Actual: strconv.FormatBool(isPrimary)})
 		}
-	} else if *isPrimaryName {
-		isPrimary = true
 	}
-	_, err = WriterDb.Exec(`
-	INSERT INTO ens (
-		name_hash, 
-		ens_name, 
-		address,
-		is_primary_name, 
-		valid_to)
-	VALUES ($1, $2, $3, $4, $5) 
-	ON CONFLICT 
-		(name_hash) 
-	DO UPDATE SET 
-		ens_name = excluded.ens_name,
-		address = excluded.address,
-		is_primary_name = excluded.is_primary_name,
-		valid_to = excluded.valid_to
-	`, nameHash[:], name, addr.Bytes(), isPrimary, expires)
+	return mismatches
+}
+
+// ResyncEnsName validates a single name right away without going through the dirty key queue, e.g. to fix a name that shows a stale address.
+// The cached resolution of the name is dropped, so the name is always resolved from the node.
+func ResyncEnsName(ctx context.Context, client *ethclient.Client, name string) error {
+	name = utils.NormalizeEnsName(name)
+	nameHash, err := ensNameHash(name)
+	if err != nil {
+		return fmt.Errorf("error hashing ens name %v: %w", name, err)
+	}
+	sharedEnsResolveCache.forgetName(nameHash)
+
+	alreadyChecked := EnsCheckedDictionary{
+		address: make(map[common.Address]bool),
+		name:    make(map[string]bool),
+	}
+	defer alreadyChecked.flushValidationAudits()
+	err = validateEnsName(ctx, client, name, &alreadyChecked, nil, nil)
 	if err != nil {
-		utils.LogError(err, fmt.Errorf("error writing ens data for name [%v]", name), 0)
 		return err
 	}
-	logger.Infof("Name [%v] resolved -> %x, expires: %v, is primary: %v", name, addr, expires, isPrimary)
-	return nil
+	return alreadyChecked.flushRemovedNames()
 }
 
-func removeEnsAddress(client *ethclient.Client, address common.Address, alreadyChecked *EnsCheckedDictionary) error {
-	name, err := GetEnsNameForAddress(address)
-	if err != nil && err != sql.ErrNoRows {
+// ResyncEnsAddress validates the primary name of a single address right away without going through the dirty key queue.
+// The cached primary name of the address is dropped, so the address is always reverse resolved from the node.
+func ResyncEnsAddress(ctx context.Context, client *ethclient.Client, address common.Address) error {
+	sharedEnsResolveCache.forgetAddress(address)
+
+	alreadyChecked := EnsCheckedDictionary{
+		address: make(map[common.Address]bool),
+		name:    make(map[string]bool),
+	}
+	defer alreadyChecked.flushValidationAudits()
+	err := validateEnsAddress(ctx, client, address, &alreadyChecked)
+	if err != nil {
 		return err
 	}
-	if name == nil {
-		return nil
+	return alreadyChecked.flushRemovedNames()
+}
+
+// revalidateEnsAddresses validates the given addresses with the configured concurrency, failed addresses are counted but do not abort the run.
+// A cancelled context stops the pending validations and is returned as the error of the run.
+func revalidateEnsAddresses(ctx context.Context, addresses [][]byte, validate func(ctx context.Context, address common.Address) error) (types.EnsImportResult, error) {
+	result := types.EnsImportResult{Total: len(addresses)}
+	var mux sync.Mutex
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(ensValidationConcurrency())
+	for _, a := range addresses {
+		address := common.BytesToAddress(a)
+		g.Go(func() error {
+			if err := gCtx.Err(); err != nil {
+				return err
+			}
+			err := validate(gCtx, address)
+			mux.Lock()
+			defer mux.Unlock()
+			if err != nil {
+				utils.LogError(err, fmt.Errorf("error revalidating primary name of address %v", address), 0)
+				result.Failed++
+				return nil
+			}
+			result.Validated++
+			return nil
+		})
 	}
-	isPrimary := false
-	return validateEnsName(client, *name, alreadyChecked, &isPrimary)
+	err := g.Wait()
+	return result, err
 }
 
-func removeEnsName(client *ethclient.Client, name string) error {
-	_, err := WriterDb.Exec(`
-	DELETE FROM ens 
-	WHERE 
-		ens_name = $1
-	;`, name)
+// readEnsNameFile reads the normalized names of a name file and the lines that could not be normalized
+func readEnsNameFile(r io.Reader) (names []string, skipped []types.EnsImportSkippedLine, err error) {
+	scanner := bufio.NewScanner(r)
+	seen := make(map[string]bool)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		name := utils.TrimEnsName(text)
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+		normalized, err := go_ens.Normalize(name)
+		if err != nil {
+			skipped = append(skipped, types.EnsImportSkippedLine{Line: line, Text: text, Reason: err.Error()})
+			continue
+		}
+		if strings.ContainsAny(normalized, " \t") {
+			skipped = append(skipped, types.EnsImportSkippedLine{Line: line, Text: text, Reason: "name contains whitespace"})
+			continue
+		}
+		if strings.Contains(normalized, "..") || strings.HasPrefix(normalized, ".") {
+			skipped = append(skipped, types.EnsImportSkippedLine{Line: line, Text: text, Reason: "name contains an empty label"})
+			continue
+		}
+		if seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		names = append(names, normalized)
+	}
+	return names, skipped, scanner.Err()
+}
+
+// GetEnsRegistrationsPerDay returns the number of registrations per day on the given chain within the time range. The day is taken from
+// the timestamp of the registered block, registrations in blocks before the merge have no block in the blocks table and are not counted.
+func GetEnsRegistrationsPerDay(chainId uint64, from, to time.Time) ([]types.EnsDailyCount, error) {
+	counts := []types.EnsDailyCount{}
+	err := ReaderDb.Select(&counts, `
+	SELECT
+		date_trunc('day', to_timestamp(blocks.exec_timestamp) AT TIME ZONE 'UTC') AS day,
+		COUNT(*) AS count
+	FROM ens_registrations
+	INNER JOIN blocks ON
+		blocks.exec_block_number = ens_registrations.block_number AND
+		blocks.status = '1'
+	WHERE
+		ens_registrations.chain_id = $1 AND
+		blocks.exec_timestamp >= $2 AND
+		blocks.exec_timestamp < $3
+	GROUP BY day
+	ORDER BY day ASC
+	`, chainId, from.Unix(), to.Unix())
+	return counts, err
+}
+
+// BackfillEnsChainId sets the chain of the registrations and renewals that were stored before the chain was recorded. A deployment indexes
+// a single chain, so they belong to the chain it is indexing.
+func BackfillEnsChainId(chainId uint64) error {
+	tx, err := WriterDb.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	registrations, err := tx.Exec(`UPDATE ens_registrations SET chain_id = $1 WHERE chain_id IS NULL`, chainId)
+	if err != nil {
+		return fmt.Errorf("error backfilling chain of ens registrations: %w", err)
+	}
+	renewals, err := tx.Exec(`UPDATE ens_renewals SET chain_id = $1 WHERE chain_id IS NULL`, chainId)
+	if err != nil {
+		return fmt.Errorf("error backfilling chain of ens renewals: %w", err)
+	}
+	err = tx.Commit()
 	if err != nil {
-		utils.LogError(err, fmt.Errorf("error deleting ens name [%v]", name), 0)
 		return err
 	}
-	logger.Infof("Ens name remove from db: %v", name)
+	registrationCount, _ := registrations.RowsAffected()
+	renewalCount, _ := renewals.RowsAffected()
+	if registrationCount > 0 || renewalCount > 0 {
+		logger.Infof("backfilled chain %v of %v ens registrations and %v renewals", chainId, registrationCount, renewalCount)
+	}
 	return nil
 }
 
-func GetAddressForEnsName(name string) (address *common.Address, err error) {
-	addressBytes := []byte{}
-	err = ReaderDb.Get(&addressBytes, `
-	SELECT address 
+const (
+	ENS_EXPORT_FORMAT_CSV    = "csv"
+	ENS_EXPORT_FORMAT_NDJSON = "ndjson"
+)
+
+// ExportEnsRecords writes the names of the ens table matching the filter to w as csv or newline delimited json. The rows are streamed from the
+// database one by one, so an export of the whole table does not hold it in memory.
+func ExportEnsRecords(w io.Writer, format string, filter types.EnsExportFilter) (exported int, err error) {
+	exporter, err := newEnsRecordExporter(w, format)
+	if err != nil {
+		return 0, err
+	}
+	conditions := []string{"TRUE"}
+	args := []interface{}{}
+	if filter.Owner != nil {
+		args = append(args, filter.Owner.Bytes())
+		conditions = append(conditions, fmt.Sprintf("owner_address = $%d", len(args)))
+	}
+	if filter.ValidToFrom != nil {
+		args = append(args, *filter.ValidToFrom)
+		conditions = append(conditions, fmt.Sprintf("valid_to >= $%d", len(args)))
+	}
+	if filter.ValidToUntil != nil {
+		args = append(args, *filter.ValidToUntil)
+		conditions = append(conditions, fmt.Sprintf("valid_to < $%d", len(args)))
+	}
+	rows, err := ReaderDb.Queryx(fmt.Sprintf(`
+	SELECT ens_name, address, owner_address, valid_to, is_primary_name, resolver_address
 	FROM ens
-	WHERE
-		ens_name = $1 AND
-		valid_to >= now()
-	`, name)
-	if err == nil && addressBytes != nil {
-		add := common.BytesToAddress(addressBytes)
-		address = &add
+	WHERE %s
+	ORDER BY ens_name
+	`, strings.Join(conditions, " AND ")), args...)
+	if err != nil {
+		return 0, fmt.Errorf("error querying ens records: %w", err)
 	}
-	return address, err
+	defer rows.Close()
+
+	for rows.Next() {
+		row := struct {
+			Name            string     `db:"ens_name"`
+			Address         []byte     `db:"address"`
+			OwnerAddress    []byte     `db:"owner_address"`
+			ValidTo         *time.Time `db:"valid_to"`
+			IsPrimaryName   bool       `db:"is_primary_name"`
+			ResolverAddress []byte     `db:"resolver_address"`
+		}{}
+		err = rows.StructScan(&row)
+		if err != nil {
+			return exported, fmt.Errorf("error scanning ens record: %w", err)
+		}
+		err = exporter.write(&types.EnsExportRecord{
+			Name:          row.Name,
+			Address:       ensExportAddress(row.Address),
+			Owner:         ensExportAddress(row.OwnerAddress),
+			ValidTo:       row.ValidTo,
+			IsPrimaryName: row.IsPrimaryName,
+			Resolver:      ensExportAddress(row.ResolverAddress),
+		})
+		if err != nil {
+			return exported, fmt.Errorf("error writing ens record of name %v: %w", row.Name, err)
+		}
+		exported++
+	}
+	if err = rows.Err(); err != nil {
+		return exported, fmt.Errorf("error reading ens records: %w", err)
+	}
+	return exported, exporter.flush()
 }
 
-func GetEnsNameForAddress(address common.Address) (name *string, err error) {
-	err = ReaderDb.Get(&name, `
-	SELECT ens_name 
-	FROM ens
-	WHERE
-		address = $1 AND
-		is_primary_name AND
-		valid_to >= now()
-	;`, address.Bytes())
-	return name, err
+// ensExportAddress hex encodes a stored address, a missing address is exported as an empty string
+func ensExportAddress(address []byte) string {
+	if len(address) == 0 {
+		return ""
+	}
+	return common.BytesToAddress(address).Hex()
+}
+
+// ensRecordExporter writes the records of an export in the requested format
+type ensRecordExporter struct {
+	csv  *csv.Writer
+	json *json.Encoder
+}
+
+// newEnsRecordExporter creates an exporter for the format, the header of a csv export is written right away
+func newEnsRecordExporter(w io.Writer, format string) (*ensRecordExporter, error) {
+	switch format {
+	case ENS_EXPORT_FORMAT_CSV:
+		exporter := &ensRecordExporter{csv: csv.NewWriter(w)}
+		err := exporter.csv.Write([]string{"name", "address", "owner", "valid_to", "is_primary_name", "resolver"})
+		return exporter, err
+	case ENS_EXPORT_FORMAT_NDJSON:
+		return &ensRecordExporter{json: json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("unsupported ens export format %q, expected %v or %v", format, ENS_EXPORT_FORMAT_CSV, ENS_EXPORT_FORMAT_NDJSON)
+}
+
+func (e *ensRecordExporter) write(record *types.EnsExportRecord) error {
+	if e.json != nil {
+		return e.json.Encode(record)
+	}
+	validTo := ""
+	if record.ValidTo != nil {
+		validTo = record.ValidTo.UTC().Format(time.RFC3339)
+	}
+	// csv rows are buffered by the writer, they reach w once its buffer is full or the export is flushed
+	return e.csv.Write([]string{
+		record.Name,
+		record.Address,
+		record.Owner,
+		validTo,
+		strconv.FormatBool(record.IsPrimaryName),
+		record.Resolver,
+	})
+}
+
+func (e *ensRecordExporter) flush() error {
+	if e.csv == nil {
+		return nil
+	}
+	e.csv.Flush()
+	return e.csv.Error()
 }
@@ -0,0 +1,770 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"eth2-exporter/ens"
+	"eth2-exporter/types"
+	"eth2-exporter/utils"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	gcp_bigtable "cloud.google.com/go/bigtable"
+	"cloud.google.com/go/bigtable/bttest"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jmoiron/sqlx"
+	go_ens "github.com/wealdtech/go-ens/v3"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// newEnsEmulatorBigtable starts an in-memory bigtable emulator with a data table, the ens rows are written to the data table like in production
+func newEnsEmulatorBigtable(t *testing.T) *Bigtable {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	srv, err := bttest.NewServer("localhost:0")
+	if err != nil {
+		t.Fatalf("error starting bigtable emulator: %v", err)
+	}
+	t.Cleanup(srv.Close)
+	conn, err := grpc.Dial(srv.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("error connecting to bigtable emulator: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	adminClient, err := gcp_bigtable.NewAdminClient(ctx, "project", "instance", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("error creating bigtable admin client: %v", err)
+	}
+	if err := adminClient.CreateTable(ctx, "data"); err != nil {
+		t.Fatalf("error creating data table: %v", err)
+	}
+	if err := adminClient.CreateColumnFamily(ctx, "data", DEFAULT_FAMILY); err != nil {
+		t.Fatalf("error creating column family: %v", err)
+	}
+	client, err := gcp_bigtable.NewClient(ctx, "project", "instance", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("error creating bigtable client: %v", err)
+	}
+	return &Bigtable{client: client, tableData: client.Open("data"), chainId: "1"}
+}
+
+func readEnsEmulatorKeys(t *testing.T, bt *Bigtable, prefix string) []string {
+	keys := []string{}
+	err := bt.getEnsTable().ReadRows(context.Background(), gcp_bigtable.PrefixRange(prefix), func(row gcp_bigtable.Row) bool {
+		keys = append(keys, row.Key())
+		return true
+	})
+	if err != nil {
+		t.Fatalf("error reading rows with prefix %v: %v", prefix, err)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// useEnsTestDb replaces the writer and reader db for the rest of the test by the postgres database of ENS_TEST_DATABASE_URL, with the
+// migrations applied and the ens tables emptied. The getters are checked against the queries postgres actually runs, without a database
+// the test is skipped.
+func useEnsTestDb(t *testing.T) {
+	url := os.Getenv("ENS_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("ENS_TEST_DATABASE_URL is not set")
+	}
+	dbConn, err := sqlx.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("error connecting to test database: %v", err)
+	}
+	writer, reader := WriterDb, ReaderDb
+	WriterDb, ReaderDb = dbConn, dbConn
+	t.Cleanup(func() {
+		WriterDb, ReaderDb = writer, reader
+		dbConn.Close()
+	})
+	if err := ApplyEmbeddedDbSchema(-2); err != nil {
+		t.Fatalf("error applying migrations: %v", err)
+	}
+	execEnsTestDb(t, `TRUNCATE ens, ens_registrations, ens_primary_name_changes, blocks CASCADE`)
+}
+
+// execEnsTestDb runs a statement on the test database
+func execEnsTestDb(t *testing.T, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := WriterDb.Exec(query, args...); err != nil {
+		t.Fatalf("error executing %v: %v", query, err)
+	}
+}
+
+// ensTestNameHash returns the name hash of a name as it is stored in the ens table
+func ensTestNameHash(t *testing.T, name string) []byte {
+	t.Helper()
+	nameHash, err := go_ens.NameHash(name)
+	if err != nil {
+		t.Fatalf("error hashing name %v: %v", name, err)
+	}
+	return nameHash[:]
+}
+
+// TestEnsIndexingEmulator transforms blocks with real ens event logs and writes the rows to a bigtable emulator, so the row keys and
+// the reads of the index are checked against the semantics of bigtable (e.g. row deletes and cell versions) which the fake table lacks.
+// The validation of the dirty keys needs the database and is covered by the unit tests.
+func TestEnsIndexingEmulator(t *testing.T) {
+	registrar := common.HexToAddress("0x253553366Da8546fC250F225fe3d25d0C782303b")
+	baseRegistrar := common.HexToAddress("0x57f1887a8BF19b14fC0dF6Fd9B2acc9Af147eA85")
+	registry := common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+	resolver := common.HexToAddress("0x231b0Ee14048e9dCcD1d247744d114a4EB5E8E63")
+	alice := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	bob := common.HexToAddress("0x05579fadcf7cc6544f7aa018a2726c85251600c5")
+
+	utils.Config = &types.Config{}
+	utils.Config.Indexer.EnsTransformer.ValidRegistrarContracts = []string{registrar.String()}
+	utils.Config.Indexer.EnsTransformer.BaseRegistrarContract = baseRegistrar.String()
+
+	label := common.HexToHash("0xaf2caa1c2ca1d027f1ac823b529d0a67cd144264b2789fa2ea4d63a67c7103cc")
+	node, err := go_ens.NameHash("vitalik.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	registerTx := common.HexToHash("0x02")
+	transferTx := common.HexToHash("0x03")
+	blocks := []*types.Eth1Block{
+		{
+			Number: 17000000,
+			Hash:   common.HexToHash("0x01").Bytes(),
+			Transactions: []*types.Eth1Transaction{{
+				Hash: registerTx.Bytes(),
+				To:   registrar.Bytes(),
+				Logs: []*types.Eth1Log{
+					newEnsTestLog(t, registry, [][]byte{ens.NewResolverTopic, node[:]}, []string{"address"}, resolver),
+					newEnsTestLog(t, registrar, [][]byte{ens.NameRegisteredTopic, label.Bytes(), common.BytesToHash(alice.Bytes()).Bytes()}, []string{"string", "uint256", "uint256"}, "vitalik", big.NewInt(1), big.NewInt(1700000000)),
+				},
+			}},
+		},
+		{
+			Number: 17000001,
+			Hash:   common.HexToHash("0x04").Bytes(),
+			Transactions: []*types.Eth1Transaction{{
+				Hash: transferTx.Bytes(),
+				To:   baseRegistrar.Bytes(),
+				Logs: []*types.Eth1Log{
+					newEnsTestLog(t, baseRegistrar, [][]byte{ens.RegistrarTransferTopic, common.BytesToHash(alice.Bytes()).Bytes(), common.BytesToHash(bob.Bytes()).Bytes(), label.Bytes()}, nil),
+				},
+			}},
+		},
+	}
+
+	bt := newEnsEmulatorBigtable(t)
+	for _, block := range blocks {
+		bulkData, _, err := bt.TransformEnsNameRegistered(block, nil)
+		if err != nil {
+			t.Fatalf("error transforming block %v: %v", block.Number, err)
+		}
+		if err := bt.getEnsTable().WriteBulk(bulkData); err != nil {
+			t.Fatalf("error writing block %v: %v", block.Number, err)
+		}
+	}
+
+	expectedIndex := []string{
+		fmt.Sprintf("1:ENS:I:A:%x:%x", alice, registerTx),
+		fmt.Sprintf("1:ENS:I:A:%x:%x", alice, transferTx),
+		fmt.Sprintf("1:ENS:I:A:%x:%x", bob, transferTx),
+		fmt.Sprintf("1:ENS:I:H:%x:%x", node, registerTx),
+	}
+	sort.Strings(expectedIndex)
+	if got := readEnsEmulatorKeys(t, bt, "1:ENS:I:"); fmt.Sprint(got) != fmt.Sprint(expectedIndex) {
+		t.Errorf("wrong index rows\nexpected: %v\ngot:      %v", expectedIndex, got)
+	}
+	expectedDirty := []string{
+		fmt.Sprintf("1:ENS:V:A:%x", bob),
+		fmt.Sprintf("1:ENS:V:A:%x", alice),
+		"1:ENS:V:N:vitalik.eth",
+	}
+	sort.Strings(expectedDirty)
+	if got := readEnsEmulatorKeys(t, bt, "1:ENS:V:"); fmt.Sprint(got) != fmt.Sprint(expectedDirty) {
+		t.Errorf("wrong dirty rows\nexpected: %v\ngot:      %v", expectedDirty, got)
+	}
+
+	// the dirty keys are read in batches by the ens update run, a batch continues after the last key of the previous one
+	first, err := bt.readEnsKeyBatch(context.Background(), "1:ENS:V", "", 2, time.Second*10)
+	if err != nil {
+		t.Fatalf("error reading first batch: %v", err)
+	}
+	rest, err := bt.readEnsKeyBatch(context.Background(), "1:ENS:V", first[len(first)-1], 2, time.Second*10)
+	if err != nil {
+		t.Fatalf("error reading second batch: %v", err)
+	}
+	if got := append(first, rest...); fmt.Sprint(got) != fmt.Sprint(expectedDirty) {
+		t.Errorf("wrong batches\nexpected: %v\ngot:      %v", expectedDirty, got)
+	}
+	// the backlog counted for the metric counts the dirty keys only
+	if count, err := bt.countEnsKeys(context.Background(), "1:ENS:V", time.Second*10); err != nil || count != len(expectedDirty) {
+		t.Errorf("expected %v dirty keys, got %v (%v)", len(expectedDirty), count, err)
+	}
+
+	// the events are kept in one row per block until the update run writes them to postgres
+	rows, err := bt.readEnsEventRows(context.Background(), "1:ENS:E:", 10, time.Second*10)
+	if err != nil {
+		t.Fatalf("error reading event rows: %v", err)
+	}
+	events, imported := decodeConfirmedEnsEvents(rows, 17000001+ensEventConfirmations)
+	if expected := []string{"1:ENS:E:000017000000", "1:ENS:E:000017000001"}; fmt.Sprint(imported) != fmt.Sprint(expected) {
+		t.Errorf("wrong event rows\nexpected: %v\ngot:      %v", expected, imported)
+	}
+	if len(events) != 2 || len(events[0].Registrations) != 1 || len(events[1].Transfers) != 1 {
+		t.Fatalf("expected a registration and a transfer, got %+v", events)
+	}
+	if common.BytesToHash(events[0].Registrations[0].NameHash) != common.Hash(node) || common.BytesToAddress(events[1].Transfers[0].To) != bob {
+		t.Errorf("wrong events, got registration %+v and transfer %+v", events[0].Registrations[0], events[1].Transfers[0])
+	}
+
+	// the transfer superseded the registration in the owner index
+	owned, err := bt.GetEnsNamesOwnedBy(alice)
+	if err != nil {
+		t.Fatalf("error reading names of alice: %v", err)
+	}
+	if len(owned) != 0 {
+		t.Errorf("expected alice to own no names after the transfer, got %v", owned)
+	}
+	owned, err = bt.GetEnsNamesOwnedBy(bob)
+	if err != nil {
+		t.Fatalf("error reading names of bob: %v", err)
+	}
+	if len(owned) != 1 || owned[0] != common.Hash(node) {
+		t.Errorf("expected bob to own %x, got %v", node, owned)
+	}
+
+	// validated keys are deleted by the ens update run
+	mutDelete := gcp_bigtable.NewMutation()
+	mutDelete.DeleteRow()
+	if err := bt.getEnsTable().WriteBulk(&types.BulkMutations{Keys: []string{"1:ENS:V:N:vitalik.eth"}, Muts: []*gcp_bigtable.Mutation{mutDelete}}); err != nil {
+		t.Fatalf("error deleting key: %v", err)
+	}
+	for _, key := range readEnsEmulatorKeys(t, bt, "1:ENS:V:") {
+		if strings.HasPrefix(key, "1:ENS:V:N:") {
+			t.Errorf("expected the name key to be deleted, got %v", key)
+		}
+	}
+}
+
+func TestGetEnsNameForAddressTwoPrimaryNames(t *testing.T) {
+	useEnsTestDb(t)
+	// an address only has two primary names before the unique index, it is restored once the rows are gone
+	execEnsTestDb(t, `DROP INDEX idx_ens_unique_primary_address`)
+	t.Cleanup(func() {
+		execEnsTestDb(t, `TRUNCATE ens CASCADE`)
+		execEnsTestDb(t, `CREATE UNIQUE INDEX idx_ens_unique_primary_address ON ens (address) WHERE is_primary_name`)
+	})
+
+	address := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	now := time.Now().UTC()
+	insert := func(name string, validTo time.Time, lastValidatedAt interface{}) {
+		execEnsTestDb(t, `
+		INSERT INTO ens (name_hash, ens_name, address, is_primary_name, valid_to, last_validated_at)
+		VALUES ($1, $2, $3, true, $4, $5)`, ensTestNameHash(t, name), name, address.Bytes(), validTo, lastValidatedAt)
+	}
+	// the stale name is written first, without the ordering it is the first row of the scan
+	insert("stale.eth", now.AddDate(1, 0, 0), now.Add(-48*time.Hour))
+	insert("never.eth", now.AddDate(1, 0, 0), nil)
+	insert("vitalik.eth", now.AddDate(1, 0, 0), now.Add(-time.Hour))
+	// an expired name is not returned even if it was validated last
+	insert("expired.eth", now.Add(-48*time.Hour), now)
+
+	name, err := GetEnsNameForAddress(address)
+	if err != nil {
+		t.Fatalf("expected two primary names not to fail the lookup, got %v", err)
+	}
+	if name == nil || *name != "vitalik.eth" {
+		t.Errorf("expected the most recently validated name vitalik.eth, got %v", name)
+	}
+
+	execEnsTestDb(t, `UPDATE ens SET last_validated_at = $1 WHERE ens_name = 'stale.eth'`, now.Add(-time.Minute))
+	name, err = GetEnsNameForAddress(address)
+	if err != nil || name == nil || *name != "stale.eth" {
+		t.Errorf("expected the revalidated name stale.eth, got %v (%v)", name, err)
+	}
+
+	if name, err := GetEnsNameForAddress(common.HexToAddress("0x1")); err != sql.ErrNoRows || name != nil {
+		t.Errorf("expected no rows for an address without primary name, got %v (%v)", name, err)
+	}
+}
+
+// insertEnsTestName writes an active name that resolves to the address, a nil address is stored as NULL
+func insertEnsTestName(t *testing.T, name string, address []byte, primary bool, validTo time.Time) {
+	t.Helper()
+	execEnsTestDb(t, `
+	INSERT INTO ens (name_hash, ens_name, address, is_primary_name, valid_to)
+	VALUES ($1, $2, NULLIF($3::bytea, ''), $4, $5)`, ensTestNameHash(t, name), name, address, primary, validTo)
+}
+
+// insertEnsTestRegistration writes a registration of a name at the given block and time
+func insertEnsTestRegistration(t *testing.T, name string, blockNumber uint64, ts time.Time, controller common.Address) {
+	t.Helper()
+	txHash := common.BigToHash(new(big.Int).SetUint64(blockNumber))
+	execEnsTestDb(t, `
+	INSERT INTO ens_registrations (name_hash, tx_hash, block_number, ts, controller, owner)
+	VALUES ($1, $2, $3, $4, $5, $6)`, ensTestNameHash(t, name), txHash.Bytes(), blockNumber, ts, controller.Bytes(), common.HexToAddress("0x1").Bytes())
+}
+
+// ensTestNameList returns the names in the order of the query
+func ensTestNameList(names []types.EnsName) []string {
+	list := make([]string, 0, len(names))
+	for _, name := range names {
+		list = append(list, name.Name)
+	}
+	return list
+}
+
+func TestGetOldestEnsNames(t *testing.T) {
+	useEnsTestDb(t)
+	controller := common.HexToAddress("0x253553366Da8546fC250F225fe3d25d0C782303b")
+	validTo := time.Now().UTC().AddDate(5, 0, 0)
+	first := time.Date(2017, 5, 4, 0, 0, 0, 0, time.UTC)
+	renewedAt := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	insertEnsTestName(t, "old.eth", nil, false, validTo)
+	insertEnsTestRegistration(t, "old.eth", 3700000, first, controller)
+	// a re-registered name counts from its latest registration
+	insertEnsTestName(t, "reregistered.eth", nil, false, validTo)
+	insertEnsTestRegistration(t, "reregistered.eth", 3600000, first.AddDate(0, -1, 0), controller)
+	insertEnsTestRegistration(t, "reregistered.eth", 13900000, renewedAt, controller)
+	insertEnsTestName(t, "new.eth", nil, false, validTo)
+	insertEnsTestRegistration(t, "new.eth", 16400000, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), controller)
+	// a backfilled name without registration and an expired name are not ranked
+	insertEnsTestName(t, "backfilled.eth", nil, false, validTo)
+	insertEnsTestName(t, "expired.eth", nil, false, time.Now().UTC().AddDate(0, 0, -2))
+	insertEnsTestRegistration(t, "expired.eth", 3500000, first.AddDate(-1, 0, 0), controller)
+
+	names, err := GetOldestEnsNames(10)
+	if err != nil {
+		t.Fatalf("error getting oldest names: %v", err)
+	}
+	if got, expected := ensTestNameList(names), []string{"old.eth", "reregistered.eth", "new.eth"}; fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Fatalf("wrong oldest names\nexpected: %v\ngot:      %v", expected, got)
+	}
+	if names[0].RegisteredAt == nil || !names[0].RegisteredAt.Equal(first) {
+		t.Errorf("expected old.eth to be registered at %v, got %v", first, names[0].RegisteredAt)
+	}
+	if names[1].RegisteredAt == nil || !names[1].RegisteredAt.Equal(renewedAt) {
+		t.Errorf("expected reregistered.eth to count from %v, got %v", renewedAt, names[1].RegisteredAt)
+	}
+
+	names, err = GetOldestEnsNames(1)
+	if err != nil || len(names) != 1 || names[0].Name != "old.eth" {
+		t.Errorf("expected the limit to return old.eth only, got %+v (%v)", names, err)
+	}
+}
+
+func TestGetEnsNameAge(t *testing.T) {
+	useEnsTestDb(t)
+	controller := common.HexToAddress("0x253553366Da8546fC250F225fe3d25d0C782303b")
+	validTo := time.Now().UTC().AddDate(1, 0, 0)
+	registeredAt := time.Now().UTC().Add(-48 * time.Hour).Truncate(time.Second)
+
+	insertEnsTestName(t, "vitalik.eth", nil, false, validTo)
+	insertEnsTestRegistration(t, "vitalik.eth", 3700000, registeredAt.AddDate(-1, 0, 0), controller)
+	insertEnsTestRegistration(t, "vitalik.eth", 17000000, registeredAt, controller)
+	insertEnsTestName(t, "backfilled.eth", nil, false, validTo)
+
+	age, err := GetEnsNameAge(" vitalik.eth. ")
+	if err != nil {
+		t.Fatalf("error getting name age: %v", err)
+	}
+	if age < 48*time.Hour || age > 49*time.Hour {
+		t.Errorf("expected the age to count from the latest registration, got %v", age)
+	}
+	if _, err := GetEnsNameAge("backfilled.eth"); err != sql.ErrNoRows {
+		t.Errorf("expected no rows for a name without registration, got %v", err)
+	}
+	if _, err := GetEnsNameAge("unknown.eth"); err != sql.ErrNoRows {
+		t.Errorf("expected no rows for an unknown name, got %v", err)
+	}
+}
+
+func TestGetEnsOneWayPrimaryNames(t *testing.T) {
+	useEnsTestDb(t)
+	alice := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	bob := common.HexToAddress("0x983110309620D911731Ac0932219af06091b6744")
+	carol := common.HexToAddress("0x225f137127d9067788314bc7fcc1f36746a3c3B5")
+	validTo := time.Now().UTC().AddDate(1, 0, 0)
+
+	// the reverse record of bob claims a name that resolves to alice
+	insertEnsTestName(t, "spoofed.eth", alice.Bytes(), true, validTo.AddDate(0, 0, 3))
+	execEnsTestDb(t, `UPDATE ens SET primary_claimed_by = $1 WHERE ens_name = 'spoofed.eth'`, bob.Bytes())
+	// a name that no longer resolves keeps its primary flag until its next validation
+	insertEnsTestName(t, "unresolved.eth", nil, true, validTo.AddDate(0, 0, 2))
+	execEnsTestDb(t, `UPDATE ens SET primary_claimed_by = $1 WHERE ens_name = 'unresolved.eth'`, bob.Bytes())
+	// a primary stored before the reverse claim was recorded
+	insertEnsTestName(t, "unclaimed.eth", bob.Bytes(), true, validTo.AddDate(0, 0, 1))
+	// verified primaries and names that are not primary are consistent
+	insertEnsTestName(t, "verified.eth", carol.Bytes(), true, validTo)
+	execEnsTestDb(t, `UPDATE ens SET primary_claimed_by = $1 WHERE ens_name = 'verified.eth'`, carol.Bytes())
+	insertEnsTestName(t, "forward.eth", alice.Bytes(), false, validTo)
+
+	names, err := GetEnsOneWayPrimaryNames(10)
+	if err != nil {
+		t.Fatalf("error getting one way primary names: %v", err)
+	}
+	if got, expected := ensTestNameList(names), []string{"spoofed.eth", "unresolved.eth", "unclaimed.eth"}; fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Fatalf("wrong one way primary names\nexpected: %v\ngot:      %v", expected, got)
+	}
+	if common.BytesToAddress(names[0].Address) != alice || names[1].Address != nil {
+		t.Errorf("expected the stored addresses to be returned, got %x and %x", names[0].Address, names[1].Address)
+	}
+
+	names, err = GetEnsOneWayPrimaryNames(1)
+	if err != nil || len(names) != 1 || names[0].Name != "spoofed.eth" {
+		t.Errorf("expected the limit to return spoofed.eth only, got %+v (%v)", names, err)
+	}
+}
+
+func TestGetEnsIdentity(t *testing.T) {
+	useEnsTestDb(t)
+	alice := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	bob := common.HexToAddress("0x983110309620D911731Ac0932219af06091b6744")
+	carol := common.HexToAddress("0x225f137127d9067788314bc7fcc1f36746a3c3B5")
+	dave := common.HexToAddress("0x05579fadcf7cc6544f7aa018a2726c85251600c5")
+	validTo := time.Now().UTC().AddDate(1, 0, 0)
+
+	insertEnsTestName(t, "vitalik.eth", alice.Bytes(), true, validTo)
+	execEnsTestDb(t, `UPDATE ens SET avatar_url = 'https://euc.li/vitalik.eth', primary_claimed_by = $1 WHERE ens_name = 'vitalik.eth'`, alice.Bytes())
+	insertEnsTestName(t, "bob.eth", bob.Bytes(), true, validTo)
+	insertEnsTestName(t, "carol.eth", carol.Bytes(), false, validTo)
+	insertEnsTestName(t, "dave.eth", dave.Bytes(), true, time.Now().UTC().AddDate(0, 0, -2))
+	execEnsTestDb(t, `UPDATE ens SET avatar_url = 'https://euc.li/dave.eth', primary_claimed_by = $1 WHERE ens_name = 'dave.eth'`, dave.Bytes())
+
+	identity, err := GetEnsIdentity(alice)
+	if err != nil {
+		t.Fatalf("error getting identity: %v", err)
+	}
+	if identity.Name != "vitalik.eth" || identity.AvatarUrl == nil || *identity.AvatarUrl != "https://euc.li/vitalik.eth" || !identity.Verified {
+		t.Errorf("expected the verified identity of vitalik.eth with avatar, got %+v", identity)
+	}
+
+	identity, err = GetEnsIdentity(bob)
+	if err != nil || identity.Name != "bob.eth" || identity.AvatarUrl != nil || identity.Verified {
+		t.Errorf("expected the unverified identity of bob.eth without avatar, got %+v (%v)", identity, err)
+	}
+
+	// an address with only a forward name and an address with an expired primary have no identity
+	for _, address := range []common.Address{carol, dave} {
+		if identity, err := GetEnsIdentity(address); err != sql.ErrNoRows || identity != nil {
+			t.Errorf("expected no identity for %v, got %+v (%v)", address, identity, err)
+		}
+	}
+}
+
+func TestGetEnsNamesWithNoAddress(t *testing.T) {
+	useEnsTestDb(t)
+	validTo := time.Now().UTC().AddDate(1, 0, 0)
+
+	insertEnsTestName(t, "unset.eth", nil, false, validTo)
+	insertEnsTestName(t, "zero.eth", common.Address{}.Bytes(), false, validTo.AddDate(1, 0, 0))
+	insertEnsTestName(t, "resolved.eth", common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045").Bytes(), false, validTo)
+	insertEnsTestName(t, "expired.eth", nil, false, time.Now().UTC().AddDate(0, 0, -2))
+	insertEnsTestName(t, "undecodable.eth", nil, false, validTo)
+	execEnsTestDb(t, `UPDATE ens SET name_undecodable = true WHERE ens_name = 'undecodable.eth'`)
+
+	names, err := GetEnsNamesWithNoAddress(50)
+	if err != nil {
+		t.Fatalf("error getting names without address: %v", err)
+	}
+	if got, expected := ensTestNameList(names), []string{"unset.eth", "zero.eth"}; fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Fatalf("wrong names without address\nexpected: %v\ngot:      %v", expected, got)
+	}
+	if names[0].Address != nil || common.BytesToAddress(names[1].Address) != (common.Address{}) || len(names[1].Address) != common.AddressLength {
+		t.Errorf("expected no address and the zero address, got %x and %x", names[0].Address, names[1].Address)
+	}
+
+	names, err = GetEnsNamesWithNoAddress(1)
+	if err != nil || len(names) != 1 || names[0].Name != "unset.eth" {
+		t.Errorf("expected the limit to return unset.eth only, got %+v (%v)", names, err)
+	}
+}
+
+func TestGetEnsExpirationBuckets(t *testing.T) {
+	useEnsTestDb(t)
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 2, 0)
+	to := from.AddDate(0, 2, 0)
+
+	expiries := []time.Time{from.AddDate(0, 0, 1), from.AddDate(0, 0, 10), from.AddDate(0, 1, 5)}
+	for i, validTo := range expiries {
+		insertEnsTestName(t, fmt.Sprintf("name%v.eth", i), nil, false, validTo)
+	}
+	// names expiring outside the range and expired names are not counted
+	insertEnsTestName(t, "later.eth", nil, false, to.AddDate(0, 0, 1))
+	insertEnsTestName(t, "earlier.eth", nil, false, from.AddDate(0, 0, -1))
+	insertEnsTestName(t, "expired.eth", nil, false, now.AddDate(0, 0, -2))
+
+	buckets, err := GetEnsExpirationBuckets(from, to, "month")
+	if err != nil {
+		t.Fatalf("error getting monthly buckets: %v", err)
+	}
+	if expected := map[time.Time]int{from: 2, from.AddDate(0, 1, 0): 1}; fmt.Sprint(buckets) != fmt.Sprint(expected) || buckets[from] != 2 {
+		t.Errorf("wrong monthly buckets\nexpected: %v\ngot:      %v", expected, buckets)
+	}
+
+	// weeks start on monday like date_trunc
+	weekStart := func(t time.Time) time.Time {
+		day := t.Truncate(24 * time.Hour)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	}
+	expected := map[time.Time]int{}
+	for _, validTo := range expiries {
+		expected[weekStart(validTo)]++
+	}
+	buckets, err = GetEnsExpirationBuckets(from, to, "week")
+	if err != nil {
+		t.Fatalf("error getting weekly buckets: %v", err)
+	}
+	if fmt.Sprint(buckets) != fmt.Sprint(expected) {
+		t.Errorf("wrong weekly buckets\nexpected: %v\ngot:      %v", expected, buckets)
+	}
+	for week, count := range expected {
+		if buckets[week] != count {
+			t.Errorf("expected %v names expiring in the week of %v, got %v", count, week, buckets[week])
+		}
+	}
+
+	if _, err := GetEnsExpirationBuckets(from, to, "day'); DROP TABLE ens; --"); err == nil {
+		t.Errorf("expected an error for an unsupported bucket")
+	}
+}
+
+// insertEnsTestBlock writes a beacon block with the given execution block, status "1" is a proposed and "3" an orphaned block
+func insertEnsTestBlock(t *testing.T, slot, blockNumber uint64, ts time.Time, status string) {
+	t.Helper()
+	blockRoot := common.BigToHash(new(big.Int).SetUint64(slot))
+	execEnsTestDb(t, `
+	INSERT INTO blocks (
+		epoch, slot, blockroot, parentroot, stateroot, signature, eth1data_depositcount, proposerslashingscount, attesterslashingscount,
+		attestationscount, depositscount, voluntaryexitscount, proposer, status, exec_block_number, exec_timestamp)
+	VALUES ($1, $2, $3, '\x', '\x', '\x', 0, 0, 0, 0, 0, 0, 0, $4, $5, $6)`, slot/32, slot, blockRoot.Bytes(), status, blockNumber, ts.Unix())
+}
+
+func TestGetEnsRegistrationsPerDay(t *testing.T) {
+	useEnsTestDb(t)
+	controller := common.HexToAddress("0x253553366Da8546fC250F225fe3d25d0C782303b")
+	from := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 2)
+
+	register := func(name string, blockNumber uint64, chainId interface{}) {
+		// the day comes from the block, the registration time is deliberately off
+		insertEnsTestRegistration(t, name, blockNumber, from.AddDate(0, 0, -5), controller)
+		execEnsTestDb(t, `UPDATE ens_registrations SET chain_id = $1 WHERE name_hash = $2`, chainId, ensTestNameHash(t, name))
+	}
+	insertEnsTestBlock(t, 100, 17600000, from.Add(10*time.Hour), "1")
+	register("first.eth", 17600000, 1)
+	insertEnsTestBlock(t, 101, 17600001, from.Add(12*time.Hour), "1")
+	register("second.eth", 17600001, 1)
+	// registrations of another chain and legacy registrations without chain are not counted
+	insertEnsTestBlock(t, 102, 17600002, from.Add(13*time.Hour), "1")
+	register("goerli.eth", 17600002, 5)
+	insertEnsTestBlock(t, 103, 17600003, from.Add(14*time.Hour), "1")
+	register("legacy.eth", 17600003, nil)
+	insertEnsTestBlock(t, 7300, 17607200, from.AddDate(0, 0, 1).Add(time.Hour), "1")
+	register("third.eth", 17607200, 1)
+	// the orphaned block of the same height does not count the registration twice
+	insertEnsTestBlock(t, 7301, 17607201, from.AddDate(0, 0, 1).Add(2*time.Hour), "1")
+	insertEnsTestBlock(t, 7302, 17607201, from.AddDate(0, 0, 1).Add(3*time.Hour), "3")
+	register("fourth.eth", 17607201, 1)
+	// registrations outside the range and before the merge are not counted
+	insertEnsTestBlock(t, 14500, 17614400, to.Add(time.Hour), "1")
+	register("later.eth", 17614400, 1)
+	register("premerge.eth", 3700000, 1)
+
+	check := func(expected []types.EnsDailyCount) {
+		t.Helper()
+		counts, err := GetEnsRegistrationsPerDay(1, from, to)
+		if err != nil {
+			t.Fatalf("error getting registrations per day: %v", err)
+		}
+		if len(counts) != len(expected) {
+			t.Fatalf("expected %v days, got %+v", len(expected), counts)
+		}
+		for i := range expected {
+			if !counts[i].Day.Equal(expected[i].Day) || counts[i].Count != expected[i].Count {
+				t.Errorf("day %v: expected %+v, got %+v", i, expected[i], counts[i])
+			}
+		}
+	}
+	check([]types.EnsDailyCount{{Day: from, Count: 2}, {Day: from.AddDate(0, 0, 1), Count: 2}})
+
+	// the legacy registration belongs to the chain of the deployment once it is backfilled
+	if err := BackfillEnsChainId(1); err != nil {
+		t.Fatalf("error backfilling chain id: %v", err)
+	}
+	check([]types.EnsDailyCount{{Day: from, Count: 3}, {Day: from.AddDate(0, 0, 1), Count: 2}})
+}
+
+func TestGetEnsRegistrationsByController(t *testing.T) {
+	useEnsTestDb(t)
+	current := common.HexToAddress("0x253553366Da8546fC250F225fe3d25d0C782303b")
+	legacy := common.HexToAddress("0x283Af0B28c62C092C9727F1Ee09c02CA627EB7F5")
+	from := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	insertEnsTestRegistration(t, "first.eth", 17600000, from.Add(time.Hour), current)
+	insertEnsTestRegistration(t, "second.eth", 17600001, from.AddDate(0, 0, 10), current)
+	insertEnsTestRegistration(t, "third.eth", 17600002, from.AddDate(0, 0, 20), legacy)
+	// registrations outside the range are not counted
+	insertEnsTestRegistration(t, "earlier.eth", 17500000, from.Add(-time.Hour), legacy)
+	insertEnsTestRegistration(t, "later.eth", 17800000, to, current)
+	// a registration without controller is not credited to the zero address
+	execEnsTestDb(t, `
+	INSERT INTO ens_registrations (name_hash, tx_hash, block_number, ts, controller, owner)
+	VALUES ($1, $2, 17600003, $3, '\x', $4)`, ensTestNameHash(t, "created.eth"), common.HexToHash("0x1").Bytes(), from.AddDate(0, 0, 5), current.Bytes())
+
+	counts, err := GetEnsRegistrationsByController(from, to)
+	if err != nil {
+		t.Fatalf("error getting registrations: %v", err)
+	}
+	if expected := map[common.Address]int{current: 2, legacy: 1}; fmt.Sprint(counts) != fmt.Sprint(expected) {
+		t.Errorf("wrong registrations per controller\nexpected: %v\ngot:      %v", expected, counts)
+	}
+
+	counts, err = GetEnsRegistrationsByController(to.AddDate(1, 0, 0), to.AddDate(2, 0, 0))
+	if err != nil || counts == nil || len(counts) != 0 {
+		t.Errorf("expected no registrations, got %v (%v)", counts, err)
+	}
+}
+
+func TestGetEnsNamesForValidatorWithdrawalAddresses(t *testing.T) {
+	useEnsTestDb(t)
+	execEnsTestDb(t, `TRUNCATE validators`)
+	alice := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	bob := common.HexToAddress("0x983110309620D911731Ac0932219af06091b6744")
+	carol := common.HexToAddress("0x225f137127d9067788314bc7fcc1f36746a3c3B5")
+	validTo := time.Now().UTC().AddDate(1, 0, 0)
+
+	insertValidator := func(index uint64, prefix byte, address common.Address) {
+		credentials := append(append([]byte{prefix}, make([]byte, 11)...), address.Bytes()...)
+		execEnsTestDb(t, `
+		INSERT INTO validators (
+			validatorindex, pubkey, withdrawableepoch, withdrawalcredentials, balance, effectivebalance, slashed, activationeligibilityepoch,
+			activationepoch, exitepoch)
+		VALUES ($1, $2, 0, $3, 0, 0, false, 0, 0, 0)`, index, common.BigToHash(new(big.Int).SetUint64(index)).Bytes(), credentials)
+	}
+	insertValidator(1, 0x01, alice)
+	insertValidator(2, 0x02, bob)
+	// the bytes of BLS credentials are not an address even if they match one
+	insertValidator(3, 0x00, alice)
+	// a withdrawal address without primary name
+	insertValidator(4, 0x01, carol)
+	// two validators sharing a withdrawal address
+	insertValidator(5, 0x01, alice)
+	insertEnsTestName(t, "vitalik.eth", alice.Bytes(), true, validTo)
+	insertEnsTestName(t, "bob.eth", bob.Bytes(), true, validTo)
+	insertEnsTestName(t, "carol.eth", carol.Bytes(), false, validTo)
+
+	names, err := GetEnsNamesForValidatorWithdrawalAddresses([]uint64{1, 2, 3, 4, 5, 6})
+	if err != nil {
+		t.Fatalf("error getting names: %v", err)
+	}
+	if expected := map[uint64]string{1: "vitalik.eth", 2: "bob.eth", 5: "vitalik.eth"}; fmt.Sprint(names) != fmt.Sprint(expected) {
+		t.Errorf("wrong names of withdrawal addresses\nexpected: %v\ngot:      %v", expected, names)
+	}
+}
+
+func TestEnsNameLookupsByHash(t *testing.T) {
+	useEnsTestDb(t)
+	controller := common.HexToAddress("0x253553366Da8546fC250F225fe3d25d0C782303b")
+	validTo := time.Now().UTC().AddDate(1, 0, 0).Truncate(time.Second)
+	// the longest name that is stored, the full name does not fit into the index but its hash does
+	long := strings.Repeat("a", ENS_MAX_NAME_LENGTH-len(".eth")) + ".eth"
+	registrationTx := common.HexToHash("0x1234")
+
+	for _, name := range []string{"vitalik.eth", long} {
+		insertEnsTestName(t, name, nil, false, validTo)
+		insertEnsTestRegistration(t, name, 17000000, time.Now().UTC().Add(-time.Hour), controller)
+		execEnsTestDb(t, `UPDATE ens SET registration_tx = $1 WHERE name_hash = $2`, registrationTx.Bytes(), ensTestNameHash(t, name))
+	}
+
+	for _, name := range []string{"vitalik.eth", long} {
+		record, err := GetEnsRecordForName(name)
+		if err != nil || !bytes.Equal(record.NameHash, ensTestNameHash(t, name)) {
+			t.Errorf("expected the record of %.20v, got %+v (%v)", name, record, err)
+		}
+		if recordValidTo, _, err := GetEnsNameValidity(name); err != nil || !recordValidTo.Equal(validTo) {
+			t.Errorf("expected %.20v to be valid until %v, got %v (%v)", name, validTo, recordValidTo, err)
+		}
+		if age, err := GetEnsNameAge(name); err != nil || age < time.Hour {
+			t.Errorf("expected %.20v to be registered an hour ago, got %v (%v)", name, age, err)
+		}
+		if txHash, err := GetEnsRegistrationTx(name); err != nil || *txHash != registrationTx {
+			t.Errorf("expected the registration tx of %.20v, got %v (%v)", name, txHash, err)
+		}
+	}
+	if _, err := GetEnsRegistrationTx("unknown.eth"); err != sql.ErrNoRows {
+		t.Errorf("expected no rows for an unknown name, got %v", err)
+	}
+
+	if err := removeEnsNames([]string{long, "unknown.eth"}); err != nil {
+		t.Fatalf("error removing names: %v", err)
+	}
+	var remaining []string
+	if err := WriterDb.Select(&remaining, `SELECT ens_name FROM ens`); err != nil || fmt.Sprint(remaining) != "[vitalik.eth]" {
+		t.Errorf("expected only vitalik.eth to remain, got %v (%v)", remaining, err)
+	}
+}
+
+func TestGetEnsNameForAddressAsOf(t *testing.T) {
+	useEnsTestDb(t)
+	alice := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	bob := common.HexToAddress("0x983110309620D911731Ac0932219af06091b6744")
+	carol := common.HexToAddress("0x225f137127d9067788314bc7fcc1f36746a3c3B5")
+	controller := common.HexToAddress("0x253553366Da8546fC250F225fe3d25d0C782303b")
+	now := time.Now().UTC()
+	change := func(address common.Address, oldName, newName string, ts time.Time) {
+		execEnsTestDb(t, `
+		INSERT INTO ens_primary_name_changes (address, old_name, new_name, ts, notified_at)
+		VALUES ($1, $2, $3, $4, $4)`, address.Bytes(), oldName, newName, ts)
+	}
+
+	// alice used old.eth until she switched to new.eth, old.eth has expired since
+	insertEnsTestName(t, "old.eth", alice.Bytes(), false, now.AddDate(0, 0, -30))
+	insertEnsTestRegistration(t, "old.eth", 14000000, now.AddDate(-2, 0, 0), controller)
+	insertEnsTestName(t, "new.eth", alice.Bytes(), true, now.AddDate(1, 0, 0))
+	insertEnsTestRegistration(t, "new.eth", 17000000, now.AddDate(0, 0, -60), controller)
+	change(alice, "", "old.eth", now.AddDate(-2, 0, 1))
+	change(alice, "old.eth", "new.eth", now.AddDate(0, 0, -40))
+	// the name of bob was backfilled without registration, bob has no recorded changes
+	insertEnsTestName(t, "backfilled.eth", bob.Bytes(), true, now.AddDate(1, 0, 0))
+	// the name of carol was registered recently
+	insertEnsTestName(t, "carol.eth", carol.Bytes(), true, now.AddDate(1, 0, 0))
+	insertEnsTestRegistration(t, "carol.eth", 17100000, now.AddDate(0, 0, -10), controller)
+
+	tests := []struct {
+		name     string
+		address  common.Address
+		at       time.Time
+		expected string
+	}{
+		{name: "valid then but expired now", address: alice, at: now.AddDate(0, 0, -100), expected: "old.eth"},
+		{name: "after the change", address: alice, at: now.AddDate(0, 0, -20), expected: "new.eth"},
+		{name: "before the first primary name", address: alice, at: now.AddDate(-3, 0, 0)},
+		{name: "without registration", address: bob, at: now.AddDate(-1, 0, 0), expected: "backfilled.eth"},
+		{name: "registered later", address: carol, at: now.AddDate(0, 0, -20)},
+		{name: "registered before", address: carol, at: now.AddDate(0, 0, -5), expected: "carol.eth"},
+	}
+	for _, tt := range tests {
+		name, err := GetEnsNameForAddressAsOf(tt.address, tt.at)
+		if tt.expected == "" {
+			if err != sql.ErrNoRows || name != nil {
+				t.Errorf("%v: expected no name, got %v (%v)", tt.name, name, err)
+			}
+			continue
+		}
+		if err != nil || name == nil || *name != tt.expected {
+			t.Errorf("%v: expected %v, got %v (%v)", tt.name, tt.expected, name, err)
+		}
+	}
+}
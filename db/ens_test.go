@@ -0,0 +1,3068 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"eth2-exporter/ens"
+	"eth2-exporter/metrics"
+	"eth2-exporter/types"
+	"eth2-exporter/utils"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	gcp_bigtable "cloud.google.com/go/bigtable"
+	"github.com/coocood/freecache"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	eth_types "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shopspring/decimal"
+	go_ens "github.com/wealdtech/go-ens/v3"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestResolveEnsNameWithResolverRequiresResolver(t *testing.T) {
+	address, err := ResolveEnsNameWithResolver(nil, "vitalik.eth", common.Address{})
+	if err == nil {
+		t.Errorf("expected an error for the zero resolver address, got address %v", address)
+	}
+}
+
+func TestEnsConfirmedRange(t *testing.T) {
+	tests := []struct {
+		lastIndexed uint64
+		startBlock  uint64
+		head        uint64
+		depth       uint64
+		batchSize   uint64
+		from        uint64
+		to          uint64
+		ok          bool
+	}{
+		{lastIndexed: 87, head: 100, depth: 12, from: 88, to: 88, ok: true},
+		{lastIndexed: 88, head: 100, depth: 12, ok: false},
+		{lastIndexed: 50, head: 100, depth: 12, from: 51, to: 88, ok: true},
+		{lastIndexed: 50, head: 100, depth: 12, batchSize: 10, from: 51, to: 60, ok: true},
+		{lastIndexed: 50, head: 100, depth: 12, batchSize: 38, from: 51, to: 88, ok: true},
+		// without a stored cursor the catch-up starts at the start block instead of the latest confirmed block
+		{lastIndexed: 0, startBlock: 20, head: 100, depth: 12, from: 20, to: 88, ok: true},
+		{lastIndexed: 0, startBlock: 20, head: 100, depth: 12, batchSize: 10, from: 20, to: 29, ok: true},
+		{lastIndexed: 0, startBlock: 95, head: 100, depth: 12, ok: false},
+		{lastIndexed: 0, startBlock: 1, head: 5, depth: 12, ok: false},
+	}
+	for _, tt := range tests {
+		from, to, ok := ensConfirmedRange(tt.lastIndexed, tt.startBlock, tt.head, tt.depth, tt.batchSize)
+		if ok != tt.ok || from != tt.from || to != tt.to {
+			t.Errorf("wrong confirmed range for last indexed %v, start %v, head %v, depth %v, batch size %v: got %v-%v (%v)", tt.lastIndexed, tt.startBlock, tt.head, tt.depth, tt.batchSize, from, to, ok)
+		}
+	}
+}
+
+func TestEnsBackfillStart(t *testing.T) {
+	tests := []struct {
+		next  uint64
+		start uint64
+		end   uint64
+		from  uint64
+		ok    bool
+	}{
+		{next: 0, start: 100, end: 200, from: 100, ok: true},
+		{next: 150, start: 100, end: 200, from: 150, ok: true},
+		{next: 200, start: 100, end: 200, from: 200, ok: true},
+		{next: 201, start: 100, end: 200, ok: false},
+		{next: 0, start: 0, end: 0, from: 0, ok: true},
+		{next: 1, start: 0, end: 0, ok: false},
+	}
+	for _, tt := range tests {
+		from, ok := ensBackfillStart(tt.next, tt.start, tt.end)
+		if ok != tt.ok || from != tt.from {
+			t.Errorf("wrong backfill start for next %v of range %v-%v: got %v (%v)", tt.next, tt.start, tt.end, from, ok)
+		}
+	}
+}
+
+// newEnsTestLog creates a log with the given topics whose data is the abi encoding of the given arguments
+func newEnsTestLog(t *testing.T, address common.Address, topics [][]byte, argTypes []string, args ...interface{}) *types.Eth1Log {
+	arguments := abi.Arguments{}
+	for _, argType := range argTypes {
+		typ, err := abi.NewType(argType, "", nil)
+		if err != nil {
+			t.Fatalf("error creating abi type %v: %v", argType, err)
+		}
+		arguments = append(arguments, abi.Argument{Type: typ})
+	}
+	data, err := arguments.Pack(args...)
+	if err != nil {
+		t.Fatalf("error packing log data: %v", err)
+	}
+	return &types.Eth1Log{
+		Address: address.Bytes(),
+		Data:    data,
+		Topics:  topics,
+	}
+}
+
+func newEnsAddressChangedBlock(t *testing.T, number uint64) *types.Eth1Block {
+	node := common.BigToHash(new(big.Int).SetUint64(number))
+	return &types.Eth1Block{
+		Number: number,
+		Hash:   common.BigToHash(new(big.Int).SetUint64(number + 1000)).Bytes(),
+		Transactions: []*types.Eth1Transaction{
+			{
+				Hash: common.BigToHash(new(big.Int).SetUint64(number + 2000)).Bytes(),
+				To:   common.HexToAddress("0x4976fb03C32e5B8cfe2b6cCB31c09Ba78EBaBa41").Bytes(),
+				Logs: []*types.Eth1Log{
+					newEnsTestLog(t, common.HexToAddress("0x4976fb03C32e5B8cfe2b6cCB31c09Ba78EBaBa41"), [][]byte{ens.AddressChangedTopic, node.Bytes()}, []string{"uint256", "bytes"}, big.NewInt(60), common.HexToAddress("0x1").Bytes()),
+				},
+			},
+		},
+	}
+}
+
+// TestTransformEnsNameRegisteredConcurrent should be run with -race, it transforms blocks concurrently using the shared filterer
+func TestTransformEnsNameRegisteredConcurrent(t *testing.T) {
+	utils.Config = &types.Config{}
+	bt := &Bigtable{chainId: "1"}
+
+	blocks := []*types.Eth1Block{}
+	for i := uint64(1); i <= 20; i++ {
+		blocks = append(blocks, newEnsAddressChangedBlock(t, i))
+	}
+
+	wg := sync.WaitGroup{}
+	for _, block := range blocks {
+		wg.Add(1)
+		go func(block *types.Eth1Block) {
+			defer wg.Done()
+			bulkData, _, err := bt.TransformEnsNameRegistered(block, nil)
+			if err != nil {
+				t.Errorf("error transforming block %v: %v", block.Number, err)
+				return
+			}
+			expected := fmt.Sprintf("1:ENS:V:H:%x", common.BigToHash(new(big.Int).SetUint64(block.Number)))
+			if !utils.SliceContains(bulkData.Keys, expected) {
+				t.Errorf("missing key %v for block %v, got %v", expected, block.Number, bulkData.Keys)
+			}
+		}(block)
+	}
+	wg.Wait()
+}
+
+// TestTransformEnsEventRow checks that the events of a block are kept in its ENS:E row instead of being written to postgres by the transform
+func TestTransformEnsEventRow(t *testing.T) {
+	utils.Config = &types.Config{}
+	bt := &Bigtable{chainId: "1"}
+
+	block := newEnsAddressChangedBlock(t, 17000000)
+	bulkData, _, err := bt.TransformEnsNameRegistered(block, nil)
+	if err != nil {
+		t.Fatalf("error transforming block: %v", err)
+	}
+	if !utils.SliceContains(bulkData.Keys, "1:ENS:E:000017000000") {
+		t.Errorf("missing the event row of the block, got %v", bulkData.Keys)
+	}
+
+	// a block without ens events gets no event row
+	bulkData, _, err = bt.TransformEnsNameRegistered(&types.Eth1Block{Number: 17000001, Hash: common.HexToHash("0x01").Bytes()}, nil)
+	if err != nil {
+		t.Fatalf("error transforming empty block: %v", err)
+	}
+	if len(bulkData.Keys) != 0 {
+		t.Errorf("expected no rows for an empty block, got %v", bulkData.Keys)
+	}
+}
+
+func TestDecodeConfirmedEnsEvents(t *testing.T) {
+	utils.Config = &types.Config{}
+	bt := &Bigtable{chainId: "1"}
+
+	row := func(number uint64, value []byte) gcp_bigtable.Row {
+		key := ensEventsKey("1", number)
+		return gcp_bigtable.Row{DEFAULT_FAMILY: []gcp_bigtable.ReadItem{{Row: key, Column: DEFAULT_FAMILY + ":" + ENS_EVENTS_COLUMN, Value: value}}}
+	}
+	rows := []gcp_bigtable.Row{row(99, []byte("{"))}
+	for _, number := range []uint64{100, 101, 200} {
+		_, events, err := bt.transformEnsBlock(newEnsAddressChangedBlock(t, number))
+		if err != nil {
+			t.Fatalf("error transforming block %v: %v", number, err)
+		}
+		value, err := json.Marshal(events)
+		if err != nil {
+			t.Fatalf("error encoding events of block %v: %v", number, err)
+		}
+		rows = append(rows, row(number, value))
+	}
+
+	// the malformed row is dropped, the import stops at the first block that is not confirmed yet
+	events, imported := decodeConfirmedEnsEvents(rows, 101+ensEventConfirmations)
+	expected := []string{"1:ENS:E:000000000099", "1:ENS:E:000000000100", "1:ENS:E:000000000101"}
+	if fmt.Sprint(imported) != fmt.Sprint(expected) {
+		t.Errorf("wrong imported rows\nexpected: %v\ngot:      %v", expected, imported)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected the events of two blocks, got %v", len(events))
+	}
+	for i, number := range []uint64{100, 101} {
+		if events[i].BlockNumber != number || len(events[i].AddressChanges) != 1 {
+			t.Errorf("block %v: wrong events %+v", number, events[i])
+			continue
+		}
+		change := events[i].AddressChanges[0]
+		node := common.BigToHash(new(big.Int).SetUint64(number))
+		if !bytes.Equal(change.NameHash, node.Bytes()) || common.BytesToAddress(change.Address) != common.HexToAddress("0x1") || change.BlockNumber != number {
+			t.Errorf("block %v: wrong address change %+v", number, change)
+		}
+	}
+
+	// nothing is imported before the first block is confirmed
+	events, imported = decodeConfirmedEnsEvents(rows[1:], 100+ensEventConfirmations-1)
+	if len(events) != 0 || len(imported) != 0 {
+		t.Errorf("expected nothing to be imported, got %v events and rows %v", len(events), imported)
+	}
+	if ensEventsConfirmed(0, ensEventConfirmations-1) {
+		t.Errorf("expected no block to be confirmed below the confirmation depth")
+	}
+
+	// the blocks checked by the reorg handling are never confirmed
+	defer func(confirmations uint64) { ensEventConfirmations = confirmations }(ensEventConfirmations)
+	if err := SetEnsReorgDepth(5); err != nil {
+		t.Fatalf("error setting reorg depth: %v", err)
+	}
+	if ensEventsConfirmed(95, 100) || !ensEventsConfirmed(94, 100) {
+		t.Errorf("expected the blocks below 95 to be confirmed with a reorg depth of 5")
+	}
+	if err := SetEnsReorgDepth(-1); err == nil {
+		t.Errorf("expected an error for a negative reorg depth")
+	}
+}
+
+func TestUndecodableEnsName(t *testing.T) {
+	name := string([]byte{0x66, 0xff, 0xfe, 0x6f})
+	if utf8.ValidString(name) {
+		t.Fatalf("expected test name to be invalid utf-8")
+	}
+	label := common.HexToHash("0xaf2caa1c2ca1d027f1ac823b529d0a67cd144264b2789fa2ea4d63a67c7103cc")
+	expected := "[af2caa1c2ca1d027f1ac823b529d0a67cd144264b2789fa2ea4d63a67c7103cc].eth"
+	if got := undecodableEnsName(label); got != expected {
+		t.Errorf("wrong placeholder name, expected %v got %v", expected, got)
+	}
+}
+
+// deletingEnsBigtable records the keys of the rows written by the validation, which only writes the deletes of validated keys
+type deletingEnsBigtable struct {
+	*fakeEnsBigtable
+	deleted []string
+}
+
+func (f *deletingEnsBigtable) WriteBulk(mutations *types.BulkMutations) error {
+	f.deleted = append(f.deleted, mutations.Keys...)
+	return nil
+}
+
+func TestValidateEnsNameUnhashable(t *testing.T) {
+	utils.Config = &types.Config{}
+	// an invalid base node makes every name of the naming service unhashable
+	utils.Config.Indexer.EnsTransformer.NameHashRoots = map[string]string{"eth": "not a node"}
+
+	alreadyChecked := &EnsCheckedDictionary{address: make(map[common.Address]bool), name: make(map[string]bool)}
+	err := validateEnsName(context.Background(), nil, "vitalik.eth", alreadyChecked, nil, nil)
+	var transientErr *ensTransientError
+	if !errors.As(err, &transientErr) {
+		t.Fatalf("expected a transient error for an unhashable name, got %v", err)
+	}
+	// a name that was not validated must not be skipped by a later key of the run
+	if alreadyChecked.name["vitalik.eth"] || alreadyChecked.skippedNames != 0 {
+		t.Errorf("expected the unhashable name to not be marked as validated")
+	}
+
+	table := &deletingEnsBigtable{fakeEnsBigtable: newFakeEnsBigtable()}
+	bt := &Bigtable{chainId: "1", ensTable: table}
+	alreadyChecked = &EnsCheckedDictionary{address: make(map[common.Address]bool), name: make(map[string]bool)}
+	err = bt.validateEnsKeys(context.Background(), nil, []string{"1:ENS:V:N:vitalik.eth"}, alreadyChecked)
+	if err != nil {
+		t.Fatalf("expected the batch to continue, got %v", err)
+	}
+	if len(table.deleted) != 0 {
+		t.Errorf("expected the key of the unhashable name to be kept, deleted %v", table.deleted)
+	}
+}
+
+func TestValidateEnsNameTooLong(t *testing.T) {
+	utils.Config = &types.Config{}
+	name := strings.Repeat("a", 100000) + ".eth"
+
+	table := &deletingEnsBigtable{fakeEnsBigtable: newFakeEnsBigtable()}
+	bt := &Bigtable{chainId: "1", ensTable: table}
+	alreadyChecked := &EnsCheckedDictionary{address: make(map[common.Address]bool), name: make(map[string]bool)}
+	key := "1:ENS:V:N:" + name
+	err := bt.validateEnsKeys(context.Background(), nil, []string{key}, alreadyChecked)
+	if err != nil {
+		t.Fatalf("expected the batch to continue, got %v", err)
+	}
+	if len(table.deleted) != 1 || table.deleted[0] != key {
+		t.Errorf("expected the key of the long name to be dropped, deleted %v", len(table.deleted))
+	}
+
+	if err := checkEnsNameLength(strings.Repeat("a", 256) + ".eth"); err == nil {
+		t.Errorf("expected an error for a label longer than %v bytes", ENS_MAX_LABEL_LENGTH)
+	}
+	if err := checkEnsNameLength(strings.Repeat("a.", 600) + "eth"); err == nil {
+		t.Errorf("expected an error for a name longer than %v bytes", ENS_MAX_NAME_LENGTH)
+	}
+	if err := checkEnsNameLength("vitalik.eth"); err != nil {
+		t.Errorf("expected vitalik.eth to be valid, got %v", err)
+	}
+	if truncated := truncateEnsName("ab😀", 3); truncated != "ab" {
+		t.Errorf("expected the truncation to not split a character, got %q", truncated)
+	}
+}
+
+func TestOrderEnsDirtyKeys(t *testing.T) {
+	alice := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	bob := common.HexToAddress("0x05579fadcf7cc6544f7aa018a2726c85251600c5")
+	dirtyKeys := []ensDirtyKey{
+		{key: "1:ENS:V:H:01", name: "vitalik.eth"},
+		{key: "1:ENS:V:A:" + alice.Hex(), address: &alice},
+		{key: "1:ENS:V:N:foo.eth", name: "foo.eth"},
+		{key: "1:ENS:V:A:" + bob.Hex(), address: &bob},
+	}
+	phases := orderEnsDirtyKeys(dirtyKeys)
+	if len(phases) != 2 {
+		t.Fatalf("expected two phases, got %v", len(phases))
+	}
+	if len(phases[0]) != 2 || *phases[0][0].address != alice || *phases[0][1].address != bob {
+		t.Errorf("expected the address keys in the first phase, got %v", phases[0])
+	}
+	if len(phases[1]) != 2 || phases[1][0].name != "vitalik.eth" || phases[1][1].name != "foo.eth" {
+		t.Errorf("expected the name keys in the second phase, got %v", phases[1])
+	}
+}
+
+func TestIsEnsRegistrarTx(t *testing.T) {
+	registrar := common.HexToAddress("0x283Af0B28c62C092C9727F1Ee09c02CA627EB7F5")
+	registry := common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+	aggregator := common.HexToAddress("0x1111111254EEB25477B68fb85Ed929f73A960582")
+	utils.Config = &types.Config{}
+	utils.Config.Indexer.EnsTransformer.ValidRegistrarContracts = []string{registrar.String()}
+
+	direct := &types.Eth1Transaction{
+		To:   registrar.Bytes(),
+		Logs: []*types.Eth1Log{{Address: registrar.Bytes()}, {Address: registry.Bytes()}},
+	}
+	proxied := &types.Eth1Transaction{
+		To:   aggregator.Bytes(),
+		Logs: []*types.Eth1Log{{Address: registrar.Bytes()}, {Address: registry.Bytes()}},
+	}
+	unrelated := &types.Eth1Transaction{
+		To:   aggregator.Bytes(),
+		Logs: []*types.Eth1Log{{Address: aggregator.Bytes()}},
+	}
+
+	if !isEnsRegistrarTx(direct) {
+		t.Errorf("expected a direct registrar call to be matched")
+	}
+	if isEnsRegistrarTx(proxied) {
+		t.Errorf("expected a proxied registrar call not to be matched without MatchEmittingContract")
+	}
+
+	utils.Config.Indexer.EnsTransformer.MatchEmittingContract = true
+	if !isEnsRegistrarTx(proxied) {
+		t.Errorf("expected a proxied registrar call to be matched with MatchEmittingContract")
+	}
+	if isEnsRegistrarTx(unrelated) {
+		t.Errorf("expected a tx without registrar logs not to be matched")
+	}
+}
+
+// fakeEnsBigtable is an in-memory ensBigtable. The content of a mutation can not be inspected,
+// so a written row only contains a single cell whose column is the row key, like the keys written by the transformer.
+type fakeEnsBigtable struct {
+	mux  sync.Mutex
+	rows map[string]gcp_bigtable.Row
+}
+
+func newFakeEnsBigtable() *fakeEnsBigtable {
+	return &fakeEnsBigtable{rows: make(map[string]gcp_bigtable.Row)}
+}
+
+func (f *fakeEnsBigtable) ReadRows(ctx context.Context, arg gcp_bigtable.RowSet, fn func(gcp_bigtable.Row) bool, opts ...gcp_bigtable.ReadOption) error {
+	f.mux.Lock()
+	keys := make([]string, 0, len(f.rows))
+	for key := range f.rows {
+		switch rowSet := arg.(type) {
+		case gcp_bigtable.RowRange:
+			if !rowSet.Contains(key) {
+				continue
+			}
+		case gcp_bigtable.RowList:
+			if !utils.SliceContains(rowSet, key) {
+				continue
+			}
+		default:
+			f.mux.Unlock()
+			return fmt.Errorf("unsupported row set %T", arg)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	rows := make([]gcp_bigtable.Row, 0, len(keys))
+	for _, key := range keys {
+		rows = append(rows, f.rows[key])
+	}
+	f.mux.Unlock()
+
+	for _, row := range rows {
+		if !fn(row) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (f *fakeEnsBigtable) WriteBulk(mutations *types.BulkMutations) error {
+	if len(mutations.Keys) != len(mutations.Muts) {
+		return fmt.Errorf("error expected same number of keys as mutations keys: %v mutations: %v", len(mutations.Keys), len(mutations.Muts))
+	}
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	for _, key := range mutations.Keys {
+		f.rows[key] = gcp_bigtable.Row{
+			DEFAULT_FAMILY: []gcp_bigtable.ReadItem{{Row: key, Column: fmt.Sprintf("%s:%s", DEFAULT_FAMILY, key)}},
+		}
+	}
+	return nil
+}
+
+func TestTransformEnsBulkRenewals(t *testing.T) {
+	registrar := common.HexToAddress("0x283Af0B28c62C092C9727F1Ee09c02CA627EB7F5")
+	registry := common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+	resolver := common.HexToAddress("0x4976fb03C32e5B8cfe2b6cCB31c09Ba78EBaBa41")
+	owner := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+
+	utils.Config = &types.Config{}
+	utils.Config.Indexer.EnsTransformer.ValidRegistrarContracts = []string{registrar.String()}
+
+	label := common.HexToHash("0xaf2caa1c2ca1d027f1ac823b529d0a67cd144264b2789fa2ea4d63a67c7103cc")
+	node, err := go_ens.NameHash("vitalik.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	txHash := common.HexToHash("0x02")
+	logs := []*types.Eth1Log{
+		newEnsTestLog(t, registry, [][]byte{ens.NewResolverTopic, node[:]}, []string{"address"}, resolver),
+		newEnsTestLog(t, registrar, [][]byte{ens.NameRegisteredTopic, label.Bytes(), common.BytesToHash(owner.Bytes()).Bytes()}, []string{"string", "uint256", "uint256"}, "vitalik", big.NewInt(1), big.NewInt(1700000000)),
+	}
+	expected := []string{
+		fmt.Sprintf("1:ENS:I:H:%x:%x", node, txHash),
+		fmt.Sprintf("1:ENS:I:A:%x:%x", owner, txHash),
+		fmt.Sprintf("1:ENS:T:%019d:%x", MAX_INT, node),
+		fmt.Sprintf("1:ENS:V:A:%x", owner),
+		"1:ENS:V:N:vitalik.eth",
+		fmt.Sprintf("1:ENS:O:A:%x:%x", owner, node),
+	}
+	// the bulk renewal contract renews three names in the same tx
+	for _, renewed := range []string{"alice", "bob", "carol"} {
+		renewedLabel := crypto.Keccak256Hash([]byte(renewed))
+		renewedNode, err := go_ens.NameHash(renewed + ".eth")
+		if err != nil {
+			t.Fatalf("error hashing name: %v", err)
+		}
+		logs = append(logs, newEnsTestLog(t, registrar, [][]byte{ens.NameRenewedTopic, renewedLabel.Bytes()}, []string{"string", "uint256", "uint256"}, renewed, big.NewInt(int64(len(renewed))), big.NewInt(1700000000)))
+		expected = append(expected,
+			fmt.Sprintf("1:ENS:I:H:%x:%x", renewedNode, txHash),
+			fmt.Sprintf("1:ENS:V:N:%s.eth", renewed))
+	}
+	block := &types.Eth1Block{
+		Number:       17000000,
+		Hash:         common.HexToHash("0x01").Bytes(),
+		Transactions: []*types.Eth1Transaction{{Hash: txHash.Bytes(), To: registrar.Bytes(), Logs: logs}},
+	}
+	bt := &Bigtable{chainId: "1"}
+	bulkData, events, err := bt.transformEnsBlock(block)
+	if err != nil {
+		t.Fatalf("error transforming block: %v", err)
+	}
+
+	keys := append([]string{}, bulkData.Keys...)
+	sort.Strings(keys)
+	sort.Strings(expected)
+	if fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Errorf("wrong keys\nexpected: %v\ngot:      %v", expected, keys)
+	}
+	if len(events.Registrations) != 1 {
+		t.Errorf("expected one registration, got %v", len(events.Registrations))
+	}
+	if len(events.Renewals) != 3 {
+		t.Fatalf("expected three renewals, got %v", len(events.Renewals))
+	}
+	for i, renewed := range []string{"alice", "bob", "carol"} {
+		renewedNode, _ := go_ens.NameHash(renewed + ".eth")
+		if common.BytesToHash(events.Renewals[i].NameHash) != common.Hash(renewedNode) || !events.Renewals[i].CostWei.Equal(decimal.NewFromInt(int64(len(renewed)))) {
+			t.Errorf("renewal %v: wrong name hash %x or cost %v", i, events.Renewals[i].NameHash, events.Renewals[i].CostWei)
+		}
+	}
+}
+
+func TestTransformEnsBulkRegistrations(t *testing.T) {
+	registrar := common.HexToAddress("0x283Af0B28c62C092C9727F1Ee09c02CA627EB7F5")
+	registry := common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+	resolver := common.HexToAddress("0x4976fb03C32e5B8cfe2b6cCB31c09Ba78EBaBa41")
+	owner := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+
+	utils.Config = &types.Config{}
+	utils.Config.Indexer.EnsTransformer.ValidRegistrarContracts = []string{registrar.String()}
+
+	// a bulk registration tool registers four names in one tx, every registration is preceded by the NewResolver event of its name
+	txHash := common.HexToHash("0x02")
+	logs := []*types.Eth1Log{}
+	expected := []string{fmt.Sprintf("1:ENS:V:A:%x", owner), fmt.Sprintf("1:ENS:I:A:%x:%x", owner, txHash)}
+	names := []string{"alice", "bob", "carol", "dave"}
+	for _, name := range names {
+		label := crypto.Keccak256Hash([]byte(name))
+		node, err := go_ens.NameHash(name + ".eth")
+		if err != nil {
+			t.Fatalf("error hashing name: %v", err)
+		}
+		logs = append(logs,
+			newEnsTestLog(t, registry, [][]byte{ens.NewResolverTopic, node[:]}, []string{"address"}, resolver),
+			newEnsTestLog(t, registrar, [][]byte{ens.NameRegisteredTopic, label.Bytes(), common.BytesToHash(owner.Bytes()).Bytes()}, []string{"string", "uint256", "uint256"}, name, big.NewInt(1), big.NewInt(1700000000)))
+		expected = append(expected,
+			fmt.Sprintf("1:ENS:I:H:%x:%x", node, txHash),
+			fmt.Sprintf("1:ENS:T:%019d:%x", MAX_INT, node),
+			fmt.Sprintf("1:ENS:V:N:%s.eth", name),
+			fmt.Sprintf("1:ENS:O:A:%x:%x", owner, node))
+	}
+	block := &types.Eth1Block{
+		Number:       17000000,
+		Hash:         common.HexToHash("0x01").Bytes(),
+		Transactions: []*types.Eth1Transaction{{Hash: txHash.Bytes(), To: registrar.Bytes(), Logs: logs}},
+	}
+	bt := &Bigtable{chainId: "1"}
+	bulkData, events, err := bt.transformEnsBlock(block)
+	if err != nil {
+		t.Fatalf("error transforming block: %v", err)
+	}
+
+	keys := append([]string{}, bulkData.Keys...)
+	sort.Strings(keys)
+	sort.Strings(expected)
+	if fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Errorf("wrong keys\nexpected: %v\ngot:      %v", expected, keys)
+	}
+	if len(events.Registrations) != len(names) {
+		t.Errorf("expected %v registrations, got %v", len(names), len(events.Registrations))
+	}
+}
+
+func TestEnsRegistrationResolvers(t *testing.T) {
+	tests := []struct {
+		name           string
+		nameRegistered []int
+		newResolver    []int
+		expected       []int
+	}{
+		{name: "single", nameRegistered: []int{5}, newResolver: []int{2}, expected: []int{2}},
+		{name: "resolver after registration", nameRegistered: []int{2}, newResolver: []int{5}, expected: []int{5}},
+		{name: "without resolver", nameRegistered: []int{2}, expected: []int{-1}},
+		{name: "interleaved", nameRegistered: []int{1, 3, 5}, newResolver: []int{0, 2, 4}, expected: []int{0, 2, 4}},
+		{name: "missing resolver", nameRegistered: []int{1, 3, 5}, newResolver: []int{0, 4}, expected: []int{0, 4, -1}},
+	}
+	for _, tt := range tests {
+		found := newEnsTxLogs()
+		found.nameRegistered = tt.nameRegistered
+		found.newResolver = tt.newResolver
+		got := found.registrationResolvers()
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("%v: expected resolvers %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestTransformEnsNameRegisteredKeys(t *testing.T) {
+	registrar := common.HexToAddress("0x283Af0B28c62C092C9727F1Ee09c02CA627EB7F5")
+	baseRegistrar := common.HexToAddress("0x57f1887a8BF19b14fC0dF6Fd9B2acc9Af147eA85")
+	registry := common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+	resolver := common.HexToAddress("0x4976fb03C32e5B8cfe2b6cCB31c09Ba78EBaBa41")
+	reverseRegistrar := common.HexToAddress("0x084b1c3C81545d370f3634392De611CaaBFf8148")
+	owner := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	controller := common.HexToAddress("0x253553366Da8546fC250F225fe3d25d0C782303b")
+
+	utils.Config = &types.Config{}
+	utils.Config.Indexer.EnsTransformer.ValidRegistrarContracts = []string{registrar.String()}
+	utils.Config.Indexer.EnsTransformer.BaseRegistrarContract = baseRegistrar.String()
+
+	registrations := []*ensRegistration{}
+
+	name := "vitalik"
+	label := common.HexToHash("0xaf2caa1c2ca1d027f1ac823b529d0a67cd144264b2789fa2ea4d63a67c7103cc")
+	node, err := go_ens.NameHash(name + ".eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	txHash := common.HexToHash("0xe627ae94bd16eb1ed8774cd4003fc25625159f13f8a2612cc1c7f8d2ab11b1d7")
+	ownerTopic := common.BytesToHash(owner.Bytes()).Bytes()
+	controllerTopic := common.BytesToHash(controller.Bytes()).Bytes()
+
+	tests := []struct {
+		name     string
+		to       common.Address
+		logs     []*types.Eth1Log
+		expected []string
+	}{
+		{
+			name: "NameRegistered",
+			to:   registrar,
+			logs: []*types.Eth1Log{
+				newEnsTestLog(t, registry, [][]byte{ens.NewResolverTopic, node[:]}, []string{"address"}, resolver),
+				newEnsTestLog(t, registrar, [][]byte{ens.NameRegisteredTopic, label.Bytes(), ownerTopic}, []string{"string", "uint256", "uint256"}, name, big.NewInt(1), big.NewInt(1700000000)),
+			},
+			expected: []string{
+				fmt.Sprintf("1:ENS:I:H:%x:%x", node, txHash),
+				fmt.Sprintf("1:ENS:I:A:%x:%x", owner, txHash),
+				fmt.Sprintf("1:ENS:T:%019d:%x", MAX_INT, node),
+				fmt.Sprintf("1:ENS:V:A:%x", owner),
+				fmt.Sprintf("1:ENS:V:N:%s.eth", name),
+				fmt.Sprintf("1:ENS:O:A:%x:%x", owner, node),
+			},
+		},
+		{
+			name: "NameRegistered full name",
+			to:   registrar,
+			logs: []*types.Eth1Log{
+				newEnsTestLog(t, registry, [][]byte{ens.NewResolverTopic, node[:]}, []string{"address"}, resolver),
+				newEnsTestLog(t, registrar, [][]byte{ens.NameRegisteredTopic, label.Bytes(), ownerTopic}, []string{"string", "uint256", "uint256"}, "Vitalik.eth", big.NewInt(1), big.NewInt(1700000000)),
+			},
+			expected: []string{
+				fmt.Sprintf("1:ENS:I:H:%x:%x", node, txHash),
+				fmt.Sprintf("1:ENS:I:A:%x:%x", owner, txHash),
+				fmt.Sprintf("1:ENS:T:%019d:%x", MAX_INT, node),
+				fmt.Sprintf("1:ENS:V:A:%x", owner),
+				fmt.Sprintf("1:ENS:V:N:%s.eth", name),
+				fmt.Sprintf("1:ENS:O:A:%x:%x", owner, node),
+			},
+		},
+		{
+			name: "NameRenewed",
+			to:   registrar,
+			logs: []*types.Eth1Log{
+				newEnsTestLog(t, registrar, [][]byte{ens.NameRenewedTopic, label.Bytes()}, []string{"string", "uint256", "uint256"}, name, big.NewInt(1), big.NewInt(1700000000)),
+			},
+			expected: []string{
+				fmt.Sprintf("1:ENS:I:H:%x:%x", node, txHash),
+				fmt.Sprintf("1:ENS:V:N:%s.eth", name),
+			},
+		},
+		{
+			name: "NameRenewed full name",
+			to:   registrar,
+			logs: []*types.Eth1Log{
+				newEnsTestLog(t, registrar, [][]byte{ens.NameRenewedTopic, label.Bytes()}, []string{"string", "uint256", "uint256"}, name+".eth", big.NewInt(1), big.NewInt(1700000000)),
+			},
+			expected: []string{
+				fmt.Sprintf("1:ENS:I:H:%x:%x", node, txHash),
+				fmt.Sprintf("1:ENS:V:N:%s.eth", name),
+			},
+		},
+		{
+			name: "NameChanged",
+			to:   reverseRegistrar,
+			logs: []*types.Eth1Log{
+				newEnsTestLog(t, registry, [][]byte{ens.NewOwnerTopic, node[:], label.Bytes()}, []string{"address"}, owner),
+				newEnsTestLog(t, resolver, [][]byte{ens.NameChangedTopic, node[:]}, []string{"string"}, name+".eth"),
+			},
+			expected: []string{
+				fmt.Sprintf("1:ENS:I:A:%x:%x", owner, txHash),
+				fmt.Sprintf("1:ENS:V:A:%x", owner),
+				fmt.Sprintf("1:ENS:O:A:%x:%x", owner, crypto.Keccak256Hash(node[:], label.Bytes())),
+			},
+		},
+		{
+			name: "NameChanged to empty",
+			to:   reverseRegistrar,
+			logs: []*types.Eth1Log{
+				newEnsTestLog(t, registry, [][]byte{ens.NewOwnerTopic, node[:], label.Bytes()}, []string{"address"}, owner),
+				newEnsTestLog(t, resolver, [][]byte{ens.NameChangedTopic, node[:]}, []string{"string"}, ""),
+			},
+			expected: []string{
+				fmt.Sprintf("1:ENS:I:A:%x:%x", owner, txHash),
+				fmt.Sprintf("1:ENS:V:A:%x", owner),
+				fmt.Sprintf("1:ENS:O:A:%x:%x", owner, crypto.Keccak256Hash(node[:], label.Bytes())),
+			},
+		},
+		{
+			name: "AddressChanged",
+			to:   resolver,
+			logs: []*types.Eth1Log{
+				newEnsTestLog(t, resolver, [][]byte{ens.AddressChangedTopic, node[:]}, []string{"uint256", "bytes"}, big.NewInt(60), owner.Bytes()),
+			},
+			expected: []string{
+				fmt.Sprintf("1:ENS:I:H:%x:%x", node, txHash),
+				fmt.Sprintf("1:ENS:V:H:%x", node),
+			},
+		},
+		{
+			name: "ControllerAdded",
+			to:   baseRegistrar,
+			logs: []*types.Eth1Log{
+				newEnsTestLog(t, baseRegistrar, [][]byte{ens.ControllerAddedTopic, controllerTopic}, nil),
+			},
+			expected: []string{
+				fmt.Sprintf("1:ENS:C:%x", controller),
+			},
+		},
+		{
+			name: "ControllerRemoved",
+			to:   baseRegistrar,
+			logs: []*types.Eth1Log{
+				newEnsTestLog(t, baseRegistrar, [][]byte{ens.ControllerRemovedTopic, controllerTopic}, nil),
+			},
+			expected: []string{
+				fmt.Sprintf("1:ENS:C:%x", controller),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		table := newFakeEnsBigtable()
+		bt := &Bigtable{chainId: "1", ensTable: table}
+		block := &types.Eth1Block{
+			Number: 17000000,
+			Hash:   common.HexToHash("0x01").Bytes(),
+			Transactions: []*types.Eth1Transaction{
+				{
+					Hash: txHash.Bytes(),
+					To:   tt.to.Bytes(),
+					Logs: tt.logs,
+				},
+			},
+		}
+
+		bulkData, events, err := bt.transformEnsBlock(block)
+		if err != nil {
+			t.Fatalf("%v: error transforming block: %v", tt.name, err)
+		}
+		registrations = append(registrations, events.Registrations...)
+		err = bt.getEnsTable().WriteBulk(bulkData)
+		if err != nil {
+			t.Fatalf("%v: error writing mutations: %v", tt.name, err)
+		}
+
+		keys := []string{}
+		err = table.ReadRows(context.Background(), gcp_bigtable.PrefixRange("1:ENS"), func(row gcp_bigtable.Row) bool {
+			keys = append(keys, row.Key())
+			return true
+		})
+		if err != nil {
+			t.Fatalf("%v: error reading rows: %v", tt.name, err)
+		}
+
+		sort.Strings(tt.expected)
+		if fmt.Sprint(keys) != fmt.Sprint(tt.expected) {
+			t.Errorf("%v: wrong keys\nexpected: %v\ngot:      %v", tt.name, tt.expected, keys)
+		}
+	}
+
+	if len(registrations) != 2 {
+		t.Fatalf("expected two registrations, got %v", registrations)
+	}
+	for _, r := range registrations {
+		if common.BytesToHash(r.NameHash) != node {
+			t.Errorf("expected registration for node %x, got %x", node, r.NameHash)
+		}
+		if common.BytesToHash(r.TxHash) != txHash {
+			t.Errorf("expected registration tx %x, got %x", txHash, r.TxHash)
+		}
+	}
+}
+
+func TestTransformEnsNameRegisteredWithoutResolver(t *testing.T) {
+	registrar := common.HexToAddress("0x283Af0B28c62C092C9727F1Ee09c02CA627EB7F5")
+	registry := common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+	resolver := common.HexToAddress("0x4976fb03C32e5B8cfe2b6cCB31c09Ba78EBaBa41")
+	owner := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+
+	utils.Config = &types.Config{}
+	utils.Config.Indexer.EnsTransformer.ValidRegistrarContracts = []string{registrar.String()}
+
+	label := common.HexToHash("0xaf2caa1c2ca1d027f1ac823b529d0a67cd144264b2789fa2ea4d63a67c7103cc")
+	node, err := go_ens.NameHash("vitalik.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	registerTx := common.HexToHash("0x02")
+	setResolverTx := common.HexToHash("0x03")
+	block := &types.Eth1Block{
+		Number: 17000000,
+		Hash:   common.HexToHash("0x01").Bytes(),
+		Transactions: []*types.Eth1Transaction{
+			{
+				// the name is registered without resolver and the owner sets it in a separate tx
+				Hash: registerTx.Bytes(),
+				To:   registrar.Bytes(),
+				Logs: []*types.Eth1Log{
+					newEnsTestLog(t, registrar, [][]byte{ens.NameRegisteredTopic, label.Bytes(), common.BytesToHash(owner.Bytes()).Bytes()}, []string{"string", "uint256", "uint256"}, "vitalik", big.NewInt(1), big.NewInt(1700000000)),
+				},
+			},
+			{
+				Hash: setResolverTx.Bytes(),
+				To:   registry.Bytes(),
+				Logs: []*types.Eth1Log{
+					newEnsTestLog(t, registry, [][]byte{ens.NewResolverTopic, node[:]}, []string{"address"}, resolver),
+				},
+			},
+		},
+	}
+	bt := &Bigtable{chainId: "1"}
+	bulkData, events, err := bt.transformEnsBlock(block)
+	if err != nil {
+		t.Fatalf("error transforming block: %v", err)
+	}
+
+	expected := []string{
+		fmt.Sprintf("1:ENS:I:H:%x:%x", node, registerTx),
+		fmt.Sprintf("1:ENS:I:A:%x:%x", owner, registerTx),
+		fmt.Sprintf("1:ENS:T:%019d:%x", MAX_INT, node),
+		fmt.Sprintf("1:ENS:V:A:%x", owner),
+		"1:ENS:V:N:vitalik.eth",
+		fmt.Sprintf("1:ENS:O:A:%x:%x", owner, node),
+	}
+	keys := append([]string{}, bulkData.Keys...)
+	sort.Strings(keys)
+	sort.Strings(expected)
+	if fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Errorf("wrong keys\nexpected: %v\ngot:      %v", expected, keys)
+	}
+	if len(events.Registrations) != 1 || common.BytesToHash(events.Registrations[0].NameHash) != node || common.BytesToHash(events.Registrations[0].TxHash) != registerTx {
+		t.Errorf("expected a registration of %x in tx %x, got %v", node, registerTx, events.Registrations)
+	}
+}
+
+func TestEnsValidationQueue(t *testing.T) {
+	queue := NewEnsValidationQueue(10)
+	bt := &Bigtable{chainId: "1"}
+	bt.SetEnsValidationQueue(queue)
+
+	validated := make(chan []string, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.Run(ctx, 100, func(keys []string) error {
+		validated <- keys
+		return nil
+	})
+
+	bt.signalEnsValidationQueue([]string{
+		"1:ENS:I:A:d8da6bf26964af9d7eed9e03e53415d37aa96045:e627ae94bd16eb1ed8774cd4003fc25625159f13f8a2612cc1c7f8d2ab11b1d7",
+		"1:ENS:V:A:d8da6bf26964af9d7eed9e03e53415d37aa96045",
+	})
+
+	select {
+	case keys := <-validated:
+		if len(keys) != 1 || keys[0] != "1:ENS:V:A:d8da6bf26964af9d7eed9e03e53415d37aa96045" {
+			t.Errorf("expected only the dirty key to be validated, got %v", keys)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("queued key was not validated")
+	}
+}
+
+func TestEnsValidationQueueFull(t *testing.T) {
+	queue := NewEnsValidationQueue(1)
+	dropped := queue.Push([]string{"1:ENS:V:N:foo", "1:ENS:V:N:bar", "1:ENS:I:H:00:00"})
+	if dropped != 1 {
+		t.Errorf("expected 1 dropped key, got %v", dropped)
+	}
+}
+
+// BenchmarkEnsNameHash hashes the names of a renewal heavy block where every name is renewed several times
+func BenchmarkEnsNameHash(b *testing.B) {
+	utils.Config = &types.Config{}
+	names := []string{}
+	for i := 0; i < 100; i++ {
+		names = append(names, fmt.Sprintf("renewed-name-%v", i%10))
+	}
+
+	b.Run("without cache", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, name := range names {
+				if _, err := ensNameHash(name); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("with cache", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			cache := ensNameHashCache{}
+			for _, name := range names {
+				if _, err := cache.nameHash(name); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+func TestTransformEnsRegistrarTransfers(t *testing.T) {
+	baseRegistrar := common.HexToAddress("0x57f1887a8BF19b14fC0dF6Fd9B2acc9Af147eA85")
+	controller := common.HexToAddress("0x253553366Da8546fC250F225fe3d25d0C782303b")
+	alice := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	bob := common.HexToAddress("0x05579fadcf7cc6544f7aa018a2726c85251600c5")
+
+	utils.Config = &types.Config{}
+	utils.Config.Indexer.EnsTransformer.BaseRegistrarContract = baseRegistrar.String()
+
+	label := common.HexToHash("0xaf2caa1c2ca1d027f1ac823b529d0a67cd144264b2789fa2ea4d63a67c7103cc")
+	transferLog := func(from, to common.Address) *types.Eth1Log {
+		return newEnsTestLog(t, baseRegistrar, [][]byte{ens.RegistrarTransferTopic, common.BytesToHash(from.Bytes()).Bytes(), common.BytesToHash(to.Bytes()).Bytes(), label.Bytes()}, nil)
+	}
+	block := &types.Eth1Block{
+		Number: 17000000,
+		Hash:   common.HexToHash("0x01").Bytes(),
+		Transactions: []*types.Eth1Transaction{
+			{
+				// the controller mints the token to itself and hands it over to the registrant
+				Hash: common.HexToHash("0x02").Bytes(),
+				To:   controller.Bytes(),
+				Logs: []*types.Eth1Log{transferLog(common.Address{}, controller), transferLog(controller, alice)},
+			},
+			{
+				Hash: common.HexToHash("0x03").Bytes(),
+				To:   baseRegistrar.Bytes(),
+				Logs: []*types.Eth1Log{transferLog(alice, bob)},
+			},
+			{
+				Hash: common.HexToHash("0x04").Bytes(),
+				To:   baseRegistrar.Bytes(),
+				Logs: []*types.Eth1Log{transferLog(bob, common.Address{})},
+			},
+		},
+	}
+
+	bt := &Bigtable{chainId: "1"}
+	bulkData, events, err := bt.transformEnsBlock(block)
+	if err != nil {
+		t.Fatalf("error transforming block: %v", err)
+	}
+
+	// the previous and the new owner of every transfer are validated again, the zero address of mints and burns is skipped
+	dirty := map[string]bool{}
+	for _, key := range bulkData.Keys {
+		if strings.HasPrefix(key, "1:ENS:V:A:") {
+			dirty[key] = true
+		}
+	}
+	for _, owner := range []common.Address{controller, alice, bob} {
+		if !dirty[fmt.Sprintf("1:ENS:V:A:%x", owner)] {
+			t.Errorf("expected %v to be marked for validation", owner.Hex())
+		}
+	}
+	if len(dirty) != 3 {
+		t.Errorf("expected 3 addresses to be marked for validation, got %v", dirty)
+	}
+
+	node, err := go_ens.NameHash("vitalik.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	// every owner of the name gets a row in the owner index, the zero address of mints and burns is skipped
+	owned := []string{}
+	for _, key := range bulkData.Keys {
+		if strings.HasPrefix(key, "1:ENS:O:A:") {
+			owned = append(owned, key)
+		}
+	}
+	expectedOwned := []string{}
+	for _, owner := range []common.Address{controller, alice, bob} {
+		expectedOwned = append(expectedOwned, fmt.Sprintf("1:ENS:O:A:%x:%x", owner, node))
+	}
+	sort.Strings(owned)
+	sort.Strings(expectedOwned)
+	if fmt.Sprint(owned) != fmt.Sprint(expectedOwned) {
+		t.Errorf("wrong owner index keys\nexpected: %v\ngot:      %v", expectedOwned, owned)
+	}
+	expected := []struct {
+		from common.Address
+		to   common.Address
+		kind string
+	}{
+		{from: common.Address{}, to: controller, kind: ENS_TRANSFER_MINT},
+		{from: controller, to: alice, kind: ENS_TRANSFER_TRANSFER},
+		{from: alice, to: bob, kind: ENS_TRANSFER_TRANSFER},
+		{from: bob, to: common.Address{}, kind: ENS_TRANSFER_BURN},
+	}
+	if len(events.Transfers) != len(expected) {
+		t.Fatalf("expected %v transfers, got %v", len(expected), len(events.Transfers))
+	}
+	for i, transfer := range events.Transfers {
+		if common.BytesToHash(transfer.NameHash) != node {
+			t.Errorf("transfer %v: expected name hash %x, got %x", i, node, transfer.NameHash)
+		}
+		if common.BytesToAddress(transfer.From) != expected[i].from || common.BytesToAddress(transfer.To) != expected[i].to {
+			t.Errorf("transfer %v: expected %v -> %v, got %x -> %x", i, expected[i].from, expected[i].to, transfer.From, transfer.To)
+		}
+		if kind := ensTransferKind(transfer.From, transfer.To); kind != expected[i].kind {
+			t.Errorf("transfer %v: expected kind %v, got %v", i, expected[i].kind, kind)
+		}
+	}
+}
+
+func TestEnsExpiry(t *testing.T) {
+	registrarExpiry := time.Unix(1700000000, 0)
+	wrapperExpiry := uint64(1707776000)
+
+	if got := ensExpiry(registrarExpiry, 0, false); !got.Equal(registrarExpiry) {
+		t.Errorf("expected the registrar expiry for an unwrapped name, got %v", got)
+	}
+	if got := ensExpiry(registrarExpiry, wrapperExpiry, true); !got.Equal(time.Unix(int64(wrapperExpiry), 0)) {
+		t.Errorf("expected the name wrapper expiry for a wrapped name, got %v", got)
+	}
+	if got := ensExpiry(registrarExpiry, 0, true); !got.Equal(registrarExpiry) {
+		t.Errorf("expected the registrar expiry for a wrapped name without wrapper expiry, got %v", got)
+	}
+}
+
+func TestEnsRegisteredName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"vitalik.eth", "vitalik.eth"},
+		{"pay.vitalik.eth", "vitalik.eth"},
+		{"a.b.vitalik.eth", "vitalik.eth"},
+		{"eth", ""},
+		{".eth", ""},
+	}
+	for _, tt := range tests {
+		got, err := ensRegisteredName(tt.name)
+		if tt.expected == "" {
+			if err == nil || !isEnsNotFoundError(err) {
+				t.Errorf("expected a not found error for %v, got %v and %v", tt.name, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.expected {
+			t.Errorf("wrong registered name of %v, expected %v got %v (%v)", tt.name, tt.expected, got, err)
+		}
+	}
+}
+
+func TestEscapeLikePattern(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{"vitalik", "vitalik"},
+		{"100%", `100\%`},
+		{"my_name", `my\_name`},
+		{`back\slash`, `back\\slash`},
+	}
+	for _, tt := range tests {
+		if got := escapeLikePattern(tt.pattern); got != tt.expected {
+			t.Errorf("wrong escaped pattern for %q, expected %q got %q", tt.pattern, tt.expected, got)
+		}
+	}
+}
+
+func TestEnsReverseResolveWithFallback(t *testing.T) {
+	noRecord := errors.New("no resolution")
+	timeout := errors.New("context deadline exceeded")
+	tests := []struct {
+		name         string
+		classic      string
+		classicErr   error
+		fallback     string
+		fallbackErr  error
+		expected     string
+		noRecord     bool
+		transient    bool
+		fallbackUsed bool
+	}{
+		{name: "classic record", classic: "vitalik.eth", expected: "vitalik.eth"},
+		{name: "classic timeout", classicErr: timeout, transient: true},
+		{name: "ensip-19 record", classicErr: noRecord, fallback: "vitalik.eth", expected: "vitalik.eth", fallbackUsed: true},
+		{name: "no record", classicErr: noRecord, fallbackErr: fmt.Errorf("%w: no ensip-19 reverse record found", errEnsNoResolution), noRecord: true, fallbackUsed: true},
+		{name: "ensip-19 timeout", classicErr: noRecord, fallbackErr: timeout, transient: true, fallbackUsed: true},
+		{name: "ensip-19 disabled", classicErr: noRecord, noRecord: true, fallbackUsed: true},
+	}
+	for _, tt := range tests {
+		fallbackUsed := false
+		name, err := ensReverseResolveWithFallback(func() (string, error) {
+			return tt.classic, tt.classicErr
+		}, func() (string, error) {
+			fallbackUsed = true
+			return tt.fallback, tt.fallbackErr
+		})
+		if name != tt.expected || fallbackUsed != tt.fallbackUsed {
+			t.Errorf("%v: expected name %q (fallback %v), got %q (fallback %v)", tt.name, tt.expected, tt.fallbackUsed, name, fallbackUsed)
+		}
+		if (err != nil && isEnsNoReverseRecordError(err)) != tt.noRecord {
+			t.Errorf("%v: expected no reverse record %v, got %v", tt.name, tt.noRecord, err)
+		}
+		if (err != nil && !isEnsNotFoundError(err)) != tt.transient {
+			t.Errorf("%v: expected transient error %v, got %v", tt.name, tt.transient, err)
+		}
+	}
+}
+
+func TestEnsip19ReverseName(t *testing.T) {
+	address := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	tests := []struct {
+		coinType uint64
+		expected string
+	}{
+		{ENSIP19_DEFAULT_COIN_TYPE, "d8da6bf26964af9d7eed9e03e53415d37aa96045.default.reverse"},
+		{0x80000000 | 10, "d8da6bf26964af9d7eed9e03e53415d37aa96045.8000000a.reverse"},
+		{0x80000000 | 8453, "d8da6bf26964af9d7eed9e03e53415d37aa96045.80002105.reverse"},
+	}
+	for _, tt := range tests {
+		if got := ensip19ReverseName(address, tt.coinType); got != tt.expected {
+			t.Errorf("wrong reverse name for coin type %x, expected %v got %v", tt.coinType, tt.expected, got)
+		}
+	}
+
+	// the classic reverse node lives below addr.reverse and must never collide with an ENSIP-19 namespace
+	classic, err := go_ens.NameHash("d8da6bf26964af9d7eed9e03e53415d37aa96045.addr.reverse")
+	if err != nil {
+		t.Fatalf("error hashing classic reverse name: %v", err)
+	}
+	for _, tt := range tests {
+		node, err := go_ens.NameHash(tt.expected)
+		if err != nil {
+			t.Fatalf("error hashing reverse name: %v", err)
+		}
+		if node == classic {
+			t.Errorf("ensip-19 reverse node of %v equals the classic reverse node", tt.expected)
+		}
+	}
+}
+
+func TestEnsTld(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"vitalik.eth", "eth"},
+		{"sub.name.eth", "eth"},
+		{"brantly.xyz", "xyz"},
+		{"nick.Box", "box"},
+		{"example.com.", "com"},
+		{"eth", "eth"},
+	}
+	for _, tt := range tests {
+		if got := ensTld(tt.name); got != tt.expected {
+			t.Errorf("wrong tld for %v, expected %v got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestEnsPrimaryStatus(t *testing.T) {
+	tests := []struct {
+		description string
+		reverseName string
+		reverseErr  error
+		isPrimary   bool
+		known       bool
+	}{
+		{"reverse record matches", "vitalik.eth", nil, true, true},
+		{"reverse record mismatches", "other.eth", nil, false, true},
+		{"no reverse record", "", fmt.Errorf("no resolution"), false, true},
+		{"no reverse resolver", "", fmt.Errorf("no resolver"), false, true},
+		{"node error", "", fmt.Errorf("Post \"http://localhost:8545\": dial tcp: connection refused"), false, false},
+	}
+	for _, tt := range tests {
+		isPrimary, known := ensPrimaryStatus("vitalik.eth", tt.reverseName, tt.reverseErr)
+		if isPrimary != tt.isPrimary || known != tt.known {
+			t.Errorf("%v: expected primary %v (known %v), got %v (known %v)", tt.description, tt.isPrimary, tt.known, isPrimary, known)
+		}
+	}
+}
+
+func TestEnsPrimaryFlagConcurrentClaims(t *testing.T) {
+	resolved := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	other := common.HexToAddress("0x983110309620D911731Ac0932219af06091b6744")
+	reverseResolve := func() (string, error) {
+		// only the reverse record of the address the name resolves to is checked
+		return "vitalik.eth", nil
+	}
+
+	for run := 0; run < 50; run++ {
+		// the stored row is updated like the upsert of validateEnsName, an unknown flag keeps the stored one
+		var mux sync.Mutex
+		storedPrimary := false
+		var storedClaimedBy []byte
+		claims := []struct {
+			claimant  common.Address
+			isPrimary bool
+		}{{resolved, true}, {other, true}, {other, false}}
+
+		var wg sync.WaitGroup
+		for _, claim := range claims {
+			wg.Add(1)
+			go func(claimant common.Address, isPrimaryName bool) {
+				defer wg.Done()
+				isPrimary, known, claimedBy, err := ensPrimaryFlag("vitalik.eth", resolved, &isPrimaryName, &claimant, reverseResolve)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				mux.Lock()
+				defer mux.Unlock()
+				if known {
+					storedPrimary = isPrimary
+					storedClaimedBy = claimedBy
+				}
+			}(claim.claimant, claim.isPrimary)
+		}
+		wg.Wait()
+
+		if !storedPrimary || !bytes.Equal(storedClaimedBy, resolved.Bytes()) {
+			t.Fatalf("run %v: expected the name to be the primary name claimed by %v, got %v claimed by %x", run, resolved.Hex(), storedPrimary, storedClaimedBy)
+		}
+	}
+}
+
+func TestEnsPrimaryFlagRelease(t *testing.T) {
+	resolved := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	isPrimaryName := false
+	isPrimary, known, claimedBy, err := ensPrimaryFlag("vitalik.eth", resolved, &isPrimaryName, &resolved, func() (string, error) {
+		t.Fatalf("the reverse record must not be read for a release by the resolved address")
+		return "", nil
+	})
+	if err != nil || isPrimary || !known || claimedBy != nil {
+		t.Errorf("expected the release by the resolved address to be applied, got %v (known %v, claimed by %x, err %v)", isPrimary, known, claimedBy, err)
+	}
+}
+
+func TestEnsBigtableKeysExportImport(t *testing.T) {
+	source := newFakeEnsBigtable()
+	err := source.WriteBulk(&types.BulkMutations{
+		Keys: []string{
+			"1:ENS:I:H:4ae569dd0aa2f6e9207e41423c956d0d27cbc376a499ee8d90fe1d84489ae9d1:e627ae94bd16eb1ed8774cd4003fc25625159f13f8a2612cc1c7f8d2ab11b1d7",
+			"1:ENS:I:A:05579fadcf7cc6544f7aa018a2726c85251600c5:e627ae94bd16eb1ed8774cd4003fc25625159f13f8a2612cc1c7f8d2ab11b1d7",
+			"1:ENS:V:N:vitalik",
+		},
+		Muts: []*gcp_bigtable.Mutation{gcp_bigtable.NewMutation(), gcp_bigtable.NewMutation(), gcp_bigtable.NewMutation()},
+	})
+	if err != nil {
+		t.Fatalf("error writing source rows: %v", err)
+	}
+
+	export := func(table *fakeEnsBigtable, prefix string) string {
+		buf := &strings.Builder{}
+		err := (&Bigtable{chainId: "1", ensTable: table}).ExportEnsBigtableKeys(buf, prefix)
+		if err != nil {
+			t.Fatalf("error exporting %v: %v", prefix, err)
+		}
+		return buf.String()
+	}
+
+	exported := export(source, "ENS:I")
+	if lines := strings.Count(exported, "\n"); lines != 2 {
+		t.Fatalf("expected 2 exported rows for ENS:I, got %v:\n%v", lines, exported)
+	}
+
+	target := newFakeEnsBigtable()
+	err = (&Bigtable{chainId: "1", ensTable: target}).ImportEnsBigtableKeys(strings.NewReader(exported))
+	if err != nil {
+		t.Fatalf("error importing rows: %v", err)
+	}
+	if reexported := export(target, "ENS:I"); reexported != exported {
+		t.Errorf("round trip changed the export\nexpected: %v\ngot:      %v", exported, reexported)
+	}
+	if rows := export(target, "ENS:V"); rows != "" {
+		t.Errorf("expected no ENS:V rows to be imported, got %v", rows)
+	}
+}
+
+func TestEnsRpcLimiter(t *testing.T) {
+	limiter := newEnsRpcLimiter(50)
+
+	start := time.Now()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 26; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := limiter.Wait(context.Background()); err != nil {
+				t.Errorf("error waiting for the limiter: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the first call uses the initial token, the remaining 25 calls have to wait 20ms each
+	if elapsed := time.Since(start); elapsed < 450*time.Millisecond {
+		t.Errorf("expected 26 calls at 50 calls per second to take at least 500ms, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx); err == nil {
+		t.Errorf("expected a cancelled context to stop the wait")
+	}
+
+	unlimited := newEnsRpcLimiter(0)
+	for i := 0; i < 100; i++ {
+		if err := unlimited.Wait(context.Background()); err != nil {
+			t.Fatalf("expected no limit without a configured rate, got %v", err)
+		}
+	}
+}
+
+func TestReadEnsNameFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "names.txt")
+	fixture := "vitalik.eth\n Nick.eth. \n\n# known names\nbad..eth\nwith space.eth\nvitalik.eth\n"
+	err := os.WriteFile(path, []byte(fixture), 0644)
+	if err != nil {
+		t.Fatalf("error writing fixture: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("error opening fixture: %v", err)
+	}
+	defer file.Close()
+
+	names, skipped, err := readEnsNameFile(file)
+	if err != nil {
+		t.Fatalf("error reading name file: %v", err)
+	}
+	if fmt.Sprint(names) != fmt.Sprint([]string{"vitalik.eth", "nick.eth"}) {
+		t.Errorf("wrong names, got %v", names)
+	}
+	if len(skipped) != 2 || skipped[0].Line != 5 || skipped[1].Line != 6 {
+		t.Errorf("expected lines 5 and 6 to be skipped, got %+v", skipped)
+	}
+}
+
+// fakeEnsCodeCaller returns the configured code for a contract and empty code for any other address
+type fakeEnsCodeCaller struct {
+	code map[common.Address][]byte
+}
+
+func (c *fakeEnsCodeCaller) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return c.code[contract], nil
+}
+
+func (c *fakeEnsCodeCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func TestEnsContractHasCode(t *testing.T) {
+	resolver := common.HexToAddress("0x4976fb03C32e5B8cfe2b6cCB31c09Ba78EBaBa41")
+	destructed := common.HexToAddress("0x1da022710dF5002339274AaDEe8D58218e9D6AB5")
+	caller := &fakeEnsCodeCaller{code: map[common.Address][]byte{resolver: {0x60, 0x80, 0x60, 0x40}}}
+
+	hasCode, err := ensContractHasCode(caller, resolver)
+	if err != nil || !hasCode {
+		t.Errorf("expected resolver %v to have code, got %v (%v)", resolver, hasCode, err)
+	}
+	hasCode, err = ensContractHasCode(caller, destructed)
+	if err != nil || hasCode {
+		t.Errorf("expected resolver %v to be codeless, got %v (%v)", destructed, hasCode, err)
+	}
+}
+
+func TestEnsValidationLatencyDurations(t *testing.T) {
+	if _, _, _, err := (ensValidationLatency{}).durations(); err == nil {
+		t.Errorf("expected an error without validations")
+	}
+
+	latency := ensValidationLatency{
+		P50: sql.NullFloat64{Float64: 20, Valid: true},
+		P95: sql.NullFloat64{Float64: 212.5, Valid: true},
+		P99: sql.NullFloat64{Float64: 45000, Valid: true},
+	}
+	p50, p95, p99, err := latency.durations()
+	if err != nil {
+		t.Fatalf("error converting percentiles: %v", err)
+	}
+	if p50 != 20*time.Millisecond || p95 != 212500*time.Microsecond || p99 != 45*time.Second {
+		t.Errorf("wrong percentiles, expected 20ms/212.5ms/45s got %v/%v/%v", p50, p95, p99)
+	}
+}
+
+func TestRevalidateEnsAddresses(t *testing.T) {
+	utils.Config = &types.Config{}
+	addresses := [][]byte{
+		common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045").Bytes(),
+		common.HexToAddress("0x983110309620D911731Ac0932219af06091b6744").Bytes(),
+		common.HexToAddress("0x225f137127d9067788314bc7fcc1f36746a3c3B5").Bytes(),
+	}
+	failing := common.BytesToAddress(addresses[2])
+
+	var mux sync.Mutex
+	validated := map[common.Address]int{}
+	result, err := revalidateEnsAddresses(context.Background(), addresses, func(ctx context.Context, address common.Address) error {
+		mux.Lock()
+		defer mux.Unlock()
+		validated[address]++
+		if address == failing {
+			return fmt.Errorf("no reverse resolver")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("error revalidating addresses: %v", err)
+	}
+	if result.Total != 3 || result.Validated != 2 || result.Failed != 1 {
+		t.Errorf("wrong result, expected 3 total, 2 validated and 1 failed, got %+v", result)
+	}
+	for _, a := range addresses {
+		if validated[common.BytesToAddress(a)] != 1 {
+			t.Errorf("expected address %x to be validated once, got %v", a, validated[common.BytesToAddress(a)])
+		}
+	}
+
+	// the configured concurrency bounds the validations running at once
+	utils.Config.Indexer.EnsTransformer.Concurrency = 2
+	running, maxRunning := int32(0), int32(0)
+	many := make([][]byte, 20)
+	for i := range many {
+		many[i] = common.BigToAddress(big.NewInt(int64(i + 1))).Bytes()
+	}
+	_, err = revalidateEnsAddresses(context.Background(), many, func(ctx context.Context, address common.Address) error {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			seen := atomic.LoadInt32(&maxRunning)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxRunning, seen, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	if err != nil || maxRunning > 2 {
+		t.Errorf("expected at most 2 concurrent validations, got %v (%v)", maxRunning, err)
+	}
+
+	// a cancelled run stops before validating any address
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := int32(0)
+	_, err = revalidateEnsAddresses(ctx, many, func(ctx context.Context, address common.Address) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+	if err != context.Canceled || calls != 0 {
+		t.Errorf("expected the cancelled run to stop, got %v after %v validations", err, calls)
+	}
+}
+
+func TestEnsEventTypesHandled(t *testing.T) {
+	for topic, eventType := range ens.EventTypes {
+		if got := ens.GetEventType(topic.Bytes()); got != eventType {
+			t.Errorf("topic %x classified as %v, expected %v", topic, got, eventType)
+		}
+		if !newEnsTxLogs().add(eventType, 0) {
+			t.Errorf("event type %v of topic %x is not handled by the transformer", eventType, topic)
+		}
+	}
+	if newEnsTxLogs().add(ens.UnknownEvent, 0) {
+		t.Errorf("expected unknown events not to be handled")
+	}
+}
+
+func TestTransformEnsRegistrationReferrers(t *testing.T) {
+	registrar := common.HexToAddress("0x59E16fcCd424Cc24e280Be16E11Bcd56fb0CE547")
+	registry := common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+	resolver := common.HexToAddress("0x231b0Ee14048e9dCcD1d247744d114a4EB5E8E63")
+	owner := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	referrerA := common.HexToAddress("0x983110309620D911731Ac0932219af06091b6744")
+	referrerB := common.HexToAddress("0x225f137127d9067788314bc7fcc1f36746a3c3B5")
+
+	utils.Config = &types.Config{}
+	utils.Config.Indexer.EnsTransformer.ValidRegistrarContracts = []string{registrar.String()}
+
+	label := common.HexToHash("0xaf2caa1c2ca1d027f1ac823b529d0a67cd144264b2789fa2ea4d63a67c7103cc")
+	node, err := go_ens.NameHash("vitalik.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	ownerTopic := common.BytesToHash(owner.Bytes()).Bytes()
+	registrationTx := func(hash byte, registered *types.Eth1Log) *types.Eth1Transaction {
+		return &types.Eth1Transaction{
+			Hash: common.BytesToHash([]byte{hash}).Bytes(),
+			To:   registrar.Bytes(),
+			Logs: []*types.Eth1Log{
+				newEnsTestLog(t, registry, [][]byte{ens.NewResolverTopic, node[:]}, []string{"address"}, resolver),
+				registered,
+			},
+		}
+	}
+	withReferrer := func(referrer common.Address) *types.Eth1Log {
+		return newEnsTestLog(t, registrar, [][]byte{ens.NameRegisteredWithReferrerTopic, label.Bytes(), ownerTopic}, []string{"string", "uint256", "uint256", "uint256", "bytes32"},
+			"vitalik", big.NewInt(1), big.NewInt(2), big.NewInt(1700000000), common.BytesToHash(referrer.Bytes()))
+	}
+
+	bt := &Bigtable{chainId: "1", ensTable: newFakeEnsBigtable()}
+	block := &types.Eth1Block{
+		Number: 17000000,
+		Hash:   common.HexToHash("0x01").Bytes(),
+		Transactions: []*types.Eth1Transaction{
+			registrationTx(1, newEnsTestLog(t, registrar, [][]byte{ens.NameRegisteredTopic, label.Bytes(), ownerTopic}, []string{"string", "uint256", "uint256"}, "vitalik", big.NewInt(1), big.NewInt(1700000000))),
+			registrationTx(2, withReferrer(referrerA)),
+			registrationTx(3, withReferrer(referrerB)),
+			registrationTx(4, withReferrer(common.Address{})),
+		},
+	}
+	_, events, err := bt.transformEnsBlock(block)
+	if err != nil {
+		t.Fatalf("error transforming block: %v", err)
+	}
+
+	expected := [][]byte{nil, referrerA.Bytes(), referrerB.Bytes(), nil}
+	if len(events.Registrations) != len(expected) {
+		t.Fatalf("expected %v registrations, got %v", len(expected), len(events.Registrations))
+	}
+	for i, r := range events.Registrations {
+		if !bytes.Equal(r.Referrer, expected[i]) {
+			t.Errorf("registration %v: expected referrer %x, got %x", i, expected[i], r.Referrer)
+		}
+		if common.BytesToHash(r.NameHash) != node || common.BytesToAddress(r.Owner) != owner {
+			t.Errorf("registration %v: wrong name hash %x or owner %x", i, r.NameHash, r.Owner)
+		}
+		// the controller with referrers reports a base cost of 1 and a premium of 2
+		expectedCost, expectedPremium := decimal.NewFromInt(3), decimal.NewNullDecimal(decimal.NewFromInt(2))
+		if i == 0 {
+			expectedCost, expectedPremium = decimal.NewFromInt(1), decimal.NullDecimal{}
+		}
+		if !r.CostWei.Equal(expectedCost) || r.PremiumWei.Valid != expectedPremium.Valid || !r.PremiumWei.Decimal.Equal(expectedPremium.Decimal) {
+			t.Errorf("registration %v: expected cost %v and premium %v, got %v and %v", i, expectedCost, expectedPremium, r.CostWei, r.PremiumWei)
+		}
+	}
+}
+
+func TestTransformEnsLegacyRegistrations(t *testing.T) {
+	auctionRegistrar := common.HexToAddress("0x6090A6e47849629b7245Dfa1Ca21D94cd15878Ef")
+	legacyController := common.HexToAddress("0xF0AD5cAd05e10572EfcEB849f6Ff0c68f9700455")
+	legacyBaseRegistrar := common.HexToAddress("0xFaC7BEA255a6990f749363002136aF6556b31e04")
+	owner := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+
+	utils.Config = &types.Config{}
+	utils.Config.Indexer.EnsTransformer.ValidRegistrarContracts = []string{legacyController.String()}
+	utils.Config.Indexer.EnsTransformer.LegacyRegistrarContracts = []string{auctionRegistrar.String(), legacyController.String()}
+
+	auctionLabel := crypto.Keccak256Hash([]byte("auction"))
+	auctionNode, err := go_ens.NameHash("auction.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	label := common.HexToHash("0xaf2caa1c2ca1d027f1ac823b529d0a67cd144264b2789fa2ea4d63a67c7103cc")
+	node, err := go_ens.NameHash("vitalik.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	ownerTopic := common.BytesToHash(owner.Bytes()).Bytes()
+	auctionTx := common.HexToHash("0x02")
+	controllerTx := common.HexToHash("0x03")
+	block := &types.Eth1Block{
+		Number: 5000000,
+		Hash:   common.HexToHash("0x01").Bytes(),
+		Transactions: []*types.Eth1Transaction{
+			{
+				// finalizing an auction only reports the label hash and the winning bid
+				Hash: auctionTx.Bytes(),
+				To:   auctionRegistrar.Bytes(),
+				Logs: []*types.Eth1Log{
+					newEnsTestLog(t, auctionRegistrar, [][]byte{ens.LegacyHashRegisteredTopic, auctionLabel.Bytes(), ownerTopic}, []string{"uint256", "uint256"}, big.NewInt(10000000000000000), big.NewInt(1500000000)),
+				},
+			},
+			{
+				// the early controller emits the current registration event next to the one of its base registrar
+				Hash: controllerTx.Bytes(),
+				To:   legacyController.Bytes(),
+				Logs: []*types.Eth1Log{
+					newEnsTestLog(t, legacyBaseRegistrar, [][]byte{ens.LegacyNameRegisteredTopic, label.Bytes(), ownerTopic}, []string{"uint256"}, big.NewInt(1600000000)),
+					newEnsTestLog(t, legacyController, [][]byte{ens.NameRegisteredTopic, label.Bytes(), ownerTopic}, []string{"string", "uint256", "uint256"}, "vitalik", big.NewInt(1), big.NewInt(1600000000)),
+				},
+			},
+			{
+				// the current base registrar emits the legacy event too, it is ignored outside of txs to a legacy registrar
+				Hash: common.HexToHash("0x04").Bytes(),
+				To:   common.HexToAddress("0x253553366Da8546fC250F225fe3d25d0C782303b").Bytes(),
+				Logs: []*types.Eth1Log{
+					newEnsTestLog(t, legacyBaseRegistrar, [][]byte{ens.LegacyNameRegisteredTopic, label.Bytes(), ownerTopic}, []string{"uint256"}, big.NewInt(1700000000)),
+				},
+			},
+		},
+	}
+	bt := &Bigtable{chainId: "1"}
+	bulkData, events, err := bt.transformEnsBlock(block)
+	if err != nil {
+		t.Fatalf("error transforming block: %v", err)
+	}
+
+	expected := []string{
+		fmt.Sprintf("1:ENS:I:H:%x:%x", auctionNode, auctionTx),
+		fmt.Sprintf("1:ENS:I:A:%x:%x", owner, auctionTx),
+		fmt.Sprintf("1:ENS:T:%019d:%x", MAX_INT, auctionNode),
+		fmt.Sprintf("1:ENS:V:H:%x", auctionNode),
+		fmt.Sprintf("1:ENS:O:A:%x:%x", owner, auctionNode),
+		fmt.Sprintf("1:ENS:I:H:%x:%x", node, controllerTx),
+		fmt.Sprintf("1:ENS:I:A:%x:%x", owner, controllerTx),
+		fmt.Sprintf("1:ENS:T:%019d:%x", MAX_INT, node),
+		"1:ENS:V:N:vitalik.eth",
+		fmt.Sprintf("1:ENS:O:A:%x:%x", owner, node),
+		fmt.Sprintf("1:ENS:V:A:%x", owner),
+	}
+	keys := append([]string{}, bulkData.Keys...)
+	sort.Strings(keys)
+	sort.Strings(expected)
+	if fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Errorf("wrong keys\nexpected: %v\ngot:      %v", expected, keys)
+	}
+
+	if len(events.Registrations) != 2 {
+		t.Fatalf("expected two registrations, got %v", events.Registrations)
+	}
+	if common.BytesToHash(events.Registrations[0].NameHash) != auctionNode || common.BytesToAddress(events.Registrations[0].Controller) != auctionRegistrar || !events.Registrations[0].CostWei.Equal(decimal.NewFromInt(10000000000000000)) {
+		t.Errorf("wrong auction registration %+v", events.Registrations[0])
+	}
+	if common.BytesToHash(events.Registrations[1].NameHash) != node || !events.Registrations[1].CostWei.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("expected the controller registration to be indexed once, got %+v", events.Registrations[1])
+	}
+}
+
+func TestGetEnsTransactionsForAddress(t *testing.T) {
+	table := newFakeEnsBigtable()
+	bt := &Bigtable{chainId: "1", ensTable: table}
+	address := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	other := common.HexToAddress("0x983110309620D911731Ac0932219af06091b6744")
+	txHashes := []common.Hash{common.HexToHash("0x01"), common.HexToHash("0x02"), common.HexToHash("0x03")}
+
+	bulk := &types.BulkMutations{}
+	for _, txHash := range txHashes {
+		bulk.Keys = append(bulk.Keys, fmt.Sprintf("1:ENS:I:A:%x:%x", address, txHash))
+		bulk.Muts = append(bulk.Muts, gcp_bigtable.NewMutation())
+	}
+	bulk.Keys = append(bulk.Keys, fmt.Sprintf("1:ENS:I:A:%x:%x", other, txHashes[0]), fmt.Sprintf("1:ENS:V:A:%x", address))
+	bulk.Muts = append(bulk.Muts, gcp_bigtable.NewMutation(), gcp_bigtable.NewMutation())
+	if err := table.WriteBulk(bulk); err != nil {
+		t.Fatal(err)
+	}
+
+	page, token, err := bt.GetEnsTransactionsForAddress(address, 2, "")
+	if err != nil || len(page) != 2 || page[0] != txHashes[0] || page[1] != txHashes[1] || token == "" {
+		t.Fatalf("unexpected first page %v with token %q (%v)", page, token, err)
+	}
+	page, token, err = bt.GetEnsTransactionsForAddress(address, 2, token)
+	if err != nil || len(page) != 1 || page[0] != txHashes[2] || token != "" {
+		t.Fatalf("unexpected last page %v with token %q (%v)", page, token, err)
+	}
+	_, _, err = bt.GetEnsTransactionsForAddress(address, 2, fmt.Sprintf("1:ENS:I:A:%x:%x", other, txHashes[0]))
+	if err == nil {
+		t.Errorf("expected an error for the page token of another address")
+	}
+}
+
+func TestReversedEnsTimestamp(t *testing.T) {
+	earlier := reversedEnsTimestamp(time.Unix(1700000000, 0))
+	later := reversedEnsTimestamp(time.Unix(1700000001, 0))
+	if len(earlier) != 19 || len(later) != 19 {
+		t.Errorf("expected padded timestamps, got %v and %v", earlier, later)
+	}
+	if later >= earlier {
+		t.Errorf("expected later registrations to sort first, got %v for the later and %v for the earlier registration", later, earlier)
+	}
+}
+
+func TestEnsRequeueBatchSize(t *testing.T) {
+	tests := []struct {
+		total    int
+		period   time.Duration
+		interval time.Duration
+		expected int
+	}{
+		{1000, 24 * time.Hour, time.Hour, 42},
+		{24, 24 * time.Hour, time.Hour, 1},
+		{10, 24 * time.Hour, time.Hour, 1},
+		{10, time.Hour, 2 * time.Hour, 10},
+		{0, 24 * time.Hour, time.Hour, 0},
+		{10, 0, time.Hour, 0},
+	}
+	for _, tt := range tests {
+		if got := ensRequeueBatchSize(tt.total, tt.period, tt.interval); got != tt.expected {
+			t.Errorf("wrong batch size for %v names, period %v and interval %v, expected %v got %v", tt.total, tt.period, tt.interval, tt.expected, got)
+		}
+	}
+}
+
+func TestQueueEnsNameHashes(t *testing.T) {
+	table := newFakeEnsBigtable()
+	bt := &Bigtable{chainId: "1", ensTable: table}
+	nameHashes := [][]byte{
+		common.HexToHash("0xee6c4522aab0003e8d14cd40a6af439055fd2577951148c14b6cea9a53475835").Bytes(),
+		common.HexToHash("0x0d0e4dbd1b0e1bd8e6b8ad5d8a30a3c8f3a0e3f84e5dcd5e4d4c5f6b2a1b0c0d").Bytes(),
+	}
+	err := bt.queueEnsNameHashes(nameHashes)
+	if err != nil {
+		t.Fatalf("error queueing name hashes: %v", err)
+	}
+
+	keys := []string{}
+	err = table.ReadRows(context.Background(), gcp_bigtable.PrefixRange("1:ENS:V:H:"), func(row gcp_bigtable.Row) bool {
+		keys = append(keys, row.Key())
+		return true
+	})
+	if err != nil {
+		t.Fatalf("error reading rows: %v", err)
+	}
+	expected := []string{fmt.Sprintf("1:ENS:V:H:%x", nameHashes[1]), fmt.Sprintf("1:ENS:V:H:%x", nameHashes[0])}
+	if fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Errorf("wrong keys\nexpected: %v\ngot:      %v", expected, keys)
+	}
+}
+
+func TestTransformEnsMulticoinAddresses(t *testing.T) {
+	resolver := common.HexToAddress("0x4976fb03C32e5B8cfe2b6cCB31c09Ba78EBaBa41")
+	node, err := go_ens.NameHash("vitalik.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	// p2pkh script of the btc address 1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa
+	btcScript := common.FromHex("0x76a91462e907b15cbf27d5425399ebf6f0fb50ebb88f1888ac")
+
+	utils.Config = &types.Config{}
+
+	bt := &Bigtable{chainId: "1", ensTable: newFakeEnsBigtable()}
+	block := &types.Eth1Block{
+		Number: 17000000,
+		Hash:   common.HexToHash("0x01").Bytes(),
+		Transactions: []*types.Eth1Transaction{
+			{
+				Hash: common.HexToHash("0x02").Bytes(),
+				To:   resolver.Bytes(),
+				Logs: []*types.Eth1Log{
+					newEnsTestLog(t, resolver, [][]byte{ens.AddressChangedTopic, node[:]}, []string{"uint256", "bytes"}, big.NewInt(ENS_ETH_COIN_TYPE), common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045").Bytes()),
+					newEnsTestLog(t, resolver, [][]byte{ens.AddressChangedTopic, node[:]}, []string{"uint256", "bytes"}, big.NewInt(0), btcScript),
+				},
+			},
+		},
+	}
+	_, events, err := bt.transformEnsBlock(block)
+	if err != nil {
+		t.Fatalf("error transforming block: %v", err)
+	}
+
+	if len(events.MulticoinAddresses) != 1 {
+		t.Fatalf("expected only the btc address to be stored, got %v addresses", len(events.MulticoinAddresses))
+	}
+	btc := events.MulticoinAddresses[0]
+	if common.BytesToHash(btc.NameHash) != node || btc.CoinType != 0 || !bytes.Equal(btc.AddressBytes, btcScript) || btc.BlockNumber != 17000000 {
+		t.Errorf("wrong btc record, got name hash %x coin type %v address %x block %v", btc.NameHash, btc.CoinType, btc.AddressBytes, btc.BlockNumber)
+	}
+
+	// only the eth address starts a window in the ens history
+	if len(events.AddressChanges) != 1 {
+		t.Fatalf("expected only the eth address change in the history, got %v changes", len(events.AddressChanges))
+	}
+	eth := events.AddressChanges[0]
+	if common.BytesToHash(eth.NameHash) != node || common.BytesToAddress(eth.Address) != common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045") || eth.BlockNumber != 17000000 {
+		t.Errorf("wrong history record, got name hash %x address %x block %v", eth.NameHash, eth.Address, eth.BlockNumber)
+	}
+}
+
+func TestEnsMulticoinAddressCleared(t *testing.T) {
+	resolver := common.HexToAddress("0x4976fb03C32e5B8cfe2b6cCB31c09Ba78EBaBa41")
+	node, err := go_ens.NameHash("vitalik.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	btcScript := common.FromHex("0x76a91462e907b15cbf27d5425399ebf6f0fb50ebb88f1888ac")
+
+	utils.Config = &types.Config{}
+
+	bt := &Bigtable{chainId: "1", ensTable: newFakeEnsBigtable()}
+	addresses := []*ensMulticoinAddress{}
+	for i, address := range [][]byte{btcScript, {}} {
+		block := &types.Eth1Block{
+			Number: 17000000 + uint64(i),
+			Hash:   common.BigToHash(big.NewInt(int64(i + 1))).Bytes(),
+			Transactions: []*types.Eth1Transaction{{
+				Hash: common.BigToHash(big.NewInt(int64(i + 10))).Bytes(),
+				To:   resolver.Bytes(),
+				Logs: []*types.Eth1Log{
+					newEnsTestLog(t, resolver, [][]byte{ens.AddressChangedTopic, node[:]}, []string{"uint256", "bytes"}, big.NewInt(0), address),
+				},
+			}},
+		}
+		_, events, err := bt.transformEnsBlock(block)
+		if err != nil {
+			t.Fatalf("error transforming block %v: %v", block.Number, err)
+		}
+		addresses = append(addresses, addresses...)
+	}
+
+	// the clearing event is passed on with an empty address, which makes the writer delete the row set by the first block
+	if len(addresses) != 2 {
+		t.Fatalf("expected the set and the cleared btc address, got %v addresses", len(addresses))
+	}
+	if !bytes.Equal(addresses[0].AddressBytes, btcScript) || len(addresses[1].AddressBytes) != 0 || addresses[1].BlockNumber != 17000001 {
+		t.Errorf("wrong btc records, got %x at %v and %x at %v", addresses[0].AddressBytes, addresses[0].BlockNumber, addresses[1].AddressBytes, addresses[1].BlockNumber)
+	}
+
+	// the validation deletes the btc row once the resolver returns no address for it
+	updated, cleared := splitClearedEnsCoinAddresses(resolveEnsCoinAddresses([]uint64{0, 2}, func(coinType uint64) ([]byte, error) {
+		if coinType == 0 {
+			return []byte{}, nil
+		}
+		return btcScript, nil
+	}))
+	if len(cleared) != 1 || cleared[0] != 0 {
+		t.Errorf("expected the btc address to be cleared, got %v", cleared)
+	}
+	if _, ok := updated[0]; ok || len(updated) != 1 {
+		t.Errorf("expected only coin type 2 to be updated, got %v", updated)
+	}
+}
+
+func TestResolveEnsCoinAddresses(t *testing.T) {
+	btcScript := common.FromHex("0x76a91462e907b15cbf27d5425399ebf6f0fb50ebb88f1888ac")
+	resolved := []uint64{}
+	addresses := resolveEnsCoinAddresses([]uint64{0, ENS_ETH_COIN_TYPE, 2, 0}, func(coinType uint64) ([]byte, error) {
+		resolved = append(resolved, coinType)
+		if coinType == 2 {
+			return nil, fmt.Errorf("unsupported coin type")
+		}
+		return btcScript, nil
+	})
+
+	if len(resolved) != 2 || resolved[0] != 0 || resolved[1] != 2 {
+		t.Errorf("expected coin types 0 and 2 to be resolved once, got %v", resolved)
+	}
+	if len(addresses) != 1 || !bytes.Equal(addresses[0], btcScript) {
+		t.Errorf("expected only the btc address, got %v", addresses)
+	}
+}
+
+func TestEnsRemovedNamesFlushedOnce(t *testing.T) {
+	calls := [][]string{}
+	ensNameRemover = func(names []string) error {
+		calls = append(calls, names)
+		return nil
+	}
+	defer func() { ensNameRemover = removeEnsNames }()
+
+	alreadyChecked := EnsCheckedDictionary{
+		address: make(map[common.Address]bool),
+		name:    make(map[string]bool),
+	}
+	g := new(errgroup.Group)
+	for i := 0; i < 25; i++ {
+		name := fmt.Sprintf("unresolvable%d.eth", i)
+		g.Go(func() error {
+			alreadyChecked.removeName(name)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatalf("error collecting names: %v", err)
+	}
+
+	if err := alreadyChecked.flushRemovedNames(); err != nil {
+		t.Fatalf("error flushing removed names: %v", err)
+	}
+	if err := alreadyChecked.flushRemovedNames(); err != nil {
+		t.Fatalf("error flushing removed names: %v", err)
+	}
+	if len(calls) != 1 {
+		t.Fatalf("expected a single delete statement, got %v", len(calls))
+	}
+	if len(calls[0]) != 25 {
+		t.Errorf("expected 25 names to be deleted, got %v", len(calls[0]))
+	}
+}
+
+func TestEnsValidationAuditsFlushedPerBatch(t *testing.T) {
+	var mux sync.Mutex
+	writes := [][]ensValidationAudit{}
+	ensValidationAuditWriter = func(audits []ensValidationAudit) error {
+		mux.Lock()
+		defer mux.Unlock()
+		writes = append(writes, audits)
+		return nil
+	}
+	defer func() { ensValidationAuditWriter = saveEnsValidationAudits }()
+
+	alreadyChecked := EnsCheckedDictionary{
+		address: make(map[common.Address]bool),
+		name:    make(map[string]bool),
+	}
+	g := new(errgroup.Group)
+	for i := 0; i < 25; i++ {
+		name := fmt.Sprintf("name%d.eth", i)
+		g.Go(func() error {
+			alreadyChecked.recordValidation(name, ENS_VALIDATION_RESOLVED, time.Millisecond)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatalf("error recording validations: %v", err)
+	}
+	if len(writes) != 0 {
+		t.Fatalf("expected the validations to be written with the batch, got %v writes", len(writes))
+	}
+	alreadyChecked.flushValidationAudits()
+	alreadyChecked.flushValidationAudits()
+	if len(writes) != 1 || len(writes[0]) != 25 {
+		t.Fatalf("expected a single write of 25 validations, got %v", writes)
+	}
+
+	// a long run writes the validations once a full audit batch was collected
+	writes = nil
+	for i := 0; i < ENS_VALIDATION_AUDIT_BATCH_SIZE+1; i++ {
+		alreadyChecked.recordValidation(fmt.Sprintf("name%d.eth", i), ENS_VALIDATION_REMOVED, time.Millisecond)
+	}
+	if len(writes) != 1 || len(writes[0]) != ENS_VALIDATION_AUDIT_BATCH_SIZE {
+		t.Fatalf("expected a full audit batch to be written, got %v writes", len(writes))
+	}
+	alreadyChecked.flushValidationAudits()
+	if len(writes) != 2 || len(writes[1]) != 1 {
+		t.Errorf("expected the remaining validation to be written by the flush, got %v writes", len(writes))
+	}
+}
+
+func TestAggregateEnsValidationStats(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcomes []ensValidationOutcomeStats
+		expected types.EnsValidationStats
+	}{
+		{
+			name:     "no validations",
+			expected: types.EnsValidationStats{},
+		},
+		{
+			name: "all outcomes",
+			outcomes: []ensValidationOutcomeStats{
+				{Outcome: ENS_VALIDATION_RESOLVED, Count: 6, TotalDurationMs: 600},
+				{Outcome: ENS_VALIDATION_REMOVED, Count: 2, TotalDurationMs: 100},
+				{Outcome: ENS_VALIDATION_RETRIED, Count: 2, TotalDurationMs: 2300},
+			},
+			expected: types.EnsValidationStats{Validated: 8, Resolved: 6, Removed: 2, Retried: 2, AvgLatencyMs: 300},
+		},
+		{
+			name: "only retries",
+			outcomes: []ensValidationOutcomeStats{
+				{Outcome: ENS_VALIDATION_RETRIED, Count: 4, TotalDurationMs: 10},
+			},
+			expected: types.EnsValidationStats{Retried: 4, AvgLatencyMs: 2.5},
+		},
+		{
+			name: "unknown outcomes are validated",
+			outcomes: []ensValidationOutcomeStats{
+				{Outcome: "unknown", Count: 1, TotalDurationMs: 5},
+			},
+			expected: types.EnsValidationStats{Validated: 1, AvgLatencyMs: 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := aggregateEnsValidationStats(tt.outcomes)
+			if *stats != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, *stats)
+			}
+		})
+	}
+}
+
+func TestTransformEnsTextChanged(t *testing.T) {
+	registrar := common.HexToAddress("0x253553366Da8546fC250F225fe3d25d0C782303b")
+	resolver := common.HexToAddress("0x231b0Ee14048e9dCcD1d247744d114a4EB5E8E63")
+	node, err := go_ens.NameHash("vitalik.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	txHash := common.HexToHash("0x02")
+
+	utils.Config = &types.Config{}
+	utils.Config.Indexer.EnsTransformer.ValidRegistrarContracts = []string{registrar.String()}
+
+	textChanged := func(key, value string) *types.Eth1Log {
+		return newEnsTestLog(t, resolver, [][]byte{ens.TextChangedTopic, node[:], crypto.Keccak256([]byte(key))}, []string{"string", "string"}, key, value)
+	}
+	table := newFakeEnsBigtable()
+	bt := &Bigtable{chainId: "1", ensTable: table}
+	block := &types.Eth1Block{
+		Number: 17000000,
+		Hash:   common.HexToHash("0x01").Bytes(),
+		Transactions: []*types.Eth1Transaction{
+			{
+				Hash: txHash.Bytes(),
+				// text records set within the registration are indexed as well
+				To: registrar.Bytes(),
+				Logs: []*types.Eth1Log{
+					textChanged("com.twitter", "VitalikButerin"),
+					textChanged("url", ""),
+					textChanged("eip155:1/erc721", "a key with colons"),
+					textChanged(strings.Repeat("x", ENS_MAX_TEXT_KEY_LENGTH+1), "too long"),
+				},
+			},
+		},
+	}
+	bulkData, _, err := bt.transformEnsBlock(block)
+	if err != nil {
+		t.Fatalf("error transforming block: %v", err)
+	}
+
+	expected := []string{
+		fmt.Sprintf("1:ENS:I:H:%x:%x", node, txHash),
+		fmt.Sprintf("1:ENS:V:T:%x:com.twitter", node),
+		fmt.Sprintf("1:ENS:V:T:%x:eip155:1/erc721", node),
+		fmt.Sprintf("1:ENS:V:T:%x:url", node),
+	}
+	keys := append([]string{}, bulkData.Keys...)
+	sort.Strings(keys)
+	sort.Strings(expected)
+	if fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Errorf("wrong keys\nexpected: %v\ngot:      %v", expected, keys)
+	}
+}
+
+func TestTransformEnsContractReverseNames(t *testing.T) {
+	resolver := common.HexToAddress("0x231b0Ee14048e9dCcD1d247744d114a4EB5E8E63")
+	reverseRegistrar := common.HexToAddress("0xa58E81fe9b61B5c3fE2AFD33CF304c454AbFc7Cb")
+	signer := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	multisig := common.HexToAddress("0x849D52316331967b6fF1198e5E32A0eB168D039d")
+	contract := common.HexToAddress("0x05579fadcf7cc6544f7aa018a2726c85251600c5")
+	multisigTx := common.HexToHash("0x02")
+	claimTx := common.HexToHash("0x03")
+
+	utils.Config = &types.Config{}
+
+	multisigNode := ens.ReverseNode(multisig)
+	contractNode := ens.ReverseNode(contract)
+	block := &types.Eth1Block{
+		Number: 17000000,
+		Hash:   common.HexToHash("0x01").Bytes(),
+		Transactions: []*types.Eth1Transaction{
+			{
+				// the multisig sets the name on the resolver of its existing reverse node
+				Hash: multisigTx.Bytes(),
+				From: signer.Bytes(),
+				To:   multisig.Bytes(),
+				Logs: []*types.Eth1Log{
+					newEnsTestLog(t, resolver, [][]byte{ens.NameChangedTopic, multisigNode[:]}, []string{"string"}, "safe.eth"),
+				},
+			},
+			{
+				// the reverse registrar claims the reverse node of the contract
+				Hash: claimTx.Bytes(),
+				From: signer.Bytes(),
+				To:   reverseRegistrar.Bytes(),
+				Logs: []*types.Eth1Log{
+					newEnsTestLog(t, reverseRegistrar, [][]byte{ens.ReverseClaimedTopic, common.BytesToHash(contract.Bytes()).Bytes(), contractNode[:]}, nil),
+					newEnsTestLog(t, resolver, [][]byte{ens.NameChangedTopic, contractNode[:]}, []string{"string"}, "contract.eth"),
+				},
+			},
+			{
+				// a claim of a reverse node that does not belong to the claimed address is ignored
+				Hash: common.HexToHash("0x04").Bytes(),
+				From: signer.Bytes(),
+				To:   reverseRegistrar.Bytes(),
+				Logs: []*types.Eth1Log{
+					newEnsTestLog(t, reverseRegistrar, [][]byte{ens.ReverseClaimedTopic, common.BytesToHash(signer.Bytes()).Bytes(), contractNode[:]}, nil),
+				},
+			},
+		},
+	}
+	bt := &Bigtable{chainId: "1", ensTable: newFakeEnsBigtable()}
+	bulkData, _, err := bt.transformEnsBlock(block)
+	if err != nil {
+		t.Fatalf("error transforming block: %v", err)
+	}
+
+	expected := []string{
+		fmt.Sprintf("1:ENS:I:A:%x:%x", multisig, multisigTx),
+		fmt.Sprintf("1:ENS:V:A:%x", multisig),
+		fmt.Sprintf("1:ENS:I:A:%x:%x", contract, claimTx),
+		fmt.Sprintf("1:ENS:V:A:%x", contract),
+	}
+	keys := append([]string{}, bulkData.Keys...)
+	sort.Strings(keys)
+	sort.Strings(expected)
+	if fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Errorf("wrong keys\nexpected: %v\ngot:      %v", expected, keys)
+	}
+}
+
+func TestParseEnsAvatar(t *testing.T) {
+	tests := []struct {
+		record   string
+		expected *ensAvatar
+		valid    bool
+	}{
+		{"https://example.com/avatar.png", &ensAvatar{url: "https://example.com/avatar.png"}, true},
+		{"ipfs://QmUbTVz1xpsQ4o5Ru3NsSr9tR9CQjR6fdVhR3jTDBSrhSd", &ensAvatar{url: "https://ipfs.io/ipfs/QmUbTVz1xpsQ4o5Ru3NsSr9tR9CQjR6fdVhR3jTDBSrhSd"}, true},
+		{"ipfs://ipfs/QmUbTVz1xpsQ4o5Ru3NsSr9tR9CQjR6fdVhR3jTDBSrhSd", &ensAvatar{url: "https://ipfs.io/ipfs/QmUbTVz1xpsQ4o5Ru3NsSr9tR9CQjR6fdVhR3jTDBSrhSd"}, true},
+		{"eip155:1/erc721:0xb7F7F6C52F2e2fdb1963Eab30438024864c313F6/2430", &ensAvatar{chainId: 1, standard: "erc721", contract: common.HexToAddress("0xb7F7F6C52F2e2fdb1963Eab30438024864c313F6"), tokenId: big.NewInt(2430)}, true},
+		{"eip155:1/ERC1155:0x495f947276749Ce646f68AC8c248420045cb7b5e/8112", &ensAvatar{chainId: 1, standard: "erc1155", contract: common.HexToAddress("0x495f947276749Ce646f68AC8c248420045cb7b5e"), tokenId: big.NewInt(8112)}, true},
+		{"http://example.com/avatar.png", nil, false},
+		{"data:image/png;base64,iVBORw0KGgo=", nil, false},
+		{"eip155:1/erc20:0xb7F7F6C52F2e2fdb1963Eab30438024864c313F6/2430", nil, false},
+		{"eip155:1/erc721:0xinvalid/2430", nil, false},
+		{"eip155:1/erc721:0xb7F7F6C52F2e2fdb1963Eab30438024864c313F6/abc", nil, false},
+		{"eip155:mainnet/erc721:0xb7F7F6C52F2e2fdb1963Eab30438024864c313F6/2430", nil, false},
+	}
+	for _, tt := range tests {
+		avatar, err := parseEnsAvatar(tt.record)
+		if !tt.valid {
+			if err == nil {
+				t.Errorf("expected an error for avatar record %v, got %+v", tt.record, avatar)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("error parsing avatar record %v: %v", tt.record, err)
+			continue
+		}
+		if avatar.url != tt.expected.url || avatar.chainId != tt.expected.chainId || avatar.standard != tt.expected.standard || avatar.contract != tt.expected.contract ||
+			(tt.expected.tokenId != nil && (avatar.tokenId == nil || avatar.tokenId.Cmp(tt.expected.tokenId) != 0)) {
+			t.Errorf("wrong avatar for record %v, expected %+v got %+v", tt.record, tt.expected, avatar)
+		}
+	}
+}
+
+// fakeEnsNFTCaller answers the NFT calls of the avatar verification with the configured owner and metadata uri
+type fakeEnsNFTCaller struct {
+	owner common.Address
+	uri   string
+	// failures is the number of calls that fail before the calls succeed
+	failures int
+	calls    int
+}
+
+func (c *fakeEnsNFTCaller) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return []byte{0x60}, nil
+}
+
+func (c *fakeEnsNFTCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	c.calls++
+	if c.calls <= c.failures {
+		return nil, fmt.Errorf("connection reset by peer")
+	}
+	pack := func(argType string, value interface{}) ([]byte, error) {
+		typ, err := abi.NewType(argType, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		return abi.Arguments{{Type: typ}}.Pack(value)
+	}
+	switch fmt.Sprintf("%x", call.Data[:4]) {
+	case "6352211e": // ownerOf(uint256)
+		return pack("address", c.owner)
+	case "00fdd58e": // balanceOf(address,uint256)
+		if common.BytesToAddress(call.Data[4:36]) == c.owner {
+			return pack("uint256", big.NewInt(1))
+		}
+		return pack("uint256", big.NewInt(0))
+	case "c87b56dd", "0e89341c": // tokenURI(uint256), uri(uint256)
+		return pack("string", c.uri)
+	}
+	return nil, fmt.Errorf("unexpected call %x", call.Data)
+}
+
+func TestResolveEnsAvatar(t *testing.T) {
+	utils.Config = &types.Config{}
+	utils.Config.Chain.Config.DepositChainID = 1
+	owner := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	other := common.HexToAddress("0x983110309620D911731Ac0932219af06091b6744")
+
+	caller := &fakeEnsNFTCaller{owner: owner, uri: "ipfs://QmMetadata/2430"}
+	url, err := resolveEnsAvatar(context.Background(), caller, "eip155:1/erc721:0xb7F7F6C52F2e2fdb1963Eab30438024864c313F6/2430", owner)
+	if err != nil || url != "https://ipfs.io/ipfs/QmMetadata/2430" {
+		t.Errorf("expected the metadata url of the owned erc721 avatar, got %v (%v)", url, err)
+	}
+	_, err = resolveEnsAvatar(context.Background(), caller, "eip155:1/erc721:0xb7F7F6C52F2e2fdb1963Eab30438024864c313F6/2430", other)
+	if err == nil {
+		t.Errorf("expected an error for an erc721 avatar owned by another address")
+	}
+
+	caller = &fakeEnsNFTCaller{owner: owner, uri: "https://api.example.com/{id}.json"}
+	url, err = resolveEnsAvatar(context.Background(), caller, "eip155:1/erc1155:0x495f947276749Ce646f68AC8c248420045cb7b5e/16", owner)
+	if err != nil || url != fmt.Sprintf("https://api.example.com/%064x.json", 16) {
+		t.Errorf("expected the metadata url of the owned erc1155 avatar, got %v (%v)", url, err)
+	}
+	_, err = resolveEnsAvatar(context.Background(), caller, "eip155:1/erc1155:0x495f947276749Ce646f68AC8c248420045cb7b5e/16", other)
+	if err == nil {
+		t.Errorf("expected an error for an erc1155 avatar not held by the address")
+	}
+
+	_, err = resolveEnsAvatar(context.Background(), caller, "eip155:137/erc721:0xb7F7F6C52F2e2fdb1963Eab30438024864c313F6/2430", owner)
+	if err == nil {
+		t.Errorf("expected an error for an nft avatar on another chain")
+	}
+	url, err = resolveEnsAvatar(context.Background(), caller, "https://example.com/avatar.png", owner)
+	if err != nil || url != "https://example.com/avatar.png" {
+		t.Errorf("expected the plain url avatar, got %v (%v)", url, err)
+	}
+}
+
+func TestTransformEnsNameWrapped(t *testing.T) {
+	nameWrapper := common.HexToAddress("0xD4416b13d2b3a9aBae7AcD5D6C2BbDBE25686401")
+	registry := common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+	resolver := common.HexToAddress("0x231b0Ee14048e9dCcD1d247744d114a4EB5E8E63")
+	owner := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	label := crypto.Keccak256Hash([]byte("vitalik"))
+	node, err := go_ens.NameHash("vitalik.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	wrapTx := common.HexToHash("0x03")
+	unwrapTx := common.HexToHash("0x04")
+
+	utils.Config = &types.Config{}
+	utils.Config.Indexer.EnsTransformer.NameWrapperContract = nameWrapper.String()
+
+	dnsName := append(append([]byte{7}, "vitalik"...), append([]byte{3}, "eth\x00"...)...)
+	table := newFakeEnsBigtable()
+	bt := &Bigtable{chainId: "1", ensTable: table}
+	block := &types.Eth1Block{
+		Number: 17000000,
+		Hash:   common.HexToHash("0x01").Bytes(),
+		Transactions: []*types.Eth1Transaction{
+			{
+				Hash: wrapTx.Bytes(),
+				To:   nameWrapper.Bytes(),
+				Logs: []*types.Eth1Log{
+					// wrapping transfers the name to the wrapper in the registry
+					newEnsTestLog(t, registry, [][]byte{ens.NewOwnerTopic, ens.EthNode[:], label.Bytes()}, []string{"address"}, nameWrapper),
+					newEnsTestLog(t, resolver, [][]byte{ens.NameChangedTopic, node[:]}, []string{"string"}, "vitalik.eth"),
+					newEnsTestLog(t, nameWrapper, [][]byte{ens.NameWrappedTopic, node[:]}, []string{"bytes", "address", "uint32", "uint64"}, dnsName, owner, uint32(196608), uint64(1893456000)),
+				},
+			},
+			{
+				Hash: unwrapTx.Bytes(),
+				To:   nameWrapper.Bytes(),
+				Logs: []*types.Eth1Log{
+					newEnsTestLog(t, nameWrapper, [][]byte{ens.NameUnwrappedTopic, node[:]}, []string{"address"}, owner),
+				},
+			},
+			{
+				// only the name wrapper is trusted to emit wrapper events
+				Hash: common.HexToHash("0x05").Bytes(),
+				To:   resolver.Bytes(),
+				Logs: []*types.Eth1Log{
+					newEnsTestLog(t, resolver, [][]byte{ens.NameUnwrappedTopic, node[:]}, []string{"address"}, resolver),
+				},
+			},
+		},
+	}
+	bulkData, _, err := bt.transformEnsBlock(block)
+	if err != nil {
+		t.Fatalf("error transforming block: %v", err)
+	}
+
+	expected := []string{
+		fmt.Sprintf("1:ENS:I:H:%x:%x", node, wrapTx),
+		fmt.Sprintf("1:ENS:I:H:%x:%x", node, unwrapTx),
+		fmt.Sprintf("1:ENS:I:A:%x:%x", owner, wrapTx),
+		fmt.Sprintf("1:ENS:I:A:%x:%x", owner, unwrapTx),
+		fmt.Sprintf("1:ENS:V:A:%x", owner),
+		fmt.Sprintf("1:ENS:V:H:%x", node),
+		"1:ENS:V:N:vitalik.eth",
+		fmt.Sprintf("1:ENS:O:A:%x:%x", owner, node),
+	}
+	keys := append([]string{}, bulkData.Keys...)
+	sort.Strings(keys)
+	sort.Strings(expected)
+	if fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Errorf("wrong keys\nexpected: %v\ngot:      %v", expected, keys)
+	}
+}
+
+func TestEnsNameWrapperOwner(t *testing.T) {
+	nameWrapper := common.HexToAddress("0xD4416b13d2b3a9aBae7AcD5D6C2BbDBE25686401")
+	owner := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	node, err := go_ens.NameHash("vitalik.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	utils.Config = &types.Config{}
+	utils.Config.Indexer.EnsTransformer.NameWrapperContract = nameWrapper.String()
+
+	caller := &fakeEnsNFTCaller{owner: owner}
+	got, err := ensNameWrapperOwner(context.Background(), caller, nameWrapper, node)
+	if err != nil || got != owner {
+		t.Errorf("expected the wrapped name to be owned by %v, got %v (%v)", owner, got, err)
+	}
+	got, err = ensNameWrapperOwner(context.Background(), caller, owner, node)
+	if err != nil || got != owner {
+		t.Errorf("expected an unwrapped owner to be kept, got %v (%v)", got, err)
+	}
+
+	// a failing node is retried, the error is returned once the attempts are exhausted
+	ensRetryBackoff = time.Millisecond
+	defer func() { ensRetryBackoff = time.Millisecond * 500 }()
+	caller = &fakeEnsNFTCaller{owner: owner, failures: 1}
+	got, err = ensNameWrapperOwner(context.Background(), caller, nameWrapper, node)
+	if err != nil || got != owner || caller.calls != 2 {
+		t.Errorf("expected the owner after a retry, got %v after %v calls (%v)", got, caller.calls, err)
+	}
+	caller = &fakeEnsNFTCaller{owner: owner, failures: 10}
+	_, err = ensNameWrapperOwner(context.Background(), caller, nameWrapper, node)
+	if err == nil || caller.calls != 3 {
+		t.Errorf("expected an error after 3 attempts, got %v after %v calls", err, caller.calls)
+	}
+}
+
+func TestEnsImportSettings(t *testing.T) {
+	utils.Config = &types.Config{}
+	batchSize, readTimeout := ensImportSettings()
+	if batchSize != 100 || readTimeout != time.Second*30 {
+		t.Errorf("expected the default batch size and read timeout, got %v and %v", batchSize, readTimeout)
+	}
+
+	utils.Config.Indexer.EnsTransformer.BatchSize = 20
+	utils.Config.Indexer.EnsTransformer.ReadTimeoutSeconds = 300
+	batchSize, readTimeout = ensImportSettings()
+	if batchSize != 20 || readTimeout != time.Minute*5 {
+		t.Errorf("expected the configured batch size and read timeout, got %v and %v", batchSize, readTimeout)
+	}
+}
+
+func TestEnsValidationConcurrency(t *testing.T) {
+	utils.Config = &types.Config{}
+	if got := ensValidationConcurrency(); got != 10 {
+		t.Errorf("expected the default concurrency, got %v", got)
+	}
+	utils.Config.Indexer.EnsTransformer.Concurrency = 4
+	if got := ensValidationConcurrency(); got != 4 {
+		t.Errorf("expected the configured concurrency, got %v", got)
+	}
+}
+
+func TestReadEnsKeyBatch(t *testing.T) {
+	table := newFakeEnsBigtable()
+	bt := &Bigtable{chainId: "1", ensTable: table}
+	keys := []string{"1:ENS:V:A:aa", "1:ENS:V:H:bb", "1:ENS:V:N:a.eth", "1:ENS:V:N:b.eth", "1:ENS:V:N:c.eth"}
+	bulk := &types.BulkMutations{}
+	for _, key := range append(keys, "1:ENS:I:H:bb", "1:ENS:W", "2:ENS:V:N:a.eth") {
+		bulk.Keys = append(bulk.Keys, key)
+		bulk.Muts = append(bulk.Muts, gcp_bigtable.NewMutation())
+	}
+	if err := table.WriteBulk(bulk); err != nil {
+		t.Fatal(err)
+	}
+
+	read := []string{}
+	after := ""
+	for i := 0; i < 5; i++ {
+		batch, err := bt.readEnsKeyBatch(context.Background(), "1:ENS:V", after, 2, time.Second)
+		if err != nil {
+			t.Fatalf("error reading key batch: %v", err)
+		}
+		if len(batch) > 2 {
+			t.Fatalf("expected at most 2 keys per batch, got %v", batch)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		read = append(read, batch...)
+		after = batch[len(batch)-1]
+	}
+	if strings.Join(read, ",") != strings.Join(keys, ",") {
+		t.Errorf("expected the keys %v, got %v", keys, read)
+	}
+	if ensPrefixSuccessor("1:ENS:V") != "1:ENS:W" || ensPrefixSuccessor("a\xff") != "b" {
+		t.Errorf("unexpected prefix successor")
+	}
+}
+
+func TestRunWithEnsTimeout(t *testing.T) {
+	err := runWithEnsTimeout(context.Background(), 0, func(ctx context.Context) error {
+		return fmt.Errorf("validation failed")
+	})
+	if err == nil || err.Error() != "validation failed" {
+		t.Errorf("expected the error of the validation, got %v", err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	abandoned := make(chan error, 1)
+	err = runWithEnsTimeout(context.Background(), time.Millisecond*10, func(ctx context.Context) error {
+		// the abandoned validation sees the deadline at its next wait for the rate limit
+		<-ctx.Done()
+		abandoned <- waitForEnsRpc(ctx)
+		return nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected the validation to time out, got %v", err)
+	}
+	if err := <-abandoned; err == nil {
+		t.Errorf("expected the abandoned validation to stop at the rate limit")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = runWithEnsTimeout(ctx, time.Minute, func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("expected the validation to be cancelled, got %v", err)
+	}
+}
+
+func TestEnsResolveCache(t *testing.T) {
+	utils.Config = &types.Config{}
+	cache := &ensResolveCache{cache: freecache.NewCache(1024 * 1024)}
+	address := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	node, err := go_ens.NameHash("vitalik.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+
+	cache.setAddress(node, address, time.Now().Add(time.Hour*24))
+	cache.setPrimaryName(address, "vitalik.eth", time.Now().Add(time.Hour*24))
+	if got, ok := cache.getAddress(node); !ok || got != address {
+		t.Errorf("expected the cached address %v, got %v (%v)", address, got, ok)
+	}
+	if got, ok := cache.getPrimaryName(address); !ok || got != "vitalik.eth" {
+		t.Errorf("expected the cached primary name, got %v (%v)", got, ok)
+	}
+
+	cache.invalidate("1:ENS:V:N:vitalik.eth")
+	if _, ok := cache.getAddress(node); ok {
+		t.Errorf("expected the address of a dirty name to be dropped")
+	}
+	cache.invalidate(fmt.Sprintf("1:ENS:V:A:%x", address))
+	if _, ok := cache.getPrimaryName(address); ok {
+		t.Errorf("expected the primary name of a dirty address to be dropped")
+	}
+	cache.setAddress(node, address, time.Now().Add(time.Hour*24))
+	cache.invalidate(fmt.Sprintf("1:ENS:V:H:%x", node))
+	if _, ok := cache.getAddress(node); ok {
+		t.Errorf("expected the address of a dirty name hash to be dropped")
+	}
+
+	// an entry must not outlive the name, even if the cache has not evicted it yet
+	expired := make([]byte, 8)
+	binary.BigEndian.PutUint64(expired, uint64(time.Now().Add(-time.Minute).Unix()))
+	err = cache.cache.Set(ensResolveCacheNameKey(node), append(expired, address.Bytes()...), 3600)
+	if err != nil {
+		t.Fatalf("error setting cache entry: %v", err)
+	}
+	if _, ok := cache.getAddress(node); ok {
+		t.Errorf("expected an expired name not to be served from the cache")
+	}
+	cache.setAddress(node, address, time.Now().Add(-time.Minute))
+	if _, ok := cache.getAddress(node); ok {
+		t.Errorf("expected an expired name not to be cached")
+	}
+
+	var unset ensResolveCache
+	unset.setAddress(node, address, time.Now().Add(time.Hour))
+	if _, ok := unset.getAddress(node); ok {
+		t.Errorf("expected no cached entries without a cache")
+	}
+}
+
+func TestIsEnsNotFoundError(t *testing.T) {
+	tests := []struct {
+		err      error
+		notFound bool
+	}{
+		{fmt.Errorf("unregistered name"), true},
+		{fmt.Errorf("no resolver"), true},
+		{fmt.Errorf("no address"), true},
+		{fmt.Errorf("execution reverted"), true},
+		{fmt.Errorf("error calling resolver: %w", bind.ErrNoCode), true},
+		{fmt.Errorf("429 Too Many Requests"), false},
+		{context.DeadlineExceeded, false},
+		{fmt.Errorf("read tcp 10.0.0.1:443: i/o timeout"), false},
+	}
+	for _, test := range tests {
+		if got := isEnsNotFoundError(test.err); got != test.notFound {
+			t.Errorf("expected not found to be %v for %v, got %v", test.notFound, test.err, got)
+		}
+	}
+}
+
+func TestRetryEnsCall(t *testing.T) {
+	utils.Config = &types.Config{}
+	ensRetryBackoff = time.Millisecond
+	defer func() { ensRetryBackoff = time.Millisecond * 500 }()
+
+	calls := 0
+	err := retryEnsCall(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("429 Too Many Requests")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("expected a transient error to be retried until it succeeds, got %v after %v calls", err, calls)
+	}
+
+	calls = 0
+	err = retryEnsCall(context.Background(), func() error {
+		calls++
+		return fmt.Errorf("i/o timeout")
+	})
+	if err == nil || calls != 3 {
+		t.Errorf("expected 3 attempts for a persistent transient error, got %v after %v calls", err, calls)
+	}
+
+	calls = 0
+	err = retryEnsCall(context.Background(), func() error {
+		calls++
+		return fmt.Errorf("unregistered name")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("expected a definitive error not to be retried, got %v after %v calls", err, calls)
+	}
+
+	utils.Config.Indexer.EnsTransformer.RetryAttempts = 5
+	calls = 0
+	_ = retryEnsCall(context.Background(), func() error {
+		calls++
+		return fmt.Errorf("i/o timeout")
+	})
+	if calls != 5 {
+		t.Errorf("expected the configured number of attempts, got %v calls", calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = 0
+	err = retryEnsCall(ctx, func() error {
+		calls++
+		return nil
+	})
+	if err != context.Canceled || calls != 0 {
+		t.Errorf("expected a cancelled context to stop the call, got %v after %v calls", err, calls)
+	}
+}
+
+// fakeEnsMulticallCaller answers the aggregate3 calls of the batch resolution, every sub call is looked up by target, method and
+// first argument in responses and fails if there is no response
+type fakeEnsMulticallCaller struct {
+	responses map[string]interface{}
+	batches   int
+}
+
+func ensMulticallResponseKey(target common.Address, method string, arg []byte) string {
+	return fmt.Sprintf("%v:%v:%x", target.Hex(), method, arg)
+}
+
+func (c *fakeEnsMulticallCaller) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return []byte{0x60}, nil
+}
+
+func (c *fakeEnsMulticallCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	c.batches++
+	aggregate := ens.Multicall3ABI.Methods["aggregate3"]
+	in, err := aggregate.Inputs.Unpack(call.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	calls := *abi.ConvertType(in[0], new([]ens.Multicall3Call)).(*[]ens.Multicall3Call)
+	results := make([]ens.Multicall3Result, len(calls))
+	for i, subCall := range calls {
+		method, err := ens.EnsCallsABI.MethodById(subCall.CallData[:4])
+		if err != nil {
+			return nil, err
+		}
+		response, ok := c.responses[ensMulticallResponseKey(subCall.Target, method.Name, subCall.CallData[4:36])]
+		if !ok {
+			continue
+		}
+		data, err := method.Outputs.Pack(response)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = ens.Multicall3Result{Success: true, ReturnData: data}
+	}
+	return aggregate.Outputs.Pack(results)
+}
+
+func TestBatchResolveEns(t *testing.T) {
+	registry := common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+	baseRegistrar := common.HexToAddress("0x57f1887a8BF19b14fC0dF6Fd9B2acc9Af147eA85")
+	resolver := common.HexToAddress("0x231b0Ee14048e9dCcD1d247744d114a4EB5E8E63")
+	reverseResolver := common.HexToAddress("0xA2C122BE93b0074270ebeE7f6b7292C7deB45047")
+	vitalik := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	other := common.HexToAddress("0x983110309620D911731Ac0932219af06091b6744")
+	expires := time.Unix(2000000000, 0)
+
+	node := func(name string) []byte {
+		hash, err := go_ens.NameHash(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash[:]
+	}
+	label := func(name string) []byte {
+		return crypto.Keccak256([]byte(name))
+	}
+	reverseNode := func(address common.Address) []byte {
+		return node(fmt.Sprintf("%x.addr.reverse", address.Bytes()))
+	}
+	caller := &fakeEnsMulticallCaller{responses: map[string]interface{}{
+		ensMulticallResponseKey(registry, "resolver", node("vitalik.eth")):              resolver,
+		ensMulticallResponseKey(registry, "owner", node("vitalik.eth")):                 vitalik,
+		ensMulticallResponseKey(baseRegistrar, "nameExpires", label("vitalik")):         big.NewInt(expires.Unix()),
+		ensMulticallResponseKey(baseRegistrar, "ownerOf", label("vitalik")):             vitalik,
+		ensMulticallResponseKey(resolver, "addr", node("vitalik.eth")):                  vitalik,
+		ensMulticallResponseKey(registry, "resolver", reverseNode(vitalik)):             reverseResolver,
+		ensMulticallResponseKey(reverseResolver, "name", reverseNode(vitalik)):          "vitalik.eth",
+		ensMulticallResponseKey(registry, "resolver", node("other.eth")):                resolver,
+		ensMulticallResponseKey(registry, "owner", node("other.eth")):                   other,
+		ensMulticallResponseKey(baseRegistrar, "nameExpires", label("other")):           big.NewInt(expires.Unix()),
+		ensMulticallResponseKey(resolver, "addr", node("other.eth")):                    other,
+		ensMulticallResponseKey(registry, "resolver", reverseNode(other)):               common.Address{},
+		ensMulticallResponseKey(registry, "resolver", node("noresolver.eth")):           common.Address{},
+		ensMulticallResponseKey(registry, "owner", node("noresolver.eth")):              other,
+		ensMulticallResponseKey(baseRegistrar, "nameExpires", label("noresolver")):      big.NewInt(expires.Unix()),
+		ensMulticallResponseKey(registry, "resolver", node("unregistered.eth")):         common.Address{},
+		ensMulticallResponseKey(registry, "owner", node("unregistered.eth")):            common.Address{},
+		ensMulticallResponseKey(baseRegistrar, "nameExpires", label("unregistered")):    big.NewInt(0),
+		ensMulticallResponseKey(registry, "resolver", node("sub.vitalik.eth")):          resolver,
+		ensMulticallResponseKey(resolver, "addr", node("sub.vitalik.eth")):              vitalik,
+		ensMulticallResponseKey(registry, "owner", node("sub.vitalik.eth")):             vitalik,
+		ensMulticallResponseKey(baseRegistrar, "nameExpires", label("sub.vitalik.eth")): big.NewInt(expires.Unix()),
+	}}
+
+	resolutions, err := batchResolveEnsWith(context.Background(), caller, common.HexToAddress(ens.Multicall3Address), registry, baseRegistrar,
+		[]string{"vitalik.eth", "other.eth", "noresolver.eth", "unregistered.eth", "sub.vitalik.eth"})
+	if err != nil {
+		t.Fatalf("error batch resolving names: %v", err)
+	}
+	if caller.batches != 4 {
+		t.Errorf("expected 4 multicall batches, got %v", caller.batches)
+	}
+	if len(resolutions) != 3 {
+		t.Errorf("expected resolutions for 3 names, got %v", len(resolutions))
+	}
+	resolution := resolutions["vitalik.eth"]
+	if resolution == nil || resolution.err != nil || resolution.address != vitalik || resolution.resolver != resolver || resolution.owner != vitalik || !resolution.expires.Equal(expires) {
+		t.Fatalf("unexpected resolution of vitalik.eth: %+v", resolution)
+	}
+	if resolution.registrant != vitalik {
+		t.Errorf("expected registrant %v of vitalik.eth, got %v", vitalik.Hex(), resolution.registrant.Hex())
+	}
+	if isPrimary, known := ensPrimaryStatus("vitalik.eth", resolution.reverseName, resolution.reverseErr); !isPrimary || !known {
+		t.Errorf("expected vitalik.eth to be the primary name of %v", vitalik.Hex())
+	}
+	resolution = resolutions["other.eth"]
+	if resolution == nil || resolution.address != other {
+		t.Fatalf("unexpected resolution of other.eth: %+v", resolution)
+	}
+	if isPrimary, known := ensPrimaryStatus("other.eth", resolution.reverseName, resolution.reverseErr); isPrimary || !known {
+		t.Errorf("expected other.eth to be known as not primary, got %v %v", isPrimary, known)
+	}
+	resolution = resolutions["noresolver.eth"]
+	if resolution == nil || resolution.err == nil || !isEnsNotFoundError(resolution.err) {
+		t.Errorf("expected a not found error for a name without resolver, got %+v", resolution)
+	}
+	if resolutions["unregistered.eth"] != nil || resolutions["sub.vitalik.eth"] != nil {
+		t.Errorf("expected unregistered names and sub names to be left to the per name resolution")
+	}
+}
+
+func TestCompareEnsRecord(t *testing.T) {
+	address := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	other := common.HexToAddress("0x983110309620D911731Ac0932219af06091b6744")
+	expires := time.Unix(1700000000, 0).UTC()
+	record := ensStoredRecord{Name: "vitalik.eth", Address: address.Bytes(), IsPrimaryName: true, ValidTo: expires}
+
+	tests := []struct {
+		name       string
+		resolution *ensResolution
+		expected   []string
+	}{
+		{
+			name:       "consistent",
+			resolution: &ensResolution{address: address, expires: expires, reverseName: "vitalik.eth"},
+		},
+		{
+			name:       "changed address",
+			resolution: &ensResolution{address: other, expires: expires, reverseErr: errors.New("no resolution")},
+			expected:   []string{ENS_MISMATCH_ADDRESS, ENS_MISMATCH_PRIMARY},
+		},
+		{
+			name:       "renewed",
+			resolution: &ensResolution{address: address, expires: expires.Add(time.Hour * 24 * 365), reverseName: "vitalik.eth"},
+			expected:   []string{ENS_MISMATCH_EXPIRY},
+		},
+		{
+			name:       "unreadable reverse record",
+			resolution: &ensResolution{address: address, expires: expires, reverseErr: errors.New("connection refused")},
+		},
+		{
+			name:       "unresolvable",
+			resolution: &ensResolution{err: errors.New("no resolver")},
+			expected:   []string{ENS_MISMATCH_ADDRESS},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kinds := []string{}
+			for _, mismatch := range compareEnsRecord(record, tt.resolution) {
+				kinds = append(kinds, mismatch.Kind)
+			}
+			if fmt.Sprint(kinds) != fmt.Sprint(tt.expected) {
+				t.Errorf("expected mismatches %v, got %v", tt.expected, kinds)
+			}
+		})
+	}
+}
+
+func TestImportEnsUpdatesCancelled(t *testing.T) {
+	utils.Config = &types.Config{}
+	table := &deletingEnsBigtable{fakeEnsBigtable: newFakeEnsBigtable()}
+	bt := &Bigtable{chainId: "1", ensTable: table}
+	if err := table.fakeEnsBigtable.WriteBulk(&types.BulkMutations{Keys: []string{"1:ENS:V:N:vitalik.eth"}, Muts: []*gcp_bigtable.Mutation{gcp_bigtable.NewMutation()}}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := bt.ImportEnsUpdates(ctx, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the run to be cancelled, got %v", err)
+	}
+	if len(table.deleted) != 0 {
+		t.Errorf("expected no keys to be deleted by a cancelled run, deleted %v", table.deleted)
+	}
+}
+
+func TestImportEnsUpdatesSuccessGauge(t *testing.T) {
+	utils.Config = &types.Config{}
+	table := &deletingEnsBigtable{fakeEnsBigtable: newFakeEnsBigtable()}
+	bt := &Bigtable{chainId: "1", ensTable: table}
+
+	metrics.EnsLastImportSuccess.Set(0)
+	if err := bt.ImportEnsUpdates(context.Background(), nil); err != nil {
+		t.Fatalf("error running ens update: %v", err)
+	}
+	if testutil.ToFloat64(metrics.EnsLastImportSuccess) == 0 {
+		t.Fatalf("expected a run without dirty keys to set the success gauge")
+	}
+
+	// a run that stops before its batches are written must not look healthy
+	if err := table.fakeEnsBigtable.WriteBulk(&types.BulkMutations{Keys: []string{"1:ENS:V:N:vitalik.eth"}, Muts: []*gcp_bigtable.Mutation{gcp_bigtable.NewMutation()}}); err != nil {
+		t.Fatal(err)
+	}
+	metrics.EnsLastImportSuccess.Set(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := bt.ImportEnsUpdates(ctx, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the run to be cancelled, got %v", err)
+	}
+	if got := testutil.ToFloat64(metrics.EnsLastImportSuccess); got != 1 {
+		t.Errorf("expected a cancelled run to keep the success gauge, got %v", got)
+	}
+}
+
+func TestDecodeEnsNameHash(t *testing.T) {
+	node, err := go_ens.NameHash("vitalik.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	decoded, err := decodeEnsNameHash(fmt.Sprintf("%x", node))
+	if err != nil || !bytes.Equal(decoded, node[:]) {
+		t.Errorf("expected %x, got %x (%v)", node, decoded, err)
+	}
+	for _, malformed := range []string{"", "zz", fmt.Sprintf("%x", node[:31]), fmt.Sprintf("%x00", node)} {
+		if _, err := decodeEnsNameHash(malformed); err == nil {
+			t.Errorf("expected an error for name hash %q", malformed)
+		}
+	}
+}
+
+func TestEnsRecordExporter(t *testing.T) {
+	validTo := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	records := []*types.EnsExportRecord{
+		{Name: "vitalik.eth", Address: "0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045", Owner: "0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045", ValidTo: &validTo, IsPrimaryName: true, Resolver: "0x231b0Ee14048e9dCcD1d247744d114a4EB5E8E63"},
+		{Name: "a,b.eth"},
+	}
+
+	var csvOut bytes.Buffer
+	exporter, err := newEnsRecordExporter(&csvOut, ENS_EXPORT_FORMAT_CSV)
+	if err != nil {
+		t.Fatalf("error creating csv exporter: %v", err)
+	}
+	for _, record := range records {
+		if err := exporter.write(record); err != nil {
+			t.Fatalf("error writing record: %v", err)
+		}
+	}
+	if err := exporter.flush(); err != nil {
+		t.Fatalf("error flushing csv: %v", err)
+	}
+	expectedCsv := "name,address,owner,valid_to,is_primary_name,resolver\n" +
+		"vitalik.eth,0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045,0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045,2030-01-02T03:04:05Z,true,0x231b0Ee14048e9dCcD1d247744d114a4EB5E8E63\n" +
+		"\"a,b.eth\",,,,false,\n"
+	if csvOut.String() != expectedCsv {
+		t.Errorf("wrong csv\nexpected: %q\ngot:      %q", expectedCsv, csvOut.String())
+	}
+
+	var jsonOut bytes.Buffer
+	exporter, err = newEnsRecordExporter(&jsonOut, ENS_EXPORT_FORMAT_NDJSON)
+	if err != nil {
+		t.Fatalf("error creating ndjson exporter: %v", err)
+	}
+	for _, record := range records {
+		if err := exporter.write(record); err != nil {
+			t.Fatalf("error writing record: %v", err)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(jsonOut.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"name":"vitalik.eth"`) || !strings.Contains(lines[1], `"valid_to":null`) {
+		t.Errorf("expected one json object per record, got %v", lines)
+	}
+
+	if _, err := newEnsRecordExporter(&jsonOut, "xml"); err == nil {
+		t.Errorf("expected an error for an unsupported format")
+	}
+}
+
+func TestEnsLostPrimaryNames(t *testing.T) {
+	alice := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	removed := []ensRemovedName{
+		{Name: "vitalik.eth", Address: alice.Bytes(), IsPrimaryName: true},
+		{Name: "other.eth", Address: alice.Bytes()},
+		{Name: "unresolved.eth", IsPrimaryName: true},
+		{Name: "zero.eth", Address: common.Address{}.Bytes(), IsPrimaryName: true},
+	}
+	lost := ensLostPrimaryNames(removed)
+	if len(lost) != 1 || lost[0].Name != "vitalik.eth" {
+		t.Errorf("expected only the primary name vitalik.eth of alice to be lost, got %v", lost)
+	}
+}
+
+func TestEnsLogFields(t *testing.T) {
+	fields := ensLogFields("AddressChanged", eth_types.Log{BlockNumber: 17000000, TxHash: common.HexToHash("0x02"), Index: 7})
+	if fields["event"] != "AddressChanged" || fields["block"] != uint64(17000000) || fields["tx"] != common.HexToHash("0x02").Hex() || fields["log_index"] != uint(7) {
+		t.Errorf("wrong log fields %v", fields)
+	}
+}
+
+func TestEnsNameHashTablesCascade(t *testing.T) {
+	migrations, err := filepath.Glob(filepath.Join("migrations", "*.sql"))
+	if err != nil || len(migrations) == 0 {
+		t.Fatalf("error listing the migrations: %v", err)
+	}
+	// the up part of every migration adding a foreign key to the ens names
+	referencing := map[string]string{}
+	for _, migration := range migrations {
+		content, err := os.ReadFile(migration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		up := strings.SplitN(string(content), "-- +goose Down", 2)[0]
+		for _, line := range strings.Split(up, "\n") {
+			if !strings.Contains(line, "REFERENCES ens (name_hash)") {
+				continue
+			}
+			fields := strings.Fields(line)
+			if len(fields) < 6 || fields[0] != "ALTER" || fields[3] != "ADD" {
+				t.Errorf("unexpected foreign key to the ens names in %v: %v", migration, line)
+				continue
+			}
+			if !strings.Contains(line, "ON DELETE CASCADE") {
+				t.Errorf("expected the foreign key of %v to cascade in %v", fields[2], migration)
+			}
+			referencing[fields[2]] = fields[5]
+		}
+	}
+
+	if len(referencing) != len(ensNameHashTables) {
+		t.Errorf("expected a foreign key for each of the %v name hash tables, got %v", ensNameHashTables, referencing)
+	}
+	for _, table := range ensNameHashTables {
+		if referencing[table] != ensNameHashForeignKey(table) {
+			t.Errorf("expected foreign key %v of %v to be validated by the cleanup, got %v", referencing[table], table, ensNameHashForeignKey(table))
+		}
+	}
+}
+
+func TestEnsPrimaryNameAsOf(t *testing.T) {
+	start := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	changes := []ensPrimaryNameChange{
+		{OldName: "", NewName: "old.eth", Ts: start},
+		{OldName: "old.eth", NewName: "new.eth", Ts: start.AddDate(0, 6, 0)},
+		{OldName: "new.eth", NewName: "", Ts: start.AddDate(1, 0, 0)},
+	}
+	// the history of an address that had a primary name before the changes were recorded
+	lateChanges := []ensPrimaryNameChange{{OldName: "old.eth", NewName: "new.eth", Ts: start}}
+
+	tests := []struct {
+		name     string
+		changes  []ensPrimaryNameChange
+		current  string
+		at       time.Time
+		expected string
+	}{
+		{name: "before the first name", changes: changes, current: "current.eth", at: start.Add(-time.Hour)},
+		{name: "at the first change", changes: changes, at: start, expected: "old.eth"},
+		{name: "after the first change", changes: changes, at: start.AddDate(0, 1, 0), expected: "old.eth"},
+		{name: "after the second change", changes: changes, at: start.AddDate(0, 7, 0), expected: "new.eth"},
+		{name: "after the name was cleared", changes: changes, current: "current.eth", at: start.AddDate(2, 0, 0)},
+		{name: "before a late change", changes: lateChanges, at: start.Add(-time.Hour), expected: "old.eth"},
+		{name: "after a late change", changes: lateChanges, at: start.Add(time.Hour), expected: "new.eth"},
+		{name: "no changes", current: "current.eth", at: start, expected: "current.eth"},
+		{name: "no changes and no name", at: start},
+	}
+	for _, tt := range tests {
+		if name := ensPrimaryNameAsOf(tt.changes, tt.current, tt.at); name != tt.expected {
+			t.Errorf("%v: expected %q, got %q", tt.name, tt.expected, name)
+		}
+	}
+}
+
+func TestEnsNameValidAsOf(t *testing.T) {
+	registeredAt := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	validTo := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	registered := ensPrimaryNameCandidate{Name: "vitalik.eth", ValidTo: validTo, RegisteredAt: sql.NullTime{Time: registeredAt, Valid: true}}
+	unindexed := ensPrimaryNameCandidate{Name: "old.eth", ValidTo: validTo}
+
+	tests := []struct {
+		name      string
+		candidate ensPrimaryNameCandidate
+		at        time.Time
+		valid     bool
+	}{
+		{name: "registered before", candidate: registered, at: registeredAt.Add(time.Hour), valid: true},
+		{name: "registered at", candidate: registered, at: registeredAt, valid: true},
+		{name: "registered after", candidate: registered, at: registeredAt.Add(-time.Hour)},
+		{name: "expired", candidate: registered, at: validTo.Add(time.Hour)},
+		{name: "expiring at", candidate: registered, at: validTo, valid: true},
+		// without a registration the expiry is all that is known about the name
+		{name: "no indexed registration", candidate: unindexed, at: registeredAt.AddDate(-3, 0, 0), valid: true},
+		{name: "no indexed registration expired", candidate: unindexed, at: validTo.Add(time.Hour)},
+	}
+	for _, tt := range tests {
+		if valid := ensNameValidAsOf(tt.candidate, tt.at); valid != tt.valid {
+			t.Errorf("%v: expected valid %v, got %v", tt.name, tt.valid, valid)
+		}
+	}
+}
+
+func TestIsEnsPrimaryNameConflict(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "no error"},
+		{name: "primary name conflict", err: &pq.Error{Code: "23505", Constraint: "idx_ens_unique_primary_address"}, expected: true},
+		{name: "wrapped conflict", err: fmt.Errorf("error writing: %w", &pq.Error{Code: "23505", Constraint: "idx_ens_unique_primary_address"}), expected: true},
+		{name: "other unique index", err: &pq.Error{Code: "23505", Constraint: "ens_pkey"}},
+		{name: "other error", err: &pq.Error{Code: "40001"}},
+		{name: "no postgres error", err: errors.New("connection refused")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isEnsPrimaryNameConflict(tt.err); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEnsNameIndexPrefix(t *testing.T) {
+	if got := ensNameIndexPrefix("vita"); got != "vita" {
+		t.Errorf("expected a short prefix to be kept, got %v", got)
+	}
+	exact := strings.Repeat("a", ENS_NAME_INDEX_PREFIX_LENGTH)
+	if got := ensNameIndexPrefix(exact); got != exact {
+		t.Errorf("expected a prefix of the indexed length to be kept, got %v characters", utf8.RuneCountInString(got))
+	}
+	// left() counts characters, so a prefix of multi byte characters keeps as many characters as a prefix of ascii characters
+	long := strings.Repeat("🦄", ENS_NAME_INDEX_PREFIX_LENGTH*4) + ".eth"
+	got := ensNameIndexPrefix(long)
+	if utf8.RuneCountInString(got) != ENS_NAME_INDEX_PREFIX_LENGTH || !strings.HasPrefix(long, got) || !utf8.ValidString(got) {
+		t.Errorf("expected the first %v characters of the prefix, got %v characters", ENS_NAME_INDEX_PREFIX_LENGTH, utf8.RuneCountInString(got))
+	}
+}
+
+func TestEnsNamesForTx(t *testing.T) {
+	from := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	to := common.HexToAddress("0x983110309620D911731Ac0932219af06091b6744")
+	other := common.HexToAddress("0x225f137127d9067788314bc7fcc1f36746a3c3B5")
+	vitalik := ensAddressName{Address: from.Bytes(), Name: "vitalik.eth"}
+	brantly := ensAddressName{Address: to.Bytes(), Name: "brantly.eth"}
+
+	tests := []struct {
+		name     string
+		from     common.Address
+		to       common.Address
+		rows     []ensAddressName
+		fromName string
+		toName   string
+	}{
+		{name: "both named", from: from, to: to, rows: []ensAddressName{brantly, vitalik}, fromName: "vitalik.eth", toName: "brantly.eth"},
+		{name: "receiver named", from: from, to: to, rows: []ensAddressName{brantly}, toName: "brantly.eth"},
+		{name: "sender named", from: from, to: to, rows: []ensAddressName{vitalik}, fromName: "vitalik.eth"},
+		{name: "no names", from: from, to: to},
+		{name: "self transfer", from: from, to: from, rows: []ensAddressName{vitalik}, fromName: "vitalik.eth", toName: "vitalik.eth"},
+		{name: "unrelated row", from: from, to: to, rows: []ensAddressName{{Address: other.Bytes(), Name: "other.eth"}}},
+	}
+	for _, tt := range tests {
+		fromName, toName := ensNamesForTx(tt.from, tt.to, tt.rows)
+		if got := ensTestName(fromName); got != tt.fromName {
+			t.Errorf("%v: expected sender name %q, got %q", tt.name, tt.fromName, got)
+		}
+		if got := ensTestName(toName); got != tt.toName {
+			t.Errorf("%v: expected receiver name %q, got %q", tt.name, tt.toName, got)
+		}
+	}
+}
+
+// ensTestName dereferences an optional name, a missing name is empty
+func ensTestName(name *string) string {
+	if name == nil {
+		return ""
+	}
+	return *name
+}
+
+func TestEnsWithdrawalAddress(t *testing.T) {
+	address := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	credentials := func(prefix byte) []byte {
+		return append(append([]byte{prefix}, make([]byte, 11)...), address.Bytes()...)
+	}
+	tests := []struct {
+		name        string
+		credentials []byte
+		ok          bool
+	}{
+		{name: "bls", credentials: credentials(0x00)},
+		{name: "execution", credentials: credentials(0x01), ok: true},
+		{name: "compounding", credentials: credentials(0x02), ok: true},
+		{name: "unknown prefix", credentials: credentials(0x03)},
+		{name: "truncated", credentials: credentials(0x01)[:31]},
+		{name: "empty"},
+	}
+	for _, tt := range tests {
+		got, ok := ensWithdrawalAddress(tt.credentials)
+		if ok != tt.ok {
+			t.Errorf("%v: expected ok %v, got %v", tt.name, tt.ok, ok)
+			continue
+		}
+		if ok && got != address {
+			t.Errorf("%v: expected address %v, got %v", tt.name, address, got)
+		}
+	}
+}
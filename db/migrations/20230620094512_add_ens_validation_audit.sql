@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add ens validation audit table';
+CREATE TABLE IF NOT EXISTS
+    ens_validation_audit (
+        id SERIAL NOT NULL,
+        ens_name TEXT NOT NULL,
+        outcome TEXT NOT NULL,
+        duration_ms INT NOT NULL DEFAULT 0,
+        ts TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW(),
+        PRIMARY KEY (id)
+    );
+CREATE INDEX IF NOT EXISTS idx_ens_validation_audit_ts ON ens_validation_audit (ts);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove ens validation audit table';
+DROP INDEX IF EXISTS idx_ens_validation_audit_ts;
+DROP TABLE IF EXISTS ens_validation_audit;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add checksummed address column to ens';
+ALTER TABLE ens ADD COLUMN IF NOT EXISTS address_hex TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove checksummed address column from ens';
+ALTER TABLE ens DROP COLUMN IF EXISTS address_hex;
+-- +goose StatementEnd
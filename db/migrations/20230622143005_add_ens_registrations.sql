@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add ens registrations table';
+CREATE TABLE IF NOT EXISTS
+    ens_registrations (
+        name_hash bytea NOT NULL,
+        tx_hash bytea NOT NULL,
+        block_number BIGINT NOT NULL,
+        ts TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+        controller bytea NOT NULL,
+        owner bytea NOT NULL,
+        PRIMARY KEY (name_hash, tx_hash)
+    );
+CREATE INDEX IF NOT EXISTS idx_ens_registrations_ts_controller ON ens_registrations (ts, controller);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove ens registrations table';
+DROP INDEX IF EXISTS idx_ens_registrations_ts_controller;
+DROP TABLE IF EXISTS ens_registrations;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add name_undecodable flag to ens';
+ALTER TABLE ens ADD COLUMN IF NOT EXISTS name_undecodable BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove name_undecodable flag from ens';
+ALTER TABLE ens DROP COLUMN IF EXISTS name_undecodable;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add primary_claimed_by to ens';
+ALTER TABLE ens ADD COLUMN IF NOT EXISTS primary_claimed_by bytea;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove primary_claimed_by from ens';
+ALTER TABLE ens DROP COLUMN IF EXISTS primary_claimed_by;
+-- +goose StatementEnd
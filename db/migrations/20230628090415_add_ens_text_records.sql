@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add ens text records table';
+CREATE TABLE IF NOT EXISTS
+    ens_text_records (
+        name_hash bytea NOT NULL,
+        key TEXT NOT NULL,
+        value TEXT NOT NULL,
+        PRIMARY KEY (name_hash, key)
+    );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove ens text records table';
+DROP TABLE IF EXISTS ens_text_records;
+-- +goose StatementEnd
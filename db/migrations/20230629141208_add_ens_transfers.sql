@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add ens transfers table';
+CREATE TABLE IF NOT EXISTS
+    ens_transfers (
+        name_hash bytea NOT NULL,
+        tx_hash bytea NOT NULL,
+        log_index INT NOT NULL,
+        block_number BIGINT NOT NULL,
+        ts TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+        from_address bytea NOT NULL,
+        to_address bytea NOT NULL,
+        PRIMARY KEY (name_hash, tx_hash, log_index)
+    );
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove ens transfers table';
+DROP TABLE IF EXISTS ens_transfers;
+-- +goose StatementEnd
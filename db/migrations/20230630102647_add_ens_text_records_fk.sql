@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - reference ens names from ens text records';
+-- the constraint is not validated so existing orphans do not block the migration, they are removed by CleanOrphanedEnsRecords
+ALTER TABLE ens_text_records ADD CONSTRAINT fk_ens_text_records_name_hash FOREIGN KEY (name_hash) REFERENCES ens (name_hash) ON DELETE CASCADE NOT VALID;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove ens text records reference';
+ALTER TABLE ens_text_records DROP CONSTRAINT IF EXISTS fk_ens_text_records_name_hash;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add tld column to ens';
+ALTER TABLE ens ADD COLUMN IF NOT EXISTS tld TEXT;
+UPDATE ens SET tld = LOWER(SUBSTRING(ens_name FROM '[^.]+$')) WHERE tld IS NULL;
+CREATE INDEX IF NOT EXISTS idx_ens_tld ON ens (tld);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove tld column from ens';
+DROP INDEX IF EXISTS idx_ens_tld;
+ALTER TABLE ens DROP COLUMN IF EXISTS tld;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add last_validated_at column to ens';
+ALTER TABLE ens ADD COLUMN IF NOT EXISTS last_validated_at TIMESTAMP WITHOUT TIME ZONE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove last_validated_at column from ens';
+ALTER TABLE ens DROP COLUMN IF EXISTS last_validated_at;
+-- +goose StatementEnd
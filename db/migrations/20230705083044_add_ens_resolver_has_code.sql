@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add resolver_has_code column to ens';
+ALTER TABLE ens ADD COLUMN IF NOT EXISTS resolver_has_code BOOLEAN;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove resolver_has_code column from ens';
+ALTER TABLE ens DROP COLUMN IF EXISTS resolver_has_code;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add chain_id column to ens registrations';
+-- registrations stored before this column existed belong to the single chain the deployment was indexing, they are left NULL
+ALTER TABLE ens_registrations ADD COLUMN IF NOT EXISTS chain_id BIGINT;
+CREATE INDEX IF NOT EXISTS idx_ens_registrations_chain_id_ts ON ens_registrations (chain_id, ts);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove chain_id column from ens registrations';
+DROP INDEX IF EXISTS idx_ens_registrations_chain_id_ts;
+ALTER TABLE ens_registrations DROP COLUMN IF EXISTS chain_id;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add registration_tx column to ens';
+ALTER TABLE ens ADD COLUMN IF NOT EXISTS registration_tx bytea;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove registration_tx column from ens';
+ALTER TABLE ens DROP COLUMN IF EXISTS registration_tx;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add referrer column to ens_registrations';
+ALTER TABLE ens_registrations ADD COLUMN IF NOT EXISTS referrer bytea;
+CREATE INDEX IF NOT EXISTS idx_ens_registrations_referrer_ts ON ens_registrations (referrer, ts) WHERE referrer IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove referrer column from ens_registrations';
+DROP INDEX IF EXISTS idx_ens_registrations_referrer_ts;
+ALTER TABLE ens_registrations DROP COLUMN IF EXISTS referrer;
+-- +goose StatementEnd
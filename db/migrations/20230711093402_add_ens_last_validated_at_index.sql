@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add index on ens last_validated_at';
+CREATE INDEX IF NOT EXISTS idx_ens_last_validated_at ON ens (last_validated_at ASC NULLS FIRST);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove index on ens last_validated_at';
+DROP INDEX IF EXISTS idx_ens_last_validated_at;
+-- +goose StatementEnd
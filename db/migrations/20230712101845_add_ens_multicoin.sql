@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add ens multicoin table';
+CREATE TABLE IF NOT EXISTS
+    ens_multicoin (
+        name_hash bytea NOT NULL,
+        coin_type BIGINT NOT NULL,
+        address bytea NOT NULL,
+        block_number BIGINT NOT NULL,
+        PRIMARY KEY (name_hash, coin_type)
+    );
+CREATE INDEX IF NOT EXISTS idx_ens_multicoin_coin_type_address ON ens_multicoin (coin_type, address);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove ens multicoin table';
+DROP INDEX IF EXISTS idx_ens_multicoin_coin_type_address;
+DROP TABLE IF EXISTS ens_multicoin;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add avatar_url column to ens';
+ALTER TABLE ens ADD COLUMN IF NOT EXISTS avatar_url TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove avatar_url column from ens';
+ALTER TABLE ens DROP COLUMN IF EXISTS avatar_url;
+-- +goose StatementEnd
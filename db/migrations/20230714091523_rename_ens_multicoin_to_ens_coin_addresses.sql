@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - rename ens multicoin table to ens coin addresses';
+ALTER TABLE ens_multicoin RENAME TO ens_coin_addresses;
+ALTER TABLE ens_coin_addresses RENAME COLUMN address TO address_bytes;
+ALTER INDEX IF EXISTS idx_ens_multicoin_coin_type_address RENAME TO idx_ens_coin_addresses_coin_type_address_bytes;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - rename ens coin addresses table to ens multicoin';
+ALTER INDEX IF EXISTS idx_ens_coin_addresses_coin_type_address_bytes RENAME TO idx_ens_multicoin_coin_type_address;
+ALTER TABLE ens_coin_addresses RENAME COLUMN address_bytes TO address;
+ALTER TABLE ens_coin_addresses RENAME TO ens_multicoin;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add resolver_address column to ens';
+ALTER TABLE ens ADD COLUMN IF NOT EXISTS resolver_address BYTEA;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove resolver_address column from ens';
+ALTER TABLE ens DROP COLUMN IF EXISTS resolver_address;
+-- +goose StatementEnd
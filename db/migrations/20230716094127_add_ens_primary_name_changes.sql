@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add ens_primary_name_changes table';
+CREATE TABLE IF NOT EXISTS ens_primary_name_changes (
+    id SERIAL PRIMARY KEY,
+    address BYTEA NOT NULL,
+    old_name TEXT NOT NULL,
+    new_name TEXT NOT NULL,
+    ts TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT now(),
+    notified_at TIMESTAMP WITHOUT TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS idx_ens_primary_name_changes_unnotified ON ens_primary_name_changes (id) WHERE notified_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - drop ens_primary_name_changes table';
+DROP TABLE IF EXISTS ens_primary_name_changes;
+-- +goose StatementEnd
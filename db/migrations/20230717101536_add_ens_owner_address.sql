@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add owner_address column to ens';
+ALTER TABLE ens ADD COLUMN IF NOT EXISTS owner_address BYTEA;
+-- +goose StatementEnd
+-- +goose StatementBegin
+SELECT 'up SQL query - backfill owner_address from the latest registration of every name';
+UPDATE ens
+SET owner_address = latest.owner
+FROM (
+    SELECT DISTINCT ON (name_hash) name_hash, owner
+    FROM ens_registrations
+    ORDER BY name_hash, block_number DESC
+) latest
+WHERE ens.name_hash = latest.name_hash AND ens.owner_address IS NULL;
+-- +goose StatementEnd
+-- +goose StatementBegin
+SELECT 'up SQL query - add index on ens owner_address';
+CREATE INDEX IF NOT EXISTS idx_ens_owner_address ON ens (owner_address);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove owner_address column from ens';
+DROP INDEX IF EXISTS idx_ens_owner_address;
+ALTER TABLE ens DROP COLUMN IF EXISTS owner_address;
+-- +goose StatementEnd
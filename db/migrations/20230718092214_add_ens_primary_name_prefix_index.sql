@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add prefix index on ens primary names';
+CREATE INDEX IF NOT EXISTS idx_ens_primary_name_prefix ON ens (ens_name text_pattern_ops) WHERE is_primary_name;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove prefix index on ens primary names';
+DROP INDEX IF EXISTS idx_ens_primary_name_prefix;
+-- +goose StatementEnd
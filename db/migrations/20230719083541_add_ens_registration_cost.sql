@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add cost columns to ens_registrations and add ens renewals table';
+ALTER TABLE ens_registrations ADD COLUMN IF NOT EXISTS cost_wei NUMERIC;
+ALTER TABLE ens_registrations ADD COLUMN IF NOT EXISTS premium_wei NUMERIC;
+CREATE INDEX IF NOT EXISTS idx_ens_registrations_cost_wei ON ens_registrations (cost_wei DESC) WHERE cost_wei IS NOT NULL;
+CREATE TABLE IF NOT EXISTS
+    ens_renewals (
+        name_hash bytea NOT NULL,
+        tx_hash bytea NOT NULL,
+        log_index INT NOT NULL,
+        block_number BIGINT NOT NULL,
+        ts TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+        cost_wei NUMERIC NOT NULL,
+        chain_id BIGINT,
+        PRIMARY KEY (name_hash, tx_hash, log_index)
+    );
+CREATE INDEX IF NOT EXISTS idx_ens_renewals_ts ON ens_renewals (ts);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove cost columns from ens_registrations and remove ens renewals table';
+DROP INDEX IF EXISTS idx_ens_renewals_ts;
+DROP TABLE IF EXISTS ens_renewals;
+DROP INDEX IF EXISTS idx_ens_registrations_cost_wei;
+ALTER TABLE ens_registrations DROP COLUMN IF EXISTS premium_wei;
+ALTER TABLE ens_registrations DROP COLUMN IF EXISTS cost_wei;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - require 32 byte name hashes in ens';
+-- rows with a malformed name hash can never be matched by a lookup of a name, so they are removed before the constraint is added
+DELETE FROM ens WHERE octet_length(name_hash) <> 32;
+ALTER TABLE ens ADD CONSTRAINT chk_ens_name_hash_length CHECK (octet_length(name_hash) = 32);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove ens name hash length check';
+ALTER TABLE ens DROP CONSTRAINT IF EXISTS chk_ens_name_hash_length;
+-- +goose StatementEnd
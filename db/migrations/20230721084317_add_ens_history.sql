@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - add ens history table';
+CREATE TABLE IF NOT EXISTS
+    ens_history (
+        name_hash bytea NOT NULL,
+        address bytea NOT NULL,
+        valid_from_block BIGINT NOT NULL,
+        valid_to_block BIGINT,
+        PRIMARY KEY (name_hash, valid_from_block)
+    );
+CREATE INDEX IF NOT EXISTS idx_ens_history_address_valid_from_block ON ens_history (address, valid_from_block);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove ens history table';
+DROP INDEX IF EXISTS idx_ens_history_address_valid_from_block;
+DROP TABLE IF EXISTS ens_history;
+-- +goose StatementEnd
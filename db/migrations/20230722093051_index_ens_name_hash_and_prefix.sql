@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - index ens names by hash and prefix so long names fit into the btree indexes';
+-- equality lookups use the md5 of the name, the full name only narrows the matches of the hash
+DROP INDEX IF EXISTS idx_ens_name;
+DROP INDEX IF EXISTS idx_ens_valid_name;
+CREATE INDEX IF NOT EXISTS idx_ens_valid_name ON ens (md5(ens_name), valid_to);
+DROP INDEX IF EXISTS idx_ens_primary_name_prefix;
+CREATE INDEX IF NOT EXISTS idx_ens_primary_name_prefix ON ens (left(ens_name, 256) text_pattern_ops) WHERE is_primary_name;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - index ens names by their full value';
+DROP INDEX IF EXISTS idx_ens_primary_name_prefix;
+CREATE INDEX IF NOT EXISTS idx_ens_primary_name_prefix ON ens (ens_name text_pattern_ops) WHERE is_primary_name;
+DROP INDEX IF EXISTS idx_ens_valid_name;
+CREATE INDEX IF NOT EXISTS idx_ens_valid_name ON ens (ens_name, valid_to);
+CREATE INDEX IF NOT EXISTS idx_ens_name ON ens (ens_name);
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - allow at most one primary ens name per address';
+UPDATE ens
+SET
+    is_primary_name = false,
+    primary_claimed_by = NULL
+WHERE
+    is_primary_name AND
+    name_hash NOT IN (
+        SELECT DISTINCT ON (address) name_hash
+        FROM ens
+        WHERE is_primary_name AND address IS NOT NULL
+        ORDER BY address, last_validated_at DESC NULLS LAST
+    ) AND
+    address IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ens_unique_primary_address ON ens (address) WHERE is_primary_name;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove unique primary ens name index';
+DROP INDEX IF EXISTS idx_ens_unique_primary_address;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - reference ens names from ens coin addresses and ens history';
+-- the constraints are not validated so existing orphans do not block the migration, they are removed by CleanOrphanedEnsRecords
+ALTER TABLE ens_coin_addresses ADD CONSTRAINT fk_ens_coin_addresses_name_hash FOREIGN KEY (name_hash) REFERENCES ens (name_hash) ON DELETE CASCADE NOT VALID;
+ALTER TABLE ens_history ADD CONSTRAINT fk_ens_history_name_hash FOREIGN KEY (name_hash) REFERENCES ens (name_hash) ON DELETE CASCADE NOT VALID;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove ens coin addresses and ens history references';
+ALTER TABLE ens_history DROP CONSTRAINT IF EXISTS fk_ens_history_name_hash;
+ALTER TABLE ens_coin_addresses DROP CONSTRAINT IF EXISTS fk_ens_coin_addresses_name_hash;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query - index the primary name changes by address for the history of the primary names';
+CREATE INDEX IF NOT EXISTS idx_ens_primary_name_changes_address_ts ON ens_primary_name_changes (address, ts);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query - remove the address index of the primary name changes';
+DROP INDEX IF EXISTS idx_ens_primary_name_changes_address_ts;
+-- +goose StatementEnd
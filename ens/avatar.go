@@ -0,0 +1,70 @@
+package ens
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ensAvatarNFTData contains the meta data of the ERC721 and ERC1155 functions that are needed to verify NFT avatars.
+var ensAvatarNFTData = &bind.MetaData{
+	ABI: "[{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"tokenId\",\"type\":\"uint256\"}],\"name\":\"ownerOf\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"tokenId\",\"type\":\"uint256\"}],\"name\":\"tokenURI\",\"outputs\":[{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"account\",\"type\":\"address\"},{\"internalType\":\"uint256\",\"name\":\"id\",\"type\":\"uint256\"}],\"name\":\"balanceOf\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"id\",\"type\":\"uint256\"}],\"name\":\"uri\",\"outputs\":[{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"}],\"stateMutability\":\"view\",\"type\":\"function\"}]",
+	Bin: "",
+}
+
+// AvatarNFTCaller is a read-only Go binding around the ERC721 or ERC1155 contract of an NFT avatar.
+type AvatarNFTCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// NewAvatarNFTCaller creates a new read-only instance of an NFT contract, bound to a specific deployed contract.
+func NewAvatarNFTCaller(address common.Address, caller bind.ContractCaller) (*AvatarNFTCaller, error) {
+	parsed, err := abi.JSON(strings.NewReader(ensAvatarNFTData.ABI))
+	if err != nil {
+		return nil, err
+	}
+	return &AvatarNFTCaller{contract: bind.NewBoundContract(address, parsed, caller, nil, nil)}, nil
+}
+
+// Solidity: function ownerOf(uint256 tokenId) view returns(address)
+func (_AvatarNFT *AvatarNFTCaller) OwnerOf(opts *bind.CallOpts, tokenId *big.Int) (common.Address, error) {
+	var out []interface{}
+	err := _AvatarNFT.contract.Call(opts, &out, "ownerOf", tokenId)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return *abi.ConvertType(out[0], new(common.Address)).(*common.Address), nil
+}
+
+// Solidity: function tokenURI(uint256 tokenId) view returns(string)
+func (_AvatarNFT *AvatarNFTCaller) TokenURI(opts *bind.CallOpts, tokenId *big.Int) (string, error) {
+	var out []interface{}
+	err := _AvatarNFT.contract.Call(opts, &out, "tokenURI", tokenId)
+	if err != nil {
+		return "", err
+	}
+	return *abi.ConvertType(out[0], new(string)).(*string), nil
+}
+
+// Solidity: function balanceOf(address account, uint256 id) view returns(uint256)
+func (_AvatarNFT *AvatarNFTCaller) BalanceOf(opts *bind.CallOpts, account common.Address, id *big.Int) (*big.Int, error) {
+	var out []interface{}
+	err := _AvatarNFT.contract.Call(opts, &out, "balanceOf", account, id)
+	if err != nil {
+		return nil, err
+	}
+	return *abi.ConvertType(out[0], new(*big.Int)).(**big.Int), nil
+}
+
+// Solidity: function uri(uint256 id) view returns(string)
+func (_AvatarNFT *AvatarNFTCaller) Uri(opts *bind.CallOpts, id *big.Int) (string, error) {
+	var out []interface{}
+	err := _AvatarNFT.contract.Call(opts, &out, "uri", id)
+	if err != nil {
+		return "", err
+	}
+	return *abi.ConvertType(out[0], new(string)).(*string), nil
+}
@@ -16,6 +16,12 @@ var ensRegistrarData = &bind.MetaData{
 	Bin: "",
 }
 
+// ensReferralRegistrarData contains the events of registrar controllers that support referrers
+var ensReferralRegistrarData = &bind.MetaData{
+	ABI: "[{\"anonymous\":false,\"inputs\":[{\"indexed\":false,\"internalType\":\"string\",\"name\":\"label\",\"type\":\"string\"},{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"labelhash\",\"type\":\"bytes32\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"baseCost\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"premium\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"expires\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"bytes32\",\"name\":\"referrer\",\"type\":\"bytes32\"}],\"name\":\"NameRegistered\",\"type\":\"event\"}]",
+	Bin: "",
+}
+
 var ensResolverControllerData = &bind.MetaData{
 	ABI: "[{\"inputs\":[{\"internalType\":\"contract ENS\",\"name\":\"_old\",\"type\":\"address\"}],\"payable\":false,\"stateMutability\":\"nonpayable\",\"type\":\"constructor\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"operator\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"bool\",\"name\":\"approved\",\"type\":\"bool\"}],\"name\":\"ApprovalForAll\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"},{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"label\",\"type\":\"bytes32\"},{\"indexed\":false,\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"}],\"name\":\"NewOwner\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"},{\"indexed\":false,\"internalType\":\"address\",\"name\":\"resolver\",\"type\":\"address\"}],\"name\":\"NewResolver\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"},{\"indexed\":false,\"internalType\":\"uint64\",\"name\":\"ttl\",\"type\":\"uint64\"}],\"name\":\"NewTTL\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"},{\"indexed\":false,\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"}],\"name\":\"Transfer\",\"type\":\"event\"},{\"constant\":true,\"inputs\":[{\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"internalType\":\"address\",\"name\":\"operator\",\"type\":\"address\"}],\"name\":\"isApprovedForAll\",\"outputs\":[{\"internalType\":\"bool\",\"name\":\"\",\"type\":\"bool\"}],\"payable\":false,\"stateMutability\":\"view\",\"type\":\"function\"},{\"constant\":true,\"inputs\":[],\"name\":\"old\",\"outputs\":[{\"internalType\":\"contract ENS\",\"name\":\"\",\"type\":\"address\"}],\"payable\":false,\"stateMutability\":\"view\",\"type\":\"function\"},{\"constant\":true,\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"}],\"name\":\"owner\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"payable\":false,\"stateMutability\":\"view\",\"type\":\"function\"},{\"constant\":true,\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"}],\"name\":\"recordExists\",\"outputs\":[{\"internalType\":\"bool\",\"name\":\"\",\"type\":\"bool\"}],\"payable\":false,\"stateMutability\":\"view\",\"type\":\"function\"},{\"constant\":true,\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"}],\"name\":\"resolver\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"payable\":false,\"stateMutability\":\"view\",\"type\":\"function\"},{\"constant\":false,\"inputs\":[{\"internalType\":\"address\",\"name\":\"operator\",\"type\":\"address\"},{\"internalType\":\"bool\",\"name\":\"approved\",\"type\":\"bool\"}],\"name\":\"setApprovalForAll\",\"outputs\":[],\"payable\":false,\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"constant\":false,\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"},{\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"}],\"name\":\"setOwner\",\"outputs\":[],\"payable\":false,\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"constant\":false,\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"},{\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"internalType\":\"address\",\"name\":\"resolver\",\"type\":\"address\"},{\"internalType\":\"uint64\",\"name\":\"ttl\",\"type\":\"uint64\"}],\"name\":\"setRecord\",\"outputs\":[],\"payable\":false,\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"constant\":false,\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"},{\"internalType\":\"address\",\"name\":\"resolver\",\"type\":\"address\"}],\"name\":\"setResolver\",\"outputs\":[],\"payable\":false,\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"constant\":false,\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"},{\"internalType\":\"bytes32\",\"name\":\"label\",\"type\":\"bytes32\"},{\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"}],\"name\":\"setSubnodeOwner\",\"outputs\":[{\"internalType\":\"bytes32\",\"name\":\"\",\"type\":\"bytes32\"}],\"payable\":false,\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"constant\":false,\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"},{\"internalType\":\"bytes32\",\"name\":\"label\",\"type\":\"bytes32\"},{\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"internalType\":\"address\",\"name\":\"resolver\",\"type\":\"address\"},{\"internalType\":\"uint64\",\"name\":\"ttl\",\"type\":\"uint64\"}],\"name\":\"setSubnodeRecord\",\"outputs\":[],\"payable\":false,\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"constant\":false,\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"},{\"internalType\":\"uint64\",\"name\":\"ttl\",\"type\":\"uint64\"}],\"name\":\"setTTL\",\"outputs\":[],\"payable\":false,\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"constant\":true,\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"}],\"name\":\"ttl\",\"outputs\":[{\"internalType\":\"uint64\",\"name\":\"\",\"type\":\"uint64\"}],\"payable\":false,\"stateMutability\":\"view\",\"type\":\"function\"}]",
 	Bin: "",
@@ -26,6 +32,11 @@ var ensResolverData = &bind.MetaData{
 	Bin: "",
 }
 
+var ensBaseRegistrarData = &bind.MetaData{
+	ABI: "[{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"controller\",\"type\":\"address\"}],\"name\":\"ControllerAdded\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"controller\",\"type\":\"address\"}],\"name\":\"ControllerRemoved\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"from\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"to\",\"type\":\"address\"},{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"tokenId\",\"type\":\"uint256\"}],\"name\":\"Transfer\",\"type\":\"event\"}]",
+	Bin: "",
+}
+
 // NameRegistered represents an NameRegistered event raised by the Ens Registar contract.
 type NameRegistered struct {
 	Name    string
@@ -36,6 +47,18 @@ type NameRegistered struct {
 	Raw     types.Log // Blockchain specific contextual infos
 }
 
+// NameRegisteredWithReferrer represents a NameRegistered event raised by a registrar controller that supports referrers.
+type NameRegisteredWithReferrer struct {
+	Label     string
+	Labelhash [32]byte
+	Owner     common.Address
+	BaseCost  *big.Int
+	Premium   *big.Int
+	Expires   *big.Int
+	Referrer  [32]byte
+	Raw       types.Log // Blockchain specific contextual infos
+}
+
 // NameRenewed represents an NameRenewed event raised by the Ens Registar contract.
 type NameRenewed struct {
 	Name    string
@@ -53,6 +76,15 @@ type AddressChanged struct {
 	Raw        types.Log // Blockchain specific contextual infos
 }
 
+// TextChanged represents a TextChanged event raised by an ENS Resolver contract.
+type TextChanged struct {
+	Node       [32]byte
+	IndexedKey common.Hash
+	Key        string
+	Value      string
+	Raw        types.Log // Blockchain specific contextual infos
+}
+
 // NameChanged represents an NameChanged event raised by an ENS Resolver contract.
 type NameChanged struct {
 	Node [32]byte
@@ -75,11 +107,35 @@ type NewResolver struct {
 	Raw      types.Log // Blockchain specific contextual infos
 }
 
+// ControllerAdded represents an ControllerAdded event raised by the Ens base registrar contract.
+type ControllerAdded struct {
+	Controller common.Address
+	Raw        types.Log // Blockchain specific contextual infos
+}
+
+// ControllerRemoved represents an ControllerRemoved event raised by the Ens base registrar contract.
+type ControllerRemoved struct {
+	Controller common.Address
+	Raw        types.Log // Blockchain specific contextual infos
+}
+
+// RegistrarTransfer represents an ERC721 Transfer event raised by the Ens base registrar contract, the token id is the label hash of the name.
+type RegistrarTransfer struct {
+	From    common.Address
+	To      common.Address
+	TokenId *big.Int
+	Raw     types.Log // Blockchain specific contextual infos
+}
+
 // EnsFilterer is a log filtering Go binding around an Ethereum contract events.
 type EnsRegistrarFilterer struct {
 	contract                   *bind.BoundContract // Generic contract wrapper for the low level calls
 	resolverControllerContract *bind.BoundContract // contract wrapper for resolver controller contract
 	resolverContract           *bind.BoundContract // contract wrapper for resolver contract
+	baseRegistrarContract      *bind.BoundContract // contract wrapper for base registrar contract
+	referralRegistrarContract  *bind.BoundContract // contract wrapper for registrar controllers that support referrers
+	nameWrapperContract        *bind.BoundContract // contract wrapper for the name wrapper contract
+	legacyRegistrarContract    *bind.BoundContract // contract wrapper for the legacy auction and permanent registrars
 }
 
 // NewEnsRegistrarFilterer creates a new log filterer instance of Ens Registart, bound to a specific deployed contract.
@@ -96,10 +152,30 @@ func NewEnsRegistrarFilterer(address common.Address, filterer bind.ContractFilte
 	if err != nil {
 		return nil, err
 	}
+	baseRegistrarContract, err := bindEnsBaseRegistrar(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	referralRegistrarContract, err := bindEnsReferralRegistrar(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	nameWrapperContract, err := bindEnsNameWrapper(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	legacyRegistrarContract, err := bindEnsLegacyRegistrar(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
 	return &EnsRegistrarFilterer{
 		contract:                   contract,
 		resolverControllerContract: resolverControllerContract,
-		resolverContract:           resolverContract}, nil
+		resolverContract:           resolverContract,
+		baseRegistrarContract:      baseRegistrarContract,
+		referralRegistrarContract:  referralRegistrarContract,
+		nameWrapperContract:        nameWrapperContract,
+		legacyRegistrarContract:    legacyRegistrarContract}, nil
 }
 
 // bindEnsRegistarController binds a generic wrapper to an already deployed contract.
@@ -129,10 +205,38 @@ func bindEnsResolver(address common.Address, caller bind.ContractCaller, transac
 	return bind.NewBoundContract(address, parsed, caller, transactor, filterer), nil
 }
 
+// bindEnsBaseRegistrar binds a generic wrapper to an already deployed contract.
+func bindEnsBaseRegistrar(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := abi.JSON(strings.NewReader(ensBaseRegistrarData.ABI))
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, parsed, caller, transactor, filterer), nil
+}
+
+// bindEnsReferralRegistrar binds a generic wrapper to an already deployed contract.
+func bindEnsReferralRegistrar(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := abi.JSON(strings.NewReader(ensReferralRegistrarData.ABI))
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, parsed, caller, transactor, filterer), nil
+}
+
 // Solidity: event NameRegistered(string name, bytes32 indexed label, address indexed owner, uint cost, uint expires);
 func (_EnsRegistrar *EnsRegistrarFilterer) ParseNameRegistered(log types.Log) (*NameRegistered, error) {
 	event := new(NameRegistered)
-	if err := _EnsRegistrar.contract.UnpackLog(event, "NameRegistered", log); err != nil {
+	if err := _EnsRegistrar.contract.UnpackLog(event, "NameRegistered", canonicalLog(log)); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// Solidity: event NameRegistered(string label, bytes32 indexed labelhash, address indexed owner, uint256 baseCost, uint256 premium, uint256 expires, bytes32 referrer);
+func (_EnsRegistrar *EnsRegistrarFilterer) ParseNameRegisteredWithReferrer(log types.Log) (*NameRegisteredWithReferrer, error) {
+	event := new(NameRegisteredWithReferrer)
+	if err := _EnsRegistrar.referralRegistrarContract.UnpackLog(event, "NameRegistered", canonicalLog(log)); err != nil {
 		return nil, err
 	}
 	event.Raw = log
@@ -142,7 +246,7 @@ func (_EnsRegistrar *EnsRegistrarFilterer) ParseNameRegistered(log types.Log) (*
 // Solidity: event NewResolver (index_topic_1 bytes32 node, address resolver)
 func (_EnsRegistrar *EnsRegistrarFilterer) ParseNewResolver(log types.Log) (*NewResolver, error) {
 	event := new(NewResolver)
-	if err := _EnsRegistrar.resolverControllerContract.UnpackLog(event, "NewResolver", log); err != nil {
+	if err := _EnsRegistrar.resolverControllerContract.UnpackLog(event, "NewResolver", canonicalLog(log)); err != nil {
 		return nil, err
 	}
 	event.Raw = log
@@ -152,7 +256,7 @@ func (_EnsRegistrar *EnsRegistrarFilterer) ParseNewResolver(log types.Log) (*New
 // Solidity: event NewOwner(bytes32 indexed node, bytes32 indexed label, address owner);
 func (_EnsRegistrar *EnsRegistrarFilterer) ParseNewOwner(log types.Log) (*NewOwner, error) {
 	event := new(NewOwner)
-	if err := _EnsRegistrar.resolverControllerContract.UnpackLog(event, "NewOwner", log); err != nil {
+	if err := _EnsRegistrar.resolverControllerContract.UnpackLog(event, "NewOwner", canonicalLog(log)); err != nil {
 		return nil, err
 	}
 	event.Raw = log
@@ -162,7 +266,17 @@ func (_EnsRegistrar *EnsRegistrarFilterer) ParseNewOwner(log types.Log) (*NewOwn
 // Solidity: event NameRenewed(string name, bytes32 indexed label, address indexed owner, uint cost, uint expires);
 func (_EnsRegistrar *EnsRegistrarFilterer) ParseNameRenewed(log types.Log) (*NameRenewed, error) {
 	event := new(NameRenewed)
-	if err := _EnsRegistrar.contract.UnpackLog(event, "NameRenewed", log); err != nil {
+	if err := _EnsRegistrar.contract.UnpackLog(event, "NameRenewed", canonicalLog(log)); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// Solidity: event TextChanged(bytes32 indexed node, string indexed indexedKey, string key, string value);
+func (_EnsRegistrar *EnsRegistrarFilterer) ParseTextChanged(log types.Log) (*TextChanged, error) {
+	event := new(TextChanged)
+	if err := _EnsRegistrar.resolverContract.UnpackLog(event, "TextChanged", canonicalLog(log)); err != nil {
 		return nil, err
 	}
 	event.Raw = log
@@ -172,7 +286,7 @@ func (_EnsRegistrar *EnsRegistrarFilterer) ParseNameRenewed(log types.Log) (*Nam
 // Solidity: event AddressChanged (index_topic_1 bytes32 node, uint256 coinType, bytes newAddress);
 func (_EnsRegistrar *EnsRegistrarFilterer) ParseAddressChanged(log types.Log) (*AddressChanged, error) {
 	event := new(AddressChanged)
-	if err := _EnsRegistrar.resolverContract.UnpackLog(event, "AddressChanged", log); err != nil {
+	if err := _EnsRegistrar.resolverContract.UnpackLog(event, "AddressChanged", canonicalLog(log)); err != nil {
 		return nil, err
 	}
 	event.Raw = log
@@ -182,7 +296,37 @@ func (_EnsRegistrar *EnsRegistrarFilterer) ParseAddressChanged(log types.Log) (*
 // Solidity: event AddressChanged (index_topic_1 bytes32 node, uint256 coinType, bytes newAddress);
 func (_EnsRegistrar *EnsRegistrarFilterer) ParseNameChanged(log types.Log) (*NameChanged, error) {
 	event := new(NameChanged)
-	if err := _EnsRegistrar.resolverContract.UnpackLog(event, "NameChanged", log); err != nil {
+	if err := _EnsRegistrar.resolverContract.UnpackLog(event, "NameChanged", canonicalLog(log)); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// Solidity: event ControllerAdded(address indexed controller);
+func (_EnsRegistrar *EnsRegistrarFilterer) ParseControllerAdded(log types.Log) (*ControllerAdded, error) {
+	event := new(ControllerAdded)
+	if err := _EnsRegistrar.baseRegistrarContract.UnpackLog(event, "ControllerAdded", canonicalLog(log)); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// Solidity: event ControllerRemoved(address indexed controller);
+func (_EnsRegistrar *EnsRegistrarFilterer) ParseControllerRemoved(log types.Log) (*ControllerRemoved, error) {
+	event := new(ControllerRemoved)
+	if err := _EnsRegistrar.baseRegistrarContract.UnpackLog(event, "ControllerRemoved", canonicalLog(log)); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// Solidity: event Transfer(address indexed from, address indexed to, uint256 indexed tokenId);
+func (_EnsRegistrar *EnsRegistrarFilterer) ParseRegistrarTransfer(log types.Log) (*RegistrarTransfer, error) {
+	event := new(RegistrarTransfer)
+	if err := _EnsRegistrar.baseRegistrarContract.UnpackLog(event, "Transfer", canonicalLog(log)); err != nil {
 		return nil, err
 	}
 	event.Raw = log
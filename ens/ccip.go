@@ -0,0 +1,264 @@
+package ens
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ensCcipReadData contains the meta data of the EIP-3668 OffchainLookup error and the ENSIP-10 resolver functions needed to resolve offchain names.
+var ensCcipReadData = &bind.MetaData{
+	ABI: "[{\"inputs\":[{\"internalType\":\"address\",\"name\":\"sender\",\"type\":\"address\"},{\"internalType\":\"string[]\",\"name\":\"urls\",\"type\":\"string[]\"},{\"internalType\":\"bytes\",\"name\":\"callData\",\"type\":\"bytes\"},{\"internalType\":\"bytes4\",\"name\":\"callbackFunction\",\"type\":\"bytes4\"},{\"internalType\":\"bytes\",\"name\":\"extraData\",\"type\":\"bytes\"}],\"name\":\"OffchainLookup\",\"type\":\"error\"},{\"inputs\":[{\"internalType\":\"bytes\",\"name\":\"name\",\"type\":\"bytes\"},{\"internalType\":\"bytes\",\"name\":\"data\",\"type\":\"bytes\"}],\"name\":\"resolve\",\"outputs\":[{\"internalType\":\"bytes\",\"name\":\"\",\"type\":\"bytes\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"}],\"name\":\"addr\",\"outputs\":[{\"internalType\":\"address payable\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes4\",\"name\":\"interfaceID\",\"type\":\"bytes4\"}],\"name\":\"supportsInterface\",\"outputs\":[{\"internalType\":\"bool\",\"name\":\"\",\"type\":\"bool\"}],\"stateMutability\":\"view\",\"type\":\"function\"}]",
+	Bin: "",
+}
+
+// OffchainLookupSelector is the selector of the OffchainLookup(address,string[],bytes,bytes4,bytes) revert of EIP-3668
+var OffchainLookupSelector = []byte{0x55, 0x6f, 0x18, 0x30}
+
+// ExtendedResolverInterfaceId is the ENSIP-10 interface id of resolvers implementing resolve(bytes,bytes)
+var ExtendedResolverInterfaceId = [4]byte{0x90, 0x61, 0xb9, 0x23}
+
+// CcipMaxLookups is the maximum number of offchain lookups followed for a single call, as recommended by EIP-3668
+const CcipMaxLookups = 4
+
+// ErrCcipGatewayUnavailable is returned if none of the gateways of an offchain lookup answered, the lookup may succeed when retried
+var ErrCcipGatewayUnavailable = errors.New("ccip-read gateway unavailable")
+
+// OffchainLookup represents an OffchainLookup revert of a contract that asks the caller to fetch the answer from one of the gateway urls.
+type OffchainLookup struct {
+	Sender           common.Address
+	Urls             []string
+	CallData         []byte
+	CallbackFunction [4]byte
+	ExtraData        []byte
+}
+
+// ParseOffchainLookup returns the OffchainLookup a failed eth_call reverted with, ok is false if the call failed for any other reason
+func ParseOffchainLookup(err error) (lookup *OffchainLookup, ok bool) {
+	var dataErr rpc.DataError
+	if err == nil || !errors.As(err, &dataErr) {
+		return nil, false
+	}
+	hexData, isString := dataErr.ErrorData().(string)
+	if !isString {
+		return nil, false
+	}
+	data, decodeErr := hexutil.Decode(hexData)
+	if decodeErr != nil || len(data) < 4 || !bytes.Equal(data[:4], OffchainLookupSelector) {
+		return nil, false
+	}
+	parsed, parseErr := abi.JSON(strings.NewReader(ensCcipReadData.ABI))
+	if parseErr != nil {
+		return nil, false
+	}
+	values, unpackErr := parsed.Errors["OffchainLookup"].Inputs.Unpack(data[4:])
+	if unpackErr != nil || len(values) != 5 {
+		return nil, false
+	}
+	return &OffchainLookup{
+		Sender:           *abi.ConvertType(values[0], new(common.Address)).(*common.Address),
+		Urls:             *abi.ConvertType(values[1], new([]string)).(*[]string),
+		CallData:         *abi.ConvertType(values[2], new([]byte)).(*[]byte),
+		CallbackFunction: *abi.ConvertType(values[3], new([4]byte)).(*[4]byte),
+		ExtraData:        *abi.ConvertType(values[4], new([]byte)).(*[]byte),
+	}, true
+}
+
+// CcipReadCaller performs eth_calls that follow the OffchainLookup reverts of EIP-3668 by querying the gateways and calling back the contract.
+type CcipReadCaller struct {
+	caller     bind.ContractCaller
+	httpClient *http.Client
+	abi        abi.ABI
+}
+
+// NewCcipReadCaller creates a new ccip-read caller, the http client is used for the gateway requests.
+func NewCcipReadCaller(caller bind.ContractCaller, httpClient *http.Client) (*CcipReadCaller, error) {
+	parsed, err := abi.JSON(strings.NewReader(ensCcipReadData.ABI))
+	if err != nil {
+		return nil, err
+	}
+	return &CcipReadCaller{caller: caller, httpClient: httpClient, abi: parsed}, nil
+}
+
+// Call calls a contract and follows its offchain lookups until it returns a result
+func (c *CcipReadCaller) Call(ctx context.Context, to common.Address, data []byte) ([]byte, error) {
+	for lookups := 0; lookups <= CcipMaxLookups; lookups++ {
+		result, err := c.caller.CallContract(ctx, ethereum.CallMsg{To: &to, Data: data}, nil)
+		if err == nil {
+			return result, nil
+		}
+		lookup, ok := ParseOffchainLookup(err)
+		if !ok {
+			return nil, err
+		}
+		if lookup.Sender != to {
+			return nil, fmt.Errorf("offchain lookup sender %v does not match the called contract %v", lookup.Sender, to)
+		}
+		response, err := c.fetchGateway(ctx, lookup)
+		if err != nil {
+			return nil, err
+		}
+		data, err = packCcipCallback(lookup.CallbackFunction, response, lookup.ExtraData)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("contract %v exceeded the maximum of %v offchain lookups", to, CcipMaxLookups)
+}
+
+// ResolveAddress resolves the address of a name through its resolver, resolvers implementing ENSIP-10 are asked via resolve(bytes,bytes)
+// so wildcard resolvers can answer for names without a node of their own
+func (c *CcipReadCaller) ResolveAddress(ctx context.Context, resolver common.Address, name string, node [32]byte) (common.Address, error) {
+	addrData, err := c.abi.Pack("addr", node)
+	if err != nil {
+		return common.Address{}, err
+	}
+	data := addrData
+	extended, err := c.supportsInterface(ctx, resolver, ExtendedResolverInterfaceId)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if extended {
+		dnsName, err := EncodeDnsName(name)
+		if err != nil {
+			return common.Address{}, err
+		}
+		data, err = c.abi.Pack("resolve", dnsName, addrData)
+		if err != nil {
+			return common.Address{}, err
+		}
+	}
+	result, err := c.Call(ctx, resolver, data)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if extended {
+		out, err := c.abi.Unpack("resolve", result)
+		if err != nil {
+			return common.Address{}, err
+		}
+		result = *abi.ConvertType(out[0], new([]byte)).(*[]byte)
+	}
+	out, err := c.abi.Unpack("addr", result)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return *abi.ConvertType(out[0], new(common.Address)).(*common.Address), nil
+}
+
+// SupportsWildcard reports whether a resolver implements ENSIP-10, only such resolvers answer for subnames without a node of their own
+func (c *CcipReadCaller) SupportsWildcard(ctx context.Context, resolver common.Address) (bool, error) {
+	return c.supportsInterface(ctx, resolver, ExtendedResolverInterfaceId)
+}
+
+func (c *CcipReadCaller) supportsInterface(ctx context.Context, contract common.Address, interfaceId [4]byte) (bool, error) {
+	data, err := c.abi.Pack("supportsInterface", interfaceId)
+	if err != nil {
+		return false, err
+	}
+	result, err := c.caller.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
+	if err != nil {
+		// resolvers predating EIP-165 revert instead of returning false
+		if strings.Contains(err.Error(), "execution reverted") {
+			return false, nil
+		}
+		return false, err
+	}
+	out, err := c.abi.Unpack("supportsInterface", result)
+	if err != nil {
+		return false, nil
+	}
+	return *abi.ConvertType(out[0], new(bool)).(*bool), nil
+}
+
+// fetchGateway queries the gateway urls of a lookup in order. Urls containing {data} are requested via GET, all others via POST.
+// A 4xx response fails the lookup, while a 5xx response or an unreachable gateway moves on to the next url.
+func (c *CcipReadCaller) fetchGateway(ctx context.Context, lookup *OffchainLookup) ([]byte, error) {
+	sender := strings.ToLower(lookup.Sender.Hex())
+	callData := hexutil.Encode(lookup.CallData)
+	var lastErr error
+	for _, url := range lookup.Urls {
+		var req *http.Request
+		var err error
+		requestUrl := strings.ReplaceAll(strings.ReplaceAll(url, "{sender}", sender), "{data}", callData)
+		if strings.Contains(url, "{data}") {
+			req, err = http.NewRequestWithContext(ctx, http.MethodGet, requestUrl, nil)
+		} else {
+			body, _ := json.Marshal(map[string]string{"data": callData, "sender": sender})
+			req, err = http.NewRequestWithContext(ctx, http.MethodPost, requestUrl, bytes.NewReader(body))
+			if req != nil {
+				req.Header.Set("Content-Type", "application/json")
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid gateway url %v: %w", url, err)
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("gateway %v returned status %v", url, resp.StatusCode)
+			continue
+		}
+		if resp.StatusCode >= 400 {
+			return nil, fmt.Errorf("gateway %v rejected the lookup with status %v: %s", url, resp.StatusCode, body)
+		}
+		var response struct {
+			Data string `json:"data"`
+		}
+		err = json.Unmarshal(body, &response)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding response of gateway %v: %w", url, err)
+		}
+		return hexutil.Decode(response.Data)
+	}
+	return nil, fmt.Errorf("%w: %v", ErrCcipGatewayUnavailable, lastErr)
+}
+
+// packCcipCallback encodes the call of the callback function with the gateway response and the extra data of the lookup
+func packCcipCallback(selector [4]byte, response, extraData []byte) ([]byte, error) {
+	bytesType, err := abi.NewType("bytes", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	args, err := abi.Arguments{{Type: bytesType}, {Type: bytesType}}.Pack(response, extraData)
+	if err != nil {
+		return nil, err
+	}
+	return append(selector[:], args...), nil
+}
+
+// EncodeDnsName converts a dotted name to its dns encoded form (length prefixed labels terminated by a zero byte), the inverse of DecodeDnsName
+func EncodeDnsName(name string) ([]byte, error) {
+	encoded := []byte{}
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if len(label) == 0 || len(label) > 255 {
+				return nil, fmt.Errorf("invalid label length %v in name %v", len(label), name)
+			}
+			encoded = append(encoded, byte(len(label)))
+			encoded = append(encoded, label...)
+		}
+	}
+	return append(encoded, 0), nil
+}
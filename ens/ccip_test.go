@@ -0,0 +1,144 @@
+package ens
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+type testRevertError struct {
+	data string
+}
+
+func (e *testRevertError) Error() string {
+	return "execution reverted"
+}
+
+func (e *testRevertError) ErrorData() interface{} {
+	return e.data
+}
+
+// fakeOffchainResolver implements ENSIP-10 and answers every resolve call with an OffchainLookup to the gateway url
+type fakeOffchainResolver struct {
+	abi       abi.ABI
+	address   common.Address
+	url       string
+	extraData []byte
+	callbacks int
+}
+
+func (r *fakeOffchainResolver) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return []byte{1}, nil
+}
+
+func (r *fakeOffchainResolver) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	switch {
+	case bytes.Equal(call.Data[:4], r.abi.Methods["supportsInterface"].ID):
+		return r.abi.Methods["supportsInterface"].Outputs.Pack(true)
+	case bytes.Equal(call.Data[:4], r.abi.Methods["resolve"].ID):
+		args, err := r.abi.Errors["OffchainLookup"].Inputs.Pack(r.address, []string{r.url}, call.Data, [4]byte{0xde, 0xad, 0xbe, 0xef}, r.extraData)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &testRevertError{data: hexutil.Encode(append(append([]byte{}, OffchainLookupSelector...), args...))}
+	case bytes.Equal(call.Data[:4], []byte{0xde, 0xad, 0xbe, 0xef}):
+		r.callbacks++
+		bytesType, _ := abi.NewType("bytes", "", nil)
+		values, err := abi.Arguments{{Type: bytesType}, {Type: bytesType}}.Unpack(call.Data[4:])
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(values[1].([]byte), r.extraData) {
+			return nil, fmt.Errorf("unexpected extra data %x", values[1])
+		}
+		return values[0].([]byte), nil
+	}
+	return nil, fmt.Errorf("unexpected call %x", call.Data)
+}
+
+func TestCcipReadResolveAddress(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(ensCcipReadData.ABI))
+	if err != nil {
+		t.Fatalf("error parsing abi: %v", err)
+	}
+	resolver := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	expected := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Path, strings.ToLower(resolver.Hex())) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		addrResult, _ := parsed.Methods["addr"].Outputs.Pack(expected)
+		resolveResult, _ := parsed.Methods["resolve"].Outputs.Pack(addrResult)
+		json.NewEncoder(w).Encode(map[string]string{"data": hexutil.Encode(resolveResult)})
+	}))
+	defer gateway.Close()
+
+	fake := &fakeOffchainResolver{abi: parsed, address: resolver, url: gateway.URL + "/{sender}/{data}.json", extraData: []byte{1, 2, 3}}
+	caller, err := NewCcipReadCaller(fake, gateway.Client())
+	if err != nil {
+		t.Fatalf("error creating caller: %v", err)
+	}
+	got, err := caller.ResolveAddress(context.Background(), resolver, "foo.offchain.eth", [32]byte{1})
+	if err != nil {
+		t.Fatalf("error resolving address: %v", err)
+	}
+	if got != expected {
+		t.Errorf("expected address %v, got %v", expected, got)
+	}
+	if fake.callbacks != 1 {
+		t.Errorf("expected one callback, got %v", fake.callbacks)
+	}
+}
+
+func TestCcipReadGatewayUnavailable(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(ensCcipReadData.ABI))
+	if err != nil {
+		t.Fatalf("error parsing abi: %v", err)
+	}
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer gateway.Close()
+
+	resolver := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	fake := &fakeOffchainResolver{abi: parsed, address: resolver, url: gateway.URL}
+	caller, err := NewCcipReadCaller(fake, gateway.Client())
+	if err != nil {
+		t.Fatalf("error creating caller: %v", err)
+	}
+	_, err = caller.ResolveAddress(context.Background(), resolver, "foo.offchain.eth", [32]byte{1})
+	if !errors.Is(err, ErrCcipGatewayUnavailable) {
+		t.Errorf("expected unavailable gateway error, got %v", err)
+	}
+}
+
+func TestEncodeDnsName(t *testing.T) {
+	encoded, err := EncodeDnsName("foo.eth")
+	if err != nil {
+		t.Fatalf("error encoding name: %v", err)
+	}
+	if !bytes.Equal(encoded, []byte("\x03foo\x03eth\x00")) {
+		t.Errorf("unexpected encoding %x", encoded)
+	}
+	decoded, err := DecodeDnsName(encoded)
+	if err != nil || decoded != "foo.eth" {
+		t.Errorf("expected foo.eth to round trip, got %v (%v)", decoded, err)
+	}
+	if _, err := EncodeDnsName("foo..eth"); err == nil {
+		t.Errorf("expected an error for an empty label")
+	}
+}
@@ -6,14 +6,50 @@ var NewResolverTopic []byte = []byte{0x33, 0x57, 0x21, 0xb0, 0x18, 0x66, 0xdc, 0
 // ca6abbe9d7f11422cb6ca7629fbf6fe9efb1c621f71ce8f02b9f2a230097404f
 var NameRegisteredTopic []byte = []byte{0xca, 0x6a, 0xbb, 0xe9, 0xd7, 0xf1, 0x14, 0x22, 0xcb, 0x6c, 0xa7, 0x62, 0x9f, 0xbf, 0x6f, 0xe9, 0xef, 0xb1, 0xc6, 0x21, 0xf7, 0x1c, 0xe8, 0xf0, 0x2b, 0x9f, 0x2a, 0x23, 0x00, 0x97, 0x40, 0x4f}
 
+// c2240194853531f1ae318dcef227de79c6ad0fd9d1b0e4fe08568415be2e08a5
+var NameRegisteredWithReferrerTopic []byte = []byte{0xc2, 0x24, 0x01, 0x94, 0x85, 0x35, 0x31, 0xf1, 0xae, 0x31, 0x8d, 0xce, 0xf2, 0x27, 0xde, 0x79, 0xc6, 0xad, 0x0f, 0xd9, 0xd1, 0xb0, 0xe4, 0xfe, 0x08, 0x56, 0x84, 0x15, 0xbe, 0x2e, 0x08, 0xa5}
+
 // 3da24c024582931cfaf8267d8ed24d13a82a8068d5bd337d30ec45cea4e506ae
 var NameRenewedTopic []byte = []byte{0x3d, 0xa2, 0x4c, 0x02, 0x45, 0x82, 0x93, 0x1c, 0xfa, 0xf8, 0x26, 0x7d, 0x8e, 0xd2, 0x4d, 0x13, 0xa8, 0x2a, 0x80, 0x68, 0xd5, 0xbd, 0x33, 0x7d, 0x30, 0xec, 0x45, 0xce, 0xa4, 0xe5, 0x06, 0xae}
 
 // 65412581168e88a1e60c6459d7f44ae83ad0832e670826c05a4e2476b57af752
 var AddressChangedTopic []byte = []byte{0x65, 0x41, 0x25, 0x81, 0x16, 0x8e, 0x88, 0xa1, 0xe6, 0x0c, 0x64, 0x59, 0xd7, 0xf4, 0x4a, 0xe8, 0x3a, 0xd0, 0x83, 0x2e, 0x67, 0x08, 0x26, 0xc0, 0x5a, 0x4e, 0x24, 0x76, 0xb5, 0x7a, 0xf7, 0x52}
 
+// 448bc014f1536726cf8d54ff3d6481ed3cbc683c2591ca204274009afa09b1a1
+var TextChangedTopic []byte = []byte{0x44, 0x8b, 0xc0, 0x14, 0xf1, 0x53, 0x67, 0x26, 0xcf, 0x8d, 0x54, 0xff, 0x3d, 0x64, 0x81, 0xed, 0x3c, 0xbc, 0x68, 0x3c, 0x25, 0x91, 0xca, 0x20, 0x42, 0x74, 0x00, 0x9a, 0xfa, 0x09, 0xb1, 0xa1}
+
 // b7d29e911041e8d9b843369e890bcb72c9388692ba48b65ac54e7214c4c348f7
 var NameChangedTopic []byte = []byte{0xb7, 0xd2, 0x9e, 0x91, 0x10, 0x41, 0xe8, 0xd9, 0xb8, 0x43, 0x36, 0x9e, 0x89, 0x0b, 0xcb, 0x72, 0xc9, 0x38, 0x86, 0x92, 0xba, 0x48, 0xb6, 0x5a, 0xc5, 0x4e, 0x72, 0x14, 0xc4, 0xc3, 0x48, 0xf7}
 
 // ce0457fe73731f824cc272376169235128c118b49d344817417c6d108d155e82
 var NewOwnerTopic []byte = []byte{0xce, 0x04, 0x57, 0xfe, 0x73, 0x73, 0x1f, 0x82, 0x4c, 0xc2, 0x72, 0x37, 0x61, 0x69, 0x23, 0x51, 0x28, 0xc1, 0x18, 0xb4, 0x9d, 0x34, 0x48, 0x17, 0x41, 0x7c, 0x6d, 0x10, 0x8d, 0x15, 0x5e, 0x82}
+
+// 0a8bb31534c0ed46f380cb867bd5c803a189ced9a764e30b3a4991a9901d7474
+var ControllerAddedTopic []byte = []byte{0x0a, 0x8b, 0xb3, 0x15, 0x34, 0xc0, 0xed, 0x46, 0xf3, 0x80, 0xcb, 0x86, 0x7b, 0xd5, 0xc8, 0x03, 0xa1, 0x89, 0xce, 0xd9, 0xa7, 0x64, 0xe3, 0x0b, 0x3a, 0x49, 0x91, 0xa9, 0x90, 0x1d, 0x74, 0x74}
+
+// 33d83959be2573f5453b12eb9d43b3499bc57d96bd2f067ba44803c859e81113
+var ControllerRemovedTopic []byte = []byte{0x33, 0xd8, 0x39, 0x59, 0xbe, 0x25, 0x73, 0xf5, 0x45, 0x3b, 0x12, 0xeb, 0x9d, 0x43, 0xb3, 0x49, 0x9b, 0xc5, 0x7d, 0x96, 0xbd, 0x2f, 0x06, 0x7b, 0xa4, 0x48, 0x03, 0xc8, 0x59, 0xe8, 0x11, 0x13}
+
+// ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef
+var RegistrarTransferTopic []byte = []byte{0xdd, 0xf2, 0x52, 0xad, 0x1b, 0xe2, 0xc8, 0x9b, 0x69, 0xc2, 0xb0, 0x68, 0xfc, 0x37, 0x8d, 0xaa, 0x95, 0x2b, 0xa7, 0xf1, 0x63, 0xc4, 0xa1, 0x16, 0x28, 0xf5, 0x5a, 0x4d, 0xf5, 0x23, 0xb3, 0xef}
+
+// 8ce7013e8abebc55c3890a68f5a27c67c3f7efa64e584de5fb22363c606fd340
+var NameWrappedTopic []byte = []byte{0x8c, 0xe7, 0x01, 0x3e, 0x8a, 0xbe, 0xbc, 0x55, 0xc3, 0x89, 0x0a, 0x68, 0xf5, 0xa2, 0x7c, 0x67, 0xc3, 0xf7, 0xef, 0xa6, 0x4e, 0x58, 0x4d, 0xe5, 0xfb, 0x22, 0x36, 0x3c, 0x60, 0x6f, 0xd3, 0x40}
+
+// ee2ba1195c65bcf218a83d874335c6bf9d9067b4c672f3c3bf16cf40de7586c4
+var NameUnwrappedTopic []byte = []byte{0xee, 0x2b, 0xa1, 0x19, 0x5c, 0x65, 0xbc, 0xf2, 0x18, 0xa8, 0x3d, 0x87, 0x43, 0x35, 0xc6, 0xbf, 0x9d, 0x90, 0x67, 0xb4, 0xc6, 0x72, 0xf3, 0xc3, 0xbf, 0x16, 0xcf, 0x40, 0xde, 0x75, 0x86, 0xc4}
+
+// 6ada868dd3058cf77a48a74489fd7963688e5464b2b0fa957ace976243270e92
+var ReverseClaimedTopic []byte = []byte{0x6a, 0xda, 0x86, 0x8d, 0xd3, 0x05, 0x8c, 0xf7, 0x7a, 0x48, 0xa7, 0x44, 0x89, 0xfd, 0x79, 0x63, 0x68, 0x8e, 0x54, 0x64, 0xb2, 0xb0, 0xfa, 0x95, 0x7a, 0xce, 0x97, 0x62, 0x43, 0x27, 0x0e, 0x92}
+
+// 0f0c27adfd84b60b6f456b0e87cdccb1e5fb9603991588d87fa99f5b6b61e670
+var LegacyHashRegisteredTopic []byte = []byte{0x0f, 0x0c, 0x27, 0xad, 0xfd, 0x84, 0xb6, 0x0b, 0x6f, 0x45, 0x6b, 0x0e, 0x87, 0xcd, 0xcc, 0xb1, 0xe5, 0xfb, 0x96, 0x03, 0x99, 0x15, 0x88, 0xd8, 0x7f, 0xa9, 0x9f, 0x5b, 0x6b, 0x61, 0xe6, 0x70}
+
+// b3d987963d01b2f68493b4bdb130988f157ea43070d4ad840fee0466ed9370d9
+var LegacyNameRegisteredTopic []byte = []byte{0xb3, 0xd9, 0x87, 0x96, 0x3d, 0x01, 0xb2, 0xf6, 0x84, 0x93, 0xb4, 0xbd, 0xb1, 0x30, 0x98, 0x8f, 0x15, 0x7e, 0xa4, 0x30, 0x70, 0xd4, 0xad, 0x84, 0x0f, 0xee, 0x04, 0x66, 0xed, 0x93, 0x70, 0xd9}
+
+// 91d1777781884d03a6757a803996e38de2a42967fb37eeaca72729271025a9e2
+var AddrReverseNode [32]byte = [32]byte{0x91, 0xd1, 0x77, 0x77, 0x81, 0x88, 0x4d, 0x03, 0xa6, 0x75, 0x7a, 0x80, 0x39, 0x96, 0xe3, 0x8d, 0xe2, 0xa4, 0x29, 0x67, 0xfb, 0x37, 0xee, 0xac, 0xa7, 0x27, 0x29, 0x27, 0x10, 0x25, 0xa9, 0xe2}
+
+// 93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae
+var EthNode [32]byte = [32]byte{0x93, 0xcd, 0xeb, 0x70, 0x8b, 0x75, 0x45, 0xdc, 0x66, 0x8e, 0xb9, 0x28, 0x01, 0x76, 0x16, 0x9d, 0x1c, 0x33, 0xcf, 0xd8, 0xed, 0x6f, 0x04, 0x69, 0x0a, 0x0b, 0xcc, 0x88, 0xa9, 0x3f, 0xc4, 0xae}
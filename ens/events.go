@@ -0,0 +1,140 @@
+package ens
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// EventType classifies the ENS events handled by the indexer
+type EventType int
+
+const (
+	UnknownEvent EventType = iota
+	NewResolverEvent
+	NameRegisteredEvent
+	NameRenewedEvent
+	AddressChangedEvent
+	NameChangedEvent
+	NewOwnerEvent
+	ControllerAddedEvent
+	ControllerRemovedEvent
+	RegistrarTransferEvent
+	NameRegisteredWithReferrerEvent
+	TextChangedEvent
+	NameWrappedEvent
+	NameUnwrappedEvent
+	ReverseClaimedEvent
+	LegacyHashRegisteredEvent
+	LegacyNameRegisteredEvent
+)
+
+// EventTypes maps the topic of every handled ENS event to its type, adding support for an event requires an entry here and a handler in the transformer
+var EventTypes = map[common.Hash]EventType{
+	common.BytesToHash(NewResolverTopic):                NewResolverEvent,
+	common.BytesToHash(NameRegisteredTopic):             NameRegisteredEvent,
+	common.BytesToHash(NameRenewedTopic):                NameRenewedEvent,
+	common.BytesToHash(AddressChangedTopic):             AddressChangedEvent,
+	common.BytesToHash(NameChangedTopic):                NameChangedEvent,
+	common.BytesToHash(NewOwnerTopic):                   NewOwnerEvent,
+	common.BytesToHash(ControllerAddedTopic):            ControllerAddedEvent,
+	common.BytesToHash(ControllerRemovedTopic):          ControllerRemovedEvent,
+	common.BytesToHash(RegistrarTransferTopic):          RegistrarTransferEvent,
+	common.BytesToHash(NameRegisteredWithReferrerTopic): NameRegisteredWithReferrerEvent,
+	common.BytesToHash(TextChangedTopic):                TextChangedEvent,
+	common.BytesToHash(NameWrappedTopic):                NameWrappedEvent,
+	common.BytesToHash(NameUnwrappedTopic):              NameUnwrappedEvent,
+	common.BytesToHash(ReverseClaimedTopic):             ReverseClaimedEvent,
+	common.BytesToHash(LegacyHashRegisteredTopic):       LegacyHashRegisteredEvent,
+	common.BytesToHash(LegacyNameRegisteredTopic):       LegacyNameRegisteredEvent,
+}
+
+// builtinEventTypes is the registry of the built-in topics, it is kept to reset the topics and to map configured topics back to them
+var builtinEventTypes = copyEventTypes(EventTypes)
+
+// EventNames maps the names the topic of an event can be configured by to the event types
+var EventNames = map[string]EventType{
+	"NewResolver":                NewResolverEvent,
+	"NameRegistered":             NameRegisteredEvent,
+	"NameRenewed":                NameRenewedEvent,
+	"AddressChanged":             AddressChangedEvent,
+	"NameChanged":                NameChangedEvent,
+	"NewOwner":                   NewOwnerEvent,
+	"ControllerAdded":            ControllerAddedEvent,
+	"ControllerRemoved":          ControllerRemovedEvent,
+	"RegistrarTransfer":          RegistrarTransferEvent,
+	"NameRegisteredWithReferrer": NameRegisteredWithReferrerEvent,
+	"TextChanged":                TextChangedEvent,
+	"NameWrapped":                NameWrappedEvent,
+	"NameUnwrapped":              NameUnwrappedEvent,
+	"ReverseClaimed":             ReverseClaimedEvent,
+	"LegacyHashRegistered":       LegacyHashRegisteredEvent,
+	"LegacyNameRegistered":       LegacyNameRegisteredEvent,
+}
+
+// canonicalTopics maps configured topics to the built-in topic of their event, so the logs are decoded with the built-in abi
+var canonicalTopics = map[common.Hash]common.Hash{}
+
+func copyEventTypes(eventTypes map[common.Hash]EventType) map[common.Hash]EventType {
+	copied := make(map[common.Hash]EventType, len(eventTypes))
+	for topic, eventType := range eventTypes {
+		copied[topic] = eventType
+	}
+	return copied
+}
+
+// SetEventTopics replaces the built-in topics of events by the configured ones, for test networks and custom registrars that emit
+// differently signed events with the same layout. The keys are event names of EventNames, the values hex encoded topics.
+// Events without a configured topic fall back to their built-in topic, an empty map restores all built-in topics.
+// The topics must be set before any block is transformed.
+func SetEventTopics(topics map[string]string) error {
+	eventTypes := copyEventTypes(builtinEventTypes)
+	canonical := map[common.Hash]common.Hash{}
+	for name, hexTopic := range topics {
+		eventType, ok := EventNames[name]
+		if !ok {
+			return fmt.Errorf("unknown ens event %v", name)
+		}
+		decoded, err := hexutil.Decode(hexTopic)
+		if err != nil || len(decoded) != common.HashLength {
+			return fmt.Errorf("invalid topic %v for ens event %v", hexTopic, name)
+		}
+		topic := common.BytesToHash(decoded)
+		for builtinTopic, builtinType := range builtinEventTypes {
+			if builtinType == eventType {
+				delete(eventTypes, builtinTopic)
+				canonical[topic] = builtinTopic
+			}
+		}
+		eventTypes[topic] = eventType
+	}
+	EventTypes = eventTypes
+	canonicalTopics = canonical
+	return nil
+}
+
+// canonicalLog returns the log with its configured topic replaced by the built-in topic of the event
+func canonicalLog(log types.Log) types.Log {
+	if len(log.Topics) == 0 {
+		return log
+	}
+	builtinTopic, ok := canonicalTopics[log.Topics[0]]
+	if !ok {
+		return log
+	}
+	topics := make([]common.Hash, len(log.Topics))
+	copy(topics, log.Topics)
+	topics[0] = builtinTopic
+	log.Topics = topics
+	return log
+}
+
+// GetEventType returns the type of the event with the given topic or UnknownEvent if it is not handled
+func GetEventType(topic []byte) EventType {
+	if len(topic) != common.HashLength {
+		return UnknownEvent
+	}
+	return EventTypes[common.BytesToHash(topic)]
+}
@@ -0,0 +1,45 @@
+package ens
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestSetEventTopics(t *testing.T) {
+	defer SetEventTopics(nil)
+
+	custom := common.HexToHash("0x01")
+	err := SetEventTopics(map[string]string{"NameRegistered": custom.Hex()})
+	if err != nil {
+		t.Fatalf("error setting topics: %v", err)
+	}
+	if GetEventType(custom.Bytes()) != NameRegisteredEvent {
+		t.Errorf("expected the configured topic to be a NameRegistered event")
+	}
+	if GetEventType(NameRegisteredTopic) != UnknownEvent {
+		t.Errorf("expected the built-in NameRegistered topic to be replaced")
+	}
+	if GetEventType(NameRenewedTopic) != NameRenewedEvent {
+		t.Errorf("expected events without a configured topic to keep their built-in topic")
+	}
+	log := canonicalLog(types.Log{Topics: []common.Hash{custom, common.HexToHash("0x02")}})
+	if log.Topics[0] != common.BytesToHash(NameRegisteredTopic) || log.Topics[1] != common.HexToHash("0x02") {
+		t.Errorf("expected the configured topic to be mapped to the built-in topic, got %v", log.Topics)
+	}
+
+	if err := SetEventTopics(map[string]string{"Unknown": custom.Hex()}); err == nil {
+		t.Errorf("expected an error for an unknown event")
+	}
+	if err := SetEventTopics(map[string]string{"NameRegistered": "0x01"}); err == nil {
+		t.Errorf("expected an error for a topic that is not 32 bytes long")
+	}
+
+	if err := SetEventTopics(nil); err != nil {
+		t.Fatalf("error restoring topics: %v", err)
+	}
+	if GetEventType(NameRegisteredTopic) != NameRegisteredEvent || GetEventType(custom.Bytes()) != UnknownEvent {
+		t.Errorf("expected the built-in topics to be restored")
+	}
+}
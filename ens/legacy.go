@@ -0,0 +1,64 @@
+package ens
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ensLegacyRegistrarData contains the meta data of the registration events of the legacy registrars, the auction registrar (2017-2019)
+// and the first permanent registrar, whose NameRegistered event differs from the one of the current controllers.
+var ensLegacyRegistrarData = &bind.MetaData{
+	ABI: "[{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"hash\",\"type\":\"bytes32\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"value\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"registrationDate\",\"type\":\"uint256\"}],\"name\":\"HashRegistered\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"id\",\"type\":\"uint256\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"expires\",\"type\":\"uint256\"}],\"name\":\"NameRegistered\",\"type\":\"event\"}]",
+	Bin: "",
+}
+
+// HashRegistered represents a HashRegistered event raised by the legacy auction registrar when an auction is finalized, the name is only known by its label hash.
+type HashRegistered struct {
+	Hash             [32]byte
+	Owner            common.Address
+	Value            *big.Int
+	RegistrationDate *big.Int
+	Raw              types.Log // Blockchain specific contextual infos
+}
+
+// LegacyNameRegistered represents a NameRegistered event raised by the legacy permanent registrar, the id is the label hash of the name.
+type LegacyNameRegistered struct {
+	Id      *big.Int
+	Owner   common.Address
+	Expires *big.Int
+	Raw     types.Log // Blockchain specific contextual infos
+}
+
+// bindEnsLegacyRegistrar binds a generic wrapper to an already deployed contract.
+func bindEnsLegacyRegistrar(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := abi.JSON(strings.NewReader(ensLegacyRegistrarData.ABI))
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, parsed, caller, transactor, filterer), nil
+}
+
+// Solidity: event HashRegistered(bytes32 indexed hash, address indexed owner, uint256 value, uint256 registrationDate)
+func (_EnsRegistrar *EnsRegistrarFilterer) ParseHashRegistered(log types.Log) (*HashRegistered, error) {
+	event := new(HashRegistered)
+	if err := _EnsRegistrar.legacyRegistrarContract.UnpackLog(event, "HashRegistered", canonicalLog(log)); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// Solidity: event NameRegistered(uint256 indexed id, address indexed owner, uint256 expires)
+func (_EnsRegistrar *EnsRegistrarFilterer) ParseLegacyNameRegistered(log types.Log) (*LegacyNameRegistered, error) {
+	event := new(LegacyNameRegistered)
+	if err := _EnsRegistrar.legacyRegistrarContract.UnpackLog(event, "NameRegistered", canonicalLog(log)); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
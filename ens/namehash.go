@@ -0,0 +1,82 @@
+package ens
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	go_ens "github.com/wealdtech/go-ens/v3"
+)
+
+// NameHasher computes the on chain node of a name. Canonical ENS hashes every name below the zero root node,
+// other naming services (e.g. Basenames) use a different base node for their names. The scheme is selected by
+// the longest configured suffix of a name, names without a configured suffix are hashed like canonical ENS names.
+type NameHasher struct {
+	roots map[string][32]byte
+}
+
+// NewNameHasher creates a NameHasher from a map of name suffixes (e.g. "base.eth") to the hex encoded node that is used as their base node
+func NewNameHasher(roots map[string]string) (*NameHasher, error) {
+	hasher := &NameHasher{roots: make(map[string][32]byte, len(roots))}
+	for suffix, root := range roots {
+		rootBytes, err := decodeNode(root)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base node %v for suffix %v: %w", root, suffix, err)
+		}
+		hasher.roots[strings.ToLower(strings.Trim(suffix, "."))] = rootBytes
+	}
+	return hasher, nil
+}
+
+// NameHash returns the node of the name using the scheme of the service the name belongs to
+func (h *NameHasher) NameHash(name string) ([32]byte, error) {
+	suffix, root, ok := h.root(name)
+	if !ok {
+		return go_ens.NameHash(name)
+	}
+	normalized, err := go_ens.Normalize(name)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	labels := strings.TrimSuffix(strings.TrimSuffix(normalized, suffix), ".")
+	return nameHashWithRoot(root, labels), nil
+}
+
+// root returns the longest configured suffix of the name and its base node
+func (h *NameHasher) root(name string) (suffix string, root [32]byte, ok bool) {
+	name = strings.ToLower(name)
+	for s, r := range h.roots {
+		if (name == s || strings.HasSuffix(name, "."+s)) && len(s) > len(suffix) {
+			suffix, root, ok = s, r, true
+		}
+	}
+	return suffix, root, ok
+}
+
+// nameHashWithRoot applies the namehash algorithm to the already normalized labels, starting at the given root instead of the zero node
+func nameHashWithRoot(root [32]byte, labels string) [32]byte {
+	hash := root
+	if labels == "" {
+		return hash
+	}
+	parts := strings.Split(labels, ".")
+	for i := len(parts) - 1; i >= 0; i-- {
+		labelHash := crypto.Keccak256([]byte(parts[i]))
+		copy(hash[:], crypto.Keccak256(hash[:], labelHash))
+	}
+	return hash
+}
+
+func decodeNode(node string) ([32]byte, error) {
+	var hash [32]byte
+	b, err := hexutil.Decode(node)
+	if err != nil {
+		return hash, err
+	}
+	if len(b) != len(hash) {
+		return hash, fmt.Errorf("expected 32 bytes but got %v", len(b))
+	}
+	copy(hash[:], b)
+	return hash, nil
+}
@@ -0,0 +1,124 @@
+package ens
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	go_ens "github.com/wealdtech/go-ens/v3"
+)
+
+func TestNameHasherCanonical(t *testing.T) {
+	hasher, err := NewNameHasher(nil)
+	if err != nil {
+		t.Fatalf("error creating hasher: %v", err)
+	}
+	expected, err := go_ens.NameHash("vitalik.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	got, err := hasher.NameHash("vitalik.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	if got != expected {
+		t.Errorf("expected canonical node %x, got %x", expected, got)
+	}
+}
+
+func TestNameHasherCustomRoot(t *testing.T) {
+	root := crypto.Keccak256Hash([]byte("custom naming service"))
+	hasher, err := NewNameHasher(map[string]string{"box": root.Hex()})
+	if err != nil {
+		t.Fatalf("error creating hasher: %v", err)
+	}
+
+	foo := crypto.Keccak256Hash(root.Bytes(), crypto.Keccak256([]byte("foo")))
+	bar := crypto.Keccak256Hash(foo.Bytes(), crypto.Keccak256([]byte("bar")))
+	tests := []struct {
+		name     string
+		expected common.Hash
+	}{
+		{name: "box", expected: root},
+		{name: "foo.box", expected: foo},
+		{name: "Bar.Foo.box", expected: bar},
+	}
+	for _, tt := range tests {
+		got, err := hasher.NameHash(tt.name)
+		if err != nil {
+			t.Fatalf("error hashing %v: %v", tt.name, err)
+		}
+		if common.Hash(got) != tt.expected {
+			t.Errorf("wrong node for %v, expected %x got %x", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestNameHasherLongestSuffix(t *testing.T) {
+	// a base node that equals the canonical node of the suffix must produce canonical nodes for its subnames
+	baseNode, err := go_ens.NameHash("base.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	hasher, err := NewNameHasher(map[string]string{
+		"eth":      crypto.Keccak256Hash([]byte("not the eth node")).Hex(),
+		"base.eth": common.Hash(baseNode).Hex(),
+	})
+	if err != nil {
+		t.Fatalf("error creating hasher: %v", err)
+	}
+	expected, err := go_ens.NameHash("jesse.base.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	got, err := hasher.NameHash("jesse.base.eth")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	if got != expected {
+		t.Errorf("expected node %x, got %x", expected, got)
+	}
+}
+
+func TestNewNameHasherInvalidRoot(t *testing.T) {
+	for _, root := range []string{"", "0x1234", "not hex"} {
+		if _, err := NewNameHasher(map[string]string{"box": root}); err == nil {
+			t.Errorf("expected an error for base node %q", root)
+		}
+	}
+}
+
+func TestDecodeDnsName(t *testing.T) {
+	tests := []struct {
+		encoded  []byte
+		expected string
+		valid    bool
+	}{
+		{append(append([]byte{7}, "vitalik"...), append([]byte{3}, "eth\x00"...)...), "vitalik.eth", true},
+		{append(append([]byte{3}, "sub"...), append(append([]byte{7}, "vitalik"...), append([]byte{3}, "eth\x00"...)...)...), "sub.vitalik.eth", true},
+		{[]byte{0}, "", true},
+		{append([]byte{7}, "vitalik"...), "", false},
+		{append([]byte{9}, "vitalik\x00"...), "", false},
+		{[]byte{0, 0}, "", false},
+	}
+	for _, test := range tests {
+		name, err := DecodeDnsName(test.encoded)
+		if test.valid && (err != nil || name != test.expected) {
+			t.Errorf("expected %x to decode to %v, got %v (%v)", test.encoded, test.expected, name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("expected an error decoding %x, got %v", test.encoded, name)
+		}
+	}
+}
+
+func TestReverseNode(t *testing.T) {
+	address := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	expected, err := go_ens.NameHash("d8da6bf26964af9d7eed9e03e53415d37aa96045.addr.reverse")
+	if err != nil {
+		t.Fatalf("error hashing name: %v", err)
+	}
+	if got := ReverseNode(address); got != expected {
+		t.Errorf("expected reverse node %x, got %x", expected, got)
+	}
+}
@@ -0,0 +1,121 @@
+package ens
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ensNameWrapperData contains the meta data of the Ens NameWrapper contract that is needed to index and read wrapped names.
+var ensNameWrapperData = &bind.MetaData{
+	ABI: "[{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"},{\"indexed\":false,\"internalType\":\"bytes\",\"name\":\"name\",\"type\":\"bytes\"},{\"indexed\":false,\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"uint32\",\"name\":\"fuses\",\"type\":\"uint32\"},{\"indexed\":false,\"internalType\":\"uint64\",\"name\":\"expiry\",\"type\":\"uint64\"}],\"name\":\"NameWrapped\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"},{\"indexed\":false,\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"}],\"name\":\"NameUnwrapped\",\"type\":\"event\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"id\",\"type\":\"uint256\"}],\"name\":\"getData\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"internalType\":\"uint32\",\"name\":\"fuses\",\"type\":\"uint32\"},{\"internalType\":\"uint64\",\"name\":\"expiry\",\"type\":\"uint64\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"id\",\"type\":\"uint256\"}],\"name\":\"ownerOf\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"}]",
+	Bin: "",
+}
+
+// NameWrapped represents a NameWrapped event raised by the Ens NameWrapper contract, the name is dns encoded.
+type NameWrapped struct {
+	Node   [32]byte
+	Name   []byte
+	Owner  common.Address
+	Fuses  uint32
+	Expiry uint64
+	Raw    types.Log // Blockchain specific contextual infos
+}
+
+// NameUnwrapped represents a NameUnwrapped event raised by the Ens NameWrapper contract.
+type NameUnwrapped struct {
+	Node  [32]byte
+	Owner common.Address
+	Raw   types.Log // Blockchain specific contextual infos
+}
+
+// NameWrapperCaller is a read-only Go binding around the Ens NameWrapper contract.
+type NameWrapperCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// NewNameWrapperCaller creates a new read-only instance of the Ens NameWrapper, bound to a specific deployed contract.
+func NewNameWrapperCaller(address common.Address, caller bind.ContractCaller) (*NameWrapperCaller, error) {
+	parsed, err := abi.JSON(strings.NewReader(ensNameWrapperData.ABI))
+	if err != nil {
+		return nil, err
+	}
+	return &NameWrapperCaller{contract: bind.NewBoundContract(address, parsed, caller, nil, nil)}, nil
+}
+
+// Solidity: function getData(uint256 id) view returns(address owner, uint32 fuses, uint64 expiry)
+func (_NameWrapper *NameWrapperCaller) GetData(opts *bind.CallOpts, node [32]byte) (owner common.Address, fuses uint32, expiry uint64, err error) {
+	var out []interface{}
+	err = _NameWrapper.contract.Call(opts, &out, "getData", new(big.Int).SetBytes(node[:]))
+	if err != nil {
+		return owner, fuses, expiry, err
+	}
+	owner = *abi.ConvertType(out[0], new(common.Address)).(*common.Address)
+	fuses = *abi.ConvertType(out[1], new(uint32)).(*uint32)
+	expiry = *abi.ConvertType(out[2], new(uint64)).(*uint64)
+	return owner, fuses, expiry, nil
+}
+
+// Solidity: function ownerOf(uint256 id) view returns(address owner)
+func (_NameWrapper *NameWrapperCaller) OwnerOf(opts *bind.CallOpts, node [32]byte) (common.Address, error) {
+	var out []interface{}
+	err := _NameWrapper.contract.Call(opts, &out, "ownerOf", new(big.Int).SetBytes(node[:]))
+	if err != nil {
+		return common.Address{}, err
+	}
+	return *abi.ConvertType(out[0], new(common.Address)).(*common.Address), nil
+}
+
+// bindEnsNameWrapper binds a generic wrapper to an already deployed contract.
+func bindEnsNameWrapper(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := abi.JSON(strings.NewReader(ensNameWrapperData.ABI))
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, parsed, caller, transactor, filterer), nil
+}
+
+// Solidity: event NameWrapped(bytes32 indexed node, bytes name, address owner, uint32 fuses, uint64 expiry)
+func (_EnsRegistrar *EnsRegistrarFilterer) ParseNameWrapped(log types.Log) (*NameWrapped, error) {
+	event := new(NameWrapped)
+	if err := _EnsRegistrar.nameWrapperContract.UnpackLog(event, "NameWrapped", canonicalLog(log)); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// Solidity: event NameUnwrapped(bytes32 indexed node, address owner)
+func (_EnsRegistrar *EnsRegistrarFilterer) ParseNameUnwrapped(log types.Log) (*NameUnwrapped, error) {
+	event := new(NameUnwrapped)
+	if err := _EnsRegistrar.nameWrapperContract.UnpackLog(event, "NameUnwrapped", canonicalLog(log)); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// DecodeDnsName converts a dns encoded name as emitted by the NameWrapper (length prefixed labels terminated by a zero byte) to its dotted form
+func DecodeDnsName(encoded []byte) (string, error) {
+	labels := []string{}
+	for i := 0; i < len(encoded); {
+		length := int(encoded[i])
+		if length == 0 {
+			if i != len(encoded)-1 {
+				return "", fmt.Errorf("unexpected data after the end of dns encoded name %x", encoded)
+			}
+			return strings.Join(labels, "."), nil
+		}
+		if i+1+length > len(encoded) {
+			return "", fmt.Errorf("label of dns encoded name %x exceeds its length", encoded)
+		}
+		labels = append(labels, string(encoded[i+1:i+1+length]))
+		i += 1 + length
+	}
+	return "", fmt.Errorf("dns encoded name %x is not terminated", encoded)
+}
@@ -0,0 +1,47 @@
+package ens
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ensNameResolverData contains the meta data of the name resolver profile that returns the name of a reverse node.
+var ensNameResolverData = &bind.MetaData{
+	ABI: "[{\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"node\",\"type\":\"bytes32\"}],\"name\":\"name\",\"outputs\":[{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"}],\"stateMutability\":\"view\",\"type\":\"function\"}]",
+	Bin: "",
+}
+
+// NameResolverCaller is a read-only Go binding around a resolver implementing the name profile.
+type NameResolverCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// NewNameResolverCaller creates a new read-only instance of a name resolver, bound to a specific deployed contract.
+func NewNameResolverCaller(address common.Address, caller bind.ContractCaller) (*NameResolverCaller, error) {
+	parsed, err := abi.JSON(strings.NewReader(ensNameResolverData.ABI))
+	if err != nil {
+		return nil, err
+	}
+	return &NameResolverCaller{contract: bind.NewBoundContract(address, parsed, caller, nil, nil)}, nil
+}
+
+// Solidity: function name(bytes32 node) view returns(string)
+func (_NameResolver *NameResolverCaller) Name(opts *bind.CallOpts, node [32]byte) (string, error) {
+	var out []interface{}
+	err := _NameResolver.contract.Call(opts, &out, "name", node)
+	if err != nil {
+		return "", err
+	}
+	return *abi.ConvertType(out[0], new(string)).(*string), nil
+}
+
+// ReverseNode returns the node of "<address>.addr.reverse" which holds the primary name of an address
+func ReverseNode(address common.Address) [32]byte {
+	label := crypto.Keccak256([]byte(hex.EncodeToString(address.Bytes())))
+	return crypto.Keccak256Hash(AddrReverseNode[:], label)
+}
@@ -0,0 +1,84 @@
+package ens
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// UniversalResolverAddress is the address the ENS Universal Resolver is deployed at on mainnet
+const UniversalResolverAddress = "0xce01f8eee7E479C928F8919abD53E553a36CeF67"
+
+// ensUniversalResolverData contains the meta data of the resolve function of the Universal Resolver.
+var ensUniversalResolverData = &bind.MetaData{
+	ABI: "[{\"inputs\":[{\"internalType\":\"bytes\",\"name\":\"name\",\"type\":\"bytes\"},{\"internalType\":\"bytes\",\"name\":\"data\",\"type\":\"bytes\"}],\"name\":\"resolve\",\"outputs\":[{\"internalType\":\"bytes\",\"name\":\"\",\"type\":\"bytes\"},{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"}]",
+	Bin: "",
+}
+
+// UniversalResolver resolves names through the Universal Resolver, which finds the resolver of a name or of its closest ancestor (ENSIP-10),
+// so subnames of wildcard resolvers (e.g. foo.uni.eth) resolve although no node was ever created for them.
+type UniversalResolver struct {
+	address     common.Address
+	abi         abi.ABI
+	resolverAbi abi.ABI
+	call        func(ctx context.Context, to common.Address, data []byte) ([]byte, error)
+}
+
+// NewUniversalResolver creates a universal resolver that calls the contract via eth_call, names of offchain resolvers fail to resolve
+func NewUniversalResolver(address common.Address, caller bind.ContractCaller) (*UniversalResolver, error) {
+	return newUniversalResolver(address, func(ctx context.Context, to common.Address, data []byte) ([]byte, error) {
+		return caller.CallContract(ctx, ethereum.CallMsg{To: &to, Data: data}, nil)
+	})
+}
+
+// NewUniversalResolverWithCcipRead creates a universal resolver that follows the offchain lookups of the contract via the ccip-read caller
+func NewUniversalResolverWithCcipRead(address common.Address, caller *CcipReadCaller) (*UniversalResolver, error) {
+	return newUniversalResolver(address, caller.Call)
+}
+
+func newUniversalResolver(address common.Address, call func(ctx context.Context, to common.Address, data []byte) ([]byte, error)) (*UniversalResolver, error) {
+	parsed, err := abi.JSON(strings.NewReader(ensUniversalResolverData.ABI))
+	if err != nil {
+		return nil, err
+	}
+	resolverAbi, err := abi.JSON(strings.NewReader(ensCcipReadData.ABI))
+	if err != nil {
+		return nil, err
+	}
+	return &UniversalResolver{address: address, abi: parsed, resolverAbi: resolverAbi, call: call}, nil
+}
+
+// ResolveAddress resolves the address of a name, the resolver that answered is returned along with the address
+func (r *UniversalResolver) ResolveAddress(ctx context.Context, name string, node [32]byte) (address common.Address, resolver common.Address, err error) {
+	addrData, err := r.resolverAbi.Pack("addr", node)
+	if err != nil {
+		return address, resolver, err
+	}
+	dnsName, err := EncodeDnsName(name)
+	if err != nil {
+		return address, resolver, err
+	}
+	data, err := r.abi.Pack("resolve", dnsName, addrData)
+	if err != nil {
+		return address, resolver, err
+	}
+	result, err := r.call(ctx, r.address, data)
+	if err != nil {
+		return address, resolver, err
+	}
+	out, err := r.abi.Unpack("resolve", result)
+	if err != nil {
+		return address, resolver, err
+	}
+	resolver = *abi.ConvertType(out[1], new(common.Address)).(*common.Address)
+	addrResult := *abi.ConvertType(out[0], new([]byte)).(*[]byte)
+	out, err = r.resolverAbi.Unpack("addr", addrResult)
+	if err != nil {
+		return address, resolver, err
+	}
+	return *abi.ConvertType(out[0], new(common.Address)).(*common.Address), resolver, nil
+}
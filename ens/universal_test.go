@@ -0,0 +1,85 @@
+package ens
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// fakeUniversalResolver answers the resolve calls of the names it knows with the address and the resolver of their wildcard parent
+type fakeUniversalResolver struct {
+	abi         abi.ABI
+	resolverAbi abi.ABI
+	resolver    common.Address
+	addresses   map[string]common.Address
+}
+
+func (r *fakeUniversalResolver) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return []byte{1}, nil
+}
+
+func (r *fakeUniversalResolver) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if !bytes.Equal(call.Data[:4], r.abi.Methods["resolve"].ID) {
+		return nil, fmt.Errorf("unexpected call %x", call.Data)
+	}
+	values, err := r.abi.Methods["resolve"].Inputs.Unpack(call.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	name, err := DecodeDnsName(values[0].([]byte))
+	if err != nil {
+		return nil, err
+	}
+	address, ok := r.addresses[name]
+	if !ok {
+		return nil, fmt.Errorf("execution reverted")
+	}
+	addrResult, err := r.resolverAbi.Methods["addr"].Outputs.Pack(address)
+	if err != nil {
+		return nil, err
+	}
+	return r.abi.Methods["resolve"].Outputs.Pack(addrResult, r.resolver)
+}
+
+func TestUniversalResolverResolveAddress(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(ensUniversalResolverData.ABI))
+	if err != nil {
+		t.Fatalf("error parsing abi: %v", err)
+	}
+	resolverAbi, err := abi.JSON(strings.NewReader(ensCcipReadData.ABI))
+	if err != nil {
+		t.Fatalf("error parsing abi: %v", err)
+	}
+	parentResolver := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	expected := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	fake := &fakeUniversalResolver{
+		abi:         parsed,
+		resolverAbi: resolverAbi,
+		resolver:    parentResolver,
+		addresses:   map[string]common.Address{"foo.uni.eth": expected},
+	}
+
+	universalResolver, err := NewUniversalResolver(common.HexToAddress(UniversalResolverAddress), fake)
+	if err != nil {
+		t.Fatalf("error creating universal resolver: %v", err)
+	}
+	address, resolver, err := universalResolver.ResolveAddress(context.Background(), "foo.uni.eth", [32]byte{1})
+	if err != nil {
+		t.Fatalf("error resolving address: %v", err)
+	}
+	if address != expected || resolver != parentResolver {
+		t.Errorf("expected address %v via resolver %v, got %v via %v", expected, parentResolver, address, resolver)
+	}
+
+	_, _, err = universalResolver.ResolveAddress(context.Background(), "bar.uni.eth", [32]byte{2})
+	if err == nil || !strings.Contains(err.Error(), "execution reverted") {
+		t.Errorf("expected a revert for an unknown subname, got %v", err)
+	}
+}
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"eth2-exporter/cache"
 	"eth2-exporter/db"
+	"eth2-exporter/rpc"
 	"eth2-exporter/types"
 	"eth2-exporter/utils"
 	"fmt"
@@ -45,6 +48,7 @@ func GetEnsDomain(search string) (*types.EnsDomainResponse, error) {
 	data := &types.EnsDomainResponse{}
 	var returnError error
 
+	search = utils.TrimEnsName(search)
 	if utils.IsValidEnsDomain(search) {
 		data.Domain = search
 
@@ -87,3 +91,158 @@ func GetEnsDomain(search string) (*types.EnsDomainResponse, error) {
 	}
 	return data, returnError //We always want to return the data if it was a valid address/domain even if there was an error getting data. A valid address might be enough for the caller.
 }
+
+// ApiEnsResolve godoc
+// @Summary Resolve an ens name to its address
+// @Tags Ens
+// @Description Returns the address, expiry and primary name status of an ens name. Names without a top level domain are qualified with ".eth".
+// @Produce  json
+// @Param name path string true "ens name"
+// @Success 200 {object} types.ApiResponse{data=types.EnsResolveResponse}
+// @Failure 404 {object} types.ApiResponse
+// @Router /api/v1/ens/resolve/{name} [get]
+func ApiEnsResolve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	name := utils.NormalizeEnsName(vars["name"])
+
+	data, err := resolveEnsName(r.Context(), name)
+	if err == sql.ErrNoRows {
+		sendErrorWithCodeResponse(w, r.URL.String(), fmt.Sprintf("ens name %v is not registered or expired", name), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Errorf("error resolving ens name %v: %v", name, err)
+		sendServerErrorResponse(w, r.URL.String(), "could not resolve ens name")
+		return
+	}
+
+	j := json.NewEncoder(w)
+	sendOKResponse(j, r.URL.String(), []interface{}{data})
+}
+
+// ensNodeResolveTimeout limits the resolution of a name via the node, which consists of several rate limited rpc calls
+const ensNodeResolveTimeout = time.Second * 10
+
+// resolveEnsName looks up a name in the db and falls back to resolving it via the node if it has not been indexed yet.
+// It returns sql.ErrNoRows for names that are not registered or expired.
+func resolveEnsName(ctx context.Context, name string) (*types.EnsResolveResponse, error) {
+	record, err := db.GetEnsRecordForName(name)
+	if err == nil && record.Address != nil {
+		return &types.EnsResolveResponse{Name: name, Address: common.BytesToAddress(record.Address).Hex(), ValidTo: record.ValidTo, IsPrimaryName: record.IsPrimaryName}, nil
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if rpc.CurrentErigonClient == nil {
+		return nil, sql.ErrNoRows
+	}
+	return db.ResolveEnsNameFromNode(ctx, rpc.CurrentErigonClient.GetNativeClient(), name, ensNodeResolveTimeout)
+}
+
+// ApiEnsReverseLookup godoc
+// @Summary Get the primary ens name of an address
+// @Tags Ens
+// @Description Returns the primary name of an address with its avatar and expiry, an address without primary name is returned with a null name.
+// @Produce  json
+// @Param address path string true "address, checksummed or lowercase"
+// @Success 200 {object} types.ApiResponse{data=types.EnsLookupResponse}
+// @Failure 400 {object} types.ApiResponse
+// @Router /api/v1/ens/reverse/{address} [get]
+func ApiEnsReverseLookup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	search := vars["address"]
+
+	if !utils.IsValidEth1Address(search) {
+		sendErrorResponse(w, r.URL.String(), fmt.Sprintf("invalid address %v", search))
+		return
+	}
+	data, err := lookupEnsName(common.HexToAddress(search))
+	if err != nil {
+		logger.Errorf("error looking up ens name of address %v: %v", search, err)
+		sendServerErrorResponse(w, r.URL.String(), "could not look up ens name")
+		return
+	}
+
+	j := json.NewEncoder(w)
+	sendOKResponse(j, r.URL.String(), []interface{}{data})
+}
+
+// lookupEnsName returns the primary name of an address with its avatar and expiry, the name is nil if the address has no primary name
+func lookupEnsName(address common.Address) (*types.EnsLookupResponse, error) {
+	data := &types.EnsLookupResponse{Address: address.Hex()}
+	identity, err := db.GetEnsIdentity(address)
+	if err == sql.ErrNoRows {
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	validTo, _, err := db.GetEnsNameValidity(identity.Name)
+	if err == sql.ErrNoRows {
+		// the name expired in between the queries
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data.Name = &identity.Name
+	data.AvatarUrl = identity.AvatarUrl
+	data.ValidTo = &validTo
+	return data, nil
+}
+
+// maxEnsLookupAddresses is the maximum number of addresses of a single batch lookup
+const maxEnsLookupAddresses = 100
+
+// ApiEnsLookupAddresses godoc
+// @Summary Get the primary ens names of multiple addresses
+// @Tags Ens
+// @Description Returns a map of the given addresses to their primary ens name, addresses without primary name are mapped to null. At most 100 addresses are accepted.
+// @Accept  json
+// @Produce  json
+// @Param request body types.EnsLookupRequest true "addresses, checksummed or lowercase"
+// @Success 200 {object} types.ApiResponse{data=map[string]string}
+// @Failure 400 {object} types.ApiResponse
+// @Router /api/v1/ens/lookup [post]
+func ApiEnsLookupAddresses(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	req := &types.EnsLookupRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		sendErrorResponse(w, r.URL.String(), "error decoding request body")
+		return
+	}
+	if len(req.Addresses) > maxEnsLookupAddresses {
+		sendErrorResponse(w, r.URL.String(), fmt.Sprintf("at most %v addresses can be looked up at once", maxEnsLookupAddresses))
+		return
+	}
+	addresses := make([]common.Address, 0, len(req.Addresses))
+	for _, address := range req.Addresses {
+		if !utils.IsValidEth1Address(address) {
+			sendErrorResponse(w, r.URL.String(), fmt.Sprintf("invalid address %v", address))
+			return
+		}
+		addresses = append(addresses, common.HexToAddress(address))
+	}
+
+	names, err := db.GetEnsNamesForAddresses(addresses)
+	if err != nil {
+		logger.Errorf("error looking up ens names of addresses: %v", err)
+		sendServerErrorResponse(w, r.URL.String(), "could not look up ens names")
+		return
+	}
+	data := make(map[string]*string, len(addresses))
+	for _, address := range addresses {
+		data[address.Hex()] = nil
+		if name, ok := names[address]; ok {
+			data[address.Hex()] = &name
+		}
+	}
+
+	j := json.NewEncoder(w)
+	sendOKResponse(j, r.URL.String(), []interface{}{data})
+}
@@ -65,6 +65,39 @@ var (
 		Name: "notifications_sent",
 		Help: "Counter of notifications sent with the channel and notification type in the label",
 	}, []string{"channel", "status"})
+	EnsKeysRead = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "ens_keys_read",
+		Help: "Counter of dirty ens keys read by the ens update runs",
+	})
+	EnsNamesValidated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ens_names_validated",
+		Help: "Counter of validated ens names with the outcome (resolved, removed, retried, failed) in the label",
+	}, []string{"outcome"})
+	EnsResolveDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ens_resolve_duration",
+		Help:    "Duration of the node calls resolving an ens name in seconds",
+		Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	})
+	EnsDirtyKeys = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ens_dirty_keys",
+		Help: "Number of dirty ens keys waiting for validation, counted periodically",
+	})
+	EnsLastImportSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ens_last_import_success",
+		Help: "Unix timestamp of the end of the last ens update run that validated all of its batches",
+	})
+	EnsLastImportDuration = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ens_last_import_duration",
+		Help: "Duration of the last successful ens update run in seconds",
+	})
+	EnsConsistencyMismatches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ens_consistency_mismatches",
+		Help: "Counter of stored ens names that differ from the chain with the kind of mismatch (address, expiry, primary) in the label",
+	}, []string{"kind"})
+	EnsConsistencyChecked = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "ens_consistency_checked",
+		Help: "Counter of stored ens names compared against the chain by the consistency checker",
+	})
 )
 
 var logger = logrus.New().WithField("module", "metrics")
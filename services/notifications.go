@@ -108,6 +108,13 @@ func notificationCollector() {
 
 			queueNotifications(notifications, db.FrontendWriterDB) // this caused the collected notifications to be queued and sent
 
+			// the ens primary name changes are queued on their own, they are only marked as notified once they were queued
+			err = queueEnsPrimaryNameChangedNotifications(epoch)
+			if err != nil {
+				metrics.Errors.WithLabelValues("notifications_collect_ens_primary_name_changed").Inc()
+				logger.Errorf("error queuing ens primary name change notifications: %v", err)
+			}
+
 			// Network DB Notifications (user related, must only run on one instance ever!!!!)
 			if utils.Config.Notifications.UserDBNotifications {
 				userNotifications, err := collectUserDbNotifications(epoch)
@@ -376,7 +383,8 @@ func collectUserDbNotifications(epoch uint64) (map[uint64]map[types.EventName][]
 	return notificationsByUserID, nil
 }
 
-func queueNotifications(notificationsByUserID map[uint64]map[types.EventName][]types.Notification, useDB *sqlx.DB) {
+// queueNotifications queues the given notifications for all channels, it returns the first error of the channels that failed to queue
+func queueNotifications(notificationsByUserID map[uint64]map[types.EventName][]types.Notification, useDB *sqlx.DB) error {
 	subByEpoch := map[uint64][]uint64{}
 
 	// prevent multiple events being sent with the same subscription id
@@ -399,19 +407,27 @@ func queueNotifications(notificationsByUserID map[uint64]map[types.EventName][]t
 		}
 	}
 
+	var queueErr error
 	err := queueEmailNotifications(notificationsByUserID, useDB)
 	if err != nil {
 		logger.WithError(err).Error("error queuing email notifications")
+		queueErr = fmt.Errorf("error queuing email notifications: %w", err)
 	}
 
 	err = queuePushNotification(notificationsByUserID, useDB)
 	if err != nil {
 		logger.WithError(err).Error("error queuing push notifications")
+		if queueErr == nil {
+			queueErr = fmt.Errorf("error queuing push notifications: %w", err)
+		}
 	}
 
 	err = queueWebhookNotifications(notificationsByUserID, useDB)
 	if err != nil {
 		logger.WithError(err).Error("error queuing webhook notifications")
+		if queueErr == nil {
+			queueErr = fmt.Errorf("error queuing webhook notifications: %w", err)
+		}
 	}
 
 	for _, events := range notificationsByUserID {
@@ -464,6 +480,7 @@ func queueNotifications(notificationsByUserID map[uint64]map[types.EventName][]t
 			logger.Errorf("failed to update internal state of notifcations: %v", err)
 		}
 	}
+	return queueErr
 }
 
 func dispatchNotifications(useDB *sqlx.DB) error {
@@ -2575,6 +2592,154 @@ func collectTaxReportNotificationNotifications(notificationsByUserID map[uint64]
 	return nil
 }
 
+type ensPrimaryNameChangedNotification struct {
+	SubscriptionID  uint64
+	UserID          uint64
+	Epoch           uint64
+	EventFilter     string
+	UnsubscribeHash sql.NullString
+	Address         []byte
+	OldName         string
+	NewName         string
+}
+
+func (n *ensPrimaryNameChangedNotification) GetLatestState() string {
+	return ""
+}
+
+func (n *ensPrimaryNameChangedNotification) GetUnsubscribeHash() string {
+	if n.UnsubscribeHash.Valid {
+		return n.UnsubscribeHash.String
+	}
+	return ""
+}
+
+func (n *ensPrimaryNameChangedNotification) GetEmailAttachment() *types.EmailAttachment {
+	return nil
+}
+
+func (n *ensPrimaryNameChangedNotification) GetSubscriptionID() uint64 {
+	return n.SubscriptionID
+}
+
+func (n *ensPrimaryNameChangedNotification) GetEpoch() uint64 {
+	return n.Epoch
+}
+
+func (n *ensPrimaryNameChangedNotification) GetEventName() types.EventName {
+	return types.EnsPrimaryNameChanged
+}
+
+func (n *ensPrimaryNameChangedNotification) GetInfo(includeUrl bool) string {
+	generalPart := fmt.Sprintf(`The ENS primary name of address 0x%x changed from %v to %v.`, n.Address, n.OldName, n.NewName)
+	if includeUrl {
+		return generalPart + fmt.Sprintf(" https://%s/address/0x%x", utils.Config.Frontend.SiteDomain, n.Address)
+	}
+	return generalPart
+}
+
+func (n *ensPrimaryNameChangedNotification) GetTitle() string {
+	return "ENS Primary Name Changed"
+}
+
+func (n *ensPrimaryNameChangedNotification) GetEventFilter() string {
+	return n.EventFilter
+}
+
+func (n *ensPrimaryNameChangedNotification) GetInfoMarkdown() string {
+	return fmt.Sprintf(`The ENS primary name of address [0x%[1]x](https://%[2]v/address/0x%[1]x) changed from %[3]v to %[4]v.`, n.Address, utils.Config.Frontend.SiteDomain, n.OldName, n.NewName)
+}
+
+// queueEnsPrimaryNameChangedNotifications queues the notifications of the unnotified primary name changes and marks the changes as
+// notified afterwards. A change whose notifications could not be queued is collected again for the next epoch.
+func queueEnsPrimaryNameChangedNotifications(epoch uint64) error {
+	start := time.Now()
+	notificationsByUserID := map[uint64]map[types.EventName][]types.Notification{}
+	ids, err := collectEnsPrimaryNameChangedNotifications(notificationsByUserID, types.EnsPrimaryNameChanged, epoch)
+	if err != nil {
+		return fmt.Errorf("error collecting ens primary name changes: %w", err)
+	}
+	logger.Infof("collecting ens primary name changes took: %v\n", time.Since(start))
+	if len(ids) == 0 {
+		return nil
+	}
+	err = queueNotifications(notificationsByUserID, db.FrontendWriterDB)
+	if err != nil {
+		return err
+	}
+	return db.MarkEnsPrimaryNameChangesNotified(ids)
+}
+
+// collectEnsPrimaryNameChangedNotifications notifies the users subscribed to an address (the event filter is the lowercase hex address
+// without 0x prefix) about the primary name changes recorded by the ens validation and returns the ids of the collected changes.
+// The subscriptions of all changed addresses are read at once.
+func collectEnsPrimaryNameChangedNotifications(notificationsByUserID map[uint64]map[types.EventName][]types.Notification, eventName types.EventName, epoch uint64) ([]uint64, error) {
+	changes, err := db.GetUnnotifiedEnsPrimaryNameChanges()
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	name := string(eventName)
+	if utils.Config.Chain.Config.ConfigName != "" {
+		name = utils.Config.Chain.Config.ConfigName + ":" + name
+	}
+
+	ids := make([]uint64, 0, len(changes))
+	filters := make([]string, 0, len(changes))
+	changesByFilter := make(map[string][]types.EnsPrimaryNameChange, len(changes))
+	for _, change := range changes {
+		ids = append(ids, change.ID)
+		filter := fmt.Sprintf("%x", change.Address)
+		if _, exists := changesByFilter[filter]; !exists {
+			filters = append(filters, filter)
+		}
+		changesByFilter[filter] = append(changesByFilter[filter], change)
+	}
+
+	var dbResult []struct {
+		SubscriptionID  uint64         `db:"id"`
+		UserID          uint64         `db:"user_id"`
+		EventFilter     string         `db:"event_filter"`
+		UnsubscribeHash sql.NullString `db:"unsubscribe_hash"`
+	}
+	err = db.FrontendWriterDB.Select(&dbResult, `
+		SELECT us.id, us.user_id, us.event_filter, ENCODE(us.unsubscribe_hash, 'hex') as unsubscribe_hash
+		FROM users_subscriptions AS us
+		WHERE us.event_name=$1 AND us.event_filter = ANY($2)
+		`,
+		name, pq.StringArray(filters))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range dbResult {
+		for _, change := range changesByFilter[r.EventFilter] {
+			n := &ensPrimaryNameChangedNotification{
+				SubscriptionID:  r.SubscriptionID,
+				UserID:          r.UserID,
+				Epoch:           epoch,
+				EventFilter:     r.EventFilter,
+				UnsubscribeHash: r.UnsubscribeHash,
+				Address:         change.Address,
+				OldName:         change.OldName,
+				NewName:         change.NewName,
+			}
+			if _, exists := notificationsByUserID[r.UserID]; !exists {
+				notificationsByUserID[r.UserID] = map[types.EventName][]types.Notification{}
+			}
+			if _, exists := notificationsByUserID[r.UserID][n.GetEventName()]; !exists {
+				notificationsByUserID[r.UserID][n.GetEventName()] = []types.Notification{}
+			}
+			notificationsByUserID[r.UserID][n.GetEventName()] = append(notificationsByUserID[r.UserID][n.GetEventName()], n)
+			metrics.NotificationsCollected.WithLabelValues(string(n.GetEventName())).Inc()
+		}
+	}
+	return ids, nil
+}
+
 type networkNotification struct {
 	SubscriptionID  uint64
 	UserID          uint64
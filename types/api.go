@@ -715,3 +715,22 @@ type EnsDomainResponse struct {
 	Address string `json:"address"`
 	Domain  string `json:"domain"`
 }
+
+// EnsLookupResponse is the primary name of an address, the fields of the name are null if the address has no primary name
+type EnsLookupResponse struct {
+	Address   string     `json:"address"`
+	Name      *string    `json:"name"`
+	AvatarUrl *string    `json:"avatar_url"`
+	ValidTo   *time.Time `json:"valid_to"`
+}
+
+type EnsLookupRequest struct {
+	Addresses []string `json:"addresses"`
+}
+
+type EnsResolveResponse struct {
+	Name          string    `json:"name"`
+	Address       string    `json:"address"`
+	ValidTo       time.Time `json:"valid_to"`
+	IsPrimaryName bool      `json:"is_primary_name"`
+}
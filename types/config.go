@@ -66,7 +66,61 @@ type Config struct {
 			Enabled bool `yaml:"enabled" envconfig:"PUBKEY_TAGS_EXPORTER_ENABLED"`
 		} `yaml:"pubkeyTagsExporter"`
 		EnsTransformer struct {
-			ValidRegistrarContracts []string `yaml:"validRegistrarContracts" envconfig:"ENS_VALID_REGISTRAR_CONTRACTS"`
+			ValidRegistrarContracts      []string `yaml:"validRegistrarContracts" envconfig:"ENS_VALID_REGISTRAR_CONTRACTS"`
+			BaseRegistrarContract        string   `yaml:"baseRegistrarContract" envconfig:"ENS_BASE_REGISTRAR_CONTRACT"`
+			AutoUpdateRegistrarContracts bool     `yaml:"autoUpdateRegistrarContracts" envconfig:"ENS_AUTO_UPDATE_REGISTRAR_CONTRACTS"`
+			StoreAddressHex              bool     `yaml:"storeAddressHex" envconfig:"ENS_STORE_ADDRESS_HEX"`
+			ConfirmationDepth            uint64   `yaml:"confirmationDepth" envconfig:"ENS_CONFIRMATION_DEPTH"`
+			MatchEmittingContract        bool     `yaml:"matchEmittingContract" envconfig:"ENS_MATCH_EMITTING_CONTRACT"`
+			// ConfirmationStartBlock is the block the confirmed ens indexing starts at when no block has been indexed yet, it is required with a ConfirmationDepth
+			ConfirmationStartBlock uint64 `yaml:"confirmationStartBlock" envconfig:"ENS_CONFIRMATION_START_BLOCK"`
+			// LegacyRegistrarContracts are the legacy auction registrar and early controllers, their txs are indexed by the HashRegistered
+			// and legacy NameRegistered events to backfill the names registered before the current controllers
+			LegacyRegistrarContracts []string `yaml:"legacyRegistrarContracts" envconfig:"ENS_LEGACY_REGISTRAR_CONTRACTS"`
+			// EventTopics replaces the built-in topics of events (e.g. NameRegistered) by hex encoded topics, for registrars that emit differently signed events
+			EventTopics map[string]string `yaml:"eventTopics" envconfig:"ENS_EVENT_TOPICS"`
+			// NameHashRoots maps name suffixes of non canonical naming services to the hex encoded base node of their names
+			NameHashRoots        map[string]string `yaml:"nameHashRoots" envconfig:"ENS_NAME_HASH_ROOTS"`
+			NameWrapperContract  string            `yaml:"nameWrapperContract" envconfig:"ENS_NAME_WRAPPER_CONTRACT"`
+			EnableEnsip19Reverse bool              `yaml:"enableEnsip19Reverse" envconfig:"ENS_ENABLE_ENSIP19_REVERSE"`
+			// Ensip19CoinTypes are the coin types whose reverse namespaces are checked, defaults to the default evm reverse namespace
+			Ensip19CoinTypes []uint64 `yaml:"ensip19CoinTypes" envconfig:"ENS_ENSIP19_COIN_TYPES"`
+			// RpcRateLimit limits the rpc lookups of the ens validation per second, 0 disables the limit. The limit is shared by all batches
+			// and update runs of the process, so it can be set to the quota of the provider minus some headroom
+			RpcRateLimit float64 `yaml:"rpcRateLimit" envconfig:"ENS_RPC_RATE_LIMIT"`
+			// RevalidationPeriod is the maximum staleness of a name, all names are requeued for validation within this period, 0 disables the requeueing
+			RevalidationPeriod time.Duration `yaml:"revalidationPeriod" envconfig:"ENS_REVALIDATION_PERIOD"`
+			// RevalidationInterval is the interval of the requeueing runs, defaults to one hour
+			RevalidationInterval time.Duration `yaml:"revalidationInterval" envconfig:"ENS_REVALIDATION_INTERVAL"`
+			// ExpiryWindowDays requeues the addresses of names expiring or expired within this many days on every revalidation interval, 0 disables it
+			ExpiryWindowDays int `yaml:"expiryWindowDays" envconfig:"ENS_EXPIRY_WINDOW_DAYS"`
+			// BatchSize is the number of dirty keys validated per batch of an ens update run, defaults to 100
+			BatchSize int `yaml:"batchSize" envconfig:"ENS_BATCH_SIZE"`
+			// NotifyPrimaryNameChanges notifies subscribed users about the primary name changes of addresses, the changes are recorded either way
+			NotifyPrimaryNameChanges bool `yaml:"notifyPrimaryNameChanges" envconfig:"ENS_NOTIFY_PRIMARY_NAME_CHANGES"`
+			// EnableCcipRead resolves names of offchain and L2 resolvers by querying their ccip-read (EIP-3668) gateways via outbound http requests
+			EnableCcipRead bool `yaml:"enableCcipRead" envconfig:"ENS_ENABLE_CCIP_READ"`
+			// Concurrency is the number of keys of a batch that are validated at once, defaults to 10
+			Concurrency int `yaml:"concurrency" envconfig:"ENS_CONCURRENCY"`
+			// ReadTimeoutSeconds limits the scan of the dirty keys of an ens update run, defaults to 30 seconds
+			ReadTimeoutSeconds int `yaml:"readTimeoutSeconds" envconfig:"ENS_READ_TIMEOUT_SECONDS"`
+			// ResolveTimeoutSeconds limits the validation of a single name or address, 0 disables the limit
+			ResolveTimeoutSeconds int `yaml:"resolveTimeoutSeconds" envconfig:"ENS_RESOLVE_TIMEOUT_SECONDS"`
+			// ResolveCacheTTL is the time resolved names and primary names are cached across ens update runs, defaults to one hour
+			ResolveCacheTTL time.Duration `yaml:"resolveCacheTTL" envconfig:"ENS_RESOLVE_CACHE_TTL"`
+			// RetryAttempts is the number of attempts of a node call that fails with a transient error, defaults to 3
+			RetryAttempts int `yaml:"retryAttempts" envconfig:"ENS_RETRY_ATTEMPTS"`
+			// BackfillBatchSize is the number of blocks transformed per batch of an ens backfill, the backfill resumes after the last completed batch, defaults to 1000
+			BackfillBatchSize int `yaml:"backfillBatchSize" envconfig:"ENS_BACKFILL_BATCH_SIZE"`
+			// MulticallContract is the Multicall3 contract the validation batches its node calls with, defaults to the canonical Multicall3 deployment
+			MulticallContract string `yaml:"multicallContract" envconfig:"ENS_MULTICALL_CONTRACT"`
+			// EnableWildcardSubnames resolves names without a node of their own through the Universal Resolver (ENSIP-10) and requeues the known
+			// subnames of wildcard parents whose records changed
+			EnableWildcardSubnames bool `yaml:"enableWildcardSubnames" envconfig:"ENS_ENABLE_WILDCARD_SUBNAMES"`
+			// UniversalResolverContract is the Universal Resolver wildcard subnames are resolved with, defaults to the mainnet deployment
+			UniversalResolverContract string `yaml:"universalResolverContract" envconfig:"ENS_UNIVERSAL_RESOLVER_CONTRACT"`
+			// AuditRetentionDays is the number of days the outcomes of the name validations are kept in the validation audit, defaults to 30 days
+			AuditRetentionDays int `yaml:"auditRetentionDays" envconfig:"ENS_AUDIT_RETENTION_DAYS"`
 		} `yaml:"ensTransformer"`
 	} `yaml:"indexer"`
 	Frontend struct {
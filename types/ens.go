@@ -0,0 +1,170 @@
+package types
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+)
+
+// EnsName is a row of the ens table
+type EnsName struct {
+	NameHash      []byte     `db:"name_hash" json:"name_hash"`
+	Name          string     `db:"ens_name" json:"name"`
+	Address       []byte     `db:"address" json:"address"`
+	IsPrimaryName bool       `db:"is_primary_name" json:"is_primary_name"`
+	ValidTo       time.Time  `db:"valid_to" json:"valid_to"`
+	RegisteredAt  *time.Time `db:"registered_at" json:"registered_at,omitempty"`
+}
+
+// EnsRecord is the resolution of a name that has not expired yet
+type EnsRecord struct {
+	NameHash      []byte    `db:"name_hash" json:"name_hash"`
+	Address       []byte    `db:"address" json:"address"`
+	ValidTo       time.Time `db:"valid_to" json:"valid_to"`
+	IsPrimaryName bool      `db:"is_primary_name" json:"is_primary_name"`
+}
+
+// EnsValidationStats summarizes the outcome of ens name validations within a time window
+type EnsValidationStats struct {
+	Validated    uint64  `db:"validated" json:"validated"`
+	Resolved     uint64  `db:"resolved" json:"resolved"`
+	Removed      uint64  `db:"removed" json:"removed"`
+	Retried      uint64  `db:"retried" json:"retried"`
+	AvgLatencyMs float64 `db:"avg_latency_ms" json:"avg_latency_ms"`
+}
+
+// EnsStats are the counters of the indexed ens names
+type EnsStats struct {
+	Total        uint64 `db:"total" json:"total"`
+	PrimaryNames uint64 `db:"primary_names" json:"primary_names"`
+	Active       uint64 `db:"active" json:"active"`
+	// Expiring is the number of active names that expire within the next 30 days
+	Expiring  uint64 `db:"expiring" json:"expiring"`
+	Addresses uint64 `db:"addresses" json:"addresses"`
+}
+
+// EnsPrimaryNameChange is a change of the primary name of an address that has not been notified yet
+type EnsPrimaryNameChange struct {
+	ID      uint64    `db:"id"`
+	Address []byte    `db:"address"`
+	OldName string    `db:"old_name"`
+	NewName string    `db:"new_name"`
+	Ts      time.Time `db:"ts"`
+}
+
+// EnsIdentity is the primary name of an address together with its avatar
+type EnsIdentity struct {
+	Name      string  `db:"ens_name" json:"name"`
+	AvatarUrl *string `db:"avatar_url" json:"avatar_url"`
+	// Verified is true if the name resolves to the address and the reverse record of the address points to the name
+	Verified bool `db:"verified" json:"verified"`
+}
+
+// EnsTransfer is a transfer of a .eth name token by the base registrar
+type EnsTransfer struct {
+	NameHash    []byte    `db:"name_hash" json:"name_hash"`
+	TxHash      []byte    `db:"tx_hash" json:"tx_hash"`
+	LogIndex    uint64    `db:"log_index" json:"log_index"`
+	BlockNumber uint64    `db:"block_number" json:"block_number"`
+	Ts          time.Time `db:"ts" json:"ts"`
+	From        []byte    `db:"from_address" json:"from"`
+	To          []byte    `db:"to_address" json:"to"`
+	// Kind is "mint" for transfers from the zero address, "burn" for transfers to the zero address and "transfer" otherwise
+	Kind string `db:"-" json:"kind"`
+}
+
+// EnsNameRecord is a name an address is or was associated with, as resolved address, primary name, registrant or token holder
+type EnsNameRecord struct {
+	NameHash      []byte    `db:"name_hash" json:"name_hash"`
+	Name          string    `db:"ens_name" json:"name"`
+	ValidTo       time.Time `db:"valid_to" json:"valid_to"`
+	IsCurrent     bool      `db:"is_current" json:"is_current"`
+	IsPrimaryName bool      `db:"is_primary_name" json:"is_primary_name"`
+}
+
+// EnsRecentRegistration is a registration read from the registration time index
+type EnsRecentRegistration struct {
+	NameHash []byte    `json:"name_hash"`
+	Name     string    `json:"name"`
+	Ts       time.Time `json:"ts"`
+}
+
+// EnsSearchResult is a name matched by SearchEns
+type EnsSearchResult struct {
+	Name          string    `db:"ens_name" json:"name"`
+	Address       []byte    `db:"address" json:"address"`
+	IsPrimaryName bool      `db:"is_primary_name" json:"is_primary_name"`
+	ValidTo       time.Time `db:"valid_to" json:"valid_to"`
+	// MatchedOn is "name", "address" or "owner" depending on which field matched the query
+	MatchedOn string `db:"matched_on" json:"matched_on"`
+}
+
+// EnsImportResult reports the outcome of seeding names from a file
+type EnsImportResult struct {
+	Total     int `json:"total"`
+	Validated int `json:"validated"`
+	Failed    int `json:"failed"`
+	// Skipped contains the malformed lines that were not validated
+	Skipped []EnsImportSkippedLine `json:"skipped"`
+}
+
+type EnsImportSkippedLine struct {
+	Line   int    `json:"line"`
+	Text   string `json:"text"`
+	Reason string `json:"reason"`
+}
+
+// EnsDailyCount is the number of ens events of a single day
+type EnsDailyCount struct {
+	Day   time.Time `db:"day" json:"day"`
+	Count int       `db:"count" json:"count"`
+}
+
+// EnsReferrerCount is the number of registrations referred by a referrer
+type EnsReferrerCount struct {
+	Referrer common.Address `json:"referrer"`
+	Count    int            `json:"count"`
+}
+
+// EnsRegistrationCost is a registration of a name with the price paid for it
+type EnsRegistrationCost struct {
+	Name    string          `db:"ens_name" json:"name"`
+	TxHash  []byte          `db:"tx_hash" json:"tx_hash"`
+	Ts      time.Time       `db:"ts" json:"ts"`
+	CostWei decimal.Decimal `db:"cost_wei" json:"cost_wei"`
+}
+
+// EnsConsistencyReport is the outcome of comparing a sample of stored names against the chain
+type EnsConsistencyReport struct {
+	Checked int `json:"checked"`
+	// Failed is the number of names that could not be compared as the node calls failed
+	Failed     int                      `json:"failed"`
+	Mismatches []EnsConsistencyMismatch `json:"mismatches"`
+}
+
+// EnsConsistencyMismatch is a stored value of a name that differs from the chain
+type EnsConsistencyMismatch struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Stored string `json:"stored"`
+	Actual string `json:"actual"`
+}
+
+// EnsExportFilter restricts an export of the ens table, unset fields do not filter
+type EnsExportFilter struct {
+	Owner *common.Address
+	// ValidToFrom and ValidToUntil select the names expiring within the window
+	ValidToFrom  *time.Time
+	ValidToUntil *time.Time
+}
+
+// EnsExportRecord is a row of an export of the ens table, the addresses are hex encoded and empty if unknown
+type EnsExportRecord struct {
+	Name          string     `json:"name"`
+	Address       string     `json:"address"`
+	Owner         string     `json:"owner"`
+	ValidTo       *time.Time `json:"valid_to"`
+	IsPrimaryName bool       `json:"is_primary_name"`
+	Resolver      string     `json:"resolver"`
+}
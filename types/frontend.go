@@ -49,6 +49,7 @@ const (
 	RocketpoolCollateralMinReached                   EventName = "rocketpool_colleteral_min"
 	RocketpoolCollateralMaxReached                   EventName = "rocketpool_colleteral_max"
 	SyncCommitteeSoon                                EventName = "validator_synccommittee_soon"
+	EnsPrimaryNameChanged                            EventName = "ens_primary_name_changed"
 )
 
 var UserIndexEvents = []EventName{
@@ -92,6 +93,7 @@ var EventLabel map[EventName]string = map[EventName]string{
 	RocketpoolCollateralMinReached:                   "You reached the rocketpool min collateral",
 	RocketpoolCollateralMaxReached:                   "You reached the rocketpool max collateral",
 	SyncCommitteeSoon:                                "Your validator(s) will soon be part of the sync committee",
+	EnsPrimaryNameChanged:                            "The ENS primary name of a watched address changed",
 }
 
 func IsUserIndexed(event EventName) bool {
@@ -132,6 +134,7 @@ var EventNames = []EventName{
 	RocketpoolCollateralMinReached,
 	RocketpoolCollateralMaxReached,
 	SyncCommitteeSoon,
+	EnsPrimaryNameChanged,
 }
 
 type EventNameDesc struct {
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"regexp"
+	"strings"
 )
 
 var ENS_ETH_REGEXP = regexp.MustCompile(`^.{3,}\.eth$`)
@@ -9,3 +10,19 @@ var ENS_ETH_REGEXP = regexp.MustCompile(`^.{3,}\.eth$`)
 func IsValidEnsDomain(text string) bool {
 	return ENS_ETH_REGEXP.MatchString(text)
 }
+
+// TrimEnsName removes surrounding whitespace and a single trailing dot of the fully qualified form from a user supplied name,
+// e.g. " name.eth. " becomes "name.eth"
+func TrimEnsName(name string) string {
+	return strings.TrimSuffix(strings.TrimSpace(name), ".")
+}
+
+// NormalizeEnsName brings a user supplied name into the form names are validated and stored in, it is trimmed, lowercased and
+// qualified with ".eth" if it has no top level domain, e.g. " Vitalik " becomes "vitalik.eth" while "name.xyz" stays as is
+func NormalizeEnsName(name string) string {
+	name = strings.ToLower(TrimEnsName(name))
+	if !strings.Contains(name, ".") {
+		name = name + ".eth"
+	}
+	return name
+}
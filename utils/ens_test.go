@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestTrimEnsName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"name.eth", "name.eth"},
+		{" name.eth. ", "name.eth"},
+		{"name.eth.", "name.eth"},
+		{"\tname.eth\n", "name.eth"},
+		{"name.eth..", "name.eth."},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := TrimEnsName(tt.name); got != tt.expected {
+			t.Errorf("wrong trimmed name for %q, expected %q got %q", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestNormalizeEnsName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"vitalik.eth", "vitalik.eth"},
+		{"Vitalik", "vitalik.eth"},
+		{" VITALIK.ETH. ", "vitalik.eth"},
+		{"sub.vitalik.eth", "sub.vitalik.eth"},
+		{"Name.XYZ", "name.xyz"},
+		{"sub.name.art.", "sub.name.art"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeEnsName(tt.name); got != tt.expected {
+			t.Errorf("wrong normalized name for %q, expected %q got %q", tt.name, tt.expected, got)
+		}
+	}
+}